@@ -44,6 +44,23 @@ const (
 	// annotation is present and status is empty, BMO will reconstruct BMH Status
 	// from the status annotation.
 	StatusAnnotation = "baremetalhost.metal3.io/status"
+
+	// RebootAnnotationPrefix is the prefix of the annotations that
+	// reboot the host. The host stays powered off while an annotation
+	// with a suffix, such as reboot.metal3.io/my-tool, is present,
+	// and is rebooted once when the annotation has no suffix. The
+	// value may hold RebootAnnotationArguments.
+	RebootAnnotationPrefix = "reboot.metal3.io"
+
+	// InspectAnnotation is the annotation that requests a new
+	// inspection of a ready host. The operator removes it once the
+	// inspection starts.
+	InspectAnnotation = "inspect.metal3.io"
+
+	// SharedCredentialsLabel is the label marking a BMC credentials
+	// secret used by several hosts. Such secrets are not owned by the
+	// hosts, so that deleting one of them does not delete the secret.
+	SharedCredentialsLabel = "baremetalhost.metal3.io/shared-credentials"
 )
 
 // RootDeviceHints holds the hints for specifying the storage location
@@ -211,6 +228,55 @@ type BMCDetails struct {
 	// insecure because it allows a man-in-the-middle to intercept the
 	// connection.
 	DisableCertificateVerification bool `json:"disableCertificateVerification,omitempty"`
+
+	// CABundleName is the name of a ConfigMap holding, under its
+	// ca.crt key, the certificates of the authorities the server
+	// certificate of the BMC is verified against when using HTTPS.
+	CABundleName string `json:"caBundleName,omitempty"`
+
+	// CertificateFingerprint pins the server certificate of the BMC
+	// by its SHA-256 fingerprint, as hexadecimal bytes optionally
+	// separated by colons. The certificate must be self-signed and
+	// valid for the address of the BMC.
+	// +kubebuilder:validation:Pattern=`^([0-9A-Fa-f]{2}:?){31}[0-9A-Fa-f]{2}$`
+	CertificateFingerprint string `json:"certificateFingerprint,omitempty"`
+}
+
+// BMCCertificateStatus records what the server certificate of the BMC
+// is verified against.
+type BMCCertificateStatus struct {
+	// CABundle names the ConfigMap the CA bundle comes from, when the
+	// certificate is not pinned.
+	CABundle string `json:"caBundle,omitempty"`
+
+	// Fingerprint is the SHA-256 fingerprint of the pinned
+	// certificate.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// CAFile is the path, as seen by the provisioning backend, of the
+	// file holding the CA bundle or the pinned certificate.
+	CAFile string `json:"caFile"`
+}
+
+// DetectedBMC records the BMC type a redfish-auto BMC address was
+// resolved to.
+type DetectedBMC struct {
+	// Address is the redfish-auto address the BMC was detected at.
+	Address string `json:"address"`
+
+	// Type is the BMC type the address was resolved to, e.g.
+	// idrac-virtualmedia.
+	Type string `json:"type"`
+
+	// ResolvedAddress is the address of the BMC used in place of the
+	// redfish-auto one.
+	ResolvedAddress string `json:"resolvedAddress"`
+
+	// Vendor is the vendor of the BMC reported by Redfish.
+	Vendor string `json:"vendor,omitempty"`
+
+	// Model is the model of the manager reported by Redfish.
+	Model string `json:"model,omitempty"`
 }
 
 // BareMetalHostSpec defines the desired state of BareMetalHost
@@ -278,6 +344,31 @@ type BareMetalHostSpec struct {
 	// the power status and hardware inventory inspection. If the
 	// Image field is filled in, this field is ignored.
 	ExternallyProvisioned bool `json:"externallyProvisioned,omitempty"`
+
+	// DeployRamdisk overrides the deploy kernel and ramdisk used to
+	// boot the provisioning agent on this host. When it is not set,
+	// the images configured for the operator are used.
+	// +optional
+	DeployRamdisk *DeployRamdisk `json:"deployRamdisk,omitempty"`
+}
+
+// DeployRamdisk holds the details of the kernel and ramdisk used to
+// boot the provisioning agent on a host.
+type DeployRamdisk struct {
+	// Kernel is the URL of the deploy kernel. Defaults to the
+	// operator's deploy kernel when empty.
+	// +optional
+	Kernel string `json:"kernel,omitempty"`
+
+	// Ramdisk is the URL of the deploy ramdisk. Defaults to the
+	// operator's deploy ramdisk when empty.
+	// +optional
+	Ramdisk string `json:"ramdisk,omitempty"`
+
+	// ExtraKernelParams are appended to the kernel command line when
+	// booting the deploy ramdisk.
+	// +optional
+	ExtraKernelParams string `json:"extraKernelParams,omitempty"`
 }
 
 // ChecksumType holds the algorithm name for the checksum
@@ -317,6 +408,22 @@ type Image struct {
 	DiskFormat *string `json:"format,omitempty"`
 }
 
+// RebootMode defines the known variations of reboot modes
+type RebootMode string
+
+const (
+	// RebootModeHard defined for hard reset of a host
+	RebootModeHard RebootMode = "hard"
+	// RebootModeSoft defined for soft reset of a host
+	RebootModeSoft RebootMode = "soft"
+)
+
+// RebootAnnotationArguments defines the arguments of the reboot
+// annotations, given as JSON in their value
+type RebootAnnotationArguments struct {
+	Mode RebootMode `json:"mode"`
+}
+
 // FIXME(dhellmann): We probably want some other module to own these
 // data structures.
 
@@ -474,6 +581,11 @@ type HardwareSystemVendor struct {
 type CredentialsStatus struct {
 	Reference *corev1.SecretReference `json:"credentials,omitempty"`
 	Version   string                  `json:"credentialsVersion,omitempty"`
+
+	// Set is which of the sets of credentials of the secret is used,
+	// 0 for the username and password keys and N for the fallback
+	// credentials under the username-N and password-N keys.
+	Set int `json:"credentialsSet,omitempty"`
 }
 
 // Match compares the saved status information with the name and
@@ -546,6 +658,13 @@ type BareMetalHostStatus struct {
 	// Information tracked by the provisioner.
 	Provisioning ProvisionStatus `json:"provisioning"`
 
+	// what the server certificate of the BMC is verified against, when
+	// it is not the default CA bundle of the provisioning backend
+	BMCCertificate *BMCCertificateStatus `json:"bmcCertificate,omitempty"`
+
+	// the BMC type detected for a redfish-auto BMC address
+	DetectedBMC *DetectedBMC `json:"detectedBMC,omitempty"`
+
 	// the last credentials we were able to validate as working
 	GoodCredentials CredentialsStatus `json:"goodCredentials,omitempty"`
 
@@ -584,6 +703,14 @@ type ProvisionStatus struct {
 
 	// BootMode indicates the boot mode used to provision the node
 	BootMode BootMode `json:"bootMode,omitempty"`
+
+	// AgentVersion is the version of the provisioning agent last
+	// reported by the deploy ramdisk booted on the host.
+	AgentVersion string `json:"agentVersion,omitempty"`
+
+	// DeployRamdisk is the deploy ramdisk override last applied to
+	// the host, so that its settings can be removed with it.
+	DeployRamdisk *DeployRamdisk `json:"deployRamdisk,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -680,6 +807,17 @@ func (host *BareMetalHost) OperationalStatus() OperationalStatus {
 	return host.Status.OperationalStatus
 }
 
+// BMCAddress returns the address of the BMC, as resolved when the
+// address in the spec uses the redfish-auto type and has been
+// detected.
+func (host *BareMetalHost) BMCAddress() string {
+	detected := host.Status.DetectedBMC
+	if detected != nil && detected.Address == host.Spec.BMC.Address {
+		return detected.ResolvedAddress
+	}
+	return host.Spec.BMC.Address
+}
+
 // CredentialsKey returns a NamespacedName suitable for loading the
 // Secret containing the credentials associated with the host.
 func (host *BareMetalHost) CredentialsKey() types.NamespacedName {
@@ -0,0 +1,175 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: Update docs/configuration.md when changing these data structures.
+
+// MaxConcurrency is the largest number of hosts the operator can be
+// configured to reconcile at the same time.
+const MaxConcurrency = 50
+
+// IronicSettings holds the settings used to talk to ironic and
+// ironic-inspector. Fields that are not set keep the value given
+// through the operator's environment.
+type IronicSettings struct {
+	// Endpoint is the URL of the ironic API.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// InspectorEndpoint is the URL of the ironic-inspector API.
+	// +optional
+	InspectorEndpoint string `json:"inspectorEndpoint,omitempty"`
+
+	// TrustedCAFile is the path of the CA bundle used to verify
+	// the ironic and ironic-inspector certificates.
+	// +optional
+	TrustedCAFile string `json:"trustedCAFile,omitempty"`
+
+	// Insecure disables verification of the ironic and
+	// ironic-inspector certificates.
+	// +optional
+	Insecure *bool `json:"insecure,omitempty"`
+
+	// DeployKernelURL is the URL of the default deploy kernel.
+	// +optional
+	DeployKernelURL string `json:"deployKernelURL,omitempty"`
+
+	// DeployRamdiskURL is the URL of the default deploy ramdisk.
+	// +optional
+	DeployRamdiskURL string `json:"deployRamdiskURL,omitempty"`
+
+	// ProvisioningLimit is the number of hosts that may be
+	// inspected, cleaned or deployed at the same time.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProvisioningLimit *int `json:"provisioningLimit,omitempty"`
+
+	// DeprovisionRequeueDelay is how long to wait between checks
+	// while a host is being deprovisioned.
+	// +optional
+	DeprovisionRequeueDelay *metav1.Duration `json:"deprovisionRequeueDelay,omitempty"`
+
+	// ProvisionRequeueDelay is how long to wait between checks
+	// while a host is being registered or provisioned.
+	// +optional
+	ProvisionRequeueDelay *metav1.Duration `json:"provisionRequeueDelay,omitempty"`
+
+	// PowerRequeueDelay is how long to wait between checks while
+	// the power state of a host is changing.
+	// +optional
+	PowerRequeueDelay *metav1.Duration `json:"powerRequeueDelay,omitempty"`
+
+	// IntrospectionRequeueDelay is how long to wait between checks
+	// while a host is being inspected.
+	// +optional
+	IntrospectionRequeueDelay *metav1.Duration `json:"introspectionRequeueDelay,omitempty"`
+
+	// SoftPowerOffTimeout is how long to wait for a soft power off
+	// before it is considered to have failed.
+	// +optional
+	SoftPowerOffTimeout *metav1.Duration `json:"softPowerOffTimeout,omitempty"`
+}
+
+// IronicBackend holds the settings of an additional ironic deployment.
+// Hosts labelled with baremetalhost.metal3.io/ironic-backend set to
+// the name of the backend are managed through it.
+type IronicBackend struct {
+	// Name identifies the backend in host labels. It must be a DNS
+	// label.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// The endpoints must be set, other settings default to the ones
+	// of the default backend.
+	IronicSettings `json:",inline"`
+}
+
+// BareMetalOperatorConfigSpec defines the desired settings of the
+// operator.
+type BareMetalOperatorConfigSpec struct {
+	// Ironic holds the settings used by the ironic provisioner.
+	// +optional
+	Ironic *IronicSettings `json:"ironic,omitempty"`
+
+	// IronicBackends lists additional ironic deployments, for example
+	// one per datacenter, that hosts can be assigned to.
+	// +optional
+	IronicBackends []IronicBackend `json:"ironicBackends,omitempty"`
+
+	// Concurrency is the number of hosts reconciled at the same
+	// time. Defaults to the BMO_CONCURRENCY environment variable.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=50
+	// +optional
+	Concurrency *int `json:"concurrency,omitempty"`
+}
+
+// BareMetalOperatorConfigStatus defines the observed state of
+// BareMetalOperatorConfig
+type BareMetalOperatorConfigStatus struct {
+	// ObservedGeneration is the generation of the spec most recently
+	// processed by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ErrorMessage explains why the spec could not be applied. The
+	// previous settings stay in effect until the error is fixed.
+	// +optional
+	ErrorMessage string `json:"errorMessage,omitempty"`
+
+	// Effective holds the settings currently used by the operator,
+	// including the ones that come from its environment.
+	// +optional
+	Effective BareMetalOperatorConfigSpec `json:"effective,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BareMetalOperatorConfig holds settings that change the behavior of
+// the operator without restarting it.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:shortName=bmoconfig
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Error",type="string",JSONPath=".status.errorMessage",description="Why the settings could not be applied"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of BareMetalOperatorConfig"
+// +kubebuilder:object:root=true
+type BareMetalOperatorConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BareMetalOperatorConfigSpec   `json:"spec,omitempty"`
+	Status BareMetalOperatorConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BareMetalOperatorConfigList contains a list of BareMetalOperatorConfig
+type BareMetalOperatorConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BareMetalOperatorConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BareMetalOperatorConfig{}, &BareMetalOperatorConfigList{})
+}
@@ -0,0 +1,155 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: Update docs/bmc-credentials.md when changing these data structures.
+
+const (
+	// PasswordRotatedAnnotation is the annotation recording on a BMC
+	// credentials secret when its password was last rotated.
+	PasswordRotatedAnnotation = "baremetalhost.metal3.io/password-rotated"
+
+	// DefaultPasswordLength is the length of the generated passwords
+	// when the policy does not give one.
+	DefaultPasswordLength = 16
+
+	// DefaultPasswordSymbols are the symbols generated passwords may
+	// contain when the policy does not list them.
+	DefaultPasswordSymbols = "-_.!#%+=@~"
+)
+
+// PasswordPolicy describes the passwords generated for BMC accounts.
+type PasswordPolicy struct {
+	// Length is the number of characters of the passwords. IPMI
+	// limits passwords to 20 characters. Defaults to 16.
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	Length int `json:"length,omitempty"`
+
+	// MinLowercase is the smallest number of lowercase letters.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinLowercase int `json:"minLowercase,omitempty"`
+
+	// MinUppercase is the smallest number of uppercase letters.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinUppercase int `json:"minUppercase,omitempty"`
+
+	// MinDigits is the smallest number of digits.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinDigits int `json:"minDigits,omitempty"`
+
+	// MinSymbols is the smallest number of symbols.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinSymbols int `json:"minSymbols,omitempty"`
+
+	// Symbols lists the symbols the passwords may contain, for BMCs
+	// accepting only some of them. An empty string allows none.
+	// Defaults to "-_.!#%+=@~".
+	// +optional
+	Symbols *string `json:"symbols,omitempty"`
+}
+
+// BMCCredentialRotationSpec defines how often and how the BMC
+// passwords of hosts are changed.
+type BMCCredentialRotationSpec struct {
+	// Interval is the time between two changes of the password of a
+	// host, e.g. 2160h for 90 days.
+	Interval metav1.Duration `json:"interval"`
+
+	// HostSelector selects the hosts of the namespace whose password
+	// is rotated. All the hosts of the namespace are selected when
+	// it is not set.
+	// +optional
+	HostSelector *metav1.LabelSelector `json:"hostSelector,omitempty"`
+
+	// Password describes the passwords generated.
+	// +optional
+	Password PasswordPolicy `json:"password,omitempty"`
+}
+
+// HostRotationStatus records the rotation of the password of a host.
+type HostRotationStatus struct {
+	// Name is the name of the host.
+	Name string `json:"name"`
+
+	// LastRotated is when the password was last changed.
+	// +optional
+	LastRotated *metav1.Time `json:"lastRotated,omitempty"`
+
+	// LastFailure is when the last attempt to change the password
+	// failed. Attempts are retried an hour later.
+	// +optional
+	LastFailure *metav1.Time `json:"lastFailure,omitempty"`
+
+	// ErrorMessage explains why the password could not be changed.
+	// +optional
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+// BMCCredentialRotationStatus defines the observed state of
+// BMCCredentialRotation
+type BMCCredentialRotationStatus struct {
+	// ErrorMessage explains why the policy cannot be applied.
+	// +optional
+	ErrorMessage string `json:"errorMessage,omitempty"`
+
+	// Hosts lists the hosts selected by the policy.
+	// +optional
+	Hosts []HostRotationStatus `json:"hosts,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BMCCredentialRotation is a policy changing the BMC passwords of
+// hosts at regular intervals. Only credentials kept in secrets used by
+// a single host are rotated.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:shortName=bmcrotation
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Interval",type="string",JSONPath=".spec.interval",description="Time between two password changes"
+// +kubebuilder:printcolumn:name="Error",type="string",JSONPath=".status.errorMessage",description="Why the policy cannot be applied"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of BMCCredentialRotation"
+// +kubebuilder:object:root=true
+type BMCCredentialRotation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BMCCredentialRotationSpec   `json:"spec,omitempty"`
+	Status BMCCredentialRotationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BMCCredentialRotationList contains a list of BMCCredentialRotation
+type BMCCredentialRotationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BMCCredentialRotation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BMCCredentialRotation{}, &BMCCredentialRotationList{})
+}
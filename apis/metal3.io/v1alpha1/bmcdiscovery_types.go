@@ -0,0 +1,189 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: Update docs/bmc-discovery.md when changing these data structures.
+
+const (
+	// DiscoveredByLabel is the label of the hosts created by a
+	// BMCDiscovery, holding its name.
+	DiscoveredByLabel = "baremetalhost.metal3.io/discovered-by"
+
+	// AllowUnverifiedTLSAnnotation, set to "true" on a BMC secret,
+	// lets a BMCDiscovery that disables certificate verification send
+	// its credentials to the Redfish services found.
+	AllowUnverifiedTLSAnnotation = "bmcdiscovery.metal3.io/allow-unverified-tls"
+
+	// DefaultRedfishPort is the port probed for Redfish services when
+	// a discovery does not list any.
+	DefaultRedfishPort = 443
+
+	// DefaultIPMIPort is the port probed for IPMI when a discovery
+	// does not list any.
+	DefaultIPMIPort = 623
+)
+
+// BMCDiscoverySpec defines the networks scanned for BMCs and how the
+// BMCs found are logged into.
+type BMCDiscoverySpec struct {
+	// Networks lists the CIDRs of the networks scanned, e.g.
+	// 192.168.111.0/24. At most 65536 addresses are scanned.
+	// +kubebuilder:validation:MinItems=1
+	Networks []string `json:"networks"`
+
+	// RedfishPorts are the HTTPS ports probed for Redfish services.
+	// Defaults to 443.
+	// +optional
+	RedfishPorts []int `json:"redfishPorts,omitempty"`
+
+	// IPMIPorts are the UDP ports probed for IPMI. Defaults to 623.
+	// +optional
+	IPMIPorts []int `json:"ipmiPorts,omitempty"`
+
+	// DisableIPMI turns off the probing of IPMI, for networks where
+	// every BMC also answers Redfish.
+	// +optional
+	DisableIPMI bool `json:"disableIPMI,omitempty"`
+
+	// CredentialsNames lists the secrets of the namespace holding the
+	// BMC credentials tried, in order, on the BMCs found. The hosts
+	// created refer to the first secret whose credentials work.
+	// +kubebuilder:validation:MinItems=1
+	CredentialsNames []string `json:"credentialsNames"`
+
+	// DisableCertificateVerification disables verification of the
+	// certificates of the Redfish services, here and in the hosts
+	// created. Only the secrets annotated with
+	// bmcdiscovery.metal3.io/allow-unverified-tls are then tried on
+	// Redfish services.
+	// +optional
+	DisableCertificateVerification bool `json:"disableCertificateVerification,omitempty"`
+
+	// Interval is the time between two scans of the networks. They
+	// are scanned once, and again when the spec changes, when it is
+	// not set.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// HostLabels are added to the hosts created.
+	// +optional
+	HostLabels map[string]string `json:"hostLabels,omitempty"`
+}
+
+// FoundBMC describes a BMC found by a scan.
+type FoundBMC struct {
+	// Address is the BMC address of the host.
+	Address string `json:"address"`
+
+	// SerialNumber is the serial number of the system managed by the
+	// BMC.
+	// +optional
+	SerialNumber string `json:"serialNumber,omitempty"`
+
+	// Vendor is the vendor of the BMC or of the system.
+	// +optional
+	Vendor string `json:"vendor,omitempty"`
+
+	// Model is the model of the BMC or of the system.
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// Host is the name of the host of the BMC, whether it was created
+	// by the discovery or already existed.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// ErrorMessage explains why no host was created for the BMC.
+	// +optional
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+// ScanProgress describes a scan under way.
+type ScanProgress struct {
+	// StartTime is when the scan started.
+	StartTime metav1.Time `json:"startTime"`
+
+	// Generation is the generation of the spec scanned.
+	Generation int64 `json:"generation"`
+
+	// Endpoints is the number of endpoints probed by the scan.
+	Endpoints int `json:"endpoints"`
+
+	// Probed is the number of endpoints probed so far.
+	Probed int `json:"probed"`
+}
+
+// BMCDiscoveryStatus defines the observed state of BMCDiscovery
+type BMCDiscoveryStatus struct {
+	// Scan is the progress of the scan under way, if any.
+	// +optional
+	Scan *ScanProgress `json:"scan,omitempty"`
+
+	// LastScan is when the networks were last scanned.
+	// +optional
+	LastScan *metav1.Time `json:"lastScan,omitempty"`
+
+	// ObservedGeneration is the generation of the spec of the last
+	// scan.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ErrorMessage explains why the networks cannot be scanned.
+	// +optional
+	ErrorMessage string `json:"errorMessage,omitempty"`
+
+	// BMCs lists the BMCs found by the last scan.
+	// +optional
+	BMCs []FoundBMC `json:"bmcs,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BMCDiscovery scans networks for Redfish and IPMI BMCs and creates a
+// BareMetalHost, named after the serial number of the system, for each
+// BMC not yet known.
+// +k8s:openapi-gen=true
+// +kubebuilder:resource:shortName=bmcdisc
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Last Scan",type="date",JSONPath=".status.lastScan",description="Time since the networks were last scanned"
+// +kubebuilder:printcolumn:name="Error",type="string",JSONPath=".status.errorMessage",description="Why the networks cannot be scanned"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of BMCDiscovery"
+// +kubebuilder:object:root=true
+type BMCDiscovery struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BMCDiscoverySpec   `json:"spec,omitempty"`
+	Status BMCDiscoveryStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BMCDiscoveryList contains a list of BMCDiscovery
+type BMCDiscoveryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BMCDiscovery `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BMCDiscovery{}, &BMCDiscoveryList{})
+}
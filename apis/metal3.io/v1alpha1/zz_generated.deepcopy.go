@@ -22,6 +22,7 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -40,6 +41,124 @@ func (in *BIOS) DeepCopy() *BIOS {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCCertificateStatus) DeepCopyInto(out *BMCCertificateStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCCertificateStatus.
+func (in *BMCCertificateStatus) DeepCopy() *BMCCertificateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCCertificateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCCredentialRotation) DeepCopyInto(out *BMCCredentialRotation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCCredentialRotation.
+func (in *BMCCredentialRotation) DeepCopy() *BMCCredentialRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCCredentialRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BMCCredentialRotation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCCredentialRotationList) DeepCopyInto(out *BMCCredentialRotationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BMCCredentialRotation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCCredentialRotationList.
+func (in *BMCCredentialRotationList) DeepCopy() *BMCCredentialRotationList {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCCredentialRotationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BMCCredentialRotationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCCredentialRotationSpec) DeepCopyInto(out *BMCCredentialRotationSpec) {
+	*out = *in
+	out.Interval = in.Interval
+	if in.HostSelector != nil {
+		in, out := &in.HostSelector, &out.HostSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Password.DeepCopyInto(&out.Password)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCCredentialRotationSpec.
+func (in *BMCCredentialRotationSpec) DeepCopy() *BMCCredentialRotationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCCredentialRotationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCCredentialRotationStatus) DeepCopyInto(out *BMCCredentialRotationStatus) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]HostRotationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCCredentialRotationStatus.
+func (in *BMCCredentialRotationStatus) DeepCopy() *BMCCredentialRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCCredentialRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BMCDetails) DeepCopyInto(out *BMCDetails) {
 	*out = *in
@@ -55,6 +174,141 @@ func (in *BMCDetails) DeepCopy() *BMCDetails {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCDiscovery) DeepCopyInto(out *BMCDiscovery) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCDiscovery.
+func (in *BMCDiscovery) DeepCopy() *BMCDiscovery {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCDiscovery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BMCDiscovery) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCDiscoveryList) DeepCopyInto(out *BMCDiscoveryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BMCDiscovery, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCDiscoveryList.
+func (in *BMCDiscoveryList) DeepCopy() *BMCDiscoveryList {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCDiscoveryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BMCDiscoveryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCDiscoverySpec) DeepCopyInto(out *BMCDiscoverySpec) {
+	*out = *in
+	if in.Networks != nil {
+		in, out := &in.Networks, &out.Networks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RedfishPorts != nil {
+		in, out := &in.RedfishPorts, &out.RedfishPorts
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPMIPorts != nil {
+		in, out := &in.IPMIPorts, &out.IPMIPorts
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.CredentialsNames != nil {
+		in, out := &in.CredentialsNames, &out.CredentialsNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HostLabels != nil {
+		in, out := &in.HostLabels, &out.HostLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCDiscoverySpec.
+func (in *BMCDiscoverySpec) DeepCopy() *BMCDiscoverySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCDiscoverySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCDiscoveryStatus) DeepCopyInto(out *BMCDiscoveryStatus) {
+	*out = *in
+	if in.Scan != nil {
+		in, out := &in.Scan, &out.Scan
+		*out = new(ScanProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastScan != nil {
+		in, out := &in.LastScan, &out.LastScan
+		*out = (*in).DeepCopy()
+	}
+	if in.BMCs != nil {
+		in, out := &in.BMCs, &out.BMCs
+		*out = make([]FoundBMC, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCDiscoveryStatus.
+func (in *BMCDiscoveryStatus) DeepCopy() *BMCDiscoveryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCDiscoveryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BareMetalHost) DeepCopyInto(out *BareMetalHost) {
 	*out = *in
@@ -155,6 +409,11 @@ func (in *BareMetalHostSpec) DeepCopyInto(out *BareMetalHostSpec) {
 		*out = new(v1.SecretReference)
 		**out = **in
 	}
+	if in.DeployRamdisk != nil {
+		in, out := &in.DeployRamdisk, &out.DeployRamdisk
+		*out = new(DeployRamdisk)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalHostSpec.
@@ -180,6 +439,16 @@ func (in *BareMetalHostStatus) DeepCopyInto(out *BareMetalHostStatus) {
 		(*in).DeepCopyInto(*out)
 	}
 	in.Provisioning.DeepCopyInto(&out.Provisioning)
+	if in.BMCCertificate != nil {
+		in, out := &in.BMCCertificate, &out.BMCCertificate
+		*out = new(BMCCertificateStatus)
+		**out = **in
+	}
+	if in.DetectedBMC != nil {
+		in, out := &in.DetectedBMC, &out.DetectedBMC
+		*out = new(DetectedBMC)
+		**out = **in
+	}
 	in.GoodCredentials.DeepCopyInto(&out.GoodCredentials)
 	in.TriedCredentials.DeepCopyInto(&out.TriedCredentials)
 	in.OperationHistory.DeepCopyInto(&out.OperationHistory)
@@ -195,6 +464,113 @@ func (in *BareMetalHostStatus) DeepCopy() *BareMetalHostStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalOperatorConfig) DeepCopyInto(out *BareMetalOperatorConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalOperatorConfig.
+func (in *BareMetalOperatorConfig) DeepCopy() *BareMetalOperatorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalOperatorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BareMetalOperatorConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalOperatorConfigList) DeepCopyInto(out *BareMetalOperatorConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BareMetalOperatorConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalOperatorConfigList.
+func (in *BareMetalOperatorConfigList) DeepCopy() *BareMetalOperatorConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalOperatorConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BareMetalOperatorConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalOperatorConfigSpec) DeepCopyInto(out *BareMetalOperatorConfigSpec) {
+	*out = *in
+	if in.Ironic != nil {
+		in, out := &in.Ironic, &out.Ironic
+		*out = new(IronicSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IronicBackends != nil {
+		in, out := &in.IronicBackends, &out.IronicBackends
+		*out = make([]IronicBackend, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Concurrency != nil {
+		in, out := &in.Concurrency, &out.Concurrency
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalOperatorConfigSpec.
+func (in *BareMetalOperatorConfigSpec) DeepCopy() *BareMetalOperatorConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalOperatorConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalOperatorConfigStatus) DeepCopyInto(out *BareMetalOperatorConfigStatus) {
+	*out = *in
+	in.Effective.DeepCopyInto(&out.Effective)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalOperatorConfigStatus.
+func (in *BareMetalOperatorConfigStatus) DeepCopy() *BareMetalOperatorConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalOperatorConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CPU) DeepCopyInto(out *CPU) {
 	*out = *in
@@ -235,6 +611,36 @@ func (in *CredentialsStatus) DeepCopy() *CredentialsStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeployRamdisk) DeepCopyInto(out *DeployRamdisk) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeployRamdisk.
+func (in *DeployRamdisk) DeepCopy() *DeployRamdisk {
+	if in == nil {
+		return nil
+	}
+	out := new(DeployRamdisk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DetectedBMC) DeepCopyInto(out *DetectedBMC) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DetectedBMC.
+func (in *DetectedBMC) DeepCopy() *DetectedBMC {
+	if in == nil {
+		return nil
+	}
+	out := new(DetectedBMC)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Firmware) DeepCopyInto(out *Firmware) {
 	*out = *in
@@ -251,6 +657,21 @@ func (in *Firmware) DeepCopy() *Firmware {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FoundBMC) DeepCopyInto(out *FoundBMC) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FoundBMC.
+func (in *FoundBMC) DeepCopy() *FoundBMC {
+	if in == nil {
+		return nil
+	}
+	out := new(FoundBMC)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HardwareDetails) DeepCopyInto(out *HardwareDetails) {
 	*out = *in
@@ -296,6 +717,29 @@ func (in *HardwareSystemVendor) DeepCopy() *HardwareSystemVendor {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostRotationStatus) DeepCopyInto(out *HostRotationStatus) {
+	*out = *in
+	if in.LastRotated != nil {
+		in, out := &in.LastRotated, &out.LastRotated
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostRotationStatus.
+func (in *HostRotationStatus) DeepCopy() *HostRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HostRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Image) DeepCopyInto(out *Image) {
 	*out = *in
@@ -316,6 +760,72 @@ func (in *Image) DeepCopy() *Image {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IronicBackend) DeepCopyInto(out *IronicBackend) {
+	*out = *in
+	in.IronicSettings.DeepCopyInto(&out.IronicSettings)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IronicBackend.
+func (in *IronicBackend) DeepCopy() *IronicBackend {
+	if in == nil {
+		return nil
+	}
+	out := new(IronicBackend)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IronicSettings) DeepCopyInto(out *IronicSettings) {
+	*out = *in
+	if in.Insecure != nil {
+		in, out := &in.Insecure, &out.Insecure
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ProvisioningLimit != nil {
+		in, out := &in.ProvisioningLimit, &out.ProvisioningLimit
+		*out = new(int)
+		**out = **in
+	}
+	if in.DeprovisionRequeueDelay != nil {
+		in, out := &in.DeprovisionRequeueDelay, &out.DeprovisionRequeueDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ProvisionRequeueDelay != nil {
+		in, out := &in.ProvisionRequeueDelay, &out.ProvisionRequeueDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PowerRequeueDelay != nil {
+		in, out := &in.PowerRequeueDelay, &out.PowerRequeueDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.IntrospectionRequeueDelay != nil {
+		in, out := &in.IntrospectionRequeueDelay, &out.IntrospectionRequeueDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.SoftPowerOffTimeout != nil {
+		in, out := &in.SoftPowerOffTimeout, &out.SoftPowerOffTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IronicSettings.
+func (in *IronicSettings) DeepCopy() *IronicSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(IronicSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NIC) DeepCopyInto(out *NIC) {
 	*out = *in
@@ -372,6 +882,26 @@ func (in *OperationMetric) DeepCopy() *OperationMetric {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PasswordPolicy) DeepCopyInto(out *PasswordPolicy) {
+	*out = *in
+	if in.Symbols != nil {
+		in, out := &in.Symbols, &out.Symbols
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PasswordPolicy.
+func (in *PasswordPolicy) DeepCopy() *PasswordPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PasswordPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProvisionStatus) DeepCopyInto(out *ProvisionStatus) {
 	*out = *in
@@ -381,6 +911,11 @@ func (in *ProvisionStatus) DeepCopyInto(out *ProvisionStatus) {
 		*out = new(RootDeviceHints)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DeployRamdisk != nil {
+		in, out := &in.DeployRamdisk, &out.DeployRamdisk
+		*out = new(DeployRamdisk)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionStatus.
@@ -393,6 +928,21 @@ func (in *ProvisionStatus) DeepCopy() *ProvisionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebootAnnotationArguments) DeepCopyInto(out *RebootAnnotationArguments) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebootAnnotationArguments.
+func (in *RebootAnnotationArguments) DeepCopy() *RebootAnnotationArguments {
+	if in == nil {
+		return nil
+	}
+	out := new(RebootAnnotationArguments)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RootDeviceHints) DeepCopyInto(out *RootDeviceHints) {
 	*out = *in
@@ -413,6 +963,22 @@ func (in *RootDeviceHints) DeepCopy() *RootDeviceHints {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanProgress) DeepCopyInto(out *ScanProgress) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanProgress.
+func (in *ScanProgress) DeepCopy() *ScanProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Storage) DeepCopyInto(out *Storage) {
 	*out = *in
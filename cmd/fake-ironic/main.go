@@ -0,0 +1,59 @@
+// fake-ironic runs an in-memory simulation of Ironic and Ironic
+// Inspector, so the operator can be run end-to-end without any
+// hardware or OpenStack services.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/fakeironic"
+)
+
+func main() {
+	var ironicAddress = flag.String("ironic-address", "127.0.0.1:6385", "host:port to serve the Ironic API on")
+	var inspectorAddress = flag.String("inspector-address", "127.0.0.1:5050", "host:port to serve the Inspector API on")
+	var stepDelay = flag.Duration("step-delay", fakeironic.DefaultStepDelay, "time spent in each intermediate provision state")
+	var failures = flag.String("fail", "", "comma separated list of node=target pairs, making the first change of the named node to the provision target fail")
+	var devLogging = flag.Bool("dev", false, "enable developer logging")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(*devLogging)))
+
+	sim := fakeironic.New()
+	sim.StepDelay = *stepDelay
+	for _, failure := range strings.Split(*failures, ",") {
+		failure = strings.TrimSpace(failure)
+		if failure == "" {
+			continue
+		}
+		parts := strings.SplitN(failure, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			fmt.Fprintf(os.Stderr, "invalid failure %q, expected node=target\n", failure)
+			os.Exit(1)
+		}
+		sim.FailNext(parts[0], nodes.TargetProvisionState(parts[1]),
+			fmt.Sprintf("Simulated failure of %s", parts[1]))
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- http.ListenAndServe(*inspectorAddress, sim.InspectorHandler())
+	}()
+	go func() {
+		errs <- http.ListenAndServe(*ironicAddress, sim.IronicHandler())
+	}()
+
+	ctrl.Log.Info("serving fake ironic",
+		"ironic", fmt.Sprintf("http://%s/v1/", *ironicAddress),
+		"inspector", fmt.Sprintf("http://%s/v1/", *inspectorAddress))
+	fmt.Fprintf(os.Stderr, "fake ironic stopped: %s\n", <-errs)
+	os.Exit(1)
+}
@@ -0,0 +1,64 @@
+// fixture-plugin serves the fixture provisioner as a provisioner plugin.
+// It is the reference implementation of the plugin protocol, and can be
+// used to test the operator without any hardware.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/plugin"
+)
+
+func main() {
+	var network = flag.String("network", "unix", "network to listen on, unix or tcp")
+	var address = flag.String("address", "/tmp/fixture-plugin.sock", "socket path or host:port to listen on")
+	var devLogging = flag.Bool("dev", false, "enable developer logging")
+	var certFile = flag.String("tls-cert-file", "", "certificate to serve TLS with, required for tcp")
+	var keyFile = flag.String("tls-key-file", "", "key of the TLS certificate")
+	var clientCAFile = flag.String("client-ca-file", "", "CA certificates the operator's client certificate must be signed by")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(*devLogging)))
+
+	var opts []grpc.ServerOption
+	if *certFile != "" {
+		creds, err := plugin.ServerCredentials(*certFile, *keyFile, *clientCAFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not set up TLS: %s\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, creds)
+	} else if *network != "unix" {
+		fmt.Fprintf(os.Stderr, "a TLS certificate is required to listen on %s\n", *network)
+		os.Exit(1)
+	}
+
+	if *network == "unix" {
+		// Remove the socket left behind by a previous run.
+		if err := os.Remove(*address); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "could not remove old socket: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	listener, err := net.Listen(*network, *address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not listen: %s\n", err)
+		os.Exit(1)
+	}
+
+	fixtures := &fixture.Fixtures{}
+	ctrl.Log.Info("serving fixture provisioner", "network", *network, "address", *address)
+	if err := plugin.Serve(listener, fixtures.New, opts...); err != nil {
+		fmt.Fprintf(os.Stderr, "plugin stopped: %s\n", err)
+		os.Exit(1)
+	}
+}
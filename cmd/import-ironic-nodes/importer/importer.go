@@ -0,0 +1,200 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/ports"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// DefaultCredentials is the key of the credentials map used for the
+// nodes that are not listed.
+const DefaultCredentials = "*"
+
+// Credentials are the credentials of the BMC of a node. The Ironic API
+// hides passwords, so they have to be supplied. The username is taken
+// from the driver_info of the node when it is left out.
+type Credentials struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// Importer turns Ironic nodes into hosts that adopt them.
+type Importer struct {
+	// Namespace of the hosts and their secrets
+	Namespace string
+	// Credentials of the BMCs, by node name or UUID
+	Credentials map[string]Credentials
+}
+
+// Imported is a host created for an Ironic node, with the secret
+// holding the credentials of its BMC.
+type Imported struct {
+	Host   *metal3v1alpha1.BareMetalHost
+	Secret *corev1.Secret
+}
+
+func (i *Importer) credentials(node *nodes.Node) (Credentials, bool) {
+	for _, key := range []string{node.Name, node.UUID, DefaultCredentials} {
+		if creds, ok := i.Credentials[key]; ok && key != "" {
+			return creds, true
+		}
+	}
+	return Credentials{}, false
+}
+
+// provisioningState returns the state in which a host takes the node
+// over. Active nodes are adopted as externally provisioned hosts,
+// which the operator leaves running. Nodes at rest are registered
+// again, which finds them through the ID in the status, and inspected.
+func provisioningState(node *nodes.Node) (metal3v1alpha1.ProvisioningState, error) {
+	switch nodes.ProvisionState(node.ProvisionState) {
+	case nodes.Active:
+		return metal3v1alpha1.StateExternallyProvisioned, nil
+	case nodes.Enroll, nodes.Manageable, nodes.Available, nodes.InspectFail, nodes.CleanFail:
+		return metal3v1alpha1.StateRegistering, nil
+	default:
+		return "", fmt.Errorf("the node is %s, wait for it to become active, manageable or available", node.ProvisionState)
+	}
+}
+
+// bootMACAddress returns the address of the port the node boots from,
+// or of its first port when none is marked as such.
+func bootMACAddress(nodePorts []ports.Port) string {
+	for _, port := range nodePorts {
+		if port.PXEEnabled {
+			return port.Address
+		}
+	}
+	if len(nodePorts) != 0 {
+		return nodePorts[0].Address
+	}
+	return ""
+}
+
+// bootMode returns the boot mode of the boot_mode capability of the
+// node, if any.
+func bootMode(node *nodes.Node) metal3v1alpha1.BootMode {
+	capabilities, _ := node.Properties["capabilities"].(string)
+	for _, capability := range strings.Split(capabilities, ",") {
+		switch strings.TrimSpace(capability) {
+		case "boot_mode:uefi":
+			return metal3v1alpha1.UEFI
+		case "boot_mode:bios":
+			return metal3v1alpha1.Legacy
+		}
+	}
+	return ""
+}
+
+// Import returns the host and secret adopting a node and its ports.
+// The status of the host is pre-seeded in the status annotation with
+// the ID of the node, so that the operator takes it over rather than
+// registering a new node.
+func (i *Importer) Import(node *nodes.Node, nodePorts []ports.Port) (*Imported, error) {
+	name := node.Name
+	if name == "" {
+		name = node.UUID
+	}
+	if msgs := validation.IsDNS1123Subdomain(name); len(msgs) != 0 {
+		return nil, fmt.Errorf("the node name %q is not a valid host name: %s", name, strings.Join(msgs, ", "))
+	}
+
+	access, err := bmc.AddressFromNode(bmc.Node{
+		Driver:        node.Driver,
+		BootInterface: node.BootInterface,
+		DriverInfo:    node.DriverInfo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	accessDetails, err := bmc.NewAccessDetails(access.Address, access.DisableCertificateVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, ok := i.credentials(node)
+	if !ok {
+		return nil, fmt.Errorf("no credentials given for the BMC")
+	}
+	if creds.Username == "" {
+		creds.Username = access.Username
+	}
+	if err := (bmc.Credentials{Username: creds.Username, Password: creds.Password}).Validate(); err != nil {
+		return nil, err
+	}
+
+	state, err := provisioningState(node)
+	if err != nil {
+		return nil, err
+	}
+
+	poweredOn := node.PowerState == string(nodes.PowerOn)
+	host := &metal3v1alpha1.BareMetalHost{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: metal3v1alpha1.GroupVersion.String(),
+			Kind:       "BareMetalHost",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: i.Namespace,
+		},
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			BMC: metal3v1alpha1.BMCDetails{
+				Address:                        access.Address,
+				CredentialsName:                name + "-bmc-secret",
+				DisableCertificateVerification: access.DisableCertificateVerification,
+			},
+			BootMACAddress:        bootMACAddress(nodePorts),
+			BootMode:              bootMode(node),
+			ExternallyProvisioned: state == metal3v1alpha1.StateExternallyProvisioned,
+			// The power state of the node is kept.
+			Online: poweredOn,
+		},
+	}
+	if accessDetails.NeedsMAC() && host.Spec.BootMACAddress == "" {
+		return nil, fmt.Errorf("BMC type %s requires a boot MAC address, and the node has no port", accessDetails.Type())
+	}
+
+	status := metal3v1alpha1.BareMetalHostStatus{
+		OperationalStatus: metal3v1alpha1.OperationalStatusOK,
+		PoweredOn:         poweredOn,
+		Provisioning: metal3v1alpha1.ProvisionStatus{
+			State:    state,
+			ID:       node.UUID,
+			BootMode: host.Spec.BootMode,
+		},
+	}
+	statusData, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	host.Annotations = map[string]string{
+		metal3v1alpha1.StatusAnnotation: string(statusData),
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      host.Spec.BMC.CredentialsName,
+			Namespace: i.Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"username": []byte(creds.Username),
+			"password": []byte(creds.Password),
+		},
+	}
+	return &Imported{Host: host, Secret: secret}, nil
+}
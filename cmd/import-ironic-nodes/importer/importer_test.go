@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/ports"
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+func newImporter() *Importer {
+	return &Importer{
+		Namespace: "metal3",
+		Credentials: map[string]Credentials{
+			DefaultCredentials: {Password: "secret"},
+			"node-1":           {Username: "root", Password: "other"},
+		},
+	}
+}
+
+func newNode(name, provisionState string) *nodes.Node {
+	return &nodes.Node{
+		UUID:           "33ce8659-7400-4c68-9535-d10766f07a58",
+		Name:           name,
+		ProvisionState: provisionState,
+		PowerState:     "power on",
+		Driver:         "redfish",
+		BootInterface:  "redfish-virtual-media",
+		DriverInfo: map[string]interface{}{
+			"redfish_address":   "https://192.168.111.1",
+			"redfish_system_id": "/redfish/v1/Systems/1",
+			"redfish_username":  "admin",
+			"redfish_password":  "******",
+			"redfish_verify_ca": false,
+			"deploy_kernel":     "http://images/ironic-python-agent.kernel",
+		},
+		Properties: map[string]interface{}{
+			"capabilities": "cpu_vt:true,boot_mode:uefi",
+		},
+	}
+}
+
+func hostStatus(t *testing.T, host *metal3v1alpha1.BareMetalHost) metal3v1alpha1.BareMetalHostStatus {
+	status := metal3v1alpha1.BareMetalHostStatus{}
+	if err := json.Unmarshal([]byte(host.Annotations[metal3v1alpha1.StatusAnnotation]), &status); err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestImportActiveNode(t *testing.T) {
+	nodePorts := []ports.Port{
+		{Address: "52:54:00:aa:bb:00"},
+		{Address: "52:54:00:aa:bb:01", PXEEnabled: true},
+	}
+
+	imported, err := newImporter().Import(newNode("node-0", "active"), nodePorts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := imported.Host
+	assert.Equal(t, "node-0", host.Name)
+	assert.Equal(t, "metal3", host.Namespace)
+	assert.Equal(t, metal3v1alpha1.BMCDetails{
+		Address:                        "redfish-virtualmedia://192.168.111.1/redfish/v1/Systems/1",
+		CredentialsName:                "node-0-bmc-secret",
+		DisableCertificateVerification: true,
+	}, host.Spec.BMC)
+	assert.Equal(t, "52:54:00:aa:bb:01", host.Spec.BootMACAddress)
+	assert.Equal(t, metal3v1alpha1.UEFI, host.Spec.BootMode)
+	assert.True(t, host.Spec.ExternallyProvisioned)
+	assert.True(t, host.Spec.Online)
+
+	status := hostStatus(t, host)
+	assert.Equal(t, metal3v1alpha1.StateExternallyProvisioned, status.Provisioning.State)
+	assert.Equal(t, "33ce8659-7400-4c68-9535-d10766f07a58", status.Provisioning.ID)
+	assert.Equal(t, metal3v1alpha1.OperationalStatusOK, status.OperationalStatus)
+	assert.True(t, status.PoweredOn)
+
+	assert.Equal(t, "node-0-bmc-secret", imported.Secret.Name)
+	assert.Equal(t, "metal3", imported.Secret.Namespace)
+	assert.Equal(t, map[string][]byte{"username": []byte("admin"), "password": []byte("secret")}, imported.Secret.Data)
+}
+
+func TestImportAvailableNode(t *testing.T) {
+	node := newNode("node-1", "available")
+	node.PowerState = "power off"
+
+	imported, err := newImporter().Import(node, []ports.Port{{Address: "52:54:00:aa:bb:00"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, imported.Host.Spec.ExternallyProvisioned)
+	assert.False(t, imported.Host.Spec.Online)
+	status := hostStatus(t, imported.Host)
+	assert.Equal(t, metal3v1alpha1.StateRegistering, status.Provisioning.State)
+	assert.Equal(t, node.UUID, status.Provisioning.ID)
+	assert.Equal(t, []byte("root"), imported.Secret.Data["username"])
+	assert.Equal(t, []byte("other"), imported.Secret.Data["password"])
+}
+
+func TestImportUnnamedNode(t *testing.T) {
+	imported, err := newImporter().Import(newNode("", "manageable"), []ports.Port{{Address: "52:54:00:aa:bb:00"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "33ce8659-7400-4c68-9535-d10766f07a58", imported.Host.Name)
+}
+
+func TestImportErrors(t *testing.T) {
+	testCases := []struct {
+		Scenario string
+		Update   func(*Importer, *nodes.Node, *[]ports.Port)
+		Error    string
+	}{
+		{
+			Scenario: "transient state",
+			Update:   func(i *Importer, node *nodes.Node, nodePorts *[]ports.Port) { node.ProvisionState = "deploying" },
+			Error:    "the node is deploying, wait for it to become active, manageable or available",
+		},
+		{
+			Scenario: "invalid name",
+			Update:   func(i *Importer, node *nodes.Node, nodePorts *[]ports.Port) { node.Name = "Node_0" },
+			Error:    `the node name "Node_0" is not a valid host name: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+		{
+			Scenario: "unsupported driver",
+			Update:   func(i *Importer, node *nodes.Node, nodePorts *[]ports.Port) { node.Driver = "fake-hardware" },
+			Error:    "no BMC type uses the fake-hardware driver",
+		},
+		{
+			Scenario: "missing credentials",
+			Update: func(i *Importer, node *nodes.Node, nodePorts *[]ports.Port) {
+				delete(i.Credentials, DefaultCredentials)
+			},
+			Error: "no credentials given for the BMC",
+		},
+		{
+			Scenario: "missing boot MAC",
+			Update:   func(i *Importer, node *nodes.Node, nodePorts *[]ports.Port) { *nodePorts = nil },
+			Error:    "BMC type redfish-virtualmedia requires a boot MAC address, and the node has no port",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			imp := newImporter()
+			node := newNode("node-0", "active")
+			nodePorts := []ports.Port{{Address: "52:54:00:aa:bb:00"}}
+			tc.Update(imp, node, &nodePorts)
+
+			_, err := imp.Import(node, nodePorts)
+			if assert.Error(t, err) {
+				assert.Equal(t, tc.Error, err.Error())
+			}
+		})
+	}
+}
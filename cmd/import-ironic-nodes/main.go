@@ -0,0 +1,148 @@
+// import-ironic-nodes is a tool that writes the BareMetalHosts and
+// Secrets adopting the nodes of an existing Ironic.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/ports"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"github.com/metal3-io/baremetal-operator/cmd/import-ironic-nodes/importer"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
+)
+
+func main() {
+	var namespace = flag.String("namespace", "", "namespace of the hosts and their secrets")
+	var credentialsPath = flag.String(
+		"credentials", "", "YAML file mapping node names or UUIDs, or \"*\", to the username and password of their BMC")
+	var nodeNames = flag.String("nodes", "", "comma-separated names or UUIDs of the nodes to import, all of them by default")
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: import-ironic-nodes [options] <ironic URI>\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if *credentialsPath == "" {
+		fmt.Fprintf(os.Stderr, "Missing -credentials argument\n")
+		os.Exit(1)
+	}
+
+	credentials := map[string]importer.Credentials{}
+	data, err := ioutil.ReadFile(*credentialsPath) // #nosec
+	if err == nil {
+		err = yaml.UnmarshalStrict(data, &credentials)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: could not read credentials %s: %s\n", *credentialsPath, err)
+		os.Exit(1)
+	}
+
+	endpoint, auth, err := clients.ConfigFromEndpointURL(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+	tlsConf := clients.TLSConfig{
+		TrustedCAFile:      os.Getenv("IRONIC_CACERT_FILE"),
+		InsecureSkipVerify: strings.ToLower(os.Getenv("IRONIC_INSECURE")) == "true",
+	}
+	client, err := clients.IronicClient(endpoint, auth, tlsConf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: could not get ironic client: %s\n", err)
+		os.Exit(1)
+	}
+
+	nodePages, err := nodes.ListDetail(client, nodes.ListOpts{}).AllPages()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: could not list nodes: %s\n", err)
+		os.Exit(1)
+	}
+	allNodes, err := nodes.ExtractNodes(nodePages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: could not list nodes: %s\n", err)
+		os.Exit(1)
+	}
+	portPages, err := ports.ListDetail(client, ports.ListOpts{}).AllPages()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: could not list ports: %s\n", err)
+		os.Exit(1)
+	}
+	allPorts, err := ports.ExtractPorts(portPages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: could not list ports: %s\n", err)
+		os.Exit(1)
+	}
+	nodePorts := make(map[string][]ports.Port)
+	for _, port := range allPorts {
+		nodePorts[port.NodeUUID] = append(nodePorts[port.NodeUUID], port)
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range strings.Split(*nodeNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			selected[name] = true
+		}
+	}
+
+	// Every node is checked before anything is written, so that a
+	// mistake does not leave a partial set of hosts behind.
+	imp := &importer.Importer{Namespace: *namespace, Credentials: credentials}
+	documents := []string{}
+	found := make(map[string]bool)
+	failed := false
+	for i := range allNodes {
+		node := &allNodes[i]
+		if len(selected) != 0 && !selected[node.Name] && !selected[node.UUID] {
+			continue
+		}
+		found[node.Name], found[node.UUID] = true, true
+
+		imported, err := imp.Import(node, nodePorts[node.UUID])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: node %s (%s): %s\n", node.UUID, node.Name, err)
+			failed = true
+			continue
+		}
+		for _, obj := range []runtime.Object{imported.Secret, imported.Host} {
+			out, err := toYAML(obj)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+				os.Exit(1)
+			}
+			documents = append(documents, string(out))
+		}
+	}
+	for name := range selected {
+		if !found[name] {
+			fmt.Fprintf(os.Stderr, "ERROR: node %s not found\n", name)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+
+	for _, document := range documents {
+		fmt.Fprintf(os.Stdout, "---\n%s", document)
+	}
+}
+
+// toYAML returns the YAML of an object, leaving out its status, which
+// is set by the operator from the status annotation of the hosts.
+func toYAML(obj runtime.Object) ([]byte, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	delete(content, "status")
+	return yaml.Marshal(content)
+}
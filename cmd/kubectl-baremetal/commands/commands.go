@@ -0,0 +1,311 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+const (
+	// pausedValue is the value of the paused annotation set by Pause.
+	pausedValue = "kubectl-baremetal"
+	// clusterPausedValue is the value of the paused annotation set by
+	// the cluster API provider, which is left for it to remove.
+	clusterPausedValue = "metal3.io/capm3"
+)
+
+// Plugin runs the commands of the kubectl baremetal plugin against the
+// hosts of a cluster.
+type Plugin struct {
+	Client client.Client
+	// Namespace of the hosts
+	Namespace string
+	// AllNamespaces lists the hosts of every namespace
+	AllNamespaces bool
+	// Out receives the output of the commands
+	Out io.Writer
+	// PollInterval is how often Wait looks at the host
+	PollInterval time.Duration
+	// Now returns the current time, to report operations in progress
+	Now func() time.Time
+}
+
+func (p *Plugin) now() time.Time {
+	if p.Now == nil {
+		return time.Now()
+	}
+	return p.Now()
+}
+
+func (p *Plugin) get(ctx context.Context, name string) (*metal3v1alpha1.BareMetalHost, error) {
+	host := &metal3v1alpha1.BareMetalHost{}
+	key := types.NamespacedName{Namespace: p.Namespace, Name: name}
+	if err := p.Client.Get(ctx, key, host); err != nil {
+		return nil, errors.Wrapf(err, "could not get host %s", name)
+	}
+	return host, nil
+}
+
+// update gets a host, applies a change to it and patches the host with
+// the result.
+func (p *Plugin) update(ctx context.Context, name string, change func(host *metal3v1alpha1.BareMetalHost) error) error {
+	host, err := p.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	patch := client.MergeFrom(host.DeepCopy())
+	if err := change(host); err != nil {
+		return err
+	}
+	if err := p.Client.Patch(ctx, host, patch); err != nil {
+		return errors.Wrapf(err, "could not update host %s", name)
+	}
+	return nil
+}
+
+func setAnnotation(host *metal3v1alpha1.BareMetalHost, annotation, value string) {
+	if host.Annotations == nil {
+		host.Annotations = map[string]string{}
+	}
+	host.Annotations[annotation] = value
+}
+
+func isPaused(host *metal3v1alpha1.BareMetalHost) bool {
+	_, paused := host.Annotations[metal3v1alpha1.PausedAnnotation]
+	return paused
+}
+
+// power returns the power state of a host, with the change under way
+// when it is not the one asked for.
+func power(host *metal3v1alpha1.BareMetalHost) string {
+	switch {
+	case host.Status.PoweredOn == host.Spec.Online:
+		if host.Status.PoweredOn {
+			return "on"
+		}
+		return "off"
+	case host.Spec.Online:
+		return "powering on"
+	default:
+		return "powering off"
+	}
+}
+
+// List writes a table of the hosts, with their state, power, hardware
+// profile and error.
+func (p *Plugin) List(ctx context.Context) error {
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	opts := []client.ListOption{}
+	if !p.AllNamespaces {
+		opts = append(opts, client.InNamespace(p.Namespace))
+	}
+	if err := p.Client.List(ctx, hosts, opts...); err != nil {
+		return errors.Wrap(err, "could not list hosts")
+	}
+
+	w := tabwriter.NewWriter(p.Out, 0, 8, 2, ' ', 0)
+	columns := []string{"NAME", "STATE", "POWER", "PROFILE", "ERROR"}
+	if p.AllNamespaces {
+		columns = append([]string{"NAMESPACE"}, columns...)
+	}
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+	for _, host := range hosts.Items {
+		state := string(host.Status.Provisioning.State)
+		if isPaused(&host) {
+			state += " (paused)"
+		}
+		row := []string{host.Name, state, power(&host), host.Status.HardwareProfile, string(host.Status.ErrorType)}
+		if p.AllNamespaces {
+			row = append([]string{host.Namespace}, row...)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// Power turns a host on or off through its online field.
+func (p *Plugin) Power(ctx context.Context, name string, on bool) error {
+	return p.update(ctx, name, func(host *metal3v1alpha1.BareMetalHost) error {
+		host.Spec.Online = on
+		return nil
+	})
+}
+
+// Reboot reboots a host once, through the reboot annotation without a
+// suffix. A hard reboot skips the soft power off.
+func (p *Plugin) Reboot(ctx context.Context, name string, hard bool) error {
+	return p.update(ctx, name, func(host *metal3v1alpha1.BareMetalHost) error {
+		value := ""
+		if hard {
+			value = fmt.Sprintf(`{"mode":%q}`, metal3v1alpha1.RebootModeHard)
+		}
+		setAnnotation(host, metal3v1alpha1.RebootAnnotationPrefix, value)
+		return nil
+	})
+}
+
+// Inspect asks for a new inspection of a ready host.
+func (p *Plugin) Inspect(ctx context.Context, name string) error {
+	return p.update(ctx, name, func(host *metal3v1alpha1.BareMetalHost) error {
+		if state := host.Status.Provisioning.State; state != metal3v1alpha1.StateReady {
+			return fmt.Errorf("host %s is %s, only ready hosts can be inspected", name, state)
+		}
+		setAnnotation(host, metal3v1alpha1.InspectAnnotation, "")
+		return nil
+	})
+}
+
+// Pause stops or resumes the reconciliation of a host, through the
+// paused annotation. A host paused by the cluster API provider is
+// resumed by it, not by Pause.
+func (p *Plugin) Pause(ctx context.Context, name string, paused bool) error {
+	return p.update(ctx, name, func(host *metal3v1alpha1.BareMetalHost) error {
+		if host.Annotations[metal3v1alpha1.PausedAnnotation] == clusterPausedValue {
+			return fmt.Errorf("host %s is paused by the cluster API provider", name)
+		}
+		if paused {
+			setAnnotation(host, metal3v1alpha1.PausedAnnotation, pausedValue)
+		} else {
+			delete(host.Annotations, metal3v1alpha1.PausedAnnotation)
+		}
+		return nil
+	})
+}
+
+// Wait waits until a host reaches a provisioning state, or the timeout
+// expires.
+func (p *Plugin) Wait(ctx context.Context, name string, state metal3v1alpha1.ProvisioningState, timeout time.Duration) error {
+	interval := p.PollInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	var current metal3v1alpha1.ProvisioningState
+	err := wait.PollImmediate(interval, timeout, func() (bool, error) {
+		host, err := p.get(ctx, name)
+		if err != nil {
+			return false, err
+		}
+		current = host.Status.Provisioning.State
+		return current == state, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for host %s to be %s, it is %s", name, state, current)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(p.Out, "host %s is %s\n", name, state)
+	return nil
+}
+
+// operationTime returns how long an operation took, or has been going
+// on for.
+func (p *Plugin) operationTime(metric metal3v1alpha1.OperationMetric) string {
+	switch {
+	case metric.Start.IsZero():
+		return ""
+	case metric.End.IsZero():
+		return fmt.Sprintf("%s (in progress)", p.now().Sub(metric.Start.Time).Round(time.Second))
+	default:
+		return metric.Duration().Round(time.Second).String()
+	}
+}
+
+// join joins the values that are set with spaces.
+func join(values ...string) string {
+	set := []string{}
+	for _, value := range values {
+		if value != "" {
+			set = append(set, value)
+		}
+	}
+	return strings.Join(set, " ")
+}
+
+func timestamp(t metav1.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Describe writes the details of a host: its state, a summary of its
+// hardware and the time its operations took.
+func (p *Plugin) Describe(ctx context.Context, name string) error {
+	host, err := p.get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(p.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", host.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", host.Namespace)
+	fmt.Fprintf(w, "State:\t%s\n", host.Status.Provisioning.State)
+	fmt.Fprintf(w, "Operational status:\t%s\n", host.Status.OperationalStatus)
+	fmt.Fprintf(w, "Power:\t%s\n", power(host))
+	fmt.Fprintf(w, "Paused:\t%t\n", isPaused(host))
+	fmt.Fprintf(w, "BMC:\t%s\n", host.Spec.BMC.Address)
+	fmt.Fprintf(w, "Boot MAC:\t%s\n", host.Spec.BootMACAddress)
+	fmt.Fprintf(w, "Profile:\t%s\n", host.Status.HardwareProfile)
+	if ref := host.Spec.ConsumerRef; ref != nil {
+		fmt.Fprintf(w, "Consumer:\t%s %s/%s\n", ref.Kind, ref.Namespace, ref.Name)
+	}
+	if host.Status.ErrorType != "" || host.Status.ErrorMessage != "" {
+		fmt.Fprintf(w, "Error:\t%s: %s\n", host.Status.ErrorType, host.Status.ErrorMessage)
+	}
+
+	fmt.Fprintf(w, "\nHardware:\n")
+	if hw := host.Status.HardwareDetails; hw == nil {
+		fmt.Fprintf(w, "  not inspected\n")
+	} else {
+		serial := ""
+		if hw.SystemVendor.SerialNumber != "" {
+			serial = fmt.Sprintf("(serial %s)", hw.SystemVendor.SerialNumber)
+		}
+		fmt.Fprintf(w, "  System:\t%s\n", join(hw.SystemVendor.Manufacturer, hw.SystemVendor.ProductName, serial))
+		fmt.Fprintf(w, "  BIOS:\t%s\n", join(hw.Firmware.BIOS.Vendor, hw.Firmware.BIOS.Version))
+		fmt.Fprintf(w, "  CPU:\t%d x %s, %.0f MHz\n",
+			hw.CPU.Count, join(hw.CPU.Arch, hw.CPU.Model), float64(hw.CPU.ClockMegahertz))
+		fmt.Fprintf(w, "  RAM:\t%d MiB\n", hw.RAMMebibytes)
+		fmt.Fprintf(w, "  Hostname:\t%s\n", hw.Hostname)
+		for _, nic := range hw.NIC {
+			fmt.Fprintf(w, "  NIC %s:\t%s\n", nic.Name, join(nic.MAC, nic.IP, fmt.Sprintf("%d Gbps", nic.SpeedGbps)))
+		}
+		for _, disk := range hw.Storage {
+			kind := "SSD"
+			if disk.Rotational {
+				kind = "HDD"
+			}
+			size := fmt.Sprintf("%d GiB", disk.SizeBytes/metal3v1alpha1.GibiByte)
+			fmt.Fprintf(w, "  Disk %s:\t%s\n", disk.Name, join(size, kind, disk.Model))
+		}
+	}
+
+	fmt.Fprintf(w, "\nOperations:\n")
+	fmt.Fprintf(w, "  OPERATION\tSTART\tEND\tDURATION\n")
+	history := host.Status.OperationHistory
+	for _, operation := range []struct {
+		name   string
+		metric metal3v1alpha1.OperationMetric
+	}{
+		{"register", history.Register},
+		{"inspect", history.Inspect},
+		{"provision", history.Provision},
+		{"deprovision", history.Deprovision},
+	} {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", operation.name,
+			timestamp(operation.metric.Start), timestamp(operation.metric.End), p.operationTime(operation.metric))
+	}
+	return w.Flush()
+}
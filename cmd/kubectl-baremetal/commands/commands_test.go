@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+var now = time.Date(2020, 11, 2, 10, 0, 0, 0, time.UTC)
+
+func newHost(namespace, name string, state metal3v1alpha1.ProvisioningState) *metal3v1alpha1.BareMetalHost {
+	return &metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			Online: true,
+			BMC: metal3v1alpha1.BMCDetails{
+				Address: "ipmi://192.168.122.1",
+			},
+		},
+		Status: metal3v1alpha1.BareMetalHostStatus{
+			PoweredOn:       true,
+			HardwareProfile: "libvirt",
+			Provisioning:    metal3v1alpha1.ProvisionStatus{State: state},
+		},
+	}
+}
+
+func newPlugin(t *testing.T, hosts ...runtime.Object) (*Plugin, *bytes.Buffer) {
+	scheme := runtime.NewScheme()
+	if err := metal3v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	return &Plugin{
+		Client:       fakeclient.NewFakeClientWithScheme(scheme, hosts...),
+		Namespace:    "metal3",
+		Out:          out,
+		PollInterval: time.Millisecond,
+		Now:          func() time.Time { return now },
+	}, out
+}
+
+func getHost(t *testing.T, p *Plugin, name string) *metal3v1alpha1.BareMetalHost {
+	host := &metal3v1alpha1.BareMetalHost{}
+	if err := p.Client.Get(context.TODO(), types.NamespacedName{Namespace: "metal3", Name: name}, host); err != nil {
+		t.Fatal(err)
+	}
+	return host
+}
+
+func TestList(t *testing.T) {
+	failed := newHost("metal3", "host-1", metal3v1alpha1.StateRegistering)
+	failed.Spec.Online = false
+	failed.Status.ErrorType = metal3v1alpha1.RegistrationError
+	failed.Status.HardwareProfile = ""
+	paused := newHost("metal3", "host-2", metal3v1alpha1.StateProvisioned)
+	paused.Annotations = map[string]string{metal3v1alpha1.PausedAnnotation: ""}
+	p, out := newPlugin(t,
+		newHost("metal3", "host-0", metal3v1alpha1.StateReady),
+		failed, paused,
+		newHost("other", "host-3", metal3v1alpha1.StateReady),
+	)
+
+	assert.NoError(t, p.List(context.TODO()))
+	assert.Equal(t, ""+
+		"NAME    STATE                 POWER         PROFILE  ERROR\n"+
+		"host-0  ready                 on            libvirt  \n"+
+		"host-1  registering           powering off           registration error\n"+
+		"host-2  provisioned (paused)  on            libvirt  \n",
+		out.String())
+
+	out.Reset()
+	p.AllNamespaces = true
+	assert.NoError(t, p.List(context.TODO()))
+	assert.Contains(t, out.String(), "NAMESPACE  NAME")
+	assert.Contains(t, out.String(), "other      host-3")
+}
+
+func TestPower(t *testing.T) {
+	p, _ := newPlugin(t, newHost("metal3", "host-0", metal3v1alpha1.StateProvisioned))
+
+	assert.NoError(t, p.Power(context.TODO(), "host-0", false))
+	assert.False(t, getHost(t, p, "host-0").Spec.Online)
+	assert.NoError(t, p.Power(context.TODO(), "host-0", true))
+	assert.True(t, getHost(t, p, "host-0").Spec.Online)
+
+	assert.Error(t, p.Power(context.TODO(), "host-1", true))
+}
+
+func TestReboot(t *testing.T) {
+	p, _ := newPlugin(t, newHost("metal3", "host-0", metal3v1alpha1.StateProvisioned))
+
+	assert.NoError(t, p.Reboot(context.TODO(), "host-0", false))
+	value, ok := getHost(t, p, "host-0").Annotations[metal3v1alpha1.RebootAnnotationPrefix]
+	assert.True(t, ok)
+	assert.Equal(t, "", value)
+
+	assert.NoError(t, p.Reboot(context.TODO(), "host-0", true))
+	value = getHost(t, p, "host-0").Annotations[metal3v1alpha1.RebootAnnotationPrefix]
+	assert.Equal(t, `{"mode":"hard"}`, value)
+}
+
+func TestInspect(t *testing.T) {
+	p, _ := newPlugin(t,
+		newHost("metal3", "host-0", metal3v1alpha1.StateReady),
+		newHost("metal3", "host-1", metal3v1alpha1.StateProvisioned),
+	)
+
+	assert.NoError(t, p.Inspect(context.TODO(), "host-0"))
+	_, ok := getHost(t, p, "host-0").Annotations[metal3v1alpha1.InspectAnnotation]
+	assert.True(t, ok)
+
+	err := p.Inspect(context.TODO(), "host-1")
+	if assert.Error(t, err) {
+		assert.Equal(t, "host host-1 is provisioned, only ready hosts can be inspected", err.Error())
+	}
+	_, ok = getHost(t, p, "host-1").Annotations[metal3v1alpha1.InspectAnnotation]
+	assert.False(t, ok)
+}
+
+func TestPause(t *testing.T) {
+	clusterPaused := newHost("metal3", "host-1", metal3v1alpha1.StateProvisioned)
+	clusterPaused.Annotations = map[string]string{metal3v1alpha1.PausedAnnotation: "metal3.io/capm3"}
+	p, _ := newPlugin(t, newHost("metal3", "host-0", metal3v1alpha1.StateProvisioned), clusterPaused)
+
+	assert.NoError(t, p.Pause(context.TODO(), "host-0", true))
+	assert.Equal(t, "kubectl-baremetal", getHost(t, p, "host-0").Annotations[metal3v1alpha1.PausedAnnotation])
+	assert.NoError(t, p.Pause(context.TODO(), "host-0", false))
+	assert.False(t, isPaused(getHost(t, p, "host-0")))
+
+	err := p.Pause(context.TODO(), "host-1", false)
+	if assert.Error(t, err) {
+		assert.Equal(t, "host host-1 is paused by the cluster API provider", err.Error())
+	}
+	assert.True(t, isPaused(getHost(t, p, "host-1")))
+}
+
+func TestWait(t *testing.T) {
+	p, out := newPlugin(t, newHost("metal3", "host-0", metal3v1alpha1.StateProvisioning))
+
+	err := p.Wait(context.TODO(), "host-0", metal3v1alpha1.StateProvisioned, 10*time.Millisecond)
+	if assert.Error(t, err) {
+		assert.Equal(t, "timed out waiting for host host-0 to be provisioned, it is provisioning", err.Error())
+	}
+
+	host := getHost(t, p, "host-0")
+	host.Status.Provisioning.State = metal3v1alpha1.StateProvisioned
+	if err := p.Client.Update(context.TODO(), host); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, p.Wait(context.TODO(), "host-0", metal3v1alpha1.StateProvisioned, time.Second))
+	assert.Equal(t, "host host-0 is provisioned\n", out.String())
+}
+
+func TestDescribe(t *testing.T) {
+	host := newHost("metal3", "host-0", metal3v1alpha1.StateProvisioning)
+	host.Status.HardwareDetails = &metal3v1alpha1.HardwareDetails{
+		SystemVendor: metal3v1alpha1.HardwareSystemVendor{
+			Manufacturer: "QEMU",
+			ProductName:  "Standard PC",
+			SerialNumber: "1234",
+		},
+		RAMMebibytes: 16384,
+		CPU:          metal3v1alpha1.CPU{Arch: "x86_64", Model: "Xeon", Count: 4, ClockMegahertz: 2400},
+		NIC:          []metal3v1alpha1.NIC{{Name: "eth0", MAC: "52:54:00:aa:bb:00", IP: "192.168.111.20", SpeedGbps: 10}},
+		Storage:      []metal3v1alpha1.Storage{{Name: "/dev/sda", SizeBytes: 50 * metal3v1alpha1.GibiByte, Rotational: true}},
+	}
+	start := metav1.NewTime(now.Add(-time.Hour))
+	host.Status.OperationHistory = metal3v1alpha1.OperationHistory{
+		Register:  metal3v1alpha1.OperationMetric{Start: start, End: metav1.NewTime(start.Add(30 * time.Second))},
+		Inspect:   metal3v1alpha1.OperationMetric{Start: start, End: metav1.NewTime(start.Add(5 * time.Minute))},
+		Provision: metal3v1alpha1.OperationMetric{Start: metav1.NewTime(now.Add(-90 * time.Second))},
+	}
+	p, out := newPlugin(t, host)
+
+	assert.NoError(t, p.Describe(context.TODO(), "host-0"))
+	for _, line := range []string{
+		"State:               provisioning",
+		"  System:         QEMU Standard PC (serial 1234)\n",
+		"  CPU:            4 x x86_64 Xeon, 2400 MHz\n",
+		"  RAM:            16384 MiB\n",
+		"  NIC eth0:       52:54:00:aa:bb:00 192.168.111.20 10 Gbps\n",
+		"  Disk /dev/sda:  50 GiB HDD\n",
+		"  register     2020-11-02T09:00:00Z  2020-11-02T09:00:30Z  30s",
+		"  inspect      2020-11-02T09:00:00Z  2020-11-02T09:05:00Z  5m0s",
+		"  provision    2020-11-02T09:58:30Z                        1m30s (in progress)",
+	} {
+		assert.Contains(t, out.String(), line)
+	}
+}
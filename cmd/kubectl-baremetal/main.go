@@ -0,0 +1,188 @@
+// kubectl-baremetal is a kubectl plugin for the day-to-day operations
+// on BareMetalHosts. Installed in the PATH, it runs as
+// "kubectl baremetal".
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/cmd/kubectl-baremetal/commands"
+)
+
+const usage = `Usage: kubectl baremetal <command> [options]
+
+Commands:
+  list                       list the hosts with their state, power, profile and error
+  describe <host>            show the details, hardware and operation history of a host
+  power on|off <host>        power a host on or off
+  reboot [--hard] <host>     reboot a host
+  inspect <host>             inspect the hardware of a ready host again
+  pause <host>               stop reconciling a host
+  unpause <host>             resume reconciling a host
+  wait --for=state=<state> <host>
+                             wait for a host to reach a provisioning state
+
+Run "kubectl baremetal <command> -h" for the options of a command.
+`
+
+// globalOptions are the options every command accepts.
+type globalOptions struct {
+	kubeconfig    string
+	context       string
+	namespace     string
+	allNamespaces bool
+}
+
+func (o *globalOptions) register(fs *flag.FlagSet, list bool) {
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
+	fs.StringVar(&o.context, "context", "", "name of the kubeconfig context to use")
+	fs.StringVar(&o.namespace, "namespace", "", "namespace of the hosts, the one of the context by default")
+	fs.StringVar(&o.namespace, "n", "", "shorthand for -namespace")
+	if list {
+		fs.BoolVar(&o.allNamespaces, "all-namespaces", false, "list the hosts of all namespaces")
+		fs.BoolVar(&o.allNamespaces, "A", false, "shorthand for -all-namespaces")
+	}
+}
+
+// parse parses the options of a command, which may come before or
+// after its arguments, and returns the arguments.
+func parse(fs *flag.FlagSet, args []string) []string {
+	positional := []string{}
+	for {
+		// The FlagSet exits on errors.
+		_ = fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// newPlugin returns a Plugin using the cluster and namespace of the
+// kubeconfig.
+func newPlugin(opts *globalOptions) (*commands.Plugin, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = opts.kubeconfig
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		rules, &clientcmd.ConfigOverrides{CurrentContext: opts.context})
+
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	namespace := opts.namespace
+	if namespace == "" {
+		if namespace, _, err = config.Namespace(); err != nil {
+			return nil, err
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	if err := metal3v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	return &commands.Plugin{
+		Client:        c,
+		Namespace:     namespace,
+		AllNamespaces: opts.allNamespaces,
+		Out:           os.Stdout,
+	}, nil
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+// hostName returns the single host name given to a command.
+func hostName(command string, args []string) string {
+	if len(args) != 1 {
+		fail("%s takes the name of a host", command)
+	}
+	return args[0]
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	switch command {
+	case "list", "describe", "power", "reboot", "inspect", "pause", "unpause", "wait":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	opts := &globalOptions{}
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	opts.register(fs, command == "list")
+	var forState *string
+	var hardReboot *bool
+	var timeout *time.Duration
+	switch command {
+	case "reboot":
+		hardReboot = fs.Bool("hard", false, "power the host off without asking the operating system to shut down")
+	case "wait":
+		forState = fs.String("for", "", "the condition to wait for, state=<provisioning state>")
+		timeout = fs.Duration("timeout", 30*time.Minute, "how long to wait")
+	}
+	args := parse(fs, os.Args[2:])
+
+	var waitState metal3v1alpha1.ProvisioningState
+	if command == "wait" {
+		state := strings.TrimPrefix(*forState, "state=")
+		if state == *forState || state == "" {
+			fail("wait needs --for=state=<provisioning state>")
+		}
+		waitState = metal3v1alpha1.ProvisioningState(state)
+	}
+
+	plugin, err := newPlugin(opts)
+	if err != nil {
+		fail("%s", err)
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "list":
+		if len(args) != 0 {
+			fail("list takes no arguments")
+		}
+		err = plugin.List(ctx)
+	case "describe":
+		err = plugin.Describe(ctx, hostName(command, args))
+	case "power":
+		if len(args) != 2 || (args[0] != "on" && args[0] != "off") {
+			fail("usage: power on|off <host>")
+		}
+		err = plugin.Power(ctx, args[1], args[0] == "on")
+	case "reboot":
+		err = plugin.Reboot(ctx, hostName(command, args), *hardReboot)
+	case "inspect":
+		err = plugin.Inspect(ctx, hostName(command, args))
+	case "pause", "unpause":
+		err = plugin.Pause(ctx, hostName(command, args), command == "pause")
+	case "wait":
+		err = plugin.Wait(ctx, hostName(command, args), waitState, *timeout)
+	}
+	if err != nil {
+		fail("%s", err)
+	}
+}
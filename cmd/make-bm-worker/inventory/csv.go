@@ -0,0 +1,101 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// csvBoolColumns and csvIntColumns are the columns whose values are
+// not strings.
+var (
+	csvBoolColumns = map[string]bool{
+		"disableCertificateVerification": true,
+		"rootDeviceHints.rotational":     true,
+	}
+	csvIntColumns = map[string]bool{
+		"rootDeviceHints.minSizeGigabytes": true,
+	}
+)
+
+// parseCSV reads hosts from CSV with a header row naming the fields of
+// each column. Nested fields are named with dots, e.g.
+//
+//	name,address,username,password,labels.rack,rootDeviceHints.deviceName
+//
+// Empty cells are left out. Label names may hold dots themselves, as
+// in labels.app.kubernetes.io/part-of.
+func parseCSV(data []byte) ([]Host, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the CSV inventory has no header row")
+	}
+	header := records[0]
+
+	hosts := []Host{}
+	for i, record := range records[1:] {
+		fields := make(map[string]interface{})
+		for column, value := range record {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if err := setCSVField(fields, strings.TrimSpace(header[column]), value); err != nil {
+				return nil, fmt.Errorf("row %d: %s", i+2, err)
+			}
+		}
+		jsonData, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		host := Host{}
+		if err := decodeStrict(jsonData, &host); err != nil {
+			return nil, fmt.Errorf("row %d: %s", i+2, err)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// setCSVField sets the value of a column in the nested fields of a
+// host.
+func setCSVField(fields map[string]interface{}, column, value string) error {
+	var typed interface{} = value
+	switch {
+	case csvBoolColumns[column]:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: not a boolean", column, value)
+		}
+		typed = b
+	case csvIntColumns[column]:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: not an integer", column, value)
+		}
+		typed = n
+	}
+
+	// Fields are nested a single level deep, and label names may
+	// hold dots themselves.
+	path := strings.SplitN(column, ".", 2)
+	if len(path) == 1 {
+		fields[column] = typed
+		return nil
+	}
+	nested, ok := fields[path[0]].(map[string]interface{})
+	if !ok {
+		nested = make(map[string]interface{})
+		fields[path[0]] = nested
+	}
+	nested[path[1]] = typed
+	return nil
+}
@@ -0,0 +1,300 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// Host is an entry of an inventory. Its string fields, other than the
+// BMC address and credentials, are templates expanded by Render.
+type Host struct {
+	Name                           string                          `json:"name,omitempty"`
+	Namespace                      string                          `json:"namespace,omitempty"`
+	Labels                         map[string]string               `json:"labels,omitempty"`
+	Address                        string                          `json:"address,omitempty"`
+	Username                       string                          `json:"username,omitempty"`
+	Password                       string                          `json:"password,omitempty"`
+	DisableCertificateVerification bool                            `json:"disableCertificateVerification,omitempty"`
+	BootMACAddress                 string                          `json:"bootMACAddress,omitempty"`
+	BootMode                       string                          `json:"bootMode,omitempty"`
+	HardwareProfile                string                          `json:"hardwareProfile,omitempty"`
+	RootDeviceHints                *metal3v1alpha1.RootDeviceHints `json:"rootDeviceHints,omitempty"`
+	Image                          *metal3v1alpha1.Image           `json:"image,omitempty"`
+}
+
+// Inventory is the format of YAML and JSON inventories, whose
+// defaults apply to every host. A plain list of hosts is accepted too.
+type Inventory struct {
+	Defaults Host   `json:"defaults,omitempty"`
+	Hosts    []Host `json:"hosts"`
+}
+
+// Formats of inventory files
+const (
+	CSV    = "csv"
+	YAML   = "yaml"
+	JSON   = "json"
+	Ironic = "ironic"
+)
+
+// Load reads the hosts of an inventory file, with their defaults
+// applied. The format is told from the extension of the file when it
+// is not given, and JSON files with a "nodes" list are read as Ironic
+// nodes.
+func Load(path, format string) ([]Host, error) {
+	data, err := ioutil.ReadFile(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = CSV
+		case ".json":
+			format = JSON
+		default:
+			format = YAML
+		}
+	}
+
+	switch format {
+	case CSV:
+		return parseCSV(data)
+	case Ironic:
+		return parseIronicNodes(data)
+	case YAML, JSON:
+		return parseStructured(data)
+	default:
+		return nil, fmt.Errorf("unknown inventory format %q, use csv, yaml, json or ironic", format)
+	}
+}
+
+func parseStructured(data []byte) ([]Host, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	jsonData = bytes.TrimSpace(jsonData)
+
+	if bytes.HasPrefix(jsonData, []byte("[")) {
+		hosts := []Host{}
+		if err := decodeStrict(jsonData, &hosts); err != nil {
+			return nil, err
+		}
+		return hosts, nil
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(jsonData, &keys); err != nil {
+		return nil, err
+	}
+	if _, ok := keys["nodes"]; ok {
+		return parseIronicNodes(jsonData)
+	}
+	inventory := Inventory{}
+	if err := decodeStrict(jsonData, &inventory); err != nil {
+		return nil, err
+	}
+	for i := range inventory.Hosts {
+		inventory.Hosts[i].SetDefaults(inventory.Defaults)
+	}
+	return inventory.Hosts, nil
+}
+
+// decodeStrict decodes JSON, rejecting unknown fields so that typos
+// in inventories are reported.
+func decodeStrict(data []byte, out interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(out)
+}
+
+// SetDefaults fills the fields the host leaves empty with the ones of
+// the defaults. Labels are merged, the ones of the host winning.
+func (h *Host) SetDefaults(defaults Host) {
+	setString := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	setString(&h.Name, defaults.Name)
+	setString(&h.Namespace, defaults.Namespace)
+	setString(&h.Address, defaults.Address)
+	setString(&h.Username, defaults.Username)
+	setString(&h.Password, defaults.Password)
+	setString(&h.BootMACAddress, defaults.BootMACAddress)
+	setString(&h.BootMode, defaults.BootMode)
+	setString(&h.HardwareProfile, defaults.HardwareProfile)
+	h.DisableCertificateVerification = h.DisableCertificateVerification || defaults.DisableCertificateVerification
+	if h.RootDeviceHints == nil && defaults.RootDeviceHints != nil {
+		h.RootDeviceHints = defaults.RootDeviceHints.DeepCopy()
+	}
+	if h.Image == nil && defaults.Image != nil {
+		h.Image = defaults.Image.DeepCopy()
+	}
+	if len(defaults.Labels) != 0 {
+		labels := make(map[string]string)
+		for key, value := range defaults.Labels {
+			labels[key] = value
+		}
+		for key, value := range h.Labels {
+			labels[key] = value
+		}
+		h.Labels = labels
+	}
+}
+
+// templateData is what the templates of a host can refer to, e.g.
+// "worker-{{ .Index }}" or "http://images/{{ .Name }}.qcow2".
+type templateData struct {
+	Host
+	// Index is the position of the host in the inventory, from 0
+	Index int
+}
+
+// Render expands the templates of the name, namespace, labels, boot
+// MAC address, root device name and image of the host. The name is
+// expanded first, so that the other fields can refer to it.
+func (h *Host) Render(index int) error {
+	render := func(field *string) error {
+		if !strings.Contains(*field, "{{") {
+			return nil
+		}
+		tmpl, err := template.New("field").Option("missingkey=error").Parse(*field)
+		if err != nil {
+			return err
+		}
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, templateData{Host: *h, Index: index}); err != nil {
+			return err
+		}
+		*field = buf.String()
+		return nil
+	}
+
+	if err := render(&h.Name); err != nil {
+		return fmt.Errorf("invalid name template: %s", err)
+	}
+	fields := map[string]*string{
+		"namespace":      &h.Namespace,
+		"bootMACAddress": &h.BootMACAddress,
+	}
+	if h.RootDeviceHints != nil {
+		fields["rootDeviceHints.deviceName"] = &h.RootDeviceHints.DeviceName
+	}
+	if h.Image != nil {
+		fields["image.url"] = &h.Image.URL
+		fields["image.checksum"] = &h.Image.Checksum
+	}
+	labels := make(map[string]string)
+	for key, value := range h.Labels {
+		if err := render(&value); err != nil {
+			return fmt.Errorf("invalid template for label %s: %s", key, err)
+		}
+		labels[key] = value
+	}
+	for name, field := range fields {
+		if err := render(field); err != nil {
+			return fmt.Errorf("invalid %s template: %s", name, err)
+		}
+	}
+	if len(labels) != 0 {
+		h.Labels = labels
+	}
+	return nil
+}
+
+// Validate returns every problem found with the host, checking the BMC
+// address with the access details of its type.
+func (h *Host) Validate() []error {
+	errs := []error{}
+	if h.Name == "" {
+		errs = append(errs, fmt.Errorf("missing name"))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(h.Name) {
+			errs = append(errs, fmt.Errorf("invalid name %q: %s", h.Name, msg))
+		}
+	}
+	if h.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(h.Namespace) {
+			errs = append(errs, fmt.Errorf("invalid namespace %q: %s", h.Namespace, msg))
+		}
+	}
+	for key, value := range h.Labels {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Errorf("invalid label %q: %s", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, fmt.Errorf("invalid value %q for label %s: %s", value, key, msg))
+		}
+	}
+
+	if h.Address == "" {
+		errs = append(errs, fmt.Errorf("missing BMC address"))
+	} else if accessDetails, err := bmc.NewAccessDetails(h.Address, h.DisableCertificateVerification); err != nil {
+		errs = append(errs, err)
+	} else if accessDetails.NeedsMAC() && h.BootMACAddress == "" {
+		errs = append(errs, fmt.Errorf("BMC type %s requires a boot MAC address", accessDetails.Type()))
+	}
+	if h.Username == "" {
+		errs = append(errs, fmt.Errorf("missing BMC username"))
+	}
+	if h.Password == "" {
+		errs = append(errs, fmt.Errorf("missing BMC password"))
+	}
+
+	if h.BootMACAddress != "" {
+		if _, err := net.ParseMAC(h.BootMACAddress); err != nil {
+			errs = append(errs, fmt.Errorf("invalid boot MAC address: %s", err))
+		}
+	}
+	if h.BootMode != "" && h.BootMode != string(metal3v1alpha1.UEFI) && h.BootMode != string(metal3v1alpha1.Legacy) {
+		errs = append(errs, fmt.Errorf("invalid boot mode %q, use \"UEFI\" or \"legacy\"", h.BootMode))
+	}
+	if h.Image != nil && h.Image.URL == "" {
+		errs = append(errs, fmt.Errorf("missing image URL"))
+	}
+	return errs
+}
+
+// Prepare applies the defaults to the hosts, renders their templates
+// and validates them. Every error found is returned, naming the host
+// it was found in.
+func Prepare(hosts []Host, defaults Host) []error {
+	errs := []error{}
+	seen := make(map[string]int)
+	for i := range hosts {
+		host := &hosts[i]
+		host.SetDefaults(defaults)
+		hostErrs := []error{}
+		if err := host.Render(i); err != nil {
+			hostErrs = append(hostErrs, err)
+		} else {
+			hostErrs = append(hostErrs, host.Validate()...)
+		}
+
+		key := host.Namespace + "/" + host.Name
+		if previous, ok := seen[key]; ok && host.Name != "" {
+			hostErrs = append(hostErrs, fmt.Errorf("same name as host %d", previous))
+		} else {
+			seen[key] = i
+		}
+
+		for _, err := range hostErrs {
+			errs = append(errs, fmt.Errorf("host %d (%s): %s", i, host.Name, err))
+		}
+	}
+	return errs
+}
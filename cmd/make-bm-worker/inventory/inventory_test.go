@@ -0,0 +1,267 @@
+package inventory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+func writeInventory(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		Scenario string
+		File     string
+		Format   string
+		Content  string
+		Expected []Host
+	}{
+		{
+			Scenario: "yaml with defaults",
+			File:     "hosts.yaml",
+			Content: `
+defaults:
+  namespace: metal3
+  username: admin
+  password: secret
+  labels:
+    rack: "1"
+hosts:
+- name: worker-0
+  address: ipmi://192.168.111.1
+  labels:
+    role: worker
+- name: worker-1
+  address: ipmi://192.168.111.2
+  password: other
+`,
+			Expected: []Host{
+				{
+					Name:      "worker-0",
+					Namespace: "metal3",
+					Labels:    map[string]string{"rack": "1", "role": "worker"},
+					Address:   "ipmi://192.168.111.1",
+					Username:  "admin",
+					Password:  "secret",
+				},
+				{
+					Name:      "worker-1",
+					Namespace: "metal3",
+					Labels:    map[string]string{"rack": "1"},
+					Address:   "ipmi://192.168.111.2",
+					Username:  "admin",
+					Password:  "other",
+				},
+			},
+		},
+		{
+			Scenario: "json list",
+			File:     "hosts.json",
+			Content:  `[{"name": "worker-0", "address": "redfish://192.168.111.1", "image": {"url": "http://images/os.qcow2"}}]`,
+			Expected: []Host{
+				{
+					Name:    "worker-0",
+					Address: "redfish://192.168.111.1",
+					Image:   &metal3v1alpha1.Image{URL: "http://images/os.qcow2"},
+				},
+			},
+		},
+		{
+			Scenario: "csv",
+			File:     "hosts.csv",
+			Content: `name,address,username,password,labels.topology.kubernetes.io/zone,rootDeviceHints.deviceName,rootDeviceHints.minSizeGigabytes,disableCertificateVerification
+worker-0,idrac://192.168.111.1,admin,secret,zone-a,/dev/sda,100,true
+worker-1,idrac://192.168.111.2,admin,secret,,,,
+`,
+			Expected: []Host{
+				{
+					Name:                           "worker-0",
+					Labels:                         map[string]string{"topology.kubernetes.io/zone": "zone-a"},
+					Address:                        "idrac://192.168.111.1",
+					Username:                       "admin",
+					Password:                       "secret",
+					DisableCertificateVerification: true,
+					RootDeviceHints:                &metal3v1alpha1.RootDeviceHints{DeviceName: "/dev/sda", MinSizeGigabytes: 100},
+				},
+				{
+					Name:     "worker-1",
+					Address:  "idrac://192.168.111.2",
+					Username: "admin",
+					Password: "secret",
+				},
+			},
+		},
+		{
+			Scenario: "ironic nodes",
+			File:     "nodes.json",
+			Content: `{"nodes": [
+  {
+    "name": "node-0",
+    "driver": "ipmi",
+    "driver_info": {"ipmi_address": "192.168.111.1", "ipmi_port": 6230, "ipmi_username": "admin", "ipmi_password": "secret"},
+    "ports": [{"address": "52:54:00:aa:bb:00"}],
+    "properties": {"capabilities": "boot_mode:uefi", "root_device": {"name": "/dev/sda", "size": ">= 100"}}
+  },
+  {
+    "name": "node-1",
+    "driver": "redfish",
+    "driver_info": {"redfish_address": "http://192.168.111.2:8000", "redfish_system_id": "/redfish/v1/Systems/1", "redfish_username": "admin", "redfish_password": "secret"}
+  }
+]}`,
+			Expected: []Host{
+				{
+					Name:            "node-0",
+					Address:         "ipmi://192.168.111.1:6230",
+					Username:        "admin",
+					Password:        "secret",
+					BootMACAddress:  "52:54:00:aa:bb:00",
+					BootMode:        "UEFI",
+					RootDeviceHints: &metal3v1alpha1.RootDeviceHints{DeviceName: "/dev/sda", MinSizeGigabytes: 100},
+				},
+				{
+					Name:     "node-1",
+					Address:  "redfish+http://192.168.111.2:8000/redfish/v1/Systems/1",
+					Username: "admin",
+					Password: "secret",
+				},
+			},
+		},
+		{
+			Scenario: "tripleo nodes",
+			File:     "instackenv.yaml",
+			Format:   Ironic,
+			Content: `{"nodes": [
+  {
+    "name": "node-0",
+    "pm_type": "pxe_ilo",
+    "pm_addr": "192.168.111.1",
+    "pm_user": "admin",
+    "pm_password": "secret",
+    "mac": ["52:54:00:aa:bb:00"],
+    "capabilities": "profile:compute,boot_mode:bios"
+  }
+]}`,
+			Expected: []Host{
+				{
+					Name:           "node-0",
+					Address:        "ilo4://192.168.111.1",
+					Username:       "admin",
+					Password:       "secret",
+					BootMACAddress: "52:54:00:aa:bb:00",
+					BootMode:       "legacy",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			hosts, err := Load(writeInventory(t, tc.File, tc.Content), tc.Format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.Expected, hosts)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	testCases := []struct {
+		Scenario string
+		File     string
+		Content  string
+		Error    string
+	}{
+		{
+			Scenario: "unknown field",
+			File:     "hosts.yaml",
+			Content:  "hosts:\n- name: worker-0\n  adress: ipmi://192.168.111.1\n",
+			Error:    `json: unknown field "adress"`,
+		},
+		{
+			Scenario: "invalid csv value",
+			File:     "hosts.csv",
+			Content:  "name,rootDeviceHints.rotational\nworker-0,maybe\n",
+			Error:    `row 2: invalid rootDeviceHints.rotational "maybe": not a boolean`,
+		},
+		{
+			Scenario: "unsupported driver",
+			File:     "nodes.json",
+			Content:  `{"nodes": [{"name": "node-0", "driver": "fake-hardware"}]}`,
+			Error:    `node 0 (node-0): unsupported driver "fake-hardware"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			_, err := Load(writeInventory(t, tc.File, tc.Content), "")
+			if assert.Error(t, err) {
+				assert.Equal(t, tc.Error, err.Error())
+			}
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	hosts := []Host{
+		{Address: "ipmi://192.168.111.1"},
+		{Address: "ipmi://192.168.111.2", Labels: map[string]string{"rack": "{{ .Name }}"}},
+	}
+	defaults := Host{
+		Name:           "worker-{{ .Index }}",
+		Namespace:      "metal3",
+		Username:       "admin",
+		Password:       "secret",
+		BootMACAddress: "52:54:00:aa:bb:0{{ .Index }}",
+		Image:          &metal3v1alpha1.Image{URL: "http://images/{{ .Name }}.qcow2"},
+	}
+
+	errs := Prepare(hosts, defaults)
+
+	assert.Empty(t, errs)
+	assert.Equal(t, "worker-0", hosts[0].Name)
+	assert.Equal(t, "52:54:00:aa:bb:00", hosts[0].BootMACAddress)
+	assert.Equal(t, "http://images/worker-0.qcow2", hosts[0].Image.URL)
+	assert.Equal(t, "worker-1", hosts[1].Name)
+	assert.Equal(t, map[string]string{"rack": "worker-1"}, hosts[1].Labels)
+	assert.Equal(t, "http://images/worker-1.qcow2", hosts[1].Image.URL)
+}
+
+func TestPrepareErrors(t *testing.T) {
+	hosts := []Host{
+		{Name: "worker-0", Address: "ipmi://192.168.111.1"},
+		{Name: "worker-0", Address: "ipmi://192.168.111.2"},
+		{Name: "Worker_2", Address: "unknown://192.168.111.3", BootMode: "bios"},
+		{Name: "worker-3", Address: "libvirt://192.168.111.4/"},
+	}
+
+	errs := Prepare(hosts, Host{Username: "admin", Password: "secret"})
+
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	assert.Equal(t, []string{
+		"host 1 (worker-0): same name as host 0",
+		`host 2 (Worker_2): invalid name "Worker_2": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		`host 2 (Worker_2): Unknown BMC type 'unknown' for address unknown://192.168.111.3`,
+		`host 2 (Worker_2): invalid boot mode "bios", use "UEFI" or "legacy"`,
+		"host 3 (worker-3): BMC type libvirt requires a boot MAC address",
+	}, messages)
+}
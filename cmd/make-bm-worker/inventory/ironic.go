@@ -0,0 +1,239 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// ironicNode is a node of a nodes.json file, as given to
+// "openstack baremetal create", or of a TripleO instackenv.json file
+// using the pm_* fields.
+type ironicNode struct {
+	Name       string                 `json:"name"`
+	Driver     string                 `json:"driver"`
+	DriverInfo map[string]interface{} `json:"driver_info"`
+	Ports      []struct {
+		Address string `json:"address"`
+	} `json:"ports"`
+	Properties struct {
+		RootDevice   map[string]interface{} `json:"root_device"`
+		Capabilities string                 `json:"capabilities"`
+	} `json:"properties"`
+
+	PMType       string                 `json:"pm_type"`
+	PMAddr       string                 `json:"pm_addr"`
+	PMPort       interface{}            `json:"pm_port"`
+	PMUser       string                 `json:"pm_user"`
+	PMPassword   string                 `json:"pm_password"`
+	PMSystemID   string                 `json:"pm_system_id"`
+	MAC          []string               `json:"mac"`
+	Capabilities string                 `json:"capabilities"`
+	RootDevice   map[string]interface{} `json:"root_device"`
+}
+
+type ironicNodes struct {
+	Nodes []ironicNode `json:"nodes"`
+}
+
+// parseIronicNodes reads the hosts of a nodes.json file. Nodes whose
+// driver has no matching BMC type are reported as errors.
+func parseIronicNodes(data []byte) ([]Host, error) {
+	nodes := ironicNodes{}
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+	hosts := []Host{}
+	for i, node := range nodes.Nodes {
+		host, err := node.host()
+		if err != nil {
+			return nil, fmt.Errorf("node %d (%s): %s", i, node.Name, err)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// ironicDriver tells how the BMC of the nodes of an Ironic driver is
+// addressed.
+type ironicDriver struct {
+	bmcType  string
+	address  string
+	port     string
+	username string
+	password string
+}
+
+var ironicDrivers = map[string]ironicDriver{
+	"ipmi":    {"ipmi", "ipmi_address", "ipmi_port", "ipmi_username", "ipmi_password"},
+	"redfish": {"redfish", "redfish_address", "", "redfish_username", "redfish_password"},
+	"idrac":   {"idrac", "drac_address", "drac_port", "drac_username", "drac_password"},
+	// The ilo hardware type drives iLO 4 and older.
+	"ilo":  {"ilo4", "ilo_address", "client_port", "ilo_username", "ilo_password"},
+	"ilo5": {"ilo5", "ilo_address", "client_port", "ilo_username", "ilo_password"},
+	"irmc": {"irmc", "irmc_address", "irmc_port", "irmc_username", "irmc_password"},
+}
+
+// tripleoDrivers maps the classic driver names still found in the
+// pm_type of TripleO nodes to hardware types.
+var tripleoDrivers = map[string]string{
+	"pxe_ipmitool": "ipmi",
+	"pxe_drac":     "idrac",
+	"pxe_ilo":      "ilo",
+	"pxe_irmc":     "irmc",
+}
+
+func (node *ironicNode) info(key string) string {
+	value, ok := node.DriverInfo[key]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// withPort joins the address and the port, when there is one.
+func withPort(address, port string) string {
+	if port == "" {
+		return address
+	}
+	return net.JoinHostPort(address, port)
+}
+
+func (node *ironicNode) host() (host Host, err error) {
+	host.Name = node.Name
+	driverName := node.Driver
+	var address, port, systemID string
+	capabilities, rootDevice, macs := node.Properties.Capabilities, node.Properties.RootDevice, []string{}
+	for _, p := range node.Ports {
+		macs = append(macs, p.Address)
+	}
+
+	if node.PMType != "" {
+		driverName = node.PMType
+		if hardwareType, ok := tripleoDrivers[driverName]; ok {
+			driverName = hardwareType
+		}
+		address, systemID = node.PMAddr, node.PMSystemID
+		if node.PMPort != nil {
+			port = fmt.Sprint(node.PMPort)
+		}
+		host.Username, host.Password = node.PMUser, node.PMPassword
+		if capabilities == "" {
+			capabilities = node.Capabilities
+		}
+		if rootDevice == nil {
+			rootDevice = node.RootDevice
+		}
+		macs = append(macs, node.MAC...)
+	}
+
+	driver, ok := ironicDrivers[driverName]
+	if !ok {
+		return host, fmt.Errorf("unsupported driver %q", driverName)
+	}
+	if node.PMType == "" {
+		address, port = node.info(driver.address), node.info(driver.port)
+		host.Username, host.Password = node.info(driver.username), node.info(driver.password)
+		systemID = node.info("redfish_system_id")
+		host.DisableCertificateVerification = node.info("redfish_verify_ca") == "false"
+	}
+	if driver.bmcType == "redfish" {
+		host.Address, err = redfishAddress(withPort(address, port), systemID)
+		if err != nil {
+			return host, err
+		}
+	} else {
+		host.Address = fmt.Sprintf("%s://%s", driver.bmcType, withPort(address, port))
+	}
+
+	if len(macs) != 0 {
+		host.BootMACAddress = macs[0]
+	}
+	for _, capability := range strings.Split(capabilities, ",") {
+		switch strings.TrimSpace(capability) {
+		case "boot_mode:uefi":
+			host.BootMode = string(metal3v1alpha1.UEFI)
+		case "boot_mode:bios":
+			host.BootMode = string(metal3v1alpha1.Legacy)
+		}
+	}
+	if len(rootDevice) != 0 {
+		host.RootDeviceHints, err = rootDeviceHints(rootDevice)
+	}
+	return host, err
+}
+
+// redfishAddress builds the BMC address of a redfish_address, which
+// may give the scheme of the Redfish service, and a system ID.
+func redfishAddress(address, systemID string) (string, error) {
+	bmcType := "redfish"
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	parsedURL, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid redfish_address: %s", err)
+	}
+	if parsedURL.Scheme == "http" {
+		bmcType = "redfish+http"
+	}
+	return fmt.Sprintf("%s://%s%s", bmcType, parsedURL.Host, systemID), nil
+}
+
+// rootDeviceHintKeys maps the root device hints of Ironic to the
+// fields of the hints of hosts.
+var rootDeviceHintKeys = map[string]string{
+	"name":                 "deviceName",
+	"hctl":                 "hctl",
+	"model":                "model",
+	"vendor":               "vendor",
+	"serial":               "serialNumber",
+	"size":                 "minSizeGigabytes",
+	"wwn":                  "wwn",
+	"wwn_with_extension":   "wwnWithExtension",
+	"wwn_vendor_extension": "wwnVendorExtension",
+	"rotational":           "rotational",
+}
+
+func rootDeviceHints(rootDevice map[string]interface{}) (*metal3v1alpha1.RootDeviceHints, error) {
+	fields := make(map[string]interface{})
+	for key, value := range rootDevice {
+		field, ok := rootDeviceHintKeys[key]
+		if !ok {
+			return nil, fmt.Errorf("unsupported root device hint %q", key)
+		}
+		switch field {
+		case "minSizeGigabytes":
+			// Ironic compares sizes with operators, e.g. ">= 100",
+			// hosts only take a minimum.
+			size := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(fmt.Sprint(value)), ">="))
+			n, err := strconv.Atoi(size)
+			if err != nil {
+				return nil, fmt.Errorf("unsupported size root device hint %q", value)
+			}
+			fields[field] = n
+		case "rotational":
+			b, err := strconv.ParseBool(fmt.Sprint(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid rotational root device hint %q", value)
+			}
+			fields[field] = b
+		default:
+			fields[field] = fmt.Sprint(value)
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	hints := &metal3v1alpha1.RootDeviceHints{}
+	if err := json.Unmarshal(data, hints); err != nil {
+		return nil, err
+	}
+	return hints, nil
+}
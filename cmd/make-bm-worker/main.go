@@ -6,9 +6,31 @@ import (
 	"os"
 	"strings"
 
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/cmd/make-bm-worker/inventory"
 	"github.com/metal3-io/baremetal-operator/cmd/make-bm-worker/templates"
 )
 
+// labelFlags collects the key=value pairs of repeated -label flags.
+type labelFlags map[string]string
+
+func (l labelFlags) String() string {
+	pairs := []string{}
+	for key, value := range l {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (l labelFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	l[parts[0]] = parts[1]
+	return nil
+}
+
 func main() {
 	var username = flag.String("user", "", "username for BMC")
 	var password = flag.String("password", "", "password for BMC")
@@ -22,55 +44,114 @@ func main() {
 		"consumer", "", "specify name of a related, existing, consumer to link")
 	var consumerNamespace = flag.String(
 		"consumer-namespace", "", "specify namespace of a related, existing, consumer to link")
+	var inventoryPath = flag.String(
+		"inventory", "", "CSV, YAML, JSON or Ironic nodes file listing the hosts to create")
+	var inventoryFormat = flag.String(
+		"inventory-format", "", "format of the inventory (csv, yaml, json or ironic), told from the file extension by default")
+	var namespace = flag.String("namespace", "", "namespace of the hosts and their secrets")
+	var imageURL = flag.String("image-url", "", "URL of the image to provision")
+	var imageChecksum = flag.String("image-checksum", "", "checksum, or URL of the checksum, of the image")
+	var imageChecksumType = flag.String("image-checksum-type", "", "checksum algorithm of the image (md5, sha256 or sha512)")
+	var rootDevice = flag.String("root-device", "", "device name of the root disk, e.g. /dev/sda")
+	labels := labelFlags{}
+	flag.Var(labels, "label", "key=value label of the hosts, may be repeated")
 
 	flag.Parse()
 
+	// With an inventory, the name argument and the flags give the
+	// defaults of the hosts listed, and the name may be a template
+	// such as "worker-{{ .Index }}".
 	hostName := flag.Arg(0)
-	if hostName == "" {
-		fmt.Fprintf(os.Stderr, "Missing name argument\n")
-		os.Exit(1)
-	}
-	if *username == "" {
-		fmt.Fprintf(os.Stderr, "Missing -user argument\n")
-		os.Exit(1)
-	}
-	if *password == "" {
-		fmt.Fprintf(os.Stderr, "Missing -password argument\n")
-		os.Exit(1)
-	}
-	if *bmcAddress == "" {
-		fmt.Fprintf(os.Stderr, "Missing -address argument\n")
-		os.Exit(1)
-	}
-
-	if *bootMode != "" && *bootMode != "UEFI" && *bootMode != "legacy" {
-		fmt.Fprintf(os.Stderr, "Invalid boot mode %q, use \"UEFI\" or \"legacy\"\n", *bootMode)
-		os.Exit(1)
+	if *inventoryPath == "" {
+		if hostName == "" {
+			fmt.Fprintf(os.Stderr, "Missing name argument\n")
+			os.Exit(1)
+		}
+		if *username == "" {
+			fmt.Fprintf(os.Stderr, "Missing -user argument\n")
+			os.Exit(1)
+		}
+		if *password == "" {
+			fmt.Fprintf(os.Stderr, "Missing -password argument\n")
+			os.Exit(1)
+		}
+		if *bmcAddress == "" {
+			fmt.Fprintf(os.Stderr, "Missing -address argument\n")
+			os.Exit(1)
+		}
+		hostName = strings.Replace(hostName, "_", "-", -1)
 	}
 
-	template := templates.Template{
-		Name:                           strings.Replace(hostName, "_", "-", -1),
-		BMCAddress:                     *bmcAddress,
-		DisableCertificateVerification: *disableCertificateVerification,
+	defaults := inventory.Host{
+		Name:                           hostName,
+		Namespace:                      *namespace,
+		Labels:                         labels,
+		Address:                        *bmcAddress,
 		Username:                       *username,
 		Password:                       *password,
+		DisableCertificateVerification: *disableCertificateVerification,
+		BootMACAddress:                 *macAddress,
+		BootMode:                       *bootMode,
 		HardwareProfile:                *hardwareProfile,
-		BootMacAddress:                 *macAddress,
-		Consumer:                       strings.TrimSpace(*consumer),
-		ConsumerNamespace:              strings.TrimSpace(*consumerNamespace),
 	}
-	if bootMode != nil {
-		template.BootMode = *bootMode
+	if *rootDevice != "" {
+		defaults.RootDeviceHints = &metal3v1alpha1.RootDeviceHints{DeviceName: *rootDevice}
+	}
+	if *imageURL != "" {
+		defaults.Image = &metal3v1alpha1.Image{
+			URL:          *imageURL,
+			Checksum:     *imageChecksum,
+			ChecksumType: metal3v1alpha1.ChecksumType(*imageChecksumType),
+		}
 	}
-	if *verbose {
-		fmt.Fprintf(os.Stderr, "%v", template)
+
+	hosts := []inventory.Host{{}}
+	if *inventoryPath != "" {
+		var err error
+		hosts, err = inventory.Load(*inventoryPath, *inventoryFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: could not read inventory %s: %s\n", *inventoryPath, err)
+			os.Exit(1)
+		}
 	}
 
-	result, err := template.Render()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+	// Every host is checked before anything is written, so that a
+	// mistake does not leave a partial set of hosts behind.
+	if errs := inventory.Prepare(hosts, defaults); len(errs) != 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		}
 		os.Exit(1)
-	} else {
-		fmt.Fprint(os.Stdout, result)
 	}
+
+	results := []string{}
+	for _, host := range hosts {
+		template := templates.Template{
+			Name:                           host.Name,
+			BMCAddress:                     host.Address,
+			DisableCertificateVerification: host.DisableCertificateVerification,
+			Username:                       host.Username,
+			Password:                       host.Password,
+			HardwareProfile:                host.HardwareProfile,
+			BootMacAddress:                 host.BootMACAddress,
+			BootMode:                       host.BootMode,
+			Consumer:                       strings.TrimSpace(*consumer),
+			ConsumerNamespace:              strings.TrimSpace(*consumerNamespace),
+			Namespace:                      host.Namespace,
+			Labels:                         host.Labels,
+			RootDeviceHints:                host.RootDeviceHints,
+			Image:                          host.Image,
+		}
+		if *verbose {
+			fmt.Fprintf(os.Stderr, "%v", template)
+		}
+
+		result, err := template.Render()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		results = append(results, result)
+	}
+	fmt.Fprint(os.Stdout, strings.Join(results, ""))
 }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/base64"
 	"text/template"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 )
 
 var templateBody = `---
@@ -11,6 +13,9 @@ apiVersion: v1
 kind: Secret
 metadata:
   name: {{ .Name }}-bmc-secret
+{{- if .Namespace }}
+  namespace: {{ .Namespace }}
+{{- end }}
 type: Opaque
 data:
   username: {{ .EncodedUsername }}
@@ -21,6 +26,15 @@ apiVersion: metal3.io/v1alpha1
 kind: BareMetalHost
 metadata:
   name: {{ .Name }}
+{{- if .Namespace }}
+  namespace: {{ .Namespace }}
+{{- end }}
+{{- if .Labels }}
+  labels:
+{{- range $key, $value := .Labels }}
+    {{ $key }}: {{ printf "%q" $value }}
+{{- end }}
+{{- end }}
 spec:
   online: true
 {{- if .HardwareProfile }}
@@ -43,6 +57,52 @@ spec:
 {{- if .DisableCertificateVerification }}
   disableCertificateVerification: true
 {{- end}}
+{{- with .RootDeviceHints }}
+  rootDeviceHints:
+{{- if .DeviceName }}
+    deviceName: {{ printf "%q" .DeviceName }}
+{{- end }}
+{{- if .HCTL }}
+    hctl: {{ printf "%q" .HCTL }}
+{{- end }}
+{{- if .Model }}
+    model: {{ printf "%q" .Model }}
+{{- end }}
+{{- if .Vendor }}
+    vendor: {{ printf "%q" .Vendor }}
+{{- end }}
+{{- if .SerialNumber }}
+    serialNumber: {{ printf "%q" .SerialNumber }}
+{{- end }}
+{{- if .MinSizeGigabytes }}
+    minSizeGigabytes: {{ .MinSizeGigabytes }}
+{{- end }}
+{{- if .WWN }}
+    wwn: {{ printf "%q" .WWN }}
+{{- end }}
+{{- if .WWNWithExtension }}
+    wwnWithExtension: {{ printf "%q" .WWNWithExtension }}
+{{- end }}
+{{- if .WWNVendorExtension }}
+    wwnVendorExtension: {{ printf "%q" .WWNVendorExtension }}
+{{- end }}
+{{- if .Rotational }}
+    rotational: {{ .Rotational }}
+{{- end }}
+{{- end }}
+{{- with .Image }}
+  image:
+    url: {{ printf "%q" .URL }}
+{{- if .Checksum }}
+    checksum: {{ printf "%q" .Checksum }}
+{{- end }}
+{{- if .ChecksumType }}
+    checksumType: {{ .ChecksumType }}
+{{- end }}
+{{- if .DiskFormat }}
+    format: {{ .DiskFormat }}
+{{- end }}
+{{- end }}
 `
 
 // Template holds the arguments to pass to the template.
@@ -57,6 +117,10 @@ type Template struct {
 	BootMode                       string
 	Consumer                       string
 	ConsumerNamespace              string
+	Namespace                      string
+	Labels                         map[string]string
+	RootDeviceHints                *metal3v1alpha1.RootDeviceHints
+	Image                          *metal3v1alpha1.Image
 }
 
 // EncodedUsername returns the username in the format needed to store
@@ -3,6 +3,8 @@ package templates
 import (
 	"strings"
 	"testing"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 )
 
 func compareStrings(t *testing.T, s1, s2 string) bool {
@@ -134,3 +136,68 @@ spec:
 		t.Fail()
 	}
 }
+
+func TestWithInventoryFields(t *testing.T) {
+	rotational := false
+	format := "qcow2"
+	template := Template{
+		Name:       "hostname",
+		BMCAddress: "bmcAddress",
+		Username:   "username",
+		Password:   "password",
+		Namespace:  "metal3",
+		Labels:     map[string]string{"rack": "1", "role": "worker"},
+		RootDeviceHints: &metal3v1alpha1.RootDeviceHints{
+			DeviceName:       "/dev/sda",
+			SerialNumber:     "12345",
+			MinSizeGigabytes: 100,
+			Rotational:       &rotational,
+		},
+		Image: &metal3v1alpha1.Image{
+			URL:          "http://images/worker.qcow2",
+			Checksum:     "http://images/worker.qcow2.md5sum",
+			ChecksumType: metal3v1alpha1.MD5,
+			DiskFormat:   &format,
+		},
+	}
+	actual, _ := template.Render()
+	expected := `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: hostname-bmc-secret
+  namespace: metal3
+type: Opaque
+data:
+  username: dXNlcm5hbWU=
+  password: cGFzc3dvcmQ=
+
+---
+apiVersion: metal3.io/v1alpha1
+kind: BareMetalHost
+metadata:
+  name: hostname
+  namespace: metal3
+  labels:
+    rack: "1"
+    role: "worker"
+spec:
+  online: true
+  bmc:
+    address: bmcAddress
+    credentialsName: hostname-bmc-secret
+  rootDeviceHints:
+    deviceName: "/dev/sda"
+    serialNumber: "12345"
+    minSizeGigabytes: 100
+    rotational: false
+  image:
+    url: "http://images/worker.qcow2"
+    checksum: "http://images/worker.qcow2.md5sum"
+    checksumType: md5
+    format: qcow2
+`
+	if !compareStrings(t, expected, actual) {
+		t.Fail()
+	}
+}
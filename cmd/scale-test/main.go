@@ -0,0 +1,93 @@
+// scale-test runs the BareMetalHost controller against a large number
+// of simulated hosts and reports how it copes, optionally comparing
+// the results with a baseline from an earlier run.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/metal3-io/baremetal-operator/pkg/scale"
+)
+
+func report(result *scale.Result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "hosts provisioned\t%d / %d\n", result.Provisioned, result.Config.Hosts)
+	fmt.Fprintf(w, "time to provisioned\tp50 %s, p90 %s, p99 %s, max %s\n",
+		result.TimeToProvisioned.P50, result.TimeToProvisioned.P90,
+		result.TimeToProvisioned.P99, result.TimeToProvisioned.Max)
+	fmt.Fprintf(w, "virtual time\t%s\n", result.VirtualTime)
+	fmt.Fprintf(w, "reconciles\t%d (%d errors)\n", result.Reconciles, result.Errors)
+	fmt.Fprintf(w, "reconcile throughput\t%.0f/s\n", result.ReconcilesPerSecond)
+	fmt.Fprintf(w, "reconciles per host\tmean %.1f, max %d\n", result.ReconcilesPerHost, result.MaxReconcilesPerHost)
+	fmt.Fprintf(w, "steady state reconciles\t%.1f per host per hour\n", result.SteadyReconcilesPerHostHour)
+	fmt.Fprintf(w, "queue depth\tmean %.1f, max %d\n", result.MeanQueueDepth, result.MaxQueueDepth)
+	fmt.Fprintf(w, "writes per host\t%.1f\n", result.WritesPerHost)
+
+	kinds := make([]string, 0, len(result.Writes))
+	for kind := range result.Writes {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "  %s writes\t%d\n", kind, result.Writes[kind])
+	}
+	w.Flush()
+}
+
+func main() {
+	config := scale.DefaultConfig()
+	flag.IntVar(&config.Hosts, "hosts", config.Hosts, "number of hosts to create")
+	flag.IntVar(&config.Concurrency, "concurrency", config.Concurrency, "number of hosts reconciled at the same time")
+	flag.StringVar(&config.Backend, "backend", config.Backend,
+		fmt.Sprintf("provisioner backing the hosts, %s or %s", scale.BackendFixture, scale.BackendFakeIronic))
+	flag.DurationVar(&config.ReconcileTime, "reconcile-time", config.ReconcileTime, "virtual time taken by each reconcile")
+	flag.DurationVar(&config.Soak, "soak", config.Soak, "virtual time to keep running once all hosts are provisioned")
+	flag.DurationVar(&config.Timeout, "timeout", config.Timeout, "virtual time after which to give up")
+	var baselinePath = flag.String("baseline", "", "compare the results with the ones saved in this file")
+	var tolerance = flag.Float64("tolerance", 0.1, "fraction by which results may be worse than the baseline")
+	var outputPath = flag.String("output", "", "save the results to this file, to be used as a baseline")
+	var devLogging = flag.Bool("dev", false, "enable developer logging")
+	flag.Parse()
+
+	if *devLogging {
+		ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	}
+
+	result, err := scale.Run(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scale test failed: %s\n", err)
+		os.Exit(1)
+	}
+	report(result)
+
+	if *outputPath != "" {
+		if err := result.Save(*outputPath); err != nil {
+			fmt.Fprintf(os.Stderr, "could not save the results: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *baselinePath != "" {
+		baseline, err := scale.Load(*baselinePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not read the baseline: %s\n", err)
+			os.Exit(1)
+		}
+		regressions := scale.Compare(baseline, result, *tolerance)
+		if len(regressions) != 0 {
+			fmt.Println("\nworse than the baseline:")
+			for _, regression := range regressions {
+				fmt.Printf("  %s\n", regression)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("\nno regression from the baseline")
+	}
+}
@@ -19,9 +19,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"reflect"
-	"strconv"
 	"strings"
 	"time"
 
@@ -32,17 +30,24 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/bmctrust"
+	"github.com/metal3-io/baremetal-operator/pkg/credentials"
 	"github.com/metal3-io/baremetal-operator/pkg/hardware"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish"
 	"github.com/metal3-io/baremetal-operator/pkg/utils"
 )
 
@@ -50,7 +55,7 @@ const (
 	hostErrorRetryDelay           = time.Second * 10
 	unmanagedRetryDelay           = time.Minute * 10
 	provisionerNotReadyRetryDelay = time.Second * 30
-	rebootAnnotationPrefix        = "reboot.metal3.io"
+	credentialsNameField          = "spec.bmc.credentialsName"
 )
 
 // BareMetalHostReconciler reconciles a BareMetalHost object
@@ -59,6 +64,22 @@ type BareMetalHostReconciler struct {
 	Log                logr.Logger
 	Scheme             *runtime.Scheme
 	ProvisionerFactory provisioner.Factory
+	// ConcurrencyLimit, when set, bounds the number of hosts
+	// reconciled at the same time.
+	ConcurrencyLimit *ConcurrencyLimit
+	// HostLocks, when set, keeps the other controllers changing a
+	// host, such as the rotation of its BMC password, from doing so
+	// while it is reconciled.
+	HostLocks *HostLocks
+	// BMCTrust provides the provisioner with the CA bundles the BMC
+	// certificates are verified against, when set.
+	BMCTrust *bmctrust.Store
+	// CredentialsResolver looks up the BMC credentials of the hosts.
+	// When nil, they are read from Kubernetes secrets.
+	CredentialsResolver credentials.Resolver
+	// DetectBMC detects the type of redfish-auto BMCs. Defaults to
+	// redfish.Detect.
+	DetectBMC func(address string, creds bmc.Credentials, disableCertificateVerification bool, caFile string) (*metal3v1alpha1.DetectedBMC, error)
 }
 
 // Instead of passing a zillion arguments to the action of a phase,
@@ -68,6 +89,8 @@ type reconcileInfo struct {
 	host              *metal3v1alpha1.BareMetalHost
 	request           ctrl.Request
 	bmcCredsSecret    *corev1.Secret
+	bmcCredsSets      []bmc.Credentials
+	bmcCredsSet       int
 	events            []corev1.Event
 	errorMessage      string
 	postSaveCallbacks []func()
@@ -82,10 +105,19 @@ func (info *reconcileInfo) publishEvent(reason, message string) {
 // +kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;update
 // +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 // Reconcile handles changes to BareMetalHost resources
 func (r *BareMetalHostReconciler) Reconcile(request ctrl.Request) (result ctrl.Result, err error) {
 
+	if r.ConcurrencyLimit != nil {
+		r.ConcurrencyLimit.Acquire()
+		defer r.ConcurrencyLimit.Release()
+	}
+	if r.HostLocks != nil {
+		defer r.HostLocks.Lock(request.NamespacedName)()
+	}
+
 	reconcileCounters.With(hostMetricLabels(request)).Inc()
 	defer func() {
 		if err != nil {
@@ -177,24 +209,64 @@ func (r *BareMetalHostReconciler) Reconcile(request ctrl.Request) (result ctrl.R
 	// Retrieve the BMC details from the host spec and validate host
 	// BMC details and build the credentials for talking to the
 	// management controller.
-	var bmcCreds *bmc.Credentials
+	var bmcCredsSets []bmc.Credentials
 	var bmcCredsSecret *corev1.Secret
+	bmcCredsSet := 0
 	haveCreds := false
 	switch host.Status.Provisioning.State {
 	case metal3v1alpha1.StateNone, metal3v1alpha1.StateUnmanaged:
-		bmcCreds = &bmc.Credentials{}
+		bmcCredsSets = []bmc.Credentials{{}}
 	default:
-		bmcCreds, bmcCredsSecret, err = r.buildAndValidateBMCCredentials(request, host)
-		if err != nil || bmcCreds == nil {
+		bmcCredsSets, bmcCredsSecret, err = r.buildAndValidateBMCCredentials(request, host)
+		if err != nil || bmcCredsSets == nil {
 			if !host.DeletionTimestamp.IsZero() {
 				// If we are in the process of deletion, try with empty credentials
-				bmcCreds = &bmc.Credentials{}
+				bmcCredsSets = []bmc.Credentials{{}}
 				bmcCredsSecret = &corev1.Secret{}
 			} else {
 				return r.credentialsErrorResult(err, request, host)
 			}
 		} else {
 			haveCreds = true
+			bmcCredsSet = credentialsSet(host, bmcCredsSecret, len(bmcCredsSets))
+		}
+	}
+
+	// The CA bundle of the BMC is written before its type is detected,
+	// so that detection verifies its certificate against it.
+	if haveCreds && r.BMCTrust != nil {
+		certificate, err := r.BMCTrust.Update(context.TODO(), host)
+		if err != nil {
+			return r.bmcCertificateErrorResult(err, request, host)
+		}
+		if !reflect.DeepEqual(certificate, host.Status.BMCCertificate) {
+			reqLogger.Info("updating the BMC certificate settings", "certificate", certificate)
+			host.Status.BMCCertificate = certificate
+			if err := r.saveHostStatus(host); err != nil {
+				return ctrl.Result{}, errors.Wrap(err, "failed to save the BMC certificate settings")
+			}
+			return ctrl.Result{Requeue: true}, nil
+		}
+	}
+
+	if haveCreds && (redfish.IsAutoDetect(host.Spec.BMC.Address) || host.Status.DetectedBMC != nil) {
+		var detected *metal3v1alpha1.DetectedBMC
+		if redfish.IsAutoDetect(host.Spec.BMC.Address) {
+			detected = host.Status.DetectedBMC
+			if detected == nil || detected.Address != host.Spec.BMC.Address {
+				detected, err = r.detectBMC(host, bmcCredsSets[bmcCredsSet])
+				if err != nil {
+					return r.bmcDetectionErrorResult(err, request, host)
+				}
+			}
+		}
+		if !reflect.DeepEqual(detected, host.Status.DetectedBMC) {
+			reqLogger.Info("updating the detected BMC type", "detected", detected)
+			host.Status.DetectedBMC = detected
+			if err := r.saveHostStatus(host); err != nil {
+				return ctrl.Result{}, errors.Wrap(err, "failed to save the detected BMC type")
+			}
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
@@ -204,8 +276,10 @@ func (r *BareMetalHostReconciler) Reconcile(request ctrl.Request) (result ctrl.R
 		host:           host,
 		request:        request,
 		bmcCredsSecret: bmcCredsSecret,
+		bmcCredsSets:   bmcCredsSets,
+		bmcCredsSet:    bmcCredsSet,
 	}
-	prov, err := r.ProvisionerFactory(*host, *bmcCreds, info.publishEvent)
+	prov, err := r.ProvisionerFactory(provisionerHost(host), bmcCredsSets[bmcCredsSet], info.publishEvent)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "failed to create provisioner")
 	}
@@ -321,7 +395,8 @@ func (r *BareMetalHostReconciler) credentialsErrorResult(err error, request ctrl
 	// as fixing the secret or the host BMC info will trigger
 	// the host to be reconciled again
 	case *EmptyBMCAddressError, *EmptyBMCSecretError,
-		*bmc.CredentialsValidationError, *bmc.UnknownBMCTypeError:
+		*bmc.CredentialsValidationError, *bmc.UnknownBMCTypeError,
+		*bmc.InvalidOptionError:
 		credentialsInvalid.Inc()
 		saveErr := r.setErrorCondition(request, host, metal3v1alpha1.RegistrationError, err.Error())
 		if saveErr != nil {
@@ -337,6 +412,52 @@ func (r *BareMetalHostReconciler) credentialsErrorResult(err error, request ctrl
 	}
 }
 
+// Problems with the certificate settings of the BMC are shown on the
+// host. The ConfigMaps are not watched, and fetching a pinned
+// certificate may fail for a while, so they are retried.
+func (r *BareMetalHostReconciler) bmcCertificateErrorResult(err error, request ctrl.Request, host *metal3v1alpha1.BareMetalHost) (ctrl.Result, error) {
+	if _, ok := err.(*bmctrust.Error); !ok {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update the BMC certificate settings")
+	}
+	saveErr := r.setErrorCondition(request, host, metal3v1alpha1.RegistrationError, err.Error())
+	if saveErr != nil {
+		return ctrl.Result{Requeue: true}, saveErr
+	}
+	r.publishEvent(request, host.NewEvent("BMCCertificateError", err.Error()))
+	return ctrl.Result{Requeue: true, RequeueAfter: hostErrorRetryDelay}, nil
+}
+
+func (r *BareMetalHostReconciler) detectBMC(host *metal3v1alpha1.BareMetalHost, creds bmc.Credentials) (*metal3v1alpha1.DetectedBMC, error) {
+	detect := r.DetectBMC
+	if detect == nil {
+		detect = redfish.Detect
+	}
+	caFile := ""
+	if host.Status.BMCCertificate != nil {
+		caFile = host.Status.BMCCertificate.CAFile
+	}
+	return detect(host.Spec.BMC.Address, creds, host.Spec.BMC.DisableCertificateVerification, caFile)
+}
+
+func (r *BareMetalHostReconciler) bmcDetectionErrorResult(err error, request ctrl.Request, host *metal3v1alpha1.BareMetalHost) (ctrl.Result, error) {
+	message := fmt.Sprintf("failed to detect the BMC type: %s", err)
+	saveErr := r.setErrorCondition(request, host, metal3v1alpha1.RegistrationError, message)
+	if saveErr != nil {
+		return ctrl.Result{Requeue: true}, saveErr
+	}
+	r.publishEvent(request, host.NewEvent("BMCDetectionError", message))
+	return ctrl.Result{Requeue: true, RequeueAfter: hostErrorRetryDelay}, nil
+}
+
+// provisionerHost returns the copy of the host passed to the
+// provisioner, with the address of the BMC resolved when its type was
+// detected.
+func provisionerHost(host *metal3v1alpha1.BareMetalHost) metal3v1alpha1.BareMetalHost {
+	hostCopy := host.DeepCopy()
+	hostCopy.Spec.BMC.Address = host.BMCAddress()
+	return *hostCopy
+}
+
 // hasRebootAnnotation checks for existence of reboot annotations and returns true if at least one exist
 func hasRebootAnnotation(host *metal3v1alpha1.BareMetalHost) bool {
 	for annotation := range host.Annotations {
@@ -347,9 +468,43 @@ func hasRebootAnnotation(host *metal3v1alpha1.BareMetalHost) bool {
 	return false
 }
 
+// hasInspectAnnotation returns true if the host has the annotation
+// asking for its hardware to be inspected again.
+func hasInspectAnnotation(host *metal3v1alpha1.BareMetalHost) bool {
+	_, ok := host.Annotations[metal3v1alpha1.InspectAnnotation]
+	return ok
+}
+
 // isRebootAnnotation returns true if the provided annotation is a reboot annotation (either suffixed or not)
 func isRebootAnnotation(annotation string) bool {
-	return strings.HasPrefix(annotation, rebootAnnotationPrefix+"/") || annotation == rebootAnnotationPrefix
+	return strings.HasPrefix(annotation, metal3v1alpha1.RebootAnnotationPrefix+"/") || annotation == metal3v1alpha1.RebootAnnotationPrefix
+}
+
+// rebootMode returns the mode of the reboot requested by the reboot
+// annotations of the host: hard when any of them asks for it, soft
+// otherwise.
+func rebootMode(info *reconcileInfo) metal3v1alpha1.RebootMode {
+	mode := metal3v1alpha1.RebootModeSoft
+	for annotation, value := range info.host.Annotations {
+		if !isRebootAnnotation(annotation) || value == "" {
+			continue
+		}
+		args := metal3v1alpha1.RebootAnnotationArguments{}
+		if err := json.Unmarshal([]byte(value), &args); err != nil {
+			info.publishEvent("InvalidAnnotationValue",
+				fmt.Sprintf("could not parse the value of %s, using the %s reboot mode: %s", annotation, mode, err))
+			continue
+		}
+		switch args.Mode {
+		case metal3v1alpha1.RebootModeHard:
+			return metal3v1alpha1.RebootModeHard
+		case metal3v1alpha1.RebootModeSoft, "":
+		default:
+			info.publishEvent("InvalidAnnotationValue",
+				fmt.Sprintf("unknown reboot mode %q in %s, using the %s reboot mode", args.Mode, annotation, mode))
+		}
+	}
+	return mode
 }
 
 // clearRebootAnnotations deletes all reboot annotations exist on the provided host
@@ -409,6 +564,12 @@ func (r *BareMetalHostReconciler) actionDeleting(prov provisioner.Provisioner, i
 		return actionContinue{provResult.RequeueAfter}
 	}
 
+	if r.BMCTrust != nil {
+		if err := r.BMCTrust.Remove(info.host); err != nil {
+			return actionError{errors.Wrap(err, "failed to remove the BMC CA bundle")}
+		}
+	}
+
 	// Remove finalizer to allow deletion
 	info.host.Finalizers = utils.FilterStringFromList(
 		info.host.Finalizers, metal3v1alpha1.BareMetalHostFinalizer)
@@ -434,10 +595,12 @@ func (r *BareMetalHostReconciler) registerHost(prov provisioner.Provisioner, inf
 		"credentials", info.host.Status.TriedCredentials)
 	dirty := false
 
-	credsChanged := !info.host.Status.TriedCredentials.Match(*info.bmcCredsSecret)
+	credsChanged := !info.host.Status.TriedCredentials.Match(*info.bmcCredsSecret) ||
+		info.host.Status.TriedCredentials.Set != info.bmcCredsSet
 	if credsChanged {
-		info.log.Info("new credentials")
+		info.log.Info("new credentials", "set", info.bmcCredsSet)
 		info.host.UpdateTriedCredentials(*info.bmcCredsSecret)
+		info.host.Status.TriedCredentials.Set = info.bmcCredsSet
 		info.postSaveCallbacks = append(info.postSaveCallbacks, updatedCredentials.Inc)
 		dirty = true
 	}
@@ -449,6 +612,9 @@ func (r *BareMetalHostReconciler) registerHost(prov provisioner.Provisioner, inf
 	}
 
 	if provResult.ErrorMessage != "" {
+		if info.bmcCredsSet+1 < len(info.bmcCredsSets) {
+			return r.registerHostWithFallback(info, provResult.ErrorMessage)
+		}
 		return recordActionFailure(info, metal3v1alpha1.RegistrationError, provResult.ErrorMessage)
 	}
 
@@ -473,10 +639,12 @@ func (r *BareMetalHostReconciler) registerHost(prov provisioner.Provisioner, inf
 	// Reaching this point means the credentials are valid and worked,
 	// so clear any previous error and record the success in the
 	// status block.
-	registeredNewCreds := !info.host.Status.GoodCredentials.Match(*info.bmcCredsSecret)
+	registeredNewCreds := !info.host.Status.GoodCredentials.Match(*info.bmcCredsSecret) ||
+		info.host.Status.GoodCredentials.Set != info.bmcCredsSet
 	if registeredNewCreds {
 		info.log.Info("updating credentials success status fields")
 		info.host.UpdateGoodCredentials(*info.bmcCredsSecret)
+		info.host.Status.GoodCredentials.Set = info.bmcCredsSet
 		info.publishEvent("BMCAccessValidated", "Verified access to BMC")
 		dirty = true
 	} else {
@@ -488,17 +656,56 @@ func (r *BareMetalHostReconciler) registerHost(prov provisioner.Provisioner, inf
 		dirty = clearError(info.host)
 	}
 
+	if !reflect.DeepEqual(info.host.Spec.DeployRamdisk, info.host.Status.Provisioning.DeployRamdisk) {
+		info.log.Info("recording the applied deploy ramdisk override")
+		info.host.Status.Provisioning.DeployRamdisk = info.host.Spec.DeployRamdisk.DeepCopy()
+		dirty = true
+	}
+
 	if dirty {
 		return actionComplete{}
 	}
 	return nil
 }
 
+// Move on to the next set of credentials of the secret after the BMC
+// rejected the current one.
+func (r *BareMetalHostReconciler) registerHostWithFallback(info *reconcileInfo, errorMessage string) actionResult {
+	info.log.Info("trying the next set of credentials",
+		"set", info.bmcCredsSet+1, "error", errorMessage)
+	info.publishEvent("BMCCredentialsRejected",
+		fmt.Sprintf("Credentials set %d failed: %s", info.bmcCredsSet, errorMessage))
+	info.bmcCredsSet++
+
+	prov, err := r.ProvisionerFactory(provisionerHost(info.host), info.bmcCredsSets[info.bmcCredsSet], info.publishEvent)
+	if err != nil {
+		return actionError{errors.Wrap(err, "failed to create provisioner")}
+	}
+	return r.registerHost(prov, info)
+}
+
+// credentialsSet picks which of the sets of credentials of the secret
+// to use: the one last tried, unless registration failed with all of
+// them, in which case they are tried again from the first.
+func credentialsSet(host *metal3v1alpha1.BareMetalHost, secret *corev1.Secret, count int) int {
+	tried := host.Status.TriedCredentials
+	switch {
+	case count > 1 && host.Status.ErrorType == metal3v1alpha1.RegistrationError:
+		return 0
+	case tried.Match(*secret) && tried.Set < count:
+		return tried.Set
+	default:
+		return 0
+	}
+}
+
 // Ensure we have the information about the hardware on the host.
 func (r *BareMetalHostReconciler) actionInspecting(prov provisioner.Provisioner, info *reconcileInfo) actionResult {
 	info.log.Info("inspecting hardware")
 
-	provResult, details, err := prov.InspectHardware(info.host.Status.ErrorType == metal3v1alpha1.InspectionError)
+	refresh := hasInspectAnnotation(info.host)
+	provResult, started, details, err := prov.InspectHardware(
+		info.host.Status.ErrorType == metal3v1alpha1.InspectionError, refresh)
 	if err != nil {
 		return actionError{errors.Wrap(err, "hardware inspection failed")}
 	}
@@ -507,6 +714,25 @@ func (r *BareMetalHostReconciler) actionInspecting(prov provisioner.Provisioner,
 		return recordActionFailure(info, metal3v1alpha1.InspectionError, provResult.ErrorMessage)
 	}
 
+	// The provisioners that cannot inspect again return no details,
+	// and the ones of the last inspection are kept.
+	if refresh && !started && !provResult.Dirty && details == nil {
+		details = info.host.Status.HardwareDetails
+	}
+
+	// The inspect annotation is removed once the inspection it asked
+	// for has started, or has completed for the provisioners that
+	// inspect in a single call, so that it is not repeated.
+	if refresh && (started || (!provResult.Dirty && details != nil)) {
+		delete(info.host.Annotations, metal3v1alpha1.InspectAnnotation)
+		if err = r.Update(context.TODO(), info.host); err != nil {
+			return actionError{errors.Wrap(err, "failed to remove inspect annotation from host")}
+		}
+		if started {
+			return actionContinue{provResult.RequeueAfter}
+		}
+	}
+
 	if provResult.Dirty || details == nil {
 		result := actionContinue{provResult.RequeueAfter}
 		if clearError(info.host) {
@@ -690,11 +916,17 @@ func (r *BareMetalHostReconciler) manageHostPower(prov provisioner.Provisioner,
 		return actionUpdate{}
 	}
 
+	if hwState.AgentVersion != "" && hwState.AgentVersion != info.host.Status.Provisioning.AgentVersion {
+		info.log.Info("updating agent version", "version", hwState.AgentVersion)
+		info.host.Status.Provisioning.AgentVersion = hwState.AgentVersion
+		return actionUpdate{}
+	}
+
 	desiredPowerOnState := info.host.Spec.Online
 
 	if !info.host.Status.PoweredOn {
-		if _, suffixlessAnnotationExists := info.host.Annotations[rebootAnnotationPrefix]; suffixlessAnnotationExists {
-			delete(info.host.Annotations, rebootAnnotationPrefix)
+		if _, suffixlessAnnotationExists := info.host.Annotations[metal3v1alpha1.RebootAnnotationPrefix]; suffixlessAnnotationExists {
+			delete(info.host.Annotations, metal3v1alpha1.RebootAnnotationPrefix)
 
 			if err = r.Update(context.TODO(), info.host); err != nil {
 				return actionError{errors.Wrap(err, "failed to remove reboot annotation from host")}
@@ -706,8 +938,10 @@ func (r *BareMetalHostReconciler) manageHostPower(prov provisioner.Provisioner,
 
 	provState := info.host.Status.Provisioning.State
 	isProvisioned := provState == metal3v1alpha1.StateProvisioned || provState == metal3v1alpha1.StateExternallyProvisioned
+	powerOffMode := metal3v1alpha1.RebootModeSoft
 	if hasRebootAnnotation(info.host) && isProvisioned {
 		desiredPowerOnState = false
+		powerOffMode = rebootMode(info)
 	}
 
 	// Power state needs to be monitored regularly, so if we leave
@@ -726,7 +960,7 @@ func (r *BareMetalHostReconciler) manageHostPower(prov provisioner.Provisioner,
 	if desiredPowerOnState {
 		provResult, err = prov.PowerOn()
 	} else {
-		provResult, err = prov.PowerOff()
+		provResult, err = prov.PowerOff(powerOffMode)
 	}
 	if err != nil {
 		return actionError{errors.Wrap(err, "failed to manage power state of host")}
@@ -875,44 +1109,52 @@ func (r *BareMetalHostReconciler) setErrorCondition(request ctrl.Request, host *
 	return
 }
 
-// Retrieve the secret containing the credentials for talking to the BMC.
-func (r *BareMetalHostReconciler) getBMCSecretAndSetOwner(request ctrl.Request, host *metal3v1alpha1.BareMetalHost) (bmcCredsSecret *corev1.Secret, err error) {
-
-	if host.Spec.BMC.CredentialsName == "" {
-		return nil, &EmptyBMCSecretError{message: "The BMC secret reference is empty"}
+// Retrieve the credentials for talking to the BMC.
+func (r *BareMetalHostReconciler) resolveBMCCredentials(request ctrl.Request, host *metal3v1alpha1.BareMetalHost) (resolved *credentials.Resolved, err error) {
+	resolver := r.CredentialsResolver
+	if resolver == nil {
+		resolver = &credentials.SecretResolver{Client: r}
 	}
-	secretKey := host.CredentialsKey()
-	bmcCredsSecret = &corev1.Secret{}
-	err = r.Get(context.TODO(), secretKey, bmcCredsSecret)
+
+	resolved, err = resolver.Resolve(context.TODO(), host, host.Spec.BMC.CredentialsName)
 	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			return nil, &ResolveBMCSecretRefError{message: fmt.Sprintf("The BMC secret %s does not exist", secretKey)}
+		switch e := err.(type) {
+		case *credentials.EmptyReferenceError:
+			return nil, &EmptyBMCSecretError{message: "The BMC secret reference is empty"}
+		case *credentials.NotFoundError:
+			return nil, &ResolveBMCSecretRefError{message: fmt.Sprintf("The BMC secret %s does not exist", e.Reference)}
 		}
 		return nil, err
 	}
 
+	// Credentials kept outside of the cluster have no secret to own.
+	if resolved.Secret == nil {
+		return resolved, nil
+	}
+
 	// Make sure the secret has the correct owner as soon as we can.
 	// This can return an SaveBMCSecretOwnerError
 	// which isn't handled causing us to immediately try again
 	// which seems fine as we expect this to be a transient failure
-	err = r.setBMCCredentialsSecretOwner(request, host, bmcCredsSecret)
+	err = r.setBMCCredentialsSecretOwner(request, host, resolved.Secret)
 	if err != nil {
-		return bmcCredsSecret, err
+		return resolved, err
 	}
 
-	return bmcCredsSecret, nil
+	return resolved, nil
 }
 
 // Make sure the credentials for the management controller look
 // right and manufacture bmc.Credentials.  This does not actually try
 // to use the credentials.
-func (r *BareMetalHostReconciler) buildAndValidateBMCCredentials(request ctrl.Request, host *metal3v1alpha1.BareMetalHost) (bmcCreds *bmc.Credentials, bmcCredsSecret *corev1.Secret, err error) {
+func (r *BareMetalHostReconciler) buildAndValidateBMCCredentials(request ctrl.Request, host *metal3v1alpha1.BareMetalHost) (bmcCredsSets []bmc.Credentials, bmcCredsSecret *corev1.Secret, err error) {
 
-	// Retrieve the BMC secret from Kubernetes for this host
-	bmcCredsSecret, err = r.getBMCSecretAndSetOwner(request, host)
+	// Retrieve the BMC credentials for this host
+	resolved, err := r.resolveBMCCredentials(request, host)
 	if err != nil {
 		return nil, nil, err
 	}
+	bmcCredsSecret = resolved.AsSecret()
 
 	// Check for a "discovered" host vs. one that we have all the info for
 	// and find empty Address or CredentialsName fields
@@ -929,22 +1171,24 @@ func (r *BareMetalHostReconciler) buildAndValidateBMCCredentials(request ctrl.Re
 		return nil, nil, err
 	}
 
-	bmcCreds = &bmc.Credentials{
-		Username: string(bmcCredsSecret.Data["username"]),
-		Password: string(bmcCredsSecret.Data["password"]),
-	}
+	bmcCredsSets = resolved.Sets()
 
-	// Verify that the secret contains the expected info.
-	err = bmcCreds.Validate()
-	if err != nil {
-		return nil, bmcCredsSecret, err
+	// Verify that the credentials contain the expected info.
+	for _, bmcCreds := range bmcCredsSets {
+		err = bmcCreds.Validate()
+		if err != nil {
+			return nil, bmcCredsSecret, err
+		}
 	}
 
-	return bmcCreds, bmcCredsSecret, nil
+	return bmcCredsSets, bmcCredsSecret, nil
 }
 
 func (r *BareMetalHostReconciler) setBMCCredentialsSecretOwner(request ctrl.Request, host *metal3v1alpha1.BareMetalHost, secret *corev1.Secret) (err error) {
 	reqLogger := r.Log.WithValues("baremetalhost", request.NamespacedName)
+	if isSharedBMCCredentialsSecret(host, secret) {
+		return r.shareBMCCredentialsSecret(request, secret)
+	}
 	if metav1.IsControlledBy(secret, host) {
 		return nil
 	}
@@ -960,6 +1204,72 @@ func (r *BareMetalHostReconciler) setBMCCredentialsSecretOwner(request ctrl.Requ
 	return nil
 }
 
+// A secret is shared when it is labelled so, or when another host
+// already owns it.
+func isSharedBMCCredentialsSecret(host *metal3v1alpha1.BareMetalHost, secret *corev1.Secret) bool {
+	if secret.Labels[metal3v1alpha1.SharedCredentialsLabel] == "true" {
+		return true
+	}
+	owner := metav1.GetControllerOf(secret)
+	return owner != nil && isHostReference(*owner) && owner.Name != host.Name
+}
+
+func isHostReference(ref metav1.OwnerReference) bool {
+	return ref.Kind == "BareMetalHost" && strings.HasPrefix(ref.APIVersion, metal3v1alpha1.GroupVersion.Group+"/")
+}
+
+// Make sure a shared secret is not owned by any host and stays
+// labelled as shared, so that hosts can come and go without deleting
+// it.
+func (r *BareMetalHostReconciler) shareBMCCredentialsSecret(request ctrl.Request, secret *corev1.Secret) error {
+	owners := []metav1.OwnerReference{}
+	for _, ref := range secret.OwnerReferences {
+		if !isHostReference(ref) {
+			owners = append(owners, ref)
+		}
+	}
+	if len(owners) == len(secret.OwnerReferences) && secret.Labels[metal3v1alpha1.SharedCredentialsLabel] == "true" {
+		return nil
+	}
+
+	r.Log.Info("sharing secret between hosts", "baremetalhost", request.NamespacedName, "secret", secret.Name)
+	secret.OwnerReferences = owners
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string)
+	}
+	secret.Labels[metal3v1alpha1.SharedCredentialsLabel] = "true"
+	if err := r.Update(context.TODO(), secret); err != nil {
+		return &SaveBMCSecretOwnerError{message: fmt.Sprintf("cannot save shared secret: %q", err.Error())}
+	}
+	return nil
+}
+
+// hostsForSecret returns the requests for the hosts referring to the
+// secret, whether they own it or share it.
+func (r *BareMetalHostReconciler) hostsForSecret(obj handler.MapObject) []reconcile.Request {
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	err := r.List(context.TODO(), hosts,
+		client.InNamespace(obj.Meta.GetNamespace()),
+		client.MatchingFields{credentialsNameField: obj.Meta.GetName()})
+	if err != nil {
+		r.Log.Error(err, "cannot list the hosts using secret",
+			"secret", types.NamespacedName{Namespace: obj.Meta.GetNamespace(), Name: obj.Meta.GetName()})
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, host := range hosts.Items {
+		// Clients without the index ignore the field selector.
+		if host.Spec.BMC.CredentialsName != obj.Meta.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: host.Namespace, Name: host.Name},
+		})
+	}
+	return requests
+}
+
 func (r *BareMetalHostReconciler) publishEvent(request ctrl.Request, event corev1.Event) {
 	reqLogger := r.Log.WithValues("baremetalhost", request.NamespacedName)
 	reqLogger.Info("publishing event", "reason", event.Reason, "message", event.Message)
@@ -1003,36 +1313,51 @@ func (r *BareMetalHostReconciler) updateEventHandler(e event.UpdateEvent) bool {
 	return true
 }
 
+// EventFilter returns the predicate deciding which changes to the
+// watched objects trigger a reconcile.
+func (r *BareMetalHostReconciler) EventFilter() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: r.updateEventHandler,
+	}
+}
+
 // SetupWithManager reigsters the reconciler to be run by the manager
 func (r *BareMetalHostReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
-	maxConcurrentReconciles := 3
-	if mcrEnv, ok := os.LookupEnv("BMO_CONCURRENCY"); ok {
-		mcr, err := strconv.Atoi(mcrEnv)
+	if r.ConcurrencyLimit == nil {
+		concurrency, err := DefaultConcurrency()
 		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("BMO_CONCURRENCY value: %s is invalid", mcrEnv))
-		}
-		if mcr > 0 {
-			ctrl.Log.Info(fmt.Sprintf("BMO_CONCURRENCY of %d is set via an environment variable", mcr))
-			maxConcurrentReconciles = mcr
-		} else {
-			ctrl.Log.Info(fmt.Sprintf("Invalid BMO_CONCURRENCY value. Operator Concurrency will be set to a default value of %d", maxConcurrentReconciles))
+			return err
 		}
-	} else {
-		ctrl.Log.Info(fmt.Sprintf("Operator Concurrency will be set to a default value of %d", maxConcurrentReconciles))
+		r.ConcurrencyLimit = NewConcurrencyLimit(concurrency)
 	}
 
+	// Start enough workers for the highest limit that can be
+	// configured, and let the ConcurrencyLimit decide how many of
+	// them run at once.
 	opts := controller.Options{
-		MaxConcurrentReconciles: maxConcurrentReconciles,
+		MaxConcurrentReconciles: metal3v1alpha1.MaxConcurrency,
+	}
+
+	err := mgr.GetFieldIndexer().IndexField(context.TODO(), &metal3v1alpha1.BareMetalHost{}, credentialsNameField,
+		func(obj runtime.Object) []string {
+			host := obj.(*metal3v1alpha1.BareMetalHost)
+			if host.Spec.BMC.CredentialsName == "" {
+				return nil
+			}
+			return []string{host.Spec.BMC.CredentialsName}
+		})
+	if err != nil {
+		return err
 	}
 
+	// Secrets may be shared by several hosts, so changes to them are
+	// sent to every host referring to them rather than to their owner.
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&metal3v1alpha1.BareMetalHost{}).
-		WithEventFilter(
-			predicate.Funcs{
-				UpdateFunc: r.updateEventHandler,
-			}).
+		WithEventFilter(r.EventFilter()).
 		WithOptions(opts).
-		Owns(&corev1.Secret{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.hostsForSecret)}).
 		Complete(r)
 }
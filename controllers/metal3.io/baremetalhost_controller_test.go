@@ -4,8 +4,17 @@ import (
 	goctx "context"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -20,9 +29,14 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/bmctrust"
+	"github.com/metal3-io/baremetal-operator/pkg/credentials"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
 	"github.com/metal3-io/baremetal-operator/pkg/utils"
 )
@@ -339,7 +353,7 @@ func TestHasRebootAnnotation(t *testing.T) {
 	}
 
 	host.Annotations = make(map[string]string)
-	suffixedAnnotation := rebootAnnotationPrefix + "/foo"
+	suffixedAnnotation := metal3v1alpha1.RebootAnnotationPrefix + "/foo"
 	host.Annotations[suffixedAnnotation] = ""
 
 	if !hasRebootAnnotation(host) {
@@ -347,7 +361,7 @@ func TestHasRebootAnnotation(t *testing.T) {
 	}
 
 	delete(host.Annotations, suffixedAnnotation)
-	host.Annotations[rebootAnnotationPrefix] = ""
+	host.Annotations[metal3v1alpha1.RebootAnnotationPrefix] = ""
 
 	if !hasRebootAnnotation(host) {
 		t.Fail()
@@ -374,7 +388,7 @@ func TestHasRebootAnnotation(t *testing.T) {
 func TestRebootWithSuffixlessAnnotation(t *testing.T) {
 	host := newDefaultHost(t)
 	host.Annotations = make(map[string]string)
-	host.Annotations[rebootAnnotationPrefix] = ""
+	host.Annotations[metal3v1alpha1.RebootAnnotationPrefix] = ""
 	host.Status.PoweredOn = true
 	host.Status.Provisioning.State = metal3v1alpha1.StateProvisioned
 	host.Spec.Online = true
@@ -396,7 +410,7 @@ func TestRebootWithSuffixlessAnnotation(t *testing.T) {
 
 	tryReconcile(t, r, host,
 		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
-			if _, exists := host.Annotations[rebootAnnotationPrefix]; exists {
+			if _, exists := host.Annotations[metal3v1alpha1.RebootAnnotationPrefix]; exists {
 				return false
 			}
 
@@ -427,12 +441,44 @@ func TestRebootWithSuffixlessAnnotation(t *testing.T) {
 	)
 }
 
+// TestRebootHardMode verifies that the mode given in the value of a
+// reboot annotation is passed to the provisioner.
+func TestRebootHardMode(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Status.Provisioning.State = metal3v1alpha1.StateProvisioned
+	host.Spec.Online = true
+	host.Spec.Image = &metal3v1alpha1.Image{URL: "foo", Checksum: "123"}
+	host.Status.Provisioning.Image.URL = "foo"
+
+	fix := fixture.Fixture{}
+	r := newTestReconcilerWithFixture(&fix, host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.PoweredOn
+		},
+	)
+
+	if host.Annotations == nil {
+		host.Annotations = map[string]string{}
+	}
+	host.Annotations[metal3v1alpha1.RebootAnnotationPrefix] = `{"mode":"hard"}`
+	r.Update(goctx.TODO(), host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return !host.Status.PoweredOn
+		},
+	)
+	assert.Equal(t, metal3v1alpha1.RebootModeHard, fix.RebootMode)
+}
+
 // TestRebootWithSuffixedAnnotation tests a full reboot cycle, with suffixed annotation
 // to verify that controller holds power off until annotation removal
 func TestRebootWithSuffixedAnnotation(t *testing.T) {
 	host := newDefaultHost(t)
 	host.Annotations = make(map[string]string)
-	annotation := rebootAnnotationPrefix + "/foo"
+	annotation := metal3v1alpha1.RebootAnnotationPrefix + "/foo"
 	host.Annotations[annotation] = ""
 	host.Status.PoweredOn = true
 	host.Status.Provisioning.State = metal3v1alpha1.StateProvisioned
@@ -510,6 +556,31 @@ func TestUpdateCredentialsSecretSuccessFields(t *testing.T) {
 
 }
 
+// TestDeployRamdiskApplied ensures that the deploy ramdisk override
+// applied to a host is recorded in its status, until it is removed.
+func TestDeployRamdiskApplied(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.DeployRamdisk = &metal3v1alpha1.DeployRamdisk{ExtraKernelParams: "console=ttyS0"}
+	r := newTestReconciler(host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.Provisioning.DeployRamdisk != nil
+		},
+	)
+	assert.Equal(t, "console=ttyS0", host.Status.Provisioning.DeployRamdisk.ExtraKernelParams)
+
+	host.Spec.DeployRamdisk = nil
+	if err := r.Update(goctx.TODO(), host); err != nil {
+		t.Fatal(err)
+	}
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.Provisioning.DeployRamdisk == nil
+		},
+	)
+}
+
 // TestUpdateGoodCredentialsOnNewSecret ensures that the
 // GoodCredentials fields are updated when the secret for a host is
 // changed to another secret that is also good.
@@ -594,6 +665,343 @@ func TestUpdateGoodCredentialsOnBadSecret(t *testing.T) {
 	)
 }
 
+// TestCredentialsOutsideOfCluster ensures that a host can be
+// registered with credentials that are not in a secret, and that the
+// secret-less credentials are not given an owner.
+func TestCredentialsOutsideOfCluster(t *testing.T) {
+	host := newDefaultHost(t)
+	dir := t.TempDir()
+	credentialsDir := filepath.Join(dir, host.Namespace, "host-0")
+	if err := os.MkdirAll(credentialsDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range map[string]string{"username": "User", "password": "Pass"} {
+		if err := ioutil.WriteFile(filepath.Join(credentialsDir, file), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	host.Spec.BMC.CredentialsName = "file:host-0"
+	r := newTestReconciler(host)
+	r.CredentialsResolver = credentials.New(r, credentials.Options{Dir: dir})
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.GoodCredentials.Version != ""
+		},
+	)
+	assert.Equal(t, "file:host-0", host.Status.GoodCredentials.Reference.Name)
+
+	// Changing the credentials makes them be tried again.
+	version := host.Status.GoodCredentials.Version
+	if err := ioutil.WriteFile(filepath.Join(credentialsDir, "password"), []byte("New"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.GoodCredentials.Version != version
+		},
+	)
+
+	host.Spec.BMC.CredentialsName = "file:host-1"
+	if err := r.Update(goctx.TODO(), host); err != nil {
+		t.Fatal(err)
+	}
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return strings.HasSuffix(host.Status.ErrorMessage, "The BMC secret file:host-1 does not exist")
+		},
+	)
+}
+
+// credentialsCheckingProvisioner stands for a BMC accepting only some
+// credentials.
+type credentialsCheckingProvisioner struct {
+	provisioner.Provisioner
+	creds    bmc.Credentials
+	accepted *bmc.Credentials
+}
+
+func (p *credentialsCheckingProvisioner) ValidateManagementAccess(credentialsChanged, force bool) (provisioner.Result, string, error) {
+	if p.creds != *p.accepted {
+		return provisioner.Result{ErrorMessage: "authentication failed"}, "", nil
+	}
+	return p.Provisioner.ValidateManagementAccess(credentialsChanged, force)
+}
+
+// TestFallbackCredentials ensures that the sets of credentials of a
+// secret are tried in order until one works.
+func TestFallbackCredentials(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.BMC.CredentialsName = "bmc-creds-fallback"
+	r := newTestReconciler(host)
+	secret := newSecret("bmc-creds-fallback", nil)
+	secret.Data = map[string][]byte{
+		"username":   []byte("site"),
+		"password":   []byte("site-pass"),
+		"username-1": []byte("ADMIN"),
+		"password-1": []byte("ADMIN"),
+	}
+	if err := r.Create(goctx.TODO(), secret); err != nil {
+		t.Fatal(err)
+	}
+
+	fix := &fixture.Fixture{}
+	accepted := bmc.Credentials{Username: "ADMIN", Password: "ADMIN"}
+	r.ProvisionerFactory = func(host metal3v1alpha1.BareMetalHost, creds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+		prov, err := fix.New(host, creds, publisher)
+		return &credentialsCheckingProvisioner{Provisioner: prov, creds: creds, accepted: &accepted}, err
+	}
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.GoodCredentials.Version != ""
+		},
+	)
+	assert.Equal(t, 1, host.Status.GoodCredentials.Set)
+	assert.Equal(t, 1, host.Status.TriedCredentials.Set)
+	assert.Empty(t, host.Status.ErrorMessage)
+
+	// Once the site account is set up on the BMC, the factory default
+	// one stops working and the credentials are tried from the first
+	// again.
+	accepted = bmc.Credentials{Username: "site", Password: "site-pass"}
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.ErrorType == metal3v1alpha1.RegistrationError
+		},
+	)
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.GoodCredentials.Set == 0
+		},
+	)
+	assert.Equal(t, 0, host.Status.TriedCredentials.Set)
+	assert.Empty(t, host.Status.ErrorMessage)
+}
+
+// TestSharedCredentialsSecret ensures that a secret used by several
+// hosts is not owned by any of them, and that changing it reconciles
+// all of them.
+func TestSharedCredentialsSecret(t *testing.T) {
+	host1 := newDefaultNamedHost("host-1", t)
+	host2 := newDefaultNamedHost("host-2", t)
+	other := newDefaultNamedHost("other", t)
+	other.Spec.BMC.CredentialsName = "other-secret"
+	r := newTestReconciler(host1, host2, other)
+
+	var secret *corev1.Secret
+	getSecret := func() {
+		secret = &corev1.Secret{}
+		key := types.NamespacedName{Namespace: namespace, Name: defaultSecretName}
+		if err := r.Get(goctx.TODO(), key, secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hasGoodCredentials := func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+		return host.Status.GoodCredentials.Version != ""
+	}
+	tryReconcile(t, r, host1, hasGoodCredentials)
+	getSecret()
+	assert.True(t, metav1.IsControlledBy(secret, host1), "a secret used by one host is owned by it")
+
+	tryReconcile(t, r, host2, hasGoodCredentials)
+	getSecret()
+	assert.Empty(t, secret.OwnerReferences)
+	assert.Equal(t, "true", secret.Labels[metal3v1alpha1.SharedCredentialsLabel])
+
+	// The first host does not take the shared secret back.
+	tryReconcile(t, r, host1, hasGoodCredentials)
+	getSecret()
+	assert.Empty(t, secret.OwnerReferences)
+
+	requests := r.hostsForSecret(handler.MapObject{Meta: secret, Object: secret})
+	assert.ElementsMatch(t, []reconcile.Request{newRequest(host1), newRequest(host2)}, requests)
+}
+
+// TestLabelledSharedCredentialsSecret ensures that a secret labelled
+// as shared is never owned by a host.
+func TestLabelledSharedCredentialsSecret(t *testing.T) {
+	host := newDefaultHost(t)
+	secret := newBMCCredsSecret("shared-secret", "User", "Pass")
+	secret.Labels = map[string]string{metal3v1alpha1.SharedCredentialsLabel: "true"}
+	host.Spec.BMC.CredentialsName = secret.Name
+	r := newTestReconciler(host)
+	if err := r.Create(goctx.TODO(), secret); err != nil {
+		t.Fatal(err)
+	}
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.GoodCredentials.Version != ""
+		},
+	)
+	if err := r.Get(goctx.TODO(), types.NamespacedName{Namespace: namespace, Name: secret.Name}, secret); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, secret.OwnerReferences)
+}
+
+// TestBMCCABundle ensures that the CA bundle of a host is recorded in
+// its status, and that a missing one is reported on the host.
+func TestBMCCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	bundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	host := newDefaultHost(t)
+	host.Spec.BMC.Address = "redfish://192.168.122.1"
+	host.Spec.BMC.CABundleName = "rack1-ca"
+	r := newTestReconciler(host)
+	dir := t.TempDir()
+	r.BMCTrust = bmctrust.New(r.Client, bmctrust.Options{Dir: dir})
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.ErrorType == metal3v1alpha1.RegistrationError
+		},
+	)
+	assert.Equal(t, "the BMC CA bundle ConfigMap rack1-ca does not exist", host.Status.ErrorMessage)
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack1-ca", Namespace: namespace},
+		Data:       map[string]string{bmctrust.CAKey: bundle},
+	}
+	if err := r.Create(goctx.TODO(), configMap); err != nil {
+		t.Fatal(err)
+	}
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.BMCCertificate != nil
+		},
+	)
+	caFile := filepath.Join(dir, namespace, host.Name+".pem")
+	assert.Equal(t, &metal3v1alpha1.BMCCertificateStatus{CABundle: "rack1-ca", CAFile: caFile}, host.Status.BMCCertificate)
+	content, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bundle, string(content))
+}
+
+// TestBMCAutoDetect ensures that the type of a redfish-auto BMC is
+// detected once and that the provisioner gets the resolved address.
+func TestBMCAutoDetect(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.BMC.Address = "redfish-auto://192.168.122.1"
+	r := newTestReconciler(host)
+
+	detections := 0
+	r.DetectBMC = func(address string, creds bmc.Credentials, disableCertificateVerification bool, caFile string) (*metal3v1alpha1.DetectedBMC, error) {
+		detections++
+		return &metal3v1alpha1.DetectedBMC{
+			Address:         address,
+			Type:            "idrac-virtualmedia",
+			ResolvedAddress: "idrac-virtualmedia://192.168.122.1/redfish/v1/Systems/System.Embedded.1",
+			Vendor:          "Dell",
+		}, nil
+	}
+	fix := &fixture.Fixture{}
+	var addresses []string
+	r.ProvisionerFactory = func(host metal3v1alpha1.BareMetalHost, creds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+		// Hosts without a provisioning state are not registered
+		// yet, so the BMC is only contacted with credentials.
+		if creds.Username != "" {
+			addresses = append(addresses, host.Spec.BMC.Address)
+		}
+		return fix.New(host, creds, publisher)
+	}
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.GoodCredentials.Version != ""
+		},
+	)
+	assert.Equal(t, "idrac-virtualmedia", host.Status.DetectedBMC.Type)
+	assert.Equal(t, "redfish-auto://192.168.122.1", host.Spec.BMC.Address)
+	assert.Equal(t, 1, detections)
+	if assert.NotEmpty(t, addresses) {
+		for _, address := range addresses {
+			assert.Equal(t, host.Status.DetectedBMC.ResolvedAddress, address)
+		}
+	}
+
+	// Changing the address to a concrete type drops the detection.
+	host.Spec.BMC.Address = "redfish://192.168.122.1/redfish/v1/Systems/1"
+	if err := r.Update(goctx.TODO(), host); err != nil {
+		t.Fatal(err)
+	}
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.DetectedBMC == nil
+		},
+	)
+	assert.Equal(t, 1, detections)
+}
+
+// TestBMCAutoDetectCABundle ensures that the CA bundle of a
+// redfish-auto BMC is written before its type is detected, and that
+// the detection verifies the certificate against it.
+func TestBMCAutoDetectCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	bundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	host := newDefaultHost(t)
+	host.Spec.BMC.Address = "redfish-auto://192.168.122.1"
+	host.Spec.BMC.CABundleName = "rack1-ca"
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack1-ca", Namespace: namespace},
+		Data:       map[string]string{bmctrust.CAKey: bundle},
+	}
+	r := newTestReconciler(host, configMap)
+	dir := t.TempDir()
+	r.BMCTrust = bmctrust.New(r.Client, bmctrust.Options{Dir: dir})
+	var caFiles []string
+	r.DetectBMC = func(address string, creds bmc.Credentials, disableCertificateVerification bool, caFile string) (*metal3v1alpha1.DetectedBMC, error) {
+		caFiles = append(caFiles, caFile)
+		return &metal3v1alpha1.DetectedBMC{
+			Address:         address,
+			Type:            "redfish-virtualmedia",
+			ResolvedAddress: "redfish-virtualmedia://192.168.122.1/redfish/v1/Systems/1",
+		}, nil
+	}
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.DetectedBMC != nil
+		},
+	)
+	caFile := filepath.Join(dir, namespace, host.Name+".pem")
+	assert.Equal(t, []string{caFile}, caFiles)
+	content, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bundle, string(content))
+}
+
+// TestBMCAutoDetectFails ensures that failing to detect the type of a
+// BMC is reported on the host.
+func TestBMCAutoDetectFails(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.BMC.Address = "redfish-auto://192.168.122.1"
+	r := newTestReconciler(host)
+	r.DetectBMC = func(address string, creds bmc.Credentials, disableCertificateVerification bool, caFile string) (*metal3v1alpha1.DetectedBMC, error) {
+		return nil, fmt.Errorf("GET /redfish/v1 failed: connection refused")
+	}
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.ErrorType == metal3v1alpha1.RegistrationError
+		},
+	)
+	assert.Equal(t, "failed to detect the BMC type: GET /redfish/v1 failed: connection refused", host.Status.ErrorMessage)
+	assert.Nil(t, host.Status.DetectedBMC)
+}
+
 // TestDiscoveredHost ensures that a host without a BMC IP and
 // credentials is placed into the "discovered" state.
 func TestDiscoveredHost(t *testing.T) {
@@ -659,6 +1067,18 @@ func TestMissingBMCParameters(t *testing.T) {
 				}),
 		},
 
+		{
+			Scenario: "invalid address option",
+			Secret:   newBMCCredsSecret("bmc-creds-ok", "User", "Pass"),
+			Host: newHost("invalid-bmc-option",
+				&metal3v1alpha1.BareMetalHostSpec{
+					BMC: metal3v1alpha1.BMCDetails{
+						Address:         "ipmi://192.168.122.1:6233?priv_level=root",
+						CredentialsName: "bmc-creds-ok",
+					},
+				}),
+		},
+
 		{
 			Scenario: "missing address",
 			Secret:   newBMCCredsSecret("bmc-creds-ok", "User", "Pass"),
@@ -1301,3 +1721,199 @@ func TestErrorCountIncrementsAlways(t *testing.T) {
 		assert.Equal(t, before+1, b.Status.ErrorCount)
 	}
 }
+
+// TestRegistrationErrorBackoff verifies that registration failures are
+// retried with an increasing delay, and that the error is cleared once
+// the host registers.
+func TestRegistrationErrorBackoff(t *testing.T) {
+	host := newDefaultHost(t)
+	fix := fixture.Fixture{}
+	fix.Inject(fixture.MethodValidateManagementAccess,
+		fixture.Fault{ErrorMessage: "BMC unreachable", Calls: 2})
+	r := newTestReconcilerWithFixture(&fix, host)
+
+	for errorCount := 1; errorCount <= 2; errorCount++ {
+		var delay time.Duration
+		tryReconcile(t, r, host,
+			func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+				delay = result.RequeueAfter
+				return host.Status.ErrorCount == errorCount
+			},
+		)
+		assert.Equal(t, metal3v1alpha1.RegistrationError, host.Status.ErrorType)
+		assert.Equal(t, "BMC unreachable", host.Status.ErrorMessage)
+		assert.Equal(t, metal3v1alpha1.StateRegistering, host.Status.Provisioning.State)
+
+		base := time.Duration(1<<uint(errorCount)) * time.Minute
+		assert.True(t, delay >= base/2 && delay <= base,
+			"delay %s for error count %d", delay, errorCount)
+	}
+
+	waitForProvisioningState(t, r, host, metal3v1alpha1.StateReady)
+	assert.Equal(t, "", host.Status.ErrorMessage)
+	assert.Equal(t, metal3v1alpha1.ErrorType(""), host.Status.ErrorType)
+	assert.Equal(t, 0, host.Status.ErrorCount)
+}
+
+// TestProvisionerErrorIsRetried verifies that an error returned by the
+// provisioner is passed on to be retried, without putting the host in
+// an error state.
+func TestProvisionerErrorIsRetried(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:      "https://example.com/image-name",
+		Checksum: "12345",
+	}
+	host.Spec.Online = true
+	fix := fixture.Fixture{}
+	fix.Inject(fixture.MethodProvision,
+		fixture.Fault{Err: errors.New("connection refused"), Calls: 1})
+	r := newTestReconcilerWithFixture(&fix, host)
+	request := newRequest(host)
+
+	var err error
+	for i := 0; i < 25 && err == nil; i++ {
+		_, err = r.Reconcile(request)
+	}
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "connection refused")
+	}
+	r.Get(goctx.TODO(), request.NamespacedName, host)
+	assert.Equal(t, metal3v1alpha1.StateProvisioning, host.Status.Provisioning.State)
+	assert.Equal(t, "", host.Status.ErrorMessage)
+
+	waitForProvisioningState(t, r, host, metal3v1alpha1.StateProvisioned)
+	assert.Equal(t, 0, host.Status.ErrorCount)
+}
+
+// TestProvisioningRequeueAfter verifies that the controller waits as
+// long as the provisioner asks it to while an operation is running.
+func TestProvisioningRequeueAfter(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:      "https://example.com/image-name",
+		Checksum: "12345",
+	}
+	host.Spec.Online = true
+	fix := fixture.Fixture{}
+	fix.Inject(fixture.MethodProvision,
+		fixture.Fault{Dirty: true, RequeueAfter: 42 * time.Second, Calls: 3})
+	r := newTestReconcilerWithFixture(&fix, host)
+
+	waits := 0
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			if host.Status.Provisioning.State == metal3v1alpha1.StateProvisioning &&
+				result.RequeueAfter == 42*time.Second {
+				waits++
+			}
+			return host.Status.Provisioning.State == metal3v1alpha1.StateProvisioned
+		},
+	)
+	assert.Equal(t, 3, waits)
+}
+
+// TestProvisioningErrorMessage verifies that a failed provisioning
+// puts the host in an error state.
+func TestProvisioningErrorMessage(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:      "https://example.com/image-name",
+		Checksum: "12345",
+	}
+	host.Spec.Online = true
+	fix := fixture.Fixture{}
+	fix.Inject(fixture.MethodProvision, fixture.Fault{ErrorMessage: "image corrupt"})
+	r := newTestReconcilerWithFixture(&fix, host)
+
+	waitForError(t, r, host)
+	assert.Equal(t, metal3v1alpha1.ProvisioningError, host.Status.ErrorType)
+	assert.Equal(t, "image corrupt", host.Status.ErrorMessage)
+	assert.Equal(t, 1, host.Status.ErrorCount)
+	assert.Equal(t, metal3v1alpha1.OperationalStatusError, host.OperationalStatus())
+}
+
+// TestPowerManagementErrorRecovers verifies that a power change is
+// retried after it fails, and that the error is cleared once it works.
+func TestPowerManagementErrorRecovers(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.Online = true
+	fix := fixture.Fixture{}
+	fix.Inject(fixture.MethodPowerOn, fixture.Fault{ErrorMessage: "BMC busy", Calls: 1})
+	r := newTestReconcilerWithFixture(&fix, host)
+
+	waitForError(t, r, host)
+	assert.Equal(t, metal3v1alpha1.PowerManagementError, host.Status.ErrorType)
+	assert.False(t, host.Status.PoweredOn)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.PoweredOn
+		},
+	)
+	assert.Equal(t, "", host.Status.ErrorMessage)
+	assert.Equal(t, 2, fix.Calls(fixture.MethodPowerOn))
+}
+
+// TestInspectAnnotation verifies that the inspect annotation sends a
+// ready host back through inspection, and is removed afterwards.
+func TestInspectAnnotation(t *testing.T) {
+	host := newDefaultHost(t)
+	fix := fixture.Fixture{}
+	r := newTestReconcilerWithFixture(&fix, host)
+
+	waitForProvisioningState(t, r, host, metal3v1alpha1.StateReady)
+	inspections := fix.Calls(fixture.MethodInspectHardware)
+
+	if host.Annotations == nil {
+		host.Annotations = map[string]string{}
+	}
+	host.Annotations[metal3v1alpha1.InspectAnnotation] = ""
+	r.Update(goctx.TODO(), host)
+
+	waitForProvisioningState(t, r, host, metal3v1alpha1.StateInspecting)
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.Provisioning.State == metal3v1alpha1.StateReady &&
+				!hasInspectAnnotation(host)
+		},
+	)
+	assert.Equal(t, inspections+1, fix.Calls(fixture.MethodInspectHardware))
+	assert.NotNil(t, host.Status.HardwareDetails)
+
+	// The host stays ready once the annotation is gone.
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return true
+		},
+	)
+	assert.Equal(t, metal3v1alpha1.StateReady, host.Status.Provisioning.State)
+}
+
+// TestInspectAnnotationKeepsDetails verifies that a host whose
+// provisioner returns no new details keeps the ones of the last
+// inspection and goes back to ready.
+func TestInspectAnnotationKeepsDetails(t *testing.T) {
+	host := newDefaultHost(t)
+	fix := fixture.Fixture{}
+	r := newTestReconcilerWithFixture(&fix, host)
+
+	waitForProvisioningState(t, r, host, metal3v1alpha1.StateReady)
+	details := host.Status.HardwareDetails
+
+	fix.Inject(fixture.MethodInspectHardware, fixture.Fault{})
+	if host.Annotations == nil {
+		host.Annotations = map[string]string{}
+	}
+	host.Annotations[metal3v1alpha1.InspectAnnotation] = ""
+	r.Update(goctx.TODO(), host)
+
+	waitForProvisioningState(t, r, host, metal3v1alpha1.StateInspecting)
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.Provisioning.State == metal3v1alpha1.StateReady &&
+				!hasInspectAnnotation(host)
+		},
+	)
+	assert.Equal(t, details, host.Status.HardwareDetails)
+}
@@ -0,0 +1,153 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// IronicSettingsApplier validates the settings of the default ironic
+// backend and of any additional backends and puts them into effect,
+// returning the complete set of values now in use. A nil settings
+// argument restores the settings from the environment.
+type IronicSettingsApplier func(settings *metal3v1alpha1.IronicSettings, backends []metal3v1alpha1.IronicBackend) (*metal3v1alpha1.IronicSettings, []metal3v1alpha1.IronicBackend, error)
+
+// BareMetalOperatorConfigReconciler applies the settings from a
+// BareMetalOperatorConfig to the running operator
+type BareMetalOperatorConfigReconciler struct {
+	client.Client
+	Log logr.Logger
+	// ConfigName identifies the BareMetalOperatorConfig used by the
+	// operator. Any others are ignored.
+	ConfigName types.NamespacedName
+	// ConcurrencyLimit is shared with the BareMetalHostReconciler.
+	ConcurrencyLimit *ConcurrencyLimit
+	// DefaultConcurrency is restored when the configuration does
+	// not set one.
+	DefaultConcurrency int
+	// ApplyIronicSettings is nil when the ironic provisioner is not
+	// in use.
+	ApplyIronicSettings IronicSettingsApplier
+}
+
+// +kubebuilder:rbac:groups=metal3.io,resources=baremetaloperatorconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=metal3.io,resources=baremetaloperatorconfigs/status,verbs=get;update;patch
+
+// Reconcile handles changes to BareMetalOperatorConfig resources
+func (r *BareMetalOperatorConfigReconciler) Reconcile(request ctrl.Request) (result ctrl.Result, err error) {
+	if request.NamespacedName != r.ConfigName {
+		return
+	}
+
+	reqLogger := r.Log.WithValues("baremetaloperatorconfig", request.NamespacedName)
+
+	config := &metal3v1alpha1.BareMetalOperatorConfig{}
+	err = r.Get(context.TODO(), request.NamespacedName, config)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			reqLogger.Info("configuration removed, restoring defaults")
+			_, err = r.apply(metal3v1alpha1.BareMetalOperatorConfigSpec{})
+			return
+		}
+		err = errors.Wrap(err, "could not load operator configuration")
+		return
+	}
+
+	newStatus := config.Status.DeepCopy()
+	newStatus.ObservedGeneration = config.Generation
+
+	effective, applyErr := r.apply(config.Spec)
+	if applyErr != nil {
+		reqLogger.Info("could not apply configuration", "error", applyErr.Error())
+		newStatus.ErrorMessage = applyErr.Error()
+	} else {
+		reqLogger.Info("applied configuration")
+		newStatus.ErrorMessage = ""
+		newStatus.Effective = effective
+	}
+
+	if !reflect.DeepEqual(config.Status, *newStatus) {
+		config.Status = *newStatus
+		err = r.Status().Update(context.TODO(), config)
+		if err != nil {
+			err = errors.Wrap(err, "failed to update operator configuration status")
+		}
+	}
+	return
+}
+
+// apply puts the settings into effect, changing nothing if any of
+// them is invalid.
+func (r *BareMetalOperatorConfigReconciler) apply(spec metal3v1alpha1.BareMetalOperatorConfigSpec) (effective metal3v1alpha1.BareMetalOperatorConfigSpec, err error) {
+	concurrency := r.DefaultConcurrency
+	if spec.Concurrency != nil {
+		concurrency = *spec.Concurrency
+		if concurrency < 1 || concurrency > metal3v1alpha1.MaxConcurrency {
+			err = fmt.Errorf("concurrency %d must be between 1 and %d",
+				concurrency, metal3v1alpha1.MaxConcurrency)
+			return
+		}
+	}
+
+	if r.ApplyIronicSettings != nil {
+		effective.Ironic, effective.IronicBackends, err = r.ApplyIronicSettings(spec.Ironic, spec.IronicBackends)
+		if err != nil {
+			err = errors.Wrap(err, "invalid ironic settings")
+			return
+		}
+	}
+
+	if r.ConcurrencyLimit != nil {
+		r.ConcurrencyLimit.SetLimit(concurrency)
+	}
+	effective.Concurrency = &concurrency
+	return
+}
+
+// SetupWithManager registers the reconciler to be run by the manager
+func (r *BareMetalOperatorConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isConfig := func(meta interface {
+		GetName() string
+		GetNamespace() string
+	}) bool {
+		return meta.GetName() == r.ConfigName.Name &&
+			meta.GetNamespace() == r.ConfigName.Namespace
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metal3v1alpha1.BareMetalOperatorConfig{}).
+		WithEventFilter(
+			predicate.Funcs{
+				CreateFunc:  func(e event.CreateEvent) bool { return isConfig(e.Meta) },
+				UpdateFunc:  func(e event.UpdateEvent) bool { return isConfig(e.MetaNew) },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return isConfig(e.Meta) },
+				GenericFunc: func(e event.GenericEvent) bool { return isConfig(e.Meta) },
+			}).
+		Complete(r)
+}
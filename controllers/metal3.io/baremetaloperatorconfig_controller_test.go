@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	goctx "context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+var testConfigName = types.NamespacedName{
+	Namespace: namespace,
+	Name:      "baremetal-operator",
+}
+
+// fakeIronicSettings records the settings it is given and reports
+// them back as the effective ones, rejecting any endpoint named
+// "invalid".
+type fakeIronicSettings struct {
+	applied         *metal3v1alpha1.IronicSettings
+	appliedBackends []metal3v1alpha1.IronicBackend
+}
+
+func (f *fakeIronicSettings) apply(settings *metal3v1alpha1.IronicSettings, backends []metal3v1alpha1.IronicBackend) (*metal3v1alpha1.IronicSettings, []metal3v1alpha1.IronicBackend, error) {
+	effective := &metal3v1alpha1.IronicSettings{Endpoint: "http://env.test/v1/"}
+	if settings != nil && settings.Endpoint != "" {
+		if settings.Endpoint == "invalid" {
+			return nil, nil, fmt.Errorf("bad endpoint")
+		}
+		effective.Endpoint = settings.Endpoint
+	}
+	f.applied = effective
+	f.appliedBackends = backends
+	return effective, backends, nil
+}
+
+func newTestConfigReconciler(ironic *fakeIronicSettings, initObjs ...runtime.Object) *BareMetalOperatorConfigReconciler {
+	return &BareMetalOperatorConfigReconciler{
+		Client:              fakeclient.NewFakeClient(initObjs...),
+		Log:                 ctrl.Log.WithName("controllers").WithName("BareMetalOperatorConfig"),
+		ConfigName:          testConfigName,
+		ConcurrencyLimit:    NewConcurrencyLimit(3),
+		DefaultConcurrency:  3,
+		ApplyIronicSettings: ironic.apply,
+	}
+}
+
+func newOperatorConfig(spec metal3v1alpha1.BareMetalOperatorConfigSpec) *metal3v1alpha1.BareMetalOperatorConfig {
+	return &metal3v1alpha1.BareMetalOperatorConfig{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "BareMetalOperatorConfig",
+			APIVersion: "metal3.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       testConfigName.Name,
+			Namespace:  testConfigName.Namespace,
+			Generation: 2,
+		},
+		Spec: spec,
+	}
+}
+
+func reconcileOperatorConfig(t *testing.T, r *BareMetalOperatorConfigReconciler) *metal3v1alpha1.BareMetalOperatorConfig {
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: testConfigName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &metal3v1alpha1.BareMetalOperatorConfig{}
+	if err := r.Get(goctx.TODO(), testConfigName, config); err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestOperatorConfigApplied(t *testing.T) {
+	concurrency := 7
+	ironic := &fakeIronicSettings{}
+	backends := []metal3v1alpha1.IronicBackend{
+		{
+			Name: "dc2",
+			IronicSettings: metal3v1alpha1.IronicSettings{
+				Endpoint:          "http://dc2.test/v1/",
+				InspectorEndpoint: "http://dc2.test:5050/v1/",
+			},
+		},
+	}
+	r := newTestConfigReconciler(ironic, newOperatorConfig(metal3v1alpha1.BareMetalOperatorConfigSpec{
+		Ironic:         &metal3v1alpha1.IronicSettings{Endpoint: "http://dc1.test/v1/"},
+		IronicBackends: backends,
+		Concurrency:    &concurrency,
+	}))
+
+	config := reconcileOperatorConfig(t, r)
+
+	assert.Equal(t, 7, r.ConcurrencyLimit.Limit())
+	assert.Equal(t, "http://dc1.test/v1/", ironic.applied.Endpoint)
+	assert.Equal(t, "", config.Status.ErrorMessage)
+	assert.Equal(t, int64(2), config.Status.ObservedGeneration)
+	assert.Equal(t, 7, *config.Status.Effective.Concurrency)
+	assert.Equal(t, "http://dc1.test/v1/", config.Status.Effective.Ironic.Endpoint)
+	assert.Equal(t, backends, ironic.appliedBackends)
+	assert.Equal(t, backends, config.Status.Effective.IronicBackends)
+}
+
+func TestOperatorConfigDefaults(t *testing.T) {
+	ironic := &fakeIronicSettings{}
+	r := newTestConfigReconciler(ironic, newOperatorConfig(metal3v1alpha1.BareMetalOperatorConfigSpec{}))
+
+	config := reconcileOperatorConfig(t, r)
+
+	assert.Equal(t, 3, r.ConcurrencyLimit.Limit())
+	assert.Equal(t, 3, *config.Status.Effective.Concurrency)
+	assert.Equal(t, "http://env.test/v1/", config.Status.Effective.Ironic.Endpoint)
+}
+
+func TestOperatorConfigInvalid(t *testing.T) {
+	cases := []struct {
+		name          string
+		spec          metal3v1alpha1.BareMetalOperatorConfigSpec
+		expectedError string
+	}{
+		{
+			name: "concurrency-too-high",
+			spec: metal3v1alpha1.BareMetalOperatorConfigSpec{
+				Concurrency: func() *int { c := metal3v1alpha1.MaxConcurrency + 1; return &c }(),
+			},
+			expectedError: "concurrency 51 must be between 1 and 50",
+		},
+		{
+			name: "ironic",
+			spec: metal3v1alpha1.BareMetalOperatorConfigSpec{
+				Concurrency: func() *int { c := 10; return &c }(),
+				Ironic:      &metal3v1alpha1.IronicSettings{Endpoint: "invalid"},
+			},
+			expectedError: "invalid ironic settings: bad endpoint",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ironic := &fakeIronicSettings{}
+			r := newTestConfigReconciler(ironic, newOperatorConfig(tc.spec))
+
+			config := reconcileOperatorConfig(t, r)
+
+			assert.Equal(t, tc.expectedError, config.Status.ErrorMessage)
+			assert.Nil(t, config.Status.Effective.Concurrency)
+			assert.Equal(t, 3, r.ConcurrencyLimit.Limit())
+		})
+	}
+}
+
+func TestOperatorConfigRemoved(t *testing.T) {
+	ironic := &fakeIronicSettings{}
+	r := newTestConfigReconciler(ironic)
+	r.ConcurrencyLimit.SetLimit(9)
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: testConfigName})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 3, r.ConcurrencyLimit.Limit())
+	assert.Equal(t, "http://env.test/v1/", ironic.applied.Endpoint)
+}
+
+func TestOperatorConfigOtherNameIgnored(t *testing.T) {
+	concurrency := 7
+	ironic := &fakeIronicSettings{}
+	other := newOperatorConfig(metal3v1alpha1.BareMetalOperatorConfigSpec{Concurrency: &concurrency})
+	other.Name = "other"
+	r := newTestConfigReconciler(ironic, other)
+
+	_, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{
+		Namespace: other.Namespace,
+		Name:      other.Name,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 3, r.ConcurrencyLimit.Limit())
+	assert.Nil(t, ironic.applied)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	l := NewConcurrencyLimit(1)
+	l.Acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		l.Acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second reconcile started while limit was reached")
+	default:
+	}
+
+	l.SetLimit(2)
+	<-acquired
+	l.Release()
+	l.Release()
+	assert.Equal(t, 2, l.Limit())
+}
+
+func TestDefaultConcurrency(t *testing.T) {
+	testCases := []struct {
+		Scenario    string
+		Value       string
+		Expected    int
+		ExpectError bool
+	}{
+		{Scenario: "valid", Value: "10", Expected: 10},
+		{Scenario: "maximum", Value: "50", Expected: 50},
+		{Scenario: "zero", Value: "0", Expected: defaultConcurrency},
+		{Scenario: "above maximum", Value: "51", ExpectError: true},
+		{Scenario: "not a number", Value: "many", ExpectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			os.Setenv("BMO_CONCURRENCY", tc.Value)
+			defer os.Unsetenv("BMO_CONCURRENCY")
+
+			concurrency, err := DefaultConcurrency()
+			if tc.ExpectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Expected, concurrency)
+		})
+	}
+}
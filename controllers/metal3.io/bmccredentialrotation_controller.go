@@ -0,0 +1,400 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/rotation"
+)
+
+const (
+	// How long to wait before trying again to change the password of
+	// a host after a failure.
+	rotationFailureRetryDelay = time.Hour
+	// How long to wait for a host to be registered with its current
+	// credentials before changing them.
+	rotationPendingRetryDelay = time.Minute
+	// The longest time between two checks of a policy, so that new
+	// hosts are noticed.
+	rotationMaxRequeueDelay = time.Hour
+	// The suffix of the name of the secret holding the new password
+	// while it is being set.
+	rotationSecretSuffix = "-rotation"
+	// The key of the previous password in that secret.
+	previousPasswordKey = "previousPassword"
+)
+
+// BMCCredentialRotationReconciler changes the BMC passwords of the
+// hosts selected by BMCCredentialRotation policies.
+type BMCCredentialRotationReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// HostLocks, when set, keeps the hosts from being reconciled while
+	// their BMC password is changed.
+	HostLocks *HostLocks
+	// ChangePassword changes the password of the BMC account of a
+	// host. Defaults to rotation.ChangePassword.
+	ChangePassword rotation.PasswordChanger
+}
+
+// +kubebuilder:rbac:groups=metal3.io,resources=bmccredentialrotations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=metal3.io,resources=bmccredentialrotations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
+
+// Reconcile changes the passwords of the hosts due for a new one
+func (r *BMCCredentialRotationReconciler) Reconcile(request ctrl.Request) (result ctrl.Result, err error) {
+	reqLogger := r.Log.WithValues("bmccredentialrotation", request.NamespacedName)
+
+	policy := &metal3v1alpha1.BMCCredentialRotation{}
+	err = r.Get(context.TODO(), request.NamespacedName, policy)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		err = errors.Wrap(err, "could not load credential rotation policy")
+		return
+	}
+
+	newStatus := policy.Status.DeepCopy()
+	defer func() {
+		if reflect.DeepEqual(policy.Status, *newStatus) {
+			return
+		}
+		policy.Status = *newStatus
+		if saveErr := r.Status().Update(context.TODO(), policy); saveErr != nil && err == nil {
+			err = errors.Wrap(saveErr, "failed to update credential rotation policy status")
+		}
+	}()
+
+	hosts, policyErr := r.selectHosts(policy)
+	if policyErr == nil {
+		policyErr = rotation.ValidatePolicy(policy.Spec.Password)
+	}
+	if policyErr == nil && policy.Spec.Interval.Duration <= 0 {
+		policyErr = fmt.Errorf("the interval must be positive")
+	}
+	if policyErr != nil {
+		reqLogger.Info("invalid policy", "error", policyErr.Error())
+		newStatus.ErrorMessage = policyErr.Error()
+		return
+	}
+	newStatus.ErrorMessage = ""
+
+	previous := make(map[string]metal3v1alpha1.HostRotationStatus)
+	for _, hostStatus := range policy.Status.Hosts {
+		previous[hostStatus.Name] = hostStatus
+	}
+	newStatus.Hosts = nil
+
+	requeueAfter := rotationMaxRequeueDelay
+	for i := range hosts {
+		host := &hosts[i]
+		hostStatus, ok := previous[host.Name]
+		if !ok {
+			hostStatus = metal3v1alpha1.HostRotationStatus{Name: host.Name}
+		}
+
+		wait, rotateErr := r.rotate(policy, host, &hostStatus)
+		newStatus.Hosts = append(newStatus.Hosts, hostStatus)
+		if rotateErr != nil {
+			reqLogger.Error(rotateErr, "failed to rotate credentials", "baremetalhost", host.Name)
+			err = rotateErr
+			continue
+		}
+		if wait > 0 && wait < requeueAfter {
+			requeueAfter = wait
+		}
+	}
+	sort.Slice(newStatus.Hosts, func(i, j int) bool {
+		return newStatus.Hosts[i].Name < newStatus.Hosts[j].Name
+	})
+
+	result.RequeueAfter = requeueAfter
+	return
+}
+
+func (r *BMCCredentialRotationReconciler) selectHosts(policy *metal3v1alpha1.BMCCredentialRotation) ([]metal3v1alpha1.BareMetalHost, error) {
+	selector := labels.Everything()
+	if policy.Spec.HostSelector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(policy.Spec.HostSelector)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid host selector")
+		}
+	}
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	err := r.List(context.TODO(), hosts,
+		client.InNamespace(policy.Namespace),
+		client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+	return hosts.Items, nil
+}
+
+func (r *BMCCredentialRotationReconciler) publishEvent(host *metal3v1alpha1.BareMetalHost, reason, message string) {
+	event := host.NewEvent(reason, message)
+	if err := r.Create(context.TODO(), &event); err != nil {
+		r.Log.Info("failed to record event, ignoring",
+			"reason", reason, "message", message, "error", err)
+	}
+}
+
+func (r *BMCCredentialRotationReconciler) changePassword(host metal3v1alpha1.BareMetalHost, creds bmc.Credentials, password string) error {
+	host = provisionerHost(&host)
+	if r.ChangePassword != nil {
+		return r.ChangePassword(host, creds, password)
+	}
+	return rotation.ChangePassword(host, creds, password)
+}
+
+// When the password was last rotated, or the secret created.
+func lastRotation(secret *corev1.Secret) time.Time {
+	if value, ok := secret.Annotations[metal3v1alpha1.PasswordRotatedAnnotation]; ok {
+		if rotated, err := time.Parse(time.RFC3339, value); err == nil {
+			return rotated
+		}
+	}
+	return secret.CreationTimestamp.Time
+}
+
+// rotate takes the next step in changing the password of the host, and
+// returns how long to wait before the following one.
+//
+// The new password, and the one it replaces, are kept in a secret of
+// their own while it is being set, so that they are not lost if the
+// operator stops. Holding the lock of the host, so that it is not
+// reconciled meanwhile, the BMC password is changed and the new one
+// replaces the old one in the secret of the host. The host controller
+// then validates the new credentials, recording them in the
+// TriedCredentials of the host, and in its GoodCredentials once they
+// work. The previous password is put back if they do not.
+func (r *BMCCredentialRotationReconciler) rotate(policy *metal3v1alpha1.BMCCredentialRotation, host *metal3v1alpha1.BareMetalHost, hostStatus *metal3v1alpha1.HostRotationStatus) (time.Duration, error) {
+	if !host.DeletionTimestamp.IsZero() || host.Spec.BMC.CredentialsName == "" {
+		return 0, nil
+	}
+	if strings.Contains(host.Spec.BMC.CredentialsName, ":") {
+		hostStatus.ErrorMessage = "credentials kept outside of the cluster are not rotated"
+		return 0, nil
+	}
+	log := r.Log.WithValues("bmccredentialrotation", policy.Name, "baremetalhost", host.Name)
+
+	secret := &corev1.Secret{}
+	err := r.Get(context.TODO(), host.CredentialsKey(), secret)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			hostStatus.ErrorMessage = fmt.Sprintf("the BMC secret %s does not exist", host.CredentialsKey())
+			return rotationPendingRetryDelay, nil
+		}
+		return 0, err
+	}
+	if secret.Labels[metal3v1alpha1.SharedCredentialsLabel] == "true" {
+		hostStatus.ErrorMessage = "credentials shared with other hosts are not rotated"
+		return 0, nil
+	}
+
+	pendingKey := types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name + rotationSecretSuffix}
+	pending := &corev1.Secret{}
+	err = r.Get(context.TODO(), pendingKey, pending)
+	switch {
+	case k8serrors.IsNotFound(err):
+		if !host.Status.GoodCredentials.Match(*secret) {
+			// The host is not registered yet, or has not yet taken
+			// in the last change of its credentials.
+			return rotationPendingRetryDelay, nil
+		}
+		if host.Status.GoodCredentials.Set != 0 {
+			// The host is managed with fallback credentials, such as
+			// the factory default account, rather than the ones
+			// rotated.
+			hostStatus.ErrorMessage = "the host does not use the first credentials of its secret"
+			return rotationPendingRetryDelay, nil
+		}
+		if wait := time.Until(lastRotation(secret).Add(policy.Spec.Interval.Duration)); wait > 0 {
+			return wait, nil
+		}
+		if hostStatus.LastFailure != nil {
+			if wait := time.Until(hostStatus.LastFailure.Add(rotationFailureRetryDelay)); wait > 0 {
+				return wait, nil
+			}
+		}
+		pending, err = r.createPendingSecret(policy, host, pendingKey, secret)
+		if err != nil {
+			return 0, err
+		}
+		log.Info("rotating BMC password")
+	case err != nil:
+		return 0, err
+	}
+
+	if !bytes.Equal(secret.Data["password"], pending.Data["password"]) {
+		return r.setPassword(host, hostStatus, secret, pending)
+	}
+
+	status := host.Status
+	switch {
+	case status.GoodCredentials.Match(*secret) && status.GoodCredentials.Set == 0:
+	case status.TriedCredentials.Match(*secret) && status.ErrorType == metal3v1alpha1.RegistrationError:
+		return r.restorePassword(host, hostStatus, secret, pending,
+			fmt.Sprintf("the new BMC password does not work: %s", status.ErrorMessage))
+	case status.GoodCredentials.Match(*secret):
+		return r.restorePassword(host, hostStatus, secret, pending,
+			"the new BMC password does not work: the host fell back to other credentials")
+	default:
+		// The host controller has not validated the new password yet.
+		return rotationPendingRetryDelay, nil
+	}
+
+	if err := r.Delete(context.TODO(), pending); err != nil && !k8serrors.IsNotFound(err) {
+		return 0, errors.Wrap(err, "failed to remove the new BMC password once saved")
+	}
+
+	log.Info("rotated BMC password")
+	r.publishEvent(host, "BMCPasswordRotated", "Changed the BMC password")
+	rotated := metav1.NewTime(lastRotation(secret))
+	hostStatus.LastRotated = &rotated
+	hostStatus.LastFailure = nil
+	hostStatus.ErrorMessage = ""
+	return policy.Spec.Interval.Duration, nil
+}
+
+// lockHost keeps the host from being reconciled until the function
+// returned is called.
+func (r *BMCCredentialRotationReconciler) lockHost(host *metal3v1alpha1.BareMetalHost) func() {
+	if r.HostLocks == nil {
+		return func() {}
+	}
+	return r.HostLocks.Lock(types.NamespacedName{Namespace: host.Namespace, Name: host.Name})
+}
+
+// setPassword changes the BMC password of the host to the pending one,
+// unless an earlier attempt already did, and saves it in the secret of
+// the host for the host controller to validate.
+func (r *BMCCredentialRotationReconciler) setPassword(host *metal3v1alpha1.BareMetalHost, hostStatus *metal3v1alpha1.HostRotationStatus, secret, pending *corev1.Secret) (time.Duration, error) {
+	defer r.lockHost(host)()
+
+	current := bmc.Credentials{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}
+	next := bmc.Credentials{
+		Username: current.Username,
+		Password: string(pending.Data["password"]),
+	}
+	if changeErr := r.changePassword(*host, current, next.Password); changeErr != nil {
+		if r.changePassword(*host, next, next.Password) != nil {
+			return r.rotationFailed(host, hostStatus, pending,
+				fmt.Sprintf("failed to change the BMC password: %s", changeErr))
+		}
+	}
+
+	secret.Data["password"] = []byte(next.Password)
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[metal3v1alpha1.PasswordRotatedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.Update(context.TODO(), secret); err != nil {
+		return 0, errors.Wrap(err, "failed to save the new BMC password")
+	}
+	return rotationPendingRetryDelay, nil
+}
+
+// restorePassword puts the previous BMC password back, on the BMC and
+// in the secret of the host, after the host controller could not use
+// the new one.
+func (r *BMCCredentialRotationReconciler) restorePassword(host *metal3v1alpha1.BareMetalHost, hostStatus *metal3v1alpha1.HostRotationStatus, secret, pending *corev1.Secret, message string) (time.Duration, error) {
+	defer r.lockHost(host)()
+
+	next := bmc.Credentials{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}
+	previous := pending.Data[previousPasswordKey]
+	if err := r.changePassword(*host, next, string(previous)); err != nil {
+		message = fmt.Sprintf("%s, and restoring the previous one failed: %s", message, err)
+	}
+	secret.Data["password"] = previous
+	if err := r.Update(context.TODO(), secret); err != nil {
+		return 0, errors.Wrap(err, "failed to restore the previous BMC password")
+	}
+	return r.rotationFailed(host, hostStatus, pending, message)
+}
+
+func (r *BMCCredentialRotationReconciler) createPendingSecret(policy *metal3v1alpha1.BMCCredentialRotation, host *metal3v1alpha1.BareMetalHost, key types.NamespacedName, secret *corev1.Secret) (*corev1.Secret, error) {
+	password, err := rotation.GeneratePassword(policy.Spec.Password)
+	if err != nil {
+		return nil, err
+	}
+	pending := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+		},
+		Data: map[string][]byte{
+			"username":          secret.Data["username"],
+			"password":          []byte(password),
+			previousPasswordKey: secret.Data["password"],
+		},
+	}
+	if err := controllerutil.SetControllerReference(host, pending, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := r.Create(context.TODO(), pending); err != nil {
+		return nil, errors.Wrap(err, "failed to save the new BMC password")
+	}
+	return pending, nil
+}
+
+func (r *BMCCredentialRotationReconciler) rotationFailed(host *metal3v1alpha1.BareMetalHost, hostStatus *metal3v1alpha1.HostRotationStatus, pending *corev1.Secret, message string) (time.Duration, error) {
+	r.Log.Info("failed to rotate BMC password", "baremetalhost", host.Name, "error", message)
+	if err := r.Delete(context.TODO(), pending); err != nil && !k8serrors.IsNotFound(err) {
+		return 0, errors.Wrap(err, "failed to remove the new BMC password")
+	}
+	r.publishEvent(host, "BMCPasswordRotationFailed", message)
+	now := metav1.Now()
+	hostStatus.LastFailure = &now
+	hostStatus.ErrorMessage = message
+	return rotationFailureRetryDelay, nil
+}
+
+// SetupWithManager registers the reconciler to be run by the manager
+func (r *BMCCredentialRotationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metal3v1alpha1.BMCCredentialRotation{}).
+		Complete(r)
+}
@@ -0,0 +1,334 @@
+package controllers
+
+import (
+	goctx "context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// fakeBMC holds the password of the BMC account of a host.
+type fakeBMC struct {
+	password string
+	changes  int
+}
+
+func (b *fakeBMC) changePassword(host metal3v1alpha1.BareMetalHost, creds bmc.Credentials, password string) error {
+	if creds.Password != b.password {
+		return fmt.Errorf("BMC rejected the credentials")
+	}
+	b.password = password
+	b.changes++
+	return nil
+}
+
+type rotationTest struct {
+	t          *testing.T
+	r          *BMCCredentialRotationReconciler
+	bmc        *fakeBMC
+	host       *metal3v1alpha1.BareMetalHost
+	secret     *corev1.Secret
+	policyName types.NamespacedName
+}
+
+// newRotationTest sets up a registered host, whose BMC secret was
+// last rotated at the given time, and a policy rotating it every day.
+func newRotationTest(t *testing.T, lastRotated time.Time) *rotationTest {
+	c := fakeclient.NewFakeClient()
+
+	secret := newSecret(defaultSecretName, nil)
+	secret.Data = map[string][]byte{"username": []byte("admin"), "password": []byte("old")}
+	secret.Annotations = map[string]string{
+		metal3v1alpha1.PasswordRotatedAnnotation: lastRotated.UTC().Format(time.RFC3339),
+	}
+	if err := c.Create(goctx.TODO(), secret); err != nil {
+		t.Fatal(err)
+	}
+
+	host := newDefaultHost(t)
+	host.Status.Provisioning.ID = "host-id"
+	host.Status.Provisioning.State = metal3v1alpha1.StateReady
+	host.UpdateGoodCredentials(*secret)
+	if err := c.Create(goctx.TODO(), host); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &metal3v1alpha1.BMCCredentialRotation{
+		ObjectMeta: metav1.ObjectMeta{Name: "every-day", Namespace: namespace},
+		Spec: metal3v1alpha1.BMCCredentialRotationSpec{
+			Interval: metav1.Duration{Duration: 24 * time.Hour},
+			Password: metal3v1alpha1.PasswordPolicy{Length: 12, MinDigits: 2},
+		},
+	}
+	if err := c.Create(goctx.TODO(), policy); err != nil {
+		t.Fatal(err)
+	}
+
+	test := &rotationTest{
+		t:          t,
+		bmc:        &fakeBMC{password: "old"},
+		host:       host,
+		secret:     secret,
+		policyName: types.NamespacedName{Namespace: namespace, Name: policy.Name},
+	}
+	test.r = &BMCCredentialRotationReconciler{
+		Client:         c,
+		Log:            ctrl.Log.WithName("controllers").WithName("BMCCredentialRotation"),
+		Scheme:         scheme.Scheme,
+		HostLocks:      NewHostLocks(),
+		ChangePassword: test.bmc.changePassword,
+	}
+	return test
+}
+
+func (test *rotationTest) reconcile() (ctrl.Result, *metal3v1alpha1.BMCCredentialRotation) {
+	result, err := test.r.Reconcile(ctrl.Request{NamespacedName: test.policyName})
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	policy := &metal3v1alpha1.BMCCredentialRotation{}
+	if err := test.r.Get(goctx.TODO(), test.policyName, policy); err != nil {
+		test.t.Fatal(err)
+	}
+	test.secret = &corev1.Secret{}
+	if err := test.r.Get(goctx.TODO(), test.host.CredentialsKey(), test.secret); err != nil {
+		test.t.Fatal(err)
+	}
+	return result, policy
+}
+
+// registerHost does what the host controller does with the secret of
+// the host, recording the credentials it holds as working, or as
+// rejected with the error message given.
+func (test *rotationTest) registerHost(errorMessage string) {
+	host := &metal3v1alpha1.BareMetalHost{}
+	if err := test.r.Get(goctx.TODO(), types.NamespacedName{Namespace: namespace, Name: test.host.Name}, host); err != nil {
+		test.t.Fatal(err)
+	}
+	host.UpdateTriedCredentials(*test.secret)
+	if errorMessage == "" {
+		host.UpdateGoodCredentials(*test.secret)
+		host.Status.ErrorType = ""
+		host.Status.ErrorMessage = ""
+	} else {
+		host.Status.ErrorType = metal3v1alpha1.RegistrationError
+		host.Status.ErrorMessage = errorMessage
+	}
+	if err := test.r.Update(goctx.TODO(), host); err != nil {
+		test.t.Fatal(err)
+	}
+}
+
+func (test *rotationTest) pendingExists() bool {
+	key := types.NamespacedName{Namespace: namespace, Name: defaultSecretName + rotationSecretSuffix}
+	err := test.r.Get(goctx.TODO(), key, &corev1.Secret{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		test.t.Fatal(err)
+	}
+	return err == nil
+}
+
+func TestRotateCredentials(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-25*time.Hour))
+
+	result, policy := test.reconcile()
+
+	assert.Equal(t, 1, test.bmc.changes)
+	assert.Len(t, test.bmc.password, 12)
+	assert.Equal(t, test.bmc.password, string(test.secret.Data["password"]))
+	assert.Equal(t, "admin", string(test.secret.Data["username"]))
+	assert.True(t, test.pendingExists())
+	assert.Equal(t, rotationPendingRetryDelay, result.RequeueAfter)
+	if assert.Len(t, policy.Status.Hosts, 1) {
+		assert.Nil(t, policy.Status.Hosts[0].LastRotated)
+	}
+
+	// The rotation is done once the host controller has validated the
+	// new password.
+	result, _ = test.reconcile()
+	assert.True(t, test.pendingExists())
+	test.registerHost("")
+	result, policy = test.reconcile()
+
+	assert.Equal(t, 1, test.bmc.changes)
+	assert.False(t, test.pendingExists())
+	assert.Equal(t, rotationMaxRequeueDelay, result.RequeueAfter)
+	if assert.Len(t, policy.Status.Hosts, 1) {
+		assert.Equal(t, test.host.Name, policy.Status.Hosts[0].Name)
+		assert.NotNil(t, policy.Status.Hosts[0].LastRotated)
+		assert.Empty(t, policy.Status.Hosts[0].ErrorMessage)
+	}
+
+	// The host has to take in the new credentials before they are
+	// changed again.
+	rotated := lastRotation(test.secret)
+	assert.WithinDuration(t, time.Now(), rotated, time.Minute)
+	test.reconcile()
+	assert.Equal(t, 1, test.bmc.changes)
+}
+
+func TestRotateCredentialsWaitsForHostReconcile(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-25*time.Hour))
+	unlock := test.r.HostLocks.Lock(types.NamespacedName{Namespace: namespace, Name: test.host.Name})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		test.r.Reconcile(ctrl.Request{NamespacedName: test.policyName})
+	}()
+	select {
+	case <-done:
+		t.Fatal("the password was changed while the host was reconciled")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	<-done
+	assert.Equal(t, 1, test.bmc.changes)
+}
+
+func TestRotateCredentialsNotDue(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-12*time.Hour))
+
+	result, _ := test.reconcile()
+
+	assert.Equal(t, 0, test.bmc.changes)
+	assert.Equal(t, "old", string(test.secret.Data["password"]))
+	assert.Equal(t, rotationMaxRequeueDelay, result.RequeueAfter)
+}
+
+func TestRotateCredentialsWaitsForRegistration(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-25*time.Hour))
+	test.host.Status.GoodCredentials = metal3v1alpha1.CredentialsStatus{}
+	if err := test.r.Update(goctx.TODO(), test.host); err != nil {
+		t.Fatal(err)
+	}
+
+	result, _ := test.reconcile()
+
+	assert.Equal(t, 0, test.bmc.changes)
+	assert.Equal(t, rotationPendingRetryDelay, result.RequeueAfter)
+}
+
+func TestRotateCredentialsResumes(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-25*time.Hour))
+
+	// The BMC password was changed before the operator stopped.
+	pending := newSecret(defaultSecretName+rotationSecretSuffix, nil)
+	pending.Data = map[string][]byte{
+		"username":          []byte("admin"),
+		"password":          []byte("already-set"),
+		previousPasswordKey: []byte("old"),
+	}
+	if err := test.r.Create(goctx.TODO(), pending); err != nil {
+		t.Fatal(err)
+	}
+	test.bmc.password = "already-set"
+
+	test.reconcile()
+	test.registerHost("")
+	test.reconcile()
+
+	assert.Equal(t, "already-set", string(test.secret.Data["password"]))
+	assert.False(t, test.pendingExists())
+}
+
+func TestRotateCredentialsValidationFails(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-25*time.Hour))
+	test.reconcile()
+	test.registerHost("authentication failed")
+
+	result, policy := test.reconcile()
+
+	assert.Equal(t, "old", test.bmc.password, "the previous password is restored")
+	assert.Equal(t, "old", string(test.secret.Data["password"]))
+	assert.False(t, test.pendingExists())
+	assert.Equal(t, rotationFailureRetryDelay, result.RequeueAfter)
+	if assert.Len(t, policy.Status.Hosts, 1) {
+		assert.Equal(t, "the new BMC password does not work: authentication failed",
+			policy.Status.Hosts[0].ErrorMessage)
+		assert.NotNil(t, policy.Status.Hosts[0].LastFailure)
+	}
+
+	// Failures are not retried right away.
+	test.reconcile()
+	assert.Equal(t, 2, test.bmc.changes)
+}
+
+func TestRotateCredentialsChangeFails(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-25*time.Hour))
+	test.bmc.password = "changed-by-hand"
+
+	_, policy := test.reconcile()
+
+	assert.Equal(t, "old", string(test.secret.Data["password"]))
+	assert.False(t, test.pendingExists())
+	if assert.Len(t, policy.Status.Hosts, 1) {
+		assert.Equal(t, "failed to change the BMC password: BMC rejected the credentials",
+			policy.Status.Hosts[0].ErrorMessage)
+	}
+}
+
+func TestRotateSharedCredentials(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-25*time.Hour))
+	test.secret.Labels = map[string]string{metal3v1alpha1.SharedCredentialsLabel: "true"}
+	if err := test.r.Update(goctx.TODO(), test.secret); err != nil {
+		t.Fatal(err)
+	}
+	test.host.UpdateGoodCredentials(*test.secret)
+	if err := test.r.Update(goctx.TODO(), test.host); err != nil {
+		t.Fatal(err)
+	}
+
+	_, policy := test.reconcile()
+
+	assert.Equal(t, 0, test.bmc.changes)
+	if assert.Len(t, policy.Status.Hosts, 1) {
+		assert.Equal(t, "credentials shared with other hosts are not rotated", policy.Status.Hosts[0].ErrorMessage)
+	}
+}
+
+func TestRotateFallbackCredentials(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-25*time.Hour))
+	test.host.Status.GoodCredentials.Set = 1
+	if err := test.r.Update(goctx.TODO(), test.host); err != nil {
+		t.Fatal(err)
+	}
+
+	result, policy := test.reconcile()
+
+	assert.Equal(t, 0, test.bmc.changes)
+	assert.Equal(t, rotationPendingRetryDelay, result.RequeueAfter)
+	if assert.Len(t, policy.Status.Hosts, 1) {
+		assert.Equal(t, "the host does not use the first credentials of its secret", policy.Status.Hosts[0].ErrorMessage)
+	}
+}
+
+func TestRotateCredentialsInvalidPolicy(t *testing.T) {
+	test := newRotationTest(t, time.Now().Add(-25*time.Hour))
+	policy := &metal3v1alpha1.BMCCredentialRotation{}
+	if err := test.r.Get(goctx.TODO(), test.policyName, policy); err != nil {
+		t.Fatal(err)
+	}
+	policy.Spec.Password = metal3v1alpha1.PasswordPolicy{Length: 8, MinDigits: 5, MinUppercase: 5}
+	if err := test.r.Update(goctx.TODO(), policy); err != nil {
+		t.Fatal(err)
+	}
+
+	_, policy = test.reconcile()
+
+	assert.Equal(t, 0, test.bmc.changes)
+	assert.Equal(t, "passwords need 10 characters of given kinds but are 8 characters long", policy.Status.ErrorMessage)
+}
@@ -0,0 +1,565 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/discovery"
+)
+
+const (
+	// How long to wait for an endpoint to answer a probe.
+	discoveryProbeTimeout = 2 * time.Second
+	// How long to wait before trying again to scan the networks of
+	// a discovery that cannot be scanned, e.g. because a secret is
+	// missing.
+	discoveryErrorRetryDelay = 5 * time.Minute
+	// How often the progress of a scan under way is recorded.
+	discoveryProgressInterval = 10 * time.Second
+)
+
+// BMCDiscoveryReconciler scans the networks of BMCDiscovery resources
+// and creates hosts for the BMCs found.
+type BMCDiscoveryReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// Probe tells whether a BMC answers at an endpoint. Defaults to
+	// discovery.Probe.
+	Probe discovery.Prober
+	// Identify logs into a BMC to identify it. Defaults to
+	// discovery.Identify.
+	Identify discovery.Identifier
+	// ProgressInterval is how often the progress of a scan under way
+	// is recorded. Defaults to 10 seconds.
+	ProgressInterval time.Duration
+
+	scansLock sync.Mutex
+	scans     map[types.NamespacedName]*discoveryScan
+}
+
+// discoveryCredentials are the credentials of a secret listed by a
+// discovery.
+type discoveryCredentials struct {
+	secret *corev1.Secret
+	creds  bmc.Credentials
+}
+
+// identifiedBMC is the result of the identification of the BMC
+// answering at an endpoint.
+type identifiedBMC struct {
+	endpoint discovery.Endpoint
+	identity *discovery.Identity
+	// secret holds the credentials that worked
+	secret       *corev1.Secret
+	errorMessage string
+}
+
+// discoveryScan is a scan of the networks of a discovery, which runs
+// in the background.
+type discoveryScan struct {
+	cancel context.CancelFunc
+
+	lock     sync.Mutex
+	progress metal3v1alpha1.ScanProgress
+	done     bool
+	results  []identifiedBMC
+}
+
+// +kubebuilder:rbac:groups=metal3.io,resources=bmcdiscoveries,verbs=get;list;watch
+// +kubebuilder:rbac:groups=metal3.io,resources=bmcdiscoveries/status,verbs=get;update;patch
+
+// Reconcile starts a scan of the networks of a discovery when they are
+// due for one, records its progress and creates the hosts of the BMCs
+// found once it is over
+func (r *BMCDiscoveryReconciler) Reconcile(request ctrl.Request) (result ctrl.Result, err error) {
+	reqLogger := r.Log.WithValues("bmcdiscovery", request.NamespacedName)
+
+	bmcDiscovery := &metal3v1alpha1.BMCDiscovery{}
+	err = r.Get(context.TODO(), request.NamespacedName, bmcDiscovery)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			r.stopScan(request.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		err = errors.Wrap(err, "could not load BMC discovery")
+		return
+	}
+
+	scan := r.getScan(request.NamespacedName)
+	if scan != nil && scan.generation() != bmcDiscovery.Generation {
+		reqLogger.Info("spec changed, cancelling scan")
+		r.stopScan(request.NamespacedName)
+		scan = nil
+	}
+
+	status := &bmcDiscovery.Status
+	if scan == nil && status.Scan == nil && status.LastScan != nil &&
+		status.ObservedGeneration == bmcDiscovery.Generation && status.ErrorMessage == "" {
+		if bmcDiscovery.Spec.Interval == nil {
+			return ctrl.Result{}, nil
+		}
+		if wait := time.Until(status.LastScan.Add(bmcDiscovery.Spec.Interval.Duration)); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	newStatus := status.DeepCopy()
+	defer func() {
+		if reflect.DeepEqual(*status, *newStatus) {
+			return
+		}
+		bmcDiscovery.Status = *newStatus
+		if saveErr := r.Status().Update(context.TODO(), bmcDiscovery); saveErr != nil && err == nil {
+			err = errors.Wrap(saveErr, "failed to update BMC discovery status")
+		}
+	}()
+
+	if scan == nil {
+		addresses, credentials, scanErr := r.prepare(bmcDiscovery)
+		if scanErr != nil {
+			reqLogger.Info("cannot scan networks", "error", scanErr.Error())
+			newStatus.Scan = nil
+			newStatus.ErrorMessage = scanErr.Error()
+			return ctrl.Result{RequeueAfter: discoveryErrorRetryDelay}, nil
+		}
+		_, found, listErr := r.knownBMCs(bmcDiscovery.Namespace)
+		if listErr != nil {
+			err = listErr
+			return
+		}
+		redfishPorts, ipmiPorts := discoveryPorts(&bmcDiscovery.Spec)
+		endpoints := []discovery.Endpoint{}
+		for _, endpoint := range discovery.Endpoints(addresses, redfishPorts, ipmiPorts) {
+			if _, known := found[endpoint.IP]; !known {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		reqLogger.Info("scanning networks", "endpoints", len(endpoints))
+		scan = r.startScan(request.NamespacedName, bmcDiscovery, endpoints, credentials)
+		newStatus.ErrorMessage = ""
+	}
+
+	progress, done, results := scan.state()
+	if !done {
+		newStatus.Scan = &progress
+		return ctrl.Result{RequeueAfter: r.progressInterval()}, nil
+	}
+	r.forgetScan(request.NamespacedName, scan)
+
+	names, found, err := r.knownBMCs(bmcDiscovery.Namespace)
+	if err != nil {
+		return
+	}
+	for _, identified := range results {
+		bmcFound, createErr := r.createHost(bmcDiscovery, identified, names)
+		if createErr != nil {
+			err = createErr
+			return
+		}
+		found[identified.endpoint.IP] = bmcFound
+	}
+
+	addresses, err := discovery.Addresses(bmcDiscovery.Spec.Networks)
+	if err != nil {
+		return
+	}
+	newStatus.BMCs = nil
+	for _, address := range addresses {
+		if bmcFound, ok := found[address]; ok {
+			newStatus.BMCs = append(newStatus.BMCs, bmcFound)
+		}
+	}
+	now := metav1.Now()
+	newStatus.Scan = nil
+	newStatus.LastScan = &now
+	newStatus.ObservedGeneration = progress.Generation
+	newStatus.ErrorMessage = ""
+	reqLogger.Info("scanned networks", "bmcs", len(newStatus.BMCs))
+
+	if bmcDiscovery.Spec.Interval != nil {
+		result.RequeueAfter = bmcDiscovery.Spec.Interval.Duration
+	}
+	return
+}
+
+func (r *BMCDiscoveryReconciler) progressInterval() time.Duration {
+	if r.ProgressInterval > 0 {
+		return r.ProgressInterval
+	}
+	return discoveryProgressInterval
+}
+
+// knownBMCs returns the names of the hosts of the namespace, and the
+// BMCs of the hosts by the IP address of the BMC.
+func (r *BMCDiscoveryReconciler) knownBMCs(namespace string) (map[string]bool, map[string]metal3v1alpha1.FoundBMC, error) {
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	if err := r.List(context.TODO(), hosts, client.InNamespace(namespace)); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list hosts")
+	}
+	names := make(map[string]bool)
+	found := make(map[string]metal3v1alpha1.FoundBMC)
+	for _, host := range hosts.Items {
+		names[host.Name] = true
+		if ip := bmcIP(host.Spec.BMC.Address); ip != "" {
+			found[ip] = metal3v1alpha1.FoundBMC{Address: host.Spec.BMC.Address, Host: host.Name}
+		}
+	}
+	return names, found, nil
+}
+
+func (s *discoveryScan) generation() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.progress.Generation
+}
+
+// state returns the progress of the scan, whether it is over, and the
+// BMCs it identified once it is.
+func (s *discoveryScan) state() (metal3v1alpha1.ScanProgress, bool, []identifiedBMC) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.progress, s.done, s.results
+}
+
+func (r *BMCDiscoveryReconciler) getScan(key types.NamespacedName) *discoveryScan {
+	r.scansLock.Lock()
+	defer r.scansLock.Unlock()
+	return r.scans[key]
+}
+
+// forgetScan drops a scan that is over, unless another one replaced
+// it.
+func (r *BMCDiscoveryReconciler) forgetScan(key types.NamespacedName, scan *discoveryScan) {
+	r.scansLock.Lock()
+	defer r.scansLock.Unlock()
+	if r.scans[key] == scan {
+		delete(r.scans, key)
+	}
+}
+
+// stopScan cancels the scan of a discovery, if there is one.
+func (r *BMCDiscoveryReconciler) stopScan(key types.NamespacedName) {
+	r.scansLock.Lock()
+	defer r.scansLock.Unlock()
+	if scan, ok := r.scans[key]; ok {
+		scan.cancel()
+		delete(r.scans, key)
+	}
+}
+
+// startScan probes the endpoints in the background, and identifies the
+// BMCs answering.
+func (r *BMCDiscoveryReconciler) startScan(key types.NamespacedName, bmcDiscovery *metal3v1alpha1.BMCDiscovery, endpoints []discovery.Endpoint, credentials []discoveryCredentials) *discoveryScan {
+	ctx, cancel := context.WithCancel(context.Background())
+	scan := &discoveryScan{
+		cancel: cancel,
+		progress: metal3v1alpha1.ScanProgress{
+			StartTime:  metav1.Now(),
+			Generation: bmcDiscovery.Generation,
+			Endpoints:  len(endpoints),
+		},
+	}
+	r.scansLock.Lock()
+	if r.scans == nil {
+		r.scans = make(map[types.NamespacedName]*discoveryScan)
+	}
+	r.scans[key] = scan
+	r.scansLock.Unlock()
+
+	log := r.Log.WithValues("bmcdiscovery", key)
+	disableCertificateVerification := bmcDiscovery.Spec.DisableCertificateVerification
+	go func() {
+		answered, err := discovery.Scan(ctx, endpoints, r.probe(), discoveryProbeTimeout, func(probed int) {
+			scan.lock.Lock()
+			defer scan.lock.Unlock()
+			scan.progress.Probed = probed
+		})
+		if err != nil {
+			log.Info("scan cancelled")
+			return
+		}
+
+		// The endpoints of an address are tried in turn until the BMC
+		// is identified through one of them.
+		results := []identifiedBMC{}
+		identified := make(map[string]bool)
+		for _, endpoint := range answered {
+			if identified[endpoint.IP] {
+				continue
+			}
+			if ctx.Err() != nil {
+				log.Info("scan cancelled")
+				return
+			}
+			result := r.identifyBMC(key, endpoint, credentials, disableCertificateVerification)
+			results = append(results, result)
+			identified[endpoint.IP] = result.identity != nil
+		}
+
+		scan.lock.Lock()
+		defer scan.lock.Unlock()
+		scan.results = results
+		scan.done = true
+	}()
+	return scan
+}
+
+// prepare lists the addresses scanned and loads the credentials tried.
+func (r *BMCDiscoveryReconciler) prepare(bmcDiscovery *metal3v1alpha1.BMCDiscovery) ([]string, []discoveryCredentials, error) {
+	if bmcDiscovery.Spec.Interval != nil && bmcDiscovery.Spec.Interval.Duration <= 0 {
+		return nil, nil, fmt.Errorf("the interval must be positive")
+	}
+	redfishPorts, ipmiPorts := discoveryPorts(&bmcDiscovery.Spec)
+	for _, ports := range [][]int{redfishPorts, ipmiPorts} {
+		for _, port := range ports {
+			if port < 1 || port > 65535 {
+				return nil, nil, fmt.Errorf("invalid port %d", port)
+			}
+		}
+	}
+	addresses, err := discovery.Addresses(bmcDiscovery.Spec.Networks)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(bmcDiscovery.Spec.CredentialsNames) == 0 {
+		return nil, nil, fmt.Errorf("no BMC credentials are listed")
+	}
+
+	credentials := []discoveryCredentials{}
+	for _, name := range bmcDiscovery.Spec.CredentialsNames {
+		secret := &corev1.Secret{}
+		key := types.NamespacedName{Namespace: bmcDiscovery.Namespace, Name: name}
+		if err := r.Get(context.TODO(), key, secret); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil, nil, fmt.Errorf("the BMC secret %s does not exist", key)
+			}
+			return nil, nil, err
+		}
+		credentials = append(credentials, discoveryCredentials{
+			secret: secret,
+			creds: bmc.Credentials{
+				Username: string(secret.Data["username"]),
+				Password: string(secret.Data["password"]),
+			},
+		})
+	}
+	return addresses, credentials, nil
+}
+
+func discoveryPorts(spec *metal3v1alpha1.BMCDiscoverySpec) (redfishPorts, ipmiPorts []int) {
+	redfishPorts = spec.RedfishPorts
+	if len(redfishPorts) == 0 {
+		redfishPorts = []int{metal3v1alpha1.DefaultRedfishPort}
+	}
+	ipmiPorts = spec.IPMIPorts
+	if len(ipmiPorts) == 0 {
+		ipmiPorts = []int{metal3v1alpha1.DefaultIPMIPort}
+	}
+	if spec.DisableIPMI {
+		ipmiPorts = nil
+	}
+	return
+}
+
+// bmcIP returns the IP address or host name in a BMC address.
+func bmcIP(address string) string {
+	if !strings.Contains(address, "://") {
+		// IPMI addresses may be given without a scheme
+		address = "ipmi://" + address
+	}
+	parsedURL, err := url.Parse(address)
+	if err != nil {
+		return ""
+	}
+	return parsedURL.Hostname()
+}
+
+func (r *BMCDiscoveryReconciler) probe() discovery.Prober {
+	if r.Probe != nil {
+		return r.Probe
+	}
+	return discovery.Probe
+}
+
+func (r *BMCDiscoveryReconciler) identify(endpoint discovery.Endpoint, creds bmc.Credentials, disableCertificateVerification bool) (*discovery.Identity, error) {
+	if r.Identify != nil {
+		return r.Identify(endpoint, creds, disableCertificateVerification)
+	}
+	return discovery.Identify(endpoint, creds, disableCertificateVerification)
+}
+
+// allowsUnverifiedTLS reports whether the credentials of the secret
+// may be sent to BMCs whose certificate is not verified.
+func allowsUnverifiedTLS(secret *corev1.Secret) bool {
+	return secret.Annotations[metal3v1alpha1.AllowUnverifiedTLSAnnotation] == "true"
+}
+
+// identifyBMC identifies the BMC answering at the endpoint with the
+// first credentials that work. The credentials are not sent to Redfish
+// services whose certificate is not verified, unless their secret
+// allows it.
+func (r *BMCDiscoveryReconciler) identifyBMC(key types.NamespacedName, endpoint discovery.Endpoint, credentials []discoveryCredentials, disableCertificateVerification bool) identifiedBMC {
+	log := r.Log.WithValues("bmcdiscovery", key, "endpoint", endpoint.String())
+	result := identifiedBMC{endpoint: endpoint}
+
+	unverified := disableCertificateVerification && endpoint.Protocol == discovery.Redfish
+	var identifyErr error
+	for _, c := range credentials {
+		if unverified && !allowsUnverifiedTLS(c.secret) {
+			continue
+		}
+		identity, err := r.identify(endpoint, c.creds, disableCertificateVerification)
+		if err == nil {
+			result.identity = identity
+			result.secret = c.secret
+			return result
+		}
+		identifyErr = err
+	}
+	if identifyErr == nil {
+		result.errorMessage = fmt.Sprintf("none of the credentials may be sent without verifying the certificate of the BMC, "+
+			"see the %s annotation", metal3v1alpha1.AllowUnverifiedTLSAnnotation)
+	} else {
+		result.errorMessage = fmt.Sprintf("none of the credentials work: %s", identifyErr)
+	}
+	log.Info("failed to identify BMC", "error", result.errorMessage)
+	return result
+}
+
+// createHost creates a host for a BMC identified by a scan, unless one
+// with the same name exists.
+func (r *BMCDiscoveryReconciler) createHost(bmcDiscovery *metal3v1alpha1.BMCDiscovery, identified identifiedBMC, names map[string]bool) (bmcFound metal3v1alpha1.FoundBMC, err error) {
+	endpoint := identified.endpoint
+	log := r.Log.WithValues("bmcdiscovery", bmcDiscovery.Name, "endpoint", endpoint.String())
+
+	identity := identified.identity
+	if identity == nil {
+		bmcFound.Address = endpoint.String()
+		bmcFound.ErrorMessage = identified.errorMessage
+		return bmcFound, nil
+	}
+
+	bmcFound = metal3v1alpha1.FoundBMC{
+		Address:      identity.Address,
+		SerialNumber: identity.SerialNumber,
+		Vendor:       identity.Vendor,
+		Model:        identity.Model,
+	}
+	name := discovery.HostName(identity.SerialNumber)
+	if name == "" {
+		bmcFound.ErrorMessage = "the BMC reports no serial number"
+		return bmcFound, nil
+	}
+	bmcFound.Host = name
+	if names[name] {
+		bmcFound.ErrorMessage = fmt.Sprintf("a host named %s already exists", name)
+		return bmcFound, nil
+	}
+
+	// The secret is loaded again, since it may have changed during
+	// the scan.
+	secret := &corev1.Secret{}
+	secretKey := types.NamespacedName{Namespace: bmcDiscovery.Namespace, Name: identified.secret.Name}
+	if err = r.Get(context.TODO(), secretKey, secret); err != nil {
+		if k8serrors.IsNotFound(err) {
+			bmcFound.ErrorMessage = fmt.Sprintf("the BMC secret %s no longer exists", secretKey)
+			return bmcFound, nil
+		}
+		return bmcFound, errors.Wrap(err, "failed to load BMC secret")
+	}
+	if err = r.shareSecret(secret); err != nil {
+		return bmcFound, err
+	}
+
+	host := &metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: bmcDiscovery.Namespace,
+			Labels:    map[string]string{},
+		},
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			BMC: metal3v1alpha1.BMCDetails{
+				Address:                        identity.Address,
+				CredentialsName:                secret.Name,
+				DisableCertificateVerification: bmcDiscovery.Spec.DisableCertificateVerification,
+			},
+			BootMACAddress: identity.BootMACAddress,
+		},
+	}
+	for key, value := range bmcDiscovery.Spec.HostLabels {
+		host.Labels[key] = value
+	}
+	host.Labels[metal3v1alpha1.DiscoveredByLabel] = bmcDiscovery.Name
+	if err = r.Create(context.TODO(), host); err != nil {
+		if k8serrors.IsAlreadyExists(err) {
+			bmcFound.ErrorMessage = fmt.Sprintf("a host named %s already exists", name)
+			return bmcFound, nil
+		}
+		return bmcFound, errors.Wrap(err, "failed to create host")
+	}
+	names[name] = true
+
+	log.Info("created host", "baremetalhost", name, "address", identity.Address)
+	event := host.NewEvent("Discovered",
+		fmt.Sprintf("Created by BMCDiscovery %s for the BMC found at %s", bmcDiscovery.Name, endpoint))
+	if err := r.Create(context.TODO(), &event); err != nil {
+		log.Info("failed to record event, ignoring", "error", err)
+	}
+	return bmcFound, nil
+}
+
+// shareSecret labels a secret as shared, so that the hosts created
+// with it do not take ownership of it.
+func (r *BMCDiscoveryReconciler) shareSecret(secret *corev1.Secret) error {
+	if secret.Labels[metal3v1alpha1.SharedCredentialsLabel] == "true" {
+		return nil
+	}
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string)
+	}
+	secret.Labels[metal3v1alpha1.SharedCredentialsLabel] = "true"
+	if err := r.Update(context.TODO(), secret); err != nil {
+		return errors.Wrap(err, "failed to label BMC secret as shared")
+	}
+	return nil
+}
+
+// SetupWithManager registers the reconciler to be run by the manager
+func (r *BMCDiscoveryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metal3v1alpha1.BMCDiscovery{}).
+		Complete(r)
+}
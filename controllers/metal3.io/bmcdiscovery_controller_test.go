@@ -0,0 +1,411 @@
+package controllers
+
+import (
+	goctx "context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/discovery"
+)
+
+// fakeNetwork stands in for the BMCs of a network, which answer on the
+// endpoints listed and accept the "good" password.
+type fakeNetwork struct {
+	lock sync.Mutex
+	// block, when set, holds the probes until it is closed
+	block      chan struct{}
+	answering  map[string]bool
+	identities map[string]*discovery.Identity
+	probed     []string
+	identified []string
+}
+
+func (n *fakeNetwork) probe(endpoint discovery.Endpoint, timeout time.Duration) bool {
+	n.lock.Lock()
+	block := n.block
+	n.lock.Unlock()
+	if block != nil {
+		<-block
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.probed = append(n.probed, endpoint.String())
+	return n.answering[endpoint.String()]
+}
+
+func (n *fakeNetwork) identify(endpoint discovery.Endpoint, creds bmc.Credentials, disableCertificateVerification bool) (*discovery.Identity, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.identified = append(n.identified, fmt.Sprintf("%s %s", endpoint, creds.Password))
+	if creds.Password != "good" {
+		return nil, fmt.Errorf("BMC rejected the credentials")
+	}
+	identity, ok := n.identities[endpoint.String()]
+	if !ok {
+		return nil, fmt.Errorf("BMC does not answer")
+	}
+	return identity, nil
+}
+
+type discoveryTest struct {
+	t       *testing.T
+	r       *BMCDiscoveryReconciler
+	network *fakeNetwork
+	key     types.NamespacedName
+}
+
+// newDiscoveryTest sets up a discovery of 192.168.111.0/29, where
+//
+//	.1 is the BMC of an existing host
+//	.2 answers Redfish and IPMI
+//	.3 answers IPMI but reports no serial number
+//	.4 answers Redfish, but not with the credentials tried
+func newDiscoveryTest(t *testing.T) *discoveryTest {
+	c := fakeclient.NewFakeClient()
+	for _, secret := range []struct{ name, password string }{{"bad-creds", "bad"}, {"good-creds", "good"}} {
+		s := newSecret(secret.name, nil)
+		s.Data = map[string][]byte{"username": []byte("admin"), "password": []byte(secret.password)}
+		if err := c.Create(goctx.TODO(), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	existing := newHost("existing", &metal3v1alpha1.BareMetalHostSpec{
+		BMC: metal3v1alpha1.BMCDetails{Address: "ipmi://192.168.111.1", CredentialsName: "good-creds"},
+	})
+	if err := c.Create(goctx.TODO(), existing); err != nil {
+		t.Fatal(err)
+	}
+
+	bmcDiscovery := &metal3v1alpha1.BMCDiscovery{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack-1", Namespace: namespace},
+		Spec: metal3v1alpha1.BMCDiscoverySpec{
+			Networks:         []string{"192.168.111.0/29"},
+			CredentialsNames: []string{"bad-creds", "good-creds"},
+			Interval:         &metav1.Duration{Duration: time.Hour},
+			HostLabels:       map[string]string{"rack": "1"},
+		},
+	}
+	if err := c.Create(goctx.TODO(), bmcDiscovery); err != nil {
+		t.Fatal(err)
+	}
+
+	network := &fakeNetwork{
+		answering: map[string]bool{
+			"ipmi://192.168.111.1:623":    true,
+			"redfish://192.168.111.2:443": true,
+			"ipmi://192.168.111.2:623":    true,
+			"ipmi://192.168.111.3:623":    true,
+			"redfish://192.168.111.4:443": true,
+		},
+		identities: map[string]*discovery.Identity{
+			"redfish://192.168.111.2:443": {
+				Address:        "idrac-virtualmedia://192.168.111.2/redfish/v1/Systems/System.Embedded.1",
+				SerialNumber:   "CZ1234567",
+				Vendor:         "Dell",
+				Model:          "iDRAC 9",
+				BootMACAddress: "52:54:00:aa:bb:02",
+			},
+			"ipmi://192.168.111.3:623": {
+				Address: "ipmi://192.168.111.3",
+			},
+		},
+	}
+	return &discoveryTest{
+		t:       t,
+		network: network,
+		key:     types.NamespacedName{Namespace: namespace, Name: bmcDiscovery.Name},
+		r: &BMCDiscoveryReconciler{
+			Client:           c,
+			Log:              ctrl.Log.WithName("controllers").WithName("BMCDiscovery"),
+			Scheme:           scheme.Scheme,
+			Probe:            network.probe,
+			Identify:         network.identify,
+			ProgressInterval: time.Millisecond,
+		},
+	}
+}
+
+func (test *discoveryTest) reconcile() (ctrl.Result, *metal3v1alpha1.BMCDiscovery) {
+	result, err := test.r.Reconcile(ctrl.Request{NamespacedName: test.key})
+	if err != nil {
+		test.t.Fatal(err)
+	}
+	bmcDiscovery := &metal3v1alpha1.BMCDiscovery{}
+	if err := test.r.Get(goctx.TODO(), test.key, bmcDiscovery); err != nil {
+		test.t.Fatal(err)
+	}
+	return result, bmcDiscovery
+}
+
+// scan reconciles the discovery until the scan it starts is over.
+func (test *discoveryTest) scan() (ctrl.Result, *metal3v1alpha1.BMCDiscovery) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, bmcDiscovery := test.reconcile()
+		if bmcDiscovery.Status.Scan == nil {
+			return result, bmcDiscovery
+		}
+		if time.Now().After(deadline) {
+			test.t.Fatal("the scan did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (test *discoveryTest) update(change func(*metal3v1alpha1.BMCDiscovery)) {
+	bmcDiscovery := &metal3v1alpha1.BMCDiscovery{}
+	if err := test.r.Get(goctx.TODO(), test.key, bmcDiscovery); err != nil {
+		test.t.Fatal(err)
+	}
+	change(bmcDiscovery)
+	if err := test.r.Update(goctx.TODO(), bmcDiscovery); err != nil {
+		test.t.Fatal(err)
+	}
+}
+
+func TestDiscoverBMCs(t *testing.T) {
+	test := newDiscoveryTest(t)
+
+	result, bmcDiscovery := test.scan()
+
+	assert.Equal(t, time.Hour, result.RequeueAfter)
+	assert.Empty(t, bmcDiscovery.Status.ErrorMessage)
+	assert.NotNil(t, bmcDiscovery.Status.LastScan)
+	assert.NotContains(t, test.network.probed, "ipmi://192.168.111.1:623")
+	assert.Len(t, test.network.probed, 10)
+	assert.Equal(t, []string{
+		"redfish://192.168.111.2:443 bad",
+		"redfish://192.168.111.2:443 good",
+		"ipmi://192.168.111.3:623 bad",
+		"ipmi://192.168.111.3:623 good",
+		"redfish://192.168.111.4:443 bad",
+		"redfish://192.168.111.4:443 good",
+	}, test.network.identified)
+	assert.Equal(t, []metal3v1alpha1.FoundBMC{
+		{
+			Address: "ipmi://192.168.111.1",
+			Host:    "existing",
+		},
+		{
+			Address:      "idrac-virtualmedia://192.168.111.2/redfish/v1/Systems/System.Embedded.1",
+			SerialNumber: "CZ1234567",
+			Vendor:       "Dell",
+			Model:        "iDRAC 9",
+			Host:         "cz1234567",
+		},
+		{
+			Address:      "ipmi://192.168.111.3",
+			ErrorMessage: "the BMC reports no serial number",
+		},
+		{
+			Address:      "redfish://192.168.111.4:443",
+			ErrorMessage: "none of the credentials work: BMC does not answer",
+		},
+	}, bmcDiscovery.Status.BMCs)
+
+	host := &metal3v1alpha1.BareMetalHost{}
+	if err := test.r.Get(goctx.TODO(), types.NamespacedName{Namespace: namespace, Name: "cz1234567"}, host); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "idrac-virtualmedia://192.168.111.2/redfish/v1/Systems/System.Embedded.1", host.Spec.BMC.Address)
+	assert.Equal(t, "good-creds", host.Spec.BMC.CredentialsName)
+	assert.Equal(t, "52:54:00:aa:bb:02", host.Spec.BootMACAddress)
+	assert.Equal(t, map[string]string{"rack": "1", metal3v1alpha1.DiscoveredByLabel: "rack-1"}, host.Labels)
+
+	secret := newSecret("good-creds", nil)
+	if err := test.r.Get(goctx.TODO(), types.NamespacedName{Namespace: namespace, Name: "good-creds"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "true", secret.Labels[metal3v1alpha1.SharedCredentialsLabel])
+
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	if err := test.r.List(goctx.TODO(), hosts, client.InNamespace(namespace)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, hosts.Items, 2)
+}
+
+func TestDiscoverBMCsInterval(t *testing.T) {
+	test := newDiscoveryTest(t)
+	test.scan()
+
+	// Nothing is scanned before the interval is over.
+	test.network.probed = nil
+	result, _ := test.reconcile()
+	assert.Empty(t, test.network.probed)
+	assert.True(t, result.RequeueAfter > 0 && result.RequeueAfter <= time.Hour)
+
+	// The hosts created are not probed again, nor created twice.
+	_, bmcDiscovery := test.reconcile()
+	bmcDiscovery.Status.LastScan = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+	if err := test.r.Status().Update(goctx.TODO(), bmcDiscovery); err != nil {
+		t.Fatal(err)
+	}
+	test.network.identified = nil
+	_, bmcDiscovery = test.scan()
+	assert.Len(t, test.network.probed, 8)
+	assert.NotContains(t, test.network.identified, "redfish://192.168.111.2:443 good")
+	if assert.Len(t, bmcDiscovery.Status.BMCs, 4) {
+		assert.Equal(t, "cz1234567", bmcDiscovery.Status.BMCs[1].Host)
+		assert.Empty(t, bmcDiscovery.Status.BMCs[1].ErrorMessage)
+	}
+}
+
+func TestDiscoverBMCsInvalid(t *testing.T) {
+	testCases := []struct {
+		Scenario string
+		Update   func(*metal3v1alpha1.BMCDiscoverySpec)
+		Error    string
+	}{
+		{
+			Scenario: "invalid network",
+			Update:   func(spec *metal3v1alpha1.BMCDiscoverySpec) { spec.Networks = []string{"192.168.111.0"} },
+			Error:    `invalid network "192.168.111.0": invalid CIDR address: 192.168.111.0`,
+		},
+		{
+			Scenario: "invalid port",
+			Update:   func(spec *metal3v1alpha1.BMCDiscoverySpec) { spec.RedfishPorts = []int{0} },
+			Error:    "invalid port 0",
+		},
+		{
+			Scenario: "missing secret",
+			Update: func(spec *metal3v1alpha1.BMCDiscoverySpec) {
+				spec.CredentialsNames = append(spec.CredentialsNames, "missing")
+			},
+			Error: "the BMC secret test-namespace/missing does not exist",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			test := newDiscoveryTest(t)
+			bmcDiscovery := &metal3v1alpha1.BMCDiscovery{}
+			if err := test.r.Get(goctx.TODO(), test.key, bmcDiscovery); err != nil {
+				t.Fatal(err)
+			}
+			tc.Update(&bmcDiscovery.Spec)
+			if err := test.r.Update(goctx.TODO(), bmcDiscovery); err != nil {
+				t.Fatal(err)
+			}
+
+			result, bmcDiscovery := test.reconcile()
+			assert.Equal(t, tc.Error, bmcDiscovery.Status.ErrorMessage)
+			assert.Equal(t, discoveryErrorRetryDelay, result.RequeueAfter)
+			assert.Nil(t, bmcDiscovery.Status.LastScan)
+			assert.Empty(t, test.network.probed)
+		})
+	}
+}
+
+func TestDiscoverBMCsInBackground(t *testing.T) {
+	test := newDiscoveryTest(t)
+	test.network.block = make(chan struct{})
+
+	result, bmcDiscovery := test.reconcile()
+	assert.Equal(t, time.Millisecond, result.RequeueAfter)
+	if assert.NotNil(t, bmcDiscovery.Status.Scan) {
+		assert.Equal(t, 10, bmcDiscovery.Status.Scan.Endpoints)
+		assert.Equal(t, 0, bmcDiscovery.Status.Scan.Probed)
+	}
+	assert.Nil(t, bmcDiscovery.Status.LastScan)
+
+	close(test.network.block)
+	_, bmcDiscovery = test.scan()
+	assert.NotNil(t, bmcDiscovery.Status.LastScan)
+	assert.Len(t, bmcDiscovery.Status.BMCs, 4)
+}
+
+func TestDiscoverBMCsSpecChange(t *testing.T) {
+	test := newDiscoveryTest(t)
+	test.network.block = make(chan struct{})
+	_, bmcDiscovery := test.reconcile()
+	assert.NotNil(t, bmcDiscovery.Status.Scan)
+
+	// The scan under way is cancelled, and a scan of the new
+	// networks started.
+	test.update(func(bmcDiscovery *metal3v1alpha1.BMCDiscovery) {
+		bmcDiscovery.Spec.Networks = []string{"192.168.111.0/30"}
+		bmcDiscovery.Generation = 2
+	})
+	_, bmcDiscovery = test.reconcile()
+	if assert.NotNil(t, bmcDiscovery.Status.Scan) {
+		assert.Equal(t, int64(2), bmcDiscovery.Status.Scan.Generation)
+		assert.Equal(t, 2, bmcDiscovery.Status.Scan.Endpoints)
+	}
+
+	close(test.network.block)
+	_, bmcDiscovery = test.scan()
+	assert.Equal(t, int64(2), bmcDiscovery.Status.ObservedGeneration)
+	assert.Len(t, bmcDiscovery.Status.BMCs, 2)
+	assert.NotContains(t, test.network.identified, "ipmi://192.168.111.3:623 good")
+}
+
+func TestDiscoverBMCsDeleted(t *testing.T) {
+	test := newDiscoveryTest(t)
+	test.network.block = make(chan struct{})
+	defer close(test.network.block)
+	_, bmcDiscovery := test.reconcile()
+	assert.NotNil(t, test.r.getScan(test.key))
+
+	if err := test.r.Delete(goctx.TODO(), bmcDiscovery); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := test.r.Reconcile(ctrl.Request{NamespacedName: test.key}); err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, test.r.getScan(test.key))
+}
+
+func TestDiscoverBMCsUnverifiedTLS(t *testing.T) {
+	test := newDiscoveryTest(t)
+	test.update(func(bmcDiscovery *metal3v1alpha1.BMCDiscovery) {
+		bmcDiscovery.Spec.DisableCertificateVerification = true
+	})
+
+	_, bmcDiscovery := test.scan()
+
+	// The credentials are only sent to IPMI BMCs.
+	for _, identified := range test.network.identified {
+		assert.NotContains(t, identified, "redfish://")
+	}
+	if assert.Len(t, bmcDiscovery.Status.BMCs, 4) {
+		assert.Equal(t, "none of the credentials may be sent without verifying the certificate of the BMC, "+
+			"see the bmcdiscovery.metal3.io/allow-unverified-tls annotation", bmcDiscovery.Status.BMCs[3].ErrorMessage)
+	}
+
+	// Once the secret allows it, its credentials are sent to the
+	// Redfish services too.
+	secret := newSecret("good-creds", nil)
+	if err := test.r.Get(goctx.TODO(), types.NamespacedName{Namespace: namespace, Name: "good-creds"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	secret.Annotations = map[string]string{metal3v1alpha1.AllowUnverifiedTLSAnnotation: "true"}
+	if err := test.r.Update(goctx.TODO(), secret); err != nil {
+		t.Fatal(err)
+	}
+	test.update(func(bmcDiscovery *metal3v1alpha1.BMCDiscovery) {
+		bmcDiscovery.Generation = 2
+	})
+	test.network.identified = nil
+
+	_, bmcDiscovery = test.scan()
+
+	assert.Contains(t, test.network.identified, "redfish://192.168.111.2:443 good")
+	assert.NotContains(t, test.network.identified, "redfish://192.168.111.2:443 bad")
+	if assert.Len(t, bmcDiscovery.Status.BMCs, 4) {
+		assert.Equal(t, "cz1234567", bmcDiscovery.Status.BMCs[1].Host)
+	}
+}
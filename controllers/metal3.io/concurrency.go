@@ -0,0 +1,151 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+const defaultConcurrency = 3
+
+// DefaultConcurrency returns the number of hosts to reconcile at the
+// same time when no BareMetalOperatorConfig says otherwise.
+func DefaultConcurrency() (int, error) {
+	concurrency := defaultConcurrency
+	if mcrEnv, ok := os.LookupEnv("BMO_CONCURRENCY"); ok {
+		mcr, err := strconv.Atoi(mcrEnv)
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("BMO_CONCURRENCY value: %s is invalid", mcrEnv))
+		}
+		if mcr > metal3v1alpha1.MaxConcurrency {
+			return 0, fmt.Errorf("BMO_CONCURRENCY value %d must be between 1 and %d",
+				mcr, metal3v1alpha1.MaxConcurrency)
+		}
+		if mcr > 0 {
+			ctrl.Log.Info(fmt.Sprintf("BMO_CONCURRENCY of %d is set via an environment variable", mcr))
+			concurrency = mcr
+		} else {
+			ctrl.Log.Info(fmt.Sprintf("Invalid BMO_CONCURRENCY value. Operator Concurrency will be set to a default value of %d", concurrency))
+		}
+	} else {
+		ctrl.Log.Info(fmt.Sprintf("Operator Concurrency will be set to a default value of %d", concurrency))
+	}
+	return concurrency, nil
+}
+
+// ConcurrencyLimit bounds the number of reconciles running at the
+// same time. Unlike the number of workers of a controller, the limit
+// can be changed while the manager is running.
+type ConcurrencyLimit struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+}
+
+// NewConcurrencyLimit returns a ConcurrencyLimit allowing limit
+// reconciles at a time.
+func NewConcurrencyLimit(limit int) *ConcurrencyLimit {
+	l := &ConcurrencyLimit{limit: limit}
+	l.cond = sync.NewCond(&l.lock)
+	return l
+}
+
+// Acquire waits until another reconcile is allowed to start.
+func (l *ConcurrencyLimit) Acquire() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+// Release marks a reconcile started with Acquire as finished.
+func (l *ConcurrencyLimit) Release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.active--
+	l.cond.Signal()
+}
+
+// Limit returns the number of reconciles allowed at a time.
+func (l *ConcurrencyLimit) Limit() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.limit
+}
+
+// SetLimit changes the number of reconciles allowed at a time.
+// Reconciles already running are not interrupted when the limit is
+// lowered.
+func (l *ConcurrencyLimit) SetLimit(limit int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.limit = limit
+	l.cond.Broadcast()
+}
+
+// HostLocks keeps the controllers changing the same host from doing so
+// at the same time, such as a reconcile of the host and a change of
+// its BMC password.
+type HostLocks struct {
+	lock  sync.Mutex
+	hosts map[types.NamespacedName]*hostLock
+}
+
+type hostLock struct {
+	sync.Mutex
+	users int
+}
+
+// NewHostLocks returns a HostLocks with no host locked.
+func NewHostLocks() *HostLocks {
+	return &HostLocks{hosts: make(map[types.NamespacedName]*hostLock)}
+}
+
+// Lock waits until no one else holds the lock of a host, takes it, and
+// returns the function releasing it.
+func (l *HostLocks) Lock(host types.NamespacedName) func() {
+	l.lock.Lock()
+	hl, ok := l.hosts[host]
+	if !ok {
+		hl = &hostLock{}
+		l.hosts[host] = hl
+	}
+	hl.users++
+	l.lock.Unlock()
+
+	hl.Lock()
+	return func() {
+		hl.Unlock()
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		hl.users--
+		if hl.users == 0 {
+			delete(l.hosts, host)
+		}
+	}
+}
@@ -172,3 +172,61 @@ func TestDemoValidationError(t *testing.T) {
 		},
 	)
 }
+
+// TestDemoScenarioProvisioned tests that a host with a scenario
+// annotation is provisioned with the hardware details of its scenario
+func TestDemoScenarioProvisioned(t *testing.T) {
+	host := newDefaultNamedHost("demo-scenario-provisioned", t)
+	host.Annotations = map[string]string{
+		demo.ScenarioAnnotation: `
+power:
+  initiallyOn: true
+hardwareDetails:
+  hostname: staged-host
+  ramMebibytes: 4096
+`,
+	}
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:      "a-url",
+		Checksum: "a-checksum",
+	}
+	host.Spec.Online = true
+	r := newDemoReconciler(host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			t.Logf("Status: %q State: %q ErrorMessage: %q",
+				host.OperationalStatus(),
+				host.Status.Provisioning.State,
+				host.Status.ErrorMessage,
+			)
+			return host.Status.Provisioning.State == metal3v1alpha1.StateProvisioned &&
+				host.Status.HardwareDetails != nil &&
+				host.Status.HardwareDetails.Hostname == "staged-host"
+		},
+	)
+}
+
+// TestDemoScenarioInspectionError tests that a host with a scenario
+// annotation reports the inspection error of its scenario
+func TestDemoScenarioInspectionError(t *testing.T) {
+	host := newDefaultNamedHost("demo-scenario-inspection-error", t)
+	host.Annotations = map[string]string{
+		demo.ScenarioAnnotation: `
+inspection:
+  error: no agent callback
+`,
+	}
+	r := newDemoReconciler(host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			t.Logf("Status: %q State: %q ErrorMessage: %q",
+				host.OperationalStatus(),
+				host.Status.Provisioning.State,
+				host.Status.ErrorMessage,
+			)
+			return host.Status.ErrorMessage == "no agent callback"
+		},
+	)
+}
@@ -5,12 +5,15 @@ go 1.15
 require (
 	github.com/go-logr/logr v0.2.1
 	github.com/go-logr/zapr v0.2.0 // indirect
+	github.com/golang/protobuf v1.4.3
 	github.com/golangci/golangci-lint v1.32.0
 	github.com/gophercloud/gophercloud v0.12.0
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus/client_golang v1.7.1
 	github.com/stretchr/testify v1.6.1
 	go.etcd.io/etcd v0.0.0-20191023171146-3cf2f69b5738
+	google.golang.org/grpc v1.40.1
+	google.golang.org/protobuf v1.25.0
 	k8s.io/api v0.20.0
 	k8s.io/apimachinery v0.20.0
 	k8s.io/client-go v0.20.0
@@ -23,6 +23,7 @@ import (
 	"runtime"
 
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -32,11 +33,15 @@ import (
 	metal3iov1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	metal3iocontroller "github.com/metal3-io/baremetal-operator/controllers/metal3.io"
 	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/bmctrust"
+	"github.com/metal3-io/baremetal-operator/pkg/credentials"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/demo"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/empty"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/plugin"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish"
 	"github.com/metal3-io/baremetal-operator/pkg/version"
 	// +kubebuilder:scaffold:imports
 )
@@ -81,6 +86,14 @@ func main() {
 	var devLogging bool
 	var runInTestMode bool
 	var runInDemoMode bool
+	var demoScenariosPath string
+	var runInRedfishMode bool
+	var configName string
+	var configNamespace string
+	var provisionerPlugins string
+	var pluginTLSOptions plugin.TLSOptions
+	var credentialsOptions credentials.Options
+	var bmcTrustOptions bmctrust.Options
 
 	// From CAPI point of view, BMO should be able to watch all namespaces
 	// in case of a deployment that is not multi-tenant. If the deployment
@@ -97,14 +110,53 @@ func main() {
 	flag.BoolVar(&runInTestMode, "test-mode", false, "disable ironic communication")
 	flag.BoolVar(&runInDemoMode, "demo-mode", false,
 		"use the demo provisioner to set host states")
+	flag.StringVar(&demoScenariosPath, "demo-scenarios", os.Getenv("DEMO_SCENARIOS"),
+		"YAML file describing how hosts behave in demo mode, such as a mounted ConfigMap")
+	flag.BoolVar(&runInRedfishMode, "redfish-mode", false,
+		"manage hosts through their BMCs with Redfish instead of using ironic")
 	flag.StringVar(&healthAddr, "health-addr", ":9440",
 		"The address the health endpoint binds to.")
+	flag.StringVar(&configName, "config-name", "baremetal-operator",
+		"Name of the BareMetalOperatorConfig holding the operator settings.")
+	flag.StringVar(&configNamespace, "config-namespace", os.Getenv("POD_NAMESPACE"),
+		"Namespace of the BareMetalOperatorConfig holding the operator settings. "+
+			"Defaults to the watched namespace.")
+	flag.StringVar(&provisionerPlugins, "provisioner-plugins", os.Getenv("PROVISIONER_PLUGINS"),
+		"Comma-separated list of name=address pairs of provisioner plugins that hosts can select with the "+
+			plugin.Label+" label.")
+	flag.StringVar(&pluginTLSOptions.CAFile, "provisioner-plugins-ca-file", os.Getenv("PROVISIONER_PLUGINS_CA_FILE"),
+		"File holding the CA certificates of provisioner plugins not reached over a unix socket, which are only used over TLS.")
+	flag.StringVar(&pluginTLSOptions.CertFile, "provisioner-plugins-cert-file", os.Getenv("PROVISIONER_PLUGINS_CERT_FILE"),
+		"File holding the client certificate presented to provisioner plugins over TLS.")
+	flag.StringVar(&pluginTLSOptions.KeyFile, "provisioner-plugins-key-file", os.Getenv("PROVISIONER_PLUGINS_KEY_FILE"),
+		"File holding the key of the client certificate presented to provisioner plugins over TLS.")
+	flag.StringVar(&credentialsOptions.VaultAddress, "vault-addr", os.Getenv("VAULT_ADDR"),
+		"Address of the Vault server holding BMC credentials referred to as vault:<path>.")
+	flag.StringVar(&credentialsOptions.VaultTokenFile, "vault-token-file", os.Getenv("VAULT_TOKEN_FILE"),
+		"File holding the Vault token, read for each request. Defaults to the VAULT_TOKEN environment variable.")
+	flag.StringVar(&credentialsOptions.VaultMount, "vault-mount", credentials.DefaultVaultMount,
+		"Mount path of the KV version 2 secrets engine holding BMC credentials.")
+	flag.StringVar(&credentialsOptions.Dir, "bmc-credentials-dir", os.Getenv("BMC_CREDENTIALS_DIR"),
+		"Directory holding BMC credentials referred to as file:<name>, such as a CSI secrets store volume.")
+	flag.StringVar(&credentialsOptions.TemplateConfigMap, "bmc-credentials-template", os.Getenv("BMC_CREDENTIALS_TEMPLATE"),
+		"Name of the ConfigMap holding, in each namespace, the template of the credentialsName of hosts without one.")
+	flag.StringVar(&bmcTrustOptions.Dir, "bmc-ca-dir", os.Getenv("BMC_CA_DIR"),
+		"Directory shared with ironic, at the same path, that the CA bundles and pinned certificates of BMCs are written to.")
+	flag.StringVar(&bmcTrustOptions.DefaultBundle, "bmc-ca-bundle", os.Getenv("BMC_CA_BUNDLE"),
+		"Name of the ConfigMap holding, in each namespace, the CA bundle of hosts that set neither "+
+			"caBundleName nor certificateFingerprint.")
 	flag.Parse()
+	credentialsOptions.VaultToken = os.Getenv("VAULT_TOKEN")
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(devLogging)))
 
 	printVersion()
 
+	if err := bmcTrustOptions.Validate(); err != nil {
+		setupLog.Error(err, "cannot start")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                  scheme,
 		MetricsBindAddress:      metricsAddr,
@@ -120,7 +172,40 @@ func main() {
 		os.Exit(1)
 	}
 
-	provisionerFactory := func(host metal3iov1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publish provisioner.EventPublisher) (provisioner.Provisioner, error) {
+	useIronic := !(runInTestMode || runInDemoMode || runInRedfishMode)
+
+	var applyIronicSettings metal3iocontroller.IronicSettingsApplier
+	if useIronic {
+		ironicConfig, err := ironic.ConfigFromEnv()
+		if err == nil {
+			err = ironic.Configure(ironicConfig)
+		}
+		if err != nil {
+			setupLog.Error(err, "cannot start")
+			os.Exit(1)
+		}
+		ironic.LogSettings()
+		applyIronicSettings = func(settings *metal3iov1alpha1.IronicSettings, backends []metal3iov1alpha1.IronicBackend) (*metal3iov1alpha1.IronicSettings, []metal3iov1alpha1.IronicBackend, error) {
+			effective, effectiveBackends, err := ironic.ApplySettings(ironicConfig, settings, backends)
+			if err != nil {
+				return nil, nil, err
+			}
+			ironic.LogSettings()
+			return effective, effectiveBackends, nil
+		}
+	}
+
+	concurrency, err := metal3iocontroller.DefaultConcurrency()
+	if err != nil {
+		setupLog.Error(err, "cannot start")
+		os.Exit(1)
+	}
+	concurrencyLimit := metal3iocontroller.NewConcurrencyLimit(concurrency)
+	hostLocks := metal3iocontroller.NewHostLocks()
+
+	demoScenarios := &demo.Scenarios{Path: demoScenariosPath}
+
+	var provisionerFactory provisioner.Factory = func(host metal3iov1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publish provisioner.EventPublisher) (provisioner.Provisioner, error) {
 		isUnmanaged := host.Spec.ExternallyProvisioned && !host.HasBMCDetails()
 
 		hostCopy := host.DeepCopy()
@@ -131,25 +216,79 @@ func main() {
 			return fix.New(*hostCopy, bmcCreds, publish)
 		} else if runInDemoMode {
 			ctrl.Log.Info("using demo provisioner")
-			return demo.New(*hostCopy, bmcCreds, publish)
+			return demoScenarios.New(*hostCopy, bmcCreds, publish)
 		} else if isUnmanaged {
 			ctrl.Log.Info("using empty provisioner")
 			return empty.New(*hostCopy, bmcCreds, publish)
+		} else if runInRedfishMode {
+			ctrl.Log.Info("using redfish provisioner")
+			return redfish.New(*hostCopy, bmcCreds, publish)
 		}
-		ironic.LogStartup()
 		return ironic.New(*hostCopy, bmcCreds, publish)
 	}
 
+	plugins, err := plugin.ParsePlugins(provisionerPlugins)
+	if err == nil && len(plugins) != 0 {
+		provisionerFactory, err = plugin.NewFactory(plugins, pluginTLSOptions, provisionerFactory)
+	}
+	if err != nil {
+		setupLog.Error(err, "cannot start")
+		os.Exit(1)
+	}
+
 	if err = (&metal3iocontroller.BareMetalHostReconciler{
-		Client:             mgr.GetClient(),
-		Log:                ctrl.Log.WithName("controllers").WithName("BareMetalHost"),
-		Scheme:             mgr.GetScheme(),
-		ProvisionerFactory: provisionerFactory,
+		Client:              mgr.GetClient(),
+		Log:                 ctrl.Log.WithName("controllers").WithName("BareMetalHost"),
+		Scheme:              mgr.GetScheme(),
+		ProvisionerFactory:  provisionerFactory,
+		ConcurrencyLimit:    concurrencyLimit,
+		HostLocks:           hostLocks,
+		CredentialsResolver: credentials.New(mgr.GetClient(), credentialsOptions),
+		BMCTrust:            bmctrust.New(mgr.GetClient(), bmcTrustOptions),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BareMetalHost")
 		os.Exit(1)
 	}
 
+	if err = (&metal3iocontroller.BMCCredentialRotationReconciler{
+		Client:    mgr.GetClient(),
+		Log:       ctrl.Log.WithName("controllers").WithName("BMCCredentialRotation"),
+		Scheme:    mgr.GetScheme(),
+		HostLocks: hostLocks,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BMCCredentialRotation")
+		os.Exit(1)
+	}
+
+	if err = (&metal3iocontroller.BMCDiscoveryReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("BMCDiscovery"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BMCDiscovery")
+		os.Exit(1)
+	}
+
+	if configNamespace == "" {
+		configNamespace = watchNamespace
+	}
+	if configNamespace == "" {
+		setupLog.Info("no namespace given for the operator configuration, BareMetalOperatorConfig resources are ignored")
+	} else if err = (&metal3iocontroller.BareMetalOperatorConfigReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("BareMetalOperatorConfig"),
+		ConfigName: types.NamespacedName{
+			Namespace: configNamespace,
+			Name:      configName,
+		},
+		ConcurrencyLimit:    concurrencyLimit,
+		DefaultConcurrency:  concurrency,
+		ApplyIronicSettings: applyIronicSettings,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BareMetalOperatorConfig")
+		os.Exit(1)
+	}
+
 	setupChecks(mgr)
 
 	// +kubebuilder:scaffold:builder
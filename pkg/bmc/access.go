@@ -10,8 +10,10 @@ import (
 )
 
 // AccessDetailsFactory describes a callable that returns a new
-// AccessDetails based on the input parameters.
-type AccessDetailsFactory func(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error)
+// AccessDetails based on the input parameters. The caFile is the path,
+// as seen by the provisioning backend, of the CA bundle the certificate
+// of the BMC is verified against, or empty for the default bundle.
+type AccessDetailsFactory func(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error)
 
 var factories = map[string]AccessDetailsFactory{}
 
@@ -108,7 +110,17 @@ func getParsedURL(address string) (parsedURL *url.URL, err error) {
 // NewAccessDetails creates an AccessDetails structure from the URL
 // for a BMC.
 func NewAccessDetails(address string, disableCertificateVerification bool) (AccessDetails, error) {
+	return newAccessDetails(address, disableCertificateVerification, "")
+}
+
+// NewAccessDetailsWithCAFile creates an AccessDetails structure from
+// the URL for a BMC whose certificate is verified against the CA
+// bundle in caFile.
+func NewAccessDetailsWithCAFile(address string, caFile string) (AccessDetails, error) {
+	return newAccessDetails(address, false, caFile)
+}
 
+func newAccessDetails(address string, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
 	if address == "" {
 		return nil, errors.New("missing BMC address")
 	}
@@ -123,5 +135,5 @@ func NewAccessDetails(address string, disableCertificateVerification bool) (Acce
 		return nil, &UnknownBMCTypeError{address, parsedURL.Scheme}
 	}
 
-	return factory(parsedURL, disableCertificateVerification)
+	return factory(parsedURL, disableCertificateVerification, caFile)
 }
@@ -561,6 +561,66 @@ func TestStaticDriverInfo(t *testing.T) {
 			boot:     "redfish-virtual-media",
 		},
 
+		{
+			Scenario:   "ilo5 ilo virtual media",
+			input:      "ilo5-ilo-virtualmedia://192.168.122.1",
+			needsMac:   true,
+			driver:     "ilo5",
+			boot:       "ilo-virtual-media",
+			management: "",
+			power:      "",
+			raid:       "ilo5",
+			vendor:     "",
+		},
+
+		{
+			Scenario:   "ilo5 ilo virtual media HTTPS",
+			input:      "ilo5-ilo-virtualmedia+https://192.168.122.1",
+			needsMac:   true,
+			driver:     "ilo5",
+			boot:       "ilo-virtual-media",
+			management: "",
+			power:      "",
+			raid:       "ilo5",
+			vendor:     "",
+		},
+
+		{
+			Scenario:   "irmc virtual media",
+			input:      "irmc-virtualmedia://192.168.122.1",
+			needsMac:   true,
+			driver:     "irmc",
+			boot:       "irmc-virtual-media",
+			management: "",
+			power:      "",
+			raid:       "irmc",
+			vendor:     "",
+		},
+
+		{
+			Scenario:   "ibmc virtual media",
+			input:      "ibmc-virtualmedia://192.168.122.1",
+			needsMac:   true,
+			driver:     "redfish",
+			boot:       "redfish-virtual-media",
+			management: "",
+			power:      "",
+			raid:       "",
+			vendor:     "",
+		},
+
+		{
+			Scenario:   "ibmc virtual media HTTP",
+			input:      "ibmc-virtualmedia+http://192.168.122.1",
+			needsMac:   true,
+			driver:     "redfish",
+			boot:       "redfish-virtual-media",
+			management: "",
+			power:      "",
+			raid:       "",
+			vendor:     "",
+		},
+
 		{
 			Scenario:   "idrac virtual media",
 			input:      "idrac-virtualmedia://192.168.122.1",
@@ -885,6 +945,53 @@ func TestDriverInfo(t *testing.T) {
 			},
 		},
 
+		{
+			Scenario: "ilo5 ilo virtual media",
+			input:    "ilo5-ilo-virtualmedia://192.168.122.1:8443",
+			expects: map[string]interface{}{
+				"ilo_address":   "192.168.122.1",
+				"client_port":   "8443",
+				"ilo_password":  "",
+				"ilo_username":  "",
+				"ilo_verify_ca": false,
+			},
+		},
+
+		{
+			Scenario: "irmc virtual media",
+			input:    "irmc-virtualmedia://192.168.122.1",
+			expects: map[string]interface{}{
+				"irmc_address":   "192.168.122.1",
+				"irmc_password":  "",
+				"irmc_username":  "",
+				"irmc_verify_ca": false,
+			},
+		},
+
+		{
+			Scenario: "ibmc virtual media",
+			input:    "ibmc-virtualmedia://192.168.122.1",
+			expects: map[string]interface{}{
+				"redfish_address":   "https://192.168.122.1",
+				"redfish_system_id": "/redfish/v1/Systems/1",
+				"redfish_password":  "",
+				"redfish_username":  "",
+				"redfish_verify_ca": false,
+			},
+		},
+
+		{
+			Scenario: "ibmc virtual media system",
+			input:    "ibmc-virtualmedia+http://192.168.122.1/redfish/v1/Systems/2",
+			expects: map[string]interface{}{
+				"redfish_address":   "http://192.168.122.1",
+				"redfish_system_id": "/redfish/v1/Systems/2",
+				"redfish_password":  "",
+				"redfish_username":  "",
+				"redfish_verify_ca": false,
+			},
+		},
+
 		{
 			Scenario: "idrac virtual media",
 			input:    "idrac-virtualmedia://192.168.122.1/foo/bar",
@@ -1044,6 +1151,44 @@ func TestDriverInfo(t *testing.T) {
 			},
 		},
 
+		{
+			Scenario: "supermicro",
+			input:    "supermicro://192.168.122.1",
+			expects: map[string]interface{}{
+				"redfish_address":   "https://192.168.122.1",
+				"redfish_system_id": "/redfish/v1/Systems/1",
+				"redfish_auth_type": "basic",
+				"redfish_password":  "",
+				"redfish_username":  "",
+				"redfish_verify_ca": false,
+			},
+		},
+
+		{
+			Scenario: "supermicro virtual media session auth",
+			input:    "supermicro-virtualmedia+http://192.168.122.1/redfish/v1/Systems/2?auth_type=session",
+			expects: map[string]interface{}{
+				"redfish_address":   "http://192.168.122.1",
+				"redfish_system_id": "/redfish/v1/Systems/2",
+				"redfish_auth_type": "session",
+				"redfish_password":  "",
+				"redfish_username":  "",
+				"redfish_verify_ca": false,
+			},
+		},
+
+		{
+			Scenario: "lenovo-xcc virtual media",
+			input:    "lenovo-xcc-virtualmedia://[fe80::fc33:62ff:fe83:8a76]:8443",
+			expects: map[string]interface{}{
+				"redfish_address":   "https://[fe80::fc33:62ff:fe83:8a76]:8443",
+				"redfish_system_id": "/redfish/v1/Systems/1",
+				"redfish_password":  "",
+				"redfish_username":  "",
+				"redfish_verify_ca": false,
+			},
+		},
+
 		{
 			Scenario: "ilo5 ipv6 port",
 			input:    "ilo5://[fe80::fc33:62ff:fe83:8a76]:8080",
@@ -1084,3 +1229,287 @@ func TestUnknownType(t *testing.T) {
 		t.Fatalf("unexpected parse success")
 	}
 }
+
+func TestDriverInfoCAFile(t *testing.T) {
+	const caFile = "/shared/bmc-ca/rack1/host-0.pem"
+	for _, tc := range []struct {
+		input string
+		key   string
+	}{
+		{input: "redfish://192.168.122.1", key: "redfish_verify_ca"},
+		{input: "redfish-virtualmedia://192.168.122.1", key: "redfish_verify_ca"},
+		{input: "idrac-virtualmedia://192.168.122.1", key: "redfish_verify_ca"},
+		{input: "ilo5-redfish://192.168.122.1", key: "redfish_verify_ca"},
+		{input: "idrac://192.168.122.1", key: "drac_verify_ca"},
+		{input: "ilo4://192.168.122.1", key: "ilo_ca_file"},
+		{input: "ilo5://192.168.122.1", key: "ilo_ca_file"},
+		{input: "irmc://192.168.122.1", key: "irmc_verify_ca"},
+		{input: "ibmc://192.168.122.1", key: "ibmc_verify_ca"},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			acc, err := NewAccessDetailsWithCAFile(tc.input, caFile)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			di := acc.DriverInfo(Credentials{})
+			if di[tc.key] != caFile {
+				t.Fatalf("unexpected value for %s: %v, expected %s", tc.key, di[tc.key], caFile)
+			}
+		})
+	}
+
+	// IPMI does not use TLS.
+	acc, err := NewAccessDetailsWithCAFile("ipmi://192.168.122.1", caFile)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	for key, value := range acc.DriverInfo(Credentials{}) {
+		if value == caFile {
+			t.Fatalf("unexpected CA file in %s", key)
+		}
+	}
+}
+
+func TestDriverInfoOptions(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected map[string]interface{}
+	}{
+		{
+			input: "ipmi://192.168.122.1?cipher_suite=17&priv_level=operator",
+			expected: map[string]interface{}{
+				"ipmi_cipher_suite": 17,
+				"ipmi_priv_level":   "OPERATOR",
+			},
+		},
+		{
+			input: "ipmi://192.168.122.1:6233?bridging=single&target_channel=7&target_address=0x72",
+			expected: map[string]interface{}{
+				"ipmi_port":           "6233",
+				"ipmi_bridging":       "single",
+				"ipmi_target_channel": "7",
+				"ipmi_target_address": "0x72",
+			},
+		},
+		{
+			input: "redfish://192.168.122.1/redfish/v1/Systems/1?system_id=/redfish/v1/Systems/2&auth_type=session",
+			expected: map[string]interface{}{
+				"redfish_system_id": "/redfish/v1/Systems/2",
+				"redfish_auth_type": "session",
+			},
+		},
+		{
+			input: "idrac-virtualmedia://192.168.122.1?auth_type=basic",
+			expected: map[string]interface{}{
+				"redfish_auth_type": "basic",
+			},
+		},
+		{
+			input: "idrac://192.168.122.1?port=8443&protocol=http&path=/wsman",
+			expected: map[string]interface{}{
+				"drac_address":  "192.168.122.1",
+				"drac_port":     8443,
+				"drac_protocol": "http",
+				"drac_path":     "/wsman",
+			},
+		},
+		{
+			input: "idrac+https://192.168.122.1:443/wsman?protocol=HTTP&port=8080",
+			expected: map[string]interface{}{
+				"drac_port":     8080,
+				"drac_protocol": "http",
+				"drac_path":     "/wsman",
+			},
+		},
+		{
+			input: "ilo5://192.168.122.1?console_port=8023&client_timeout=120",
+			expected: map[string]interface{}{
+				"console_port":   8023,
+				"client_timeout": 120,
+			},
+		},
+		{
+			input: "irmc://192.168.122.1?auth_method=digest",
+			expected: map[string]interface{}{
+				"irmc_auth_method": "digest",
+			},
+		},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			acc, err := NewAccessDetails(tc.input, false)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			di := acc.DriverInfo(Credentials{})
+			for key, value := range tc.expected {
+				if di[key] != value {
+					t.Errorf("unexpected value for %s: %#v, expected %#v", key, di[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    "ipmi://192.168.122.1?cipher_suite=18",
+			expected: `Invalid option 'cipher_suite' for BMC type 'ipmi': "18" is not an integer between 0 and 17`,
+		},
+		{
+			input:    "ipmi://192.168.122.1?priv_level=root",
+			expected: `Invalid option 'priv_level' for BMC type 'ipmi': "root" is not one of ADMINISTRATOR, CALLBACK, OPERATOR, USER`,
+		},
+		{
+			input:    "ipmi://192.168.122.1?target_address=0x100",
+			expected: `Invalid option 'target_address' for BMC type 'ipmi': "0x100" is not a number between 0 and 0xff`,
+		},
+		{
+			input:    "redfish://192.168.122.1?system_id=Systems/1",
+			expected: `Invalid option 'system_id' for BMC type 'redfish': "Systems/1" is not an absolute path`,
+		},
+		{
+			input:    "redfish://192.168.122.1?auth_type=basic&auth_type=session",
+			expected: `Invalid option 'auth_type' for BMC type 'redfish': set more than once`,
+		},
+		{
+			input:    "redfish+http://192.168.122.1?cipher_suite=3",
+			expected: `Invalid option 'cipher_suite' for BMC type 'redfish+http': supported options are auth_type, system_id`,
+		},
+		{
+			input:    "idrac://192.168.122.1?auth_type=basic",
+			expected: `Invalid option 'auth_type' for BMC type 'idrac': supported options are path, port, protocol`,
+		},
+		{
+			input:    "idrac://192.168.122.1?port=0",
+			expected: `Invalid option 'port' for BMC type 'idrac': "0" is not an integer between 1 and 65535`,
+		},
+		{
+			input:    "idrac://192.168.122.1?protocol=ftp",
+			expected: `Invalid option 'protocol' for BMC type 'idrac': "ftp" is not one of http, https`,
+		},
+		{
+			input:    "idrac://192.168.122.1?path=wsman",
+			expected: `Invalid option 'path' for BMC type 'idrac': "wsman" is not an absolute path`,
+		},
+		{
+			input:    "ibmc://192.168.122.1?auth_type=basic",
+			expected: `Invalid option 'auth_type' for BMC type 'ibmc': supported options are none`,
+		},
+		{
+			input:    "ipmi://192.168.122.1?priv_level=%zz",
+			expected: `Invalid options for BMC type 'ipmi': invalid URL escape "%zz"`,
+		},
+	} {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := NewAccessDetails(tc.input, false)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if _, ok := err.(*InvalidOptionError); !ok {
+				t.Errorf("unexpected error type %T", err)
+			}
+			if err.Error() != tc.expected {
+				t.Errorf("unexpected error %q, expected %q", err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestVendorRedfishInterfaces(t *testing.T) {
+	for _, tc := range []struct {
+		Scenario   string
+		input      string
+		needsMac   bool
+		driver     string
+		boot       string
+		management string
+		power      string
+		raid       string
+		vendor     string
+	}{
+		{
+			Scenario:   "supermicro",
+			input:      "supermicro://192.168.122.1",
+			needsMac:   true,
+			driver:     "redfish",
+			boot:       "ipxe",
+			management: "redfish",
+			power:      "redfish",
+			raid:       "no-raid",
+			vendor:     "no-vendor",
+		},
+
+		{
+			Scenario:   "supermicro virtual media",
+			input:      "supermicro-virtualmedia+https://192.168.122.1",
+			needsMac:   true,
+			driver:     "redfish",
+			boot:       "redfish-virtual-media",
+			management: "redfish",
+			power:      "redfish",
+			raid:       "no-raid",
+			vendor:     "no-vendor",
+		},
+
+		{
+			Scenario:   "lenovo-xcc",
+			input:      "lenovo-xcc://192.168.122.1",
+			needsMac:   true,
+			driver:     "redfish",
+			boot:       "ipxe",
+			management: "redfish",
+			power:      "redfish",
+			raid:       "redfish",
+			vendor:     "no-vendor",
+		},
+
+		{
+			Scenario:   "lenovo-xcc virtual media",
+			input:      "lenovo-xcc-virtualmedia://192.168.122.1",
+			needsMac:   true,
+			driver:     "redfish",
+			boot:       "redfish-virtual-media",
+			management: "redfish",
+			power:      "redfish",
+			raid:       "redfish",
+			vendor:     "no-vendor",
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			acc, err := NewAccessDetails(tc.input, false)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if acc.NeedsMAC() != tc.needsMac {
+				t.Fatalf("MAC needed: %v , expected %v", acc.NeedsMAC(), tc.needsMac)
+			}
+			if acc.Driver() != tc.driver {
+				t.Fatalf("Unexpected driver %q, expected %q", acc.Driver(), tc.driver)
+			}
+			if acc.BootInterface() != tc.boot {
+				t.Fatalf("Unexpected boot interface %q, expected %q",
+					acc.BootInterface(), tc.boot)
+			}
+			if acc.ManagementInterface() != tc.management {
+				t.Fatalf("Unexpected management interface %q, expected %q",
+					acc.ManagementInterface(), tc.management)
+			}
+			if acc.PowerInterface() != tc.power {
+				t.Fatalf("Unexpected power interface %q, expected %q",
+					acc.PowerInterface(), tc.power)
+			}
+			if acc.RAIDInterface() != tc.raid {
+				t.Fatalf("Unexpected RAID interface %q, expected %q",
+					acc.RAIDInterface(), tc.raid)
+			}
+			if acc.VendorInterface() != tc.vendor {
+				t.Fatalf("Unexpected vendor interface %q, expected %q",
+					acc.VendorInterface(), tc.vendor)
+			}
+		})
+	}
+}
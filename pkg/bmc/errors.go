@@ -26,3 +26,20 @@ func (e CredentialsValidationError) Error() string {
 	return fmt.Sprintf("Validation error with BMC credentials: %s",
 		e.message)
 }
+
+// InvalidOptionError is returned when the query parameters of the BMC
+// address are not options of the BMC type, or have invalid values.
+type InvalidOptionError struct {
+	bmcType string
+	option  string
+	message string
+}
+
+func (e InvalidOptionError) Error() string {
+	if e.option == "" {
+		return fmt.Sprintf("Invalid options for BMC type '%s': %s",
+			e.bmcType, e.message)
+	}
+	return fmt.Sprintf("Invalid option '%s' for BMC type '%s': %s",
+		e.option, e.bmcType, e.message)
+}
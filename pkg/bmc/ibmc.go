@@ -7,22 +7,45 @@ import (
 
 func init() {
 	RegisterFactory("ibmc", newIbmcAccessDetails, []string{"http", "https"})
+	RegisterFactory("ibmc-virtualmedia", newIbmcVirtualMediaAccessDetails, []string{"http", "https"})
 }
 
-func newIbmcAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+func newIbmcAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := noOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
 	return &ibmcAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		host:                           parsedURL.Host,
 		path:                           parsedURL.Path,
 		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
 	}, nil
 }
 
+// iBMCs manage a single system.
+const ibmcDefaultSystemID = "/redfish/v1/Systems/1"
+
+// newIbmcVirtualMediaAccessDetails drives iBMCs through Redfish, as
+// the ibmc driver of ironic has no virtual media boot interface.
+func newIbmcVirtualMediaAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	if parsedURL.Path == "" || parsedURL.Path == "/" {
+		withSystemID := *parsedURL
+		withSystemID.Path = ibmcDefaultSystemID
+		parsedURL = &withSystemID
+	}
+	return newRedfishVirtualMediaAccessDetails(parsedURL, disableCertificateVerification, caFile)
+}
+
 type ibmcAccessDetails struct {
 	bmcType                        string
 	host                           string
 	path                           string
 	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
 }
 
 func (a *ibmcAccessDetails) Type() string {
@@ -73,6 +96,11 @@ func (a *ibmcAccessDetails) DriverInfo(bmcCreds Credentials) map[string]interfac
 
 	if a.disableCertificateVerification {
 		result["ibmc_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["ibmc_verify_ca"] = a.caFile
+	}
+	for key, value := range a.driverOptions {
+		result[key] = value
 	}
 
 	return result
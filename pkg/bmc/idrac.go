@@ -9,13 +9,19 @@ func init() {
 	RegisterFactory("idrac", newIDRACAccessDetails, []string{"http", "https"})
 }
 
-func newIDRACAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+func newIDRACAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := iDRACOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
 	return &iDracAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		portNum:                        parsedURL.Port(),
 		hostname:                       parsedURL.Hostname(),
 		path:                           parsedURL.Path,
 		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
 	}, nil
 }
 
@@ -25,6 +31,8 @@ type iDracAccessDetails struct {
 	hostname                       string
 	path                           string
 	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
 }
 
 func (a *iDracAccessDetails) Type() string {
@@ -58,6 +66,8 @@ func (a *iDracAccessDetails) DriverInfo(bmcCreds Credentials) map[string]interfa
 	}
 	if a.disableCertificateVerification {
 		result["drac_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["drac_verify_ca"] = a.caFile
 	}
 
 	schemes := strings.Split(a.bmcType, "+")
@@ -70,6 +80,9 @@ func (a *iDracAccessDetails) DriverInfo(bmcCreds Credentials) map[string]interfa
 	if a.path != "" {
 		result["drac_path"] = a.path
 	}
+	for key, value := range a.driverOptions {
+		result[key] = value
+	}
 
 	return result
 }
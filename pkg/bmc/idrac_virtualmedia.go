@@ -9,12 +9,18 @@ func init() {
 	RegisterFactory("idrac-virtualmedia", newRedfishiDracVirtualMediaAccessDetails, schemes)
 }
 
-func newRedfishiDracVirtualMediaAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+func newRedfishiDracVirtualMediaAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := redfishOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
 	return &redfishiDracVirtualMediaAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		host:                           parsedURL.Host,
 		path:                           parsedURL.Path,
 		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
 	}, nil
 }
 
@@ -23,6 +29,8 @@ type redfishiDracVirtualMediaAccessDetails struct {
 	host                           string
 	path                           string
 	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
 }
 
 func (a *redfishiDracVirtualMediaAccessDetails) Type() string {
@@ -56,6 +64,11 @@ func (a *redfishiDracVirtualMediaAccessDetails) DriverInfo(bmcCreds Credentials)
 
 	if a.disableCertificateVerification {
 		result["redfish_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["redfish_verify_ca"] = a.caFile
+	}
+	for key, value := range a.driverOptions {
+		result[key] = value
 	}
 
 	return result
@@ -10,12 +10,18 @@ func init() {
 	RegisterFactory("ilo4", newILOAccessDetails, []string{"https"})
 }
 
-func newILOAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+func newILOAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := iLOOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
 	return &iLOAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		portNum:                        parsedURL.Port(),
 		hostname:                       parsedURL.Hostname(),
 		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
 	}, nil
 }
 
@@ -24,6 +30,8 @@ type iLOAccessDetails struct {
 	portNum                        string
 	hostname                       string
 	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
 }
 
 func (a *iLOAccessDetails) Type() string {
@@ -61,11 +69,16 @@ func (a *iLOAccessDetails) DriverInfo(bmcCreds Credentials) map[string]interface
 
 	if a.disableCertificateVerification {
 		result["ilo_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["ilo_ca_file"] = a.caFile
 	}
 
 	if a.portNum != "" {
 		result["client_port"] = a.portNum
 	}
+	for key, value := range a.driverOptions {
+		result[key] = value
+	}
 
 	return result
 }
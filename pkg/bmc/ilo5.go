@@ -4,18 +4,26 @@ package bmc
 
 import (
 	"net/url"
+	"strings"
 )
 
 func init() {
 	RegisterFactory("ilo5", newILO5AccessDetails, []string{"https"})
+	RegisterFactory("ilo5-ilo-virtualmedia", newILO5AccessDetails, []string{"https"})
 }
 
-func newILO5AccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+func newILO5AccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := iLOOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
 	return &iLO5AccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		portNum:                        parsedURL.Port(),
 		hostname:                       parsedURL.Hostname(),
 		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
 	}, nil
 }
 
@@ -24,6 +32,8 @@ type iLO5AccessDetails struct {
 	portNum                        string
 	hostname                       string
 	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
 }
 
 func (a *iLO5AccessDetails) Type() string {
@@ -61,16 +71,24 @@ func (a *iLO5AccessDetails) DriverInfo(bmcCreds Credentials) map[string]interfac
 
 	if a.disableCertificateVerification {
 		result["ilo_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["ilo_ca_file"] = a.caFile
 	}
 
 	if a.portNum != "" {
 		result["client_port"] = a.portNum
 	}
+	for key, value := range a.driverOptions {
+		result[key] = value
+	}
 
 	return result
 }
 
 func (a *iLO5AccessDetails) BootInterface() string {
+	if strings.HasPrefix(a.bmcType, "ilo5-ilo-virtualmedia") {
+		return "ilo-virtual-media"
+	}
 	return "ilo-ipxe"
 }
 
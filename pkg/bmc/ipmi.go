@@ -9,12 +9,17 @@ func init() {
 	RegisterFactory("libvirt", newIPMIAccessDetails, []string{})
 }
 
-func newIPMIAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+func newIPMIAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := ipmiOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
 	return &ipmiAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		portNum:                        parsedURL.Port(),
 		hostname:                       parsedURL.Hostname(),
 		disableCertificateVerification: disableCertificateVerification,
+		driverOptions:                  driverOptions,
 	}, nil
 }
 
@@ -23,6 +28,7 @@ type ipmiAccessDetails struct {
 	portNum                        string
 	hostname                       string
 	disableCertificateVerification bool
+	driverOptions                  map[string]interface{}
 }
 
 const ipmiDefaultPort = "623"
@@ -70,6 +76,9 @@ func (a *ipmiAccessDetails) DriverInfo(bmcCreds Credentials) map[string]interfac
 	if a.portNum == "" {
 		result["ipmi_port"] = ipmiDefaultPort
 	}
+	for key, value := range a.driverOptions {
+		result[key] = value
+	}
 	return result
 }
 
@@ -6,14 +6,21 @@ import (
 
 func init() {
 	RegisterFactory("irmc", newIRMCAccessDetails, []string{})
+	RegisterFactory("irmc-virtualmedia", newIRMCAccessDetails, []string{})
 }
 
-func newIRMCAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+func newIRMCAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := iRMCOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
 	return &iRMCAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		portNum:                        parsedURL.Port(),
 		hostname:                       parsedURL.Hostname(),
 		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
 	}, nil
 }
 
@@ -22,6 +29,8 @@ type iRMCAccessDetails struct {
 	portNum                        string
 	hostname                       string
 	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
 }
 
 func (a *iRMCAccessDetails) Type() string {
@@ -31,7 +40,14 @@ func (a *iRMCAccessDetails) Type() string {
 // NeedsMAC returns true when the host is going to need a separate
 // port created rather than having it discovered.
 func (a *iRMCAccessDetails) NeedsMAC() bool {
-	return false
+	// Without PXE, inspection cannot discover the ports, so the
+	// virtual media variant needs the MAC address like the other
+	// virtual media types.
+	return a.virtualMedia()
+}
+
+func (a *iRMCAccessDetails) virtualMedia() bool {
+	return a.bmcType == "irmc-virtualmedia"
 }
 
 func (a *iRMCAccessDetails) Driver() string {
@@ -56,16 +72,24 @@ func (a *iRMCAccessDetails) DriverInfo(bmcCreds Credentials) map[string]interfac
 
 	if a.disableCertificateVerification {
 		result["irmc_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["irmc_verify_ca"] = a.caFile
 	}
 
 	if a.portNum != "" {
 		result["irmc_port"] = a.portNum
 	}
+	for key, value := range a.driverOptions {
+		result[key] = value
+	}
 
 	return result
 }
 
 func (a *iRMCAccessDetails) BootInterface() string {
+	if a.virtualMedia() {
+		return "irmc-virtual-media"
+	}
 	return "pxe"
 }
 
@@ -0,0 +1,201 @@
+package bmc
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Node holds the fields of an existing Ironic node that tell how its
+// BMC is reached.
+type Node struct {
+	Driver        string
+	BootInterface string
+	DriverInfo    map[string]interface{}
+}
+
+// NodeAccess is how the BMC of an existing Ironic node is reached, as
+// given to a host.
+type NodeAccess struct {
+	// Address is the BMC address of a host managing the node.
+	Address string
+	// Username is the username found in the driver_info of the node.
+	// The Ironic API hides passwords, so there is no Password.
+	Username                       string
+	DisableCertificateVerification bool
+}
+
+// nodeAddressFunc returns the BMC address of a node of a driver.
+type nodeAddressFunc func(node Node) (string, error)
+
+// nodeAddresses maps the Ironic drivers to the functions reversing the
+// DriverInfo of the BMC types using them.
+var nodeAddresses = map[string]struct {
+	prefix  string
+	address nodeAddressFunc
+}{
+	"ipmi":    {"ipmi", ipmiNodeAddress},
+	"redfish": {"redfish", redfishNodeAddress},
+	"idrac":   {"drac", iDracNodeAddress},
+	"ilo":     {"ilo", iLONodeAddress},
+	"ilo5":    {"ilo", iLO5NodeAddress},
+	"irmc":    {"irmc", iRMCNodeAddress},
+	"ibmc":    {"ibmc", ibmcNodeAddress},
+}
+
+// AddressFromNode reverses DriverInfo: it returns the BMC address whose
+// DriverInfo and BootInterface are the ones of an existing Ironic node.
+// The types sharing a driver and interfaces, such as libvirt and ipmi
+// or the vendor flavours of redfish, cannot be told apart, and the
+// generic one is returned.
+func AddressFromNode(node Node) (NodeAccess, error) {
+	driver, ok := nodeAddresses[node.Driver]
+	if !ok {
+		return NodeAccess{}, fmt.Errorf("no BMC type uses the %s driver", node.Driver)
+	}
+	address, err := driver.address(node)
+	if err != nil {
+		return NodeAccess{}, err
+	}
+
+	prefix := driver.prefix
+	if _, ok := node.DriverInfo["redfish_address"]; ok {
+		// idrac-virtualmedia
+		prefix = "redfish"
+	}
+	access := NodeAccess{
+		Address:  address,
+		Username: stringInfo(node.DriverInfo, prefix+"_username"),
+	}
+	if verifyCA, ok := node.DriverInfo[prefix+"_verify_ca"]; ok {
+		access.DisableCertificateVerification = strings.EqualFold(fmt.Sprint(verifyCA), "false")
+	}
+	return access, nil
+}
+
+// stringInfo returns a field of driver_info as a string.
+func stringInfo(driverInfo map[string]interface{}, key string) string {
+	value, ok := driverInfo[key]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// nodeURL builds the BMC address of a node, with the options found in
+// its driver_info as query parameters.
+func nodeURL(bmcType, host, port, path string, driverInfo map[string]interface{}, opts options, skip ...string) (string, error) {
+	if host == "" {
+		return "", fmt.Errorf("the driver_info of the node has no BMC address")
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	query := url.Values{}
+	for name, opt := range opts {
+		if value, ok := driverInfo[opt.driverInfoKey]; ok && !contains(skip, name) {
+			query.Set(name, fmt.Sprint(value))
+		}
+	}
+	address := url.URL{
+		Scheme:   bmcType,
+		Host:     host,
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+	return address.String(), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceNodeURL builds the BMC address of a node whose driver_info
+// gives the URL of the service of the BMC, such as redfish_address,
+// whose scheme is added to the BMC type when it is not the default.
+func serviceNodeURL(bmcType, serviceAddress, defaultScheme, path string, driverInfo map[string]interface{}, opts options, skip ...string) (string, error) {
+	if serviceAddress == "" {
+		return "", fmt.Errorf("the driver_info of the node has no BMC address")
+	}
+	if !strings.Contains(serviceAddress, "://") {
+		serviceAddress = defaultScheme + "://" + serviceAddress
+	}
+	parsedURL, err := url.Parse(serviceAddress)
+	if err != nil {
+		return "", fmt.Errorf("invalid BMC address %q: %s", serviceAddress, err)
+	}
+	if parsedURL.Scheme != defaultScheme {
+		bmcType = bmcType + "+" + parsedURL.Scheme
+	}
+	if path == "" {
+		path = parsedURL.Path
+	}
+	return nodeURL(bmcType, parsedURL.Hostname(), parsedURL.Port(), path, driverInfo, opts, skip...)
+}
+
+func ipmiNodeAddress(node Node) (string, error) {
+	port := stringInfo(node.DriverInfo, "ipmi_port")
+	if port == ipmiDefaultPort {
+		port = ""
+	}
+	return nodeURL("ipmi", stringInfo(node.DriverInfo, "ipmi_address"), port, "",
+		node.DriverInfo, ipmiOptions)
+}
+
+func redfishNodeAddress(node Node) (string, error) {
+	bmcType := "redfish"
+	if node.BootInterface == "redfish-virtual-media" {
+		bmcType = "redfish-virtualmedia"
+	}
+	// The system ID is the path of the address rather than an option.
+	return serviceNodeURL(bmcType, stringInfo(node.DriverInfo, "redfish_address"), redfishDefaultScheme,
+		stringInfo(node.DriverInfo, "redfish_system_id"), node.DriverInfo, redfishOptions, "system_id")
+}
+
+func iDracNodeAddress(node Node) (string, error) {
+	if redfishAddress := stringInfo(node.DriverInfo, "redfish_address"); redfishAddress != "" {
+		return serviceNodeURL("idrac-virtualmedia", redfishAddress, redfishDefaultScheme,
+			stringInfo(node.DriverInfo, "redfish_system_id"), node.DriverInfo, redfishOptions, "system_id")
+	}
+	bmcType := "idrac"
+	if protocol := stringInfo(node.DriverInfo, "drac_protocol"); protocol != "" {
+		bmcType = bmcType + "+" + protocol
+	}
+	return nodeURL(bmcType, stringInfo(node.DriverInfo, "drac_address"), stringInfo(node.DriverInfo, "drac_port"),
+		stringInfo(node.DriverInfo, "drac_path"), node.DriverInfo, iDRACOptions, "port", "protocol", "path")
+}
+
+func iLONodeAddress(node Node) (string, error) {
+	return nodeURL("ilo4", stringInfo(node.DriverInfo, "ilo_address"), stringInfo(node.DriverInfo, "client_port"), "",
+		node.DriverInfo, iLOOptions)
+}
+
+func iLO5NodeAddress(node Node) (string, error) {
+	bmcType := "ilo5"
+	if node.BootInterface == "ilo-virtual-media" {
+		bmcType = "ilo5-ilo-virtualmedia"
+	}
+	return nodeURL(bmcType, stringInfo(node.DriverInfo, "ilo_address"), stringInfo(node.DriverInfo, "client_port"), "",
+		node.DriverInfo, iLOOptions)
+}
+
+func iRMCNodeAddress(node Node) (string, error) {
+	bmcType := "irmc"
+	if node.BootInterface == "irmc-virtual-media" {
+		bmcType = "irmc-virtualmedia"
+	}
+	return nodeURL(bmcType, stringInfo(node.DriverInfo, "irmc_address"), stringInfo(node.DriverInfo, "irmc_port"), "",
+		node.DriverInfo, iRMCOptions)
+}
+
+func ibmcNodeAddress(node Node) (string, error) {
+	return serviceNodeURL("ibmc", stringInfo(node.DriverInfo, "ibmc_address"), ibmcDefaultScheme, "",
+		node.DriverInfo, noOptions)
+}
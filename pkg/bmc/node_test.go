@@ -0,0 +1,154 @@
+package bmc
+
+import (
+	"testing"
+)
+
+func TestAddressFromNode(t *testing.T) {
+	for _, tc := range []struct {
+		Scenario string
+		input    string
+		disable  bool
+		expected string
+	}{
+		{
+			Scenario: "ipmi",
+			input:    "ipmi://192.168.122.1",
+			expected: "ipmi://192.168.122.1",
+		},
+		{
+			Scenario: "ipmi port and options",
+			input:    "ipmi://192.168.122.1:6233?priv_level=OPERATOR&bridging=single",
+			expected: "ipmi://192.168.122.1:6233?bridging=single&priv_level=OPERATOR",
+		},
+		{
+			Scenario: "ipmi ipv6",
+			input:    "ipmi://[fe80::fc33:62ff:fe83:8a76]:6233",
+			expected: "ipmi://[fe80::fc33:62ff:fe83:8a76]:6233",
+		},
+		{
+			Scenario: "libvirt",
+			input:    "libvirt://192.168.122.1",
+			expected: "ipmi://192.168.122.1",
+		},
+		{
+			Scenario: "redfish",
+			input:    "redfish://192.168.122.1/redfish/v1/Systems/1",
+			disable:  true,
+			expected: "redfish://192.168.122.1/redfish/v1/Systems/1",
+		},
+		{
+			Scenario: "redfish http",
+			input:    "redfish+http://192.168.122.1:8000/redfish/v1/Systems/1?auth_type=basic",
+			expected: "redfish+http://192.168.122.1:8000/redfish/v1/Systems/1?auth_type=basic",
+		},
+		{
+			Scenario: "redfish virtual media",
+			input:    "redfish-virtualmedia://192.168.122.1/redfish/v1/Systems/1",
+			expected: "redfish-virtualmedia://192.168.122.1/redfish/v1/Systems/1",
+		},
+		{
+			Scenario: "supermicro",
+			input:    "supermicro://192.168.122.1",
+			expected: "redfish://192.168.122.1/redfish/v1/Systems/1?auth_type=basic",
+		},
+		{
+			Scenario: "idrac",
+			input:    "idrac+http://192.168.122.1:8080/wsman",
+			expected: "idrac+http://192.168.122.1:8080/wsman",
+		},
+		{
+			Scenario: "idrac virtual media",
+			input:    "idrac-virtualmedia://192.168.122.1/redfish/v1/Systems/System.Embedded.1",
+			disable:  true,
+			expected: "idrac-virtualmedia://192.168.122.1/redfish/v1/Systems/System.Embedded.1",
+		},
+		{
+			Scenario: "ilo4",
+			input:    "ilo4://192.168.122.1:8443",
+			disable:  true,
+			expected: "ilo4://192.168.122.1:8443",
+		},
+		{
+			Scenario: "ilo5",
+			input:    "ilo5://192.168.122.1?client_timeout=120",
+			expected: "ilo5://192.168.122.1?client_timeout=120",
+		},
+		{
+			Scenario: "ilo5 ilo virtual media",
+			input:    "ilo5-ilo-virtualmedia://192.168.122.1",
+			expected: "ilo5-ilo-virtualmedia://192.168.122.1",
+		},
+		{
+			Scenario: "irmc",
+			input:    "irmc://192.168.122.1:8443",
+			expected: "irmc://192.168.122.1:8443",
+		},
+		{
+			Scenario: "irmc virtual media",
+			input:    "irmc-virtualmedia://192.168.122.1",
+			expected: "irmc-virtualmedia://192.168.122.1",
+		},
+		{
+			Scenario: "ibmc",
+			input:    "ibmc+http://192.168.122.1:8080",
+			expected: "ibmc+http://192.168.122.1:8080",
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			acc, err := NewAccessDetails(tc.input, tc.disable)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			node := Node{
+				Driver:        acc.Driver(),
+				BootInterface: acc.BootInterface(),
+				DriverInfo:    acc.DriverInfo(Credentials{Username: "admin", Password: "******"}),
+			}
+
+			access, err := AddressFromNode(node)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if access.Address != tc.expected {
+				t.Errorf("unexpected address %q, expected %q", access.Address, tc.expected)
+			}
+			if access.Username != "admin" {
+				t.Errorf("unexpected username %q", access.Username)
+			}
+			if access.DisableCertificateVerification != tc.disable {
+				t.Errorf("unexpected disableCertificateVerification %v", access.DisableCertificateVerification)
+			}
+
+			if _, err := NewAccessDetails(access.Address, false); err != nil {
+				t.Errorf("invalid address %q: %v", access.Address, err)
+			}
+		})
+	}
+}
+
+func TestAddressFromNodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		Scenario string
+		node     Node
+		expected string
+	}{
+		{
+			Scenario: "unknown driver",
+			node:     Node{Driver: "fake-hardware"},
+			expected: "no BMC type uses the fake-hardware driver",
+		},
+		{
+			Scenario: "missing address",
+			node:     Node{Driver: "ipmi", DriverInfo: map[string]interface{}{"ipmi_username": "admin"}},
+			expected: "the driver_info of the node has no BMC address",
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			_, err := AddressFromNode(tc.node)
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("unexpected error %v, expected %q", err, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package bmc
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// option describes a query parameter of BMC addresses that tunes the
+// driver, such as ipmi://host?priv_level=OPERATOR.
+type option struct {
+	// driverInfoKey is the driver_info field the option sets.
+	driverInfoKey string
+	// parse validates the value and returns the one to pass to the
+	// driver.
+	parse func(value string) (interface{}, error)
+}
+
+// options maps the query parameters a BMC type accepts to the
+// driver_info fields they set.
+type options map[string]option
+
+// parse validates the query parameters of the address and returns the
+// driver_info fields they set. Unknown parameters are rejected rather
+// than ignored, so that typos are not silently dropped.
+func (o options) parse(parsedURL *url.URL) (map[string]interface{}, error) {
+	query, err := url.ParseQuery(parsedURL.RawQuery)
+	if err != nil {
+		return nil, &InvalidOptionError{bmcType: parsedURL.Scheme, message: err.Error()}
+	}
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	driverInfo := map[string]interface{}{}
+	for _, name := range names {
+		opt, ok := o[name]
+		if !ok {
+			return nil, &InvalidOptionError{bmcType: parsedURL.Scheme, option: name,
+				message: fmt.Sprintf("supported options are %s", o.names())}
+		}
+		values := query[name]
+		if len(values) > 1 {
+			return nil, &InvalidOptionError{bmcType: parsedURL.Scheme, option: name,
+				message: "set more than once"}
+		}
+		value, err := opt.parse(values[0])
+		if err != nil {
+			return nil, &InvalidOptionError{bmcType: parsedURL.Scheme, option: name, message: err.Error()}
+		}
+		driverInfo[opt.driverInfoKey] = value
+	}
+	return driverInfo, nil
+}
+
+func (o options) names() string {
+	if len(o) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// oneOf accepts the given values, in any case, and passes them on as
+// written in the list.
+func oneOf(allowed ...string) func(string) (interface{}, error) {
+	return func(value string) (interface{}, error) {
+		for _, a := range allowed {
+			if strings.EqualFold(value, a) {
+				return a, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+// intRange accepts decimal integers between min and max.
+func intRange(min, max int) func(string) (interface{}, error) {
+	return func(value string) (interface{}, error) {
+		i, err := strconv.Atoi(value)
+		if err != nil || i < min || i > max {
+			return nil, fmt.Errorf("%q is not an integer between %d and %d", value, min, max)
+		}
+		return i, nil
+	}
+}
+
+// ipmiByte accepts the addresses and channels of IPMI bridging, in
+// decimal or in hexadecimal with a 0x prefix.
+func ipmiByte(value string) (interface{}, error) {
+	if _, err := strconv.ParseUint(value, 0, 8); err != nil {
+		return nil, fmt.Errorf("%q is not a number between 0 and 0xff", value)
+	}
+	return value, nil
+}
+
+// absolutePath accepts absolute paths, such as the one of a system in
+// the Redfish API.
+func absolutePath(value string) (interface{}, error) {
+	if !strings.HasPrefix(value, "/") {
+		return nil, fmt.Errorf("%q is not an absolute path", value)
+	}
+	return value, nil
+}
+
+var ipmiOptions = options{
+	"cipher_suite":    {"ipmi_cipher_suite", intRange(0, 17)},
+	"priv_level":      {"ipmi_priv_level", oneOf("ADMINISTRATOR", "CALLBACK", "OPERATOR", "USER")},
+	"bridging":        {"ipmi_bridging", oneOf("no", "single", "dual")},
+	"local_address":   {"ipmi_local_address", ipmiByte},
+	"transit_channel": {"ipmi_transit_channel", ipmiByte},
+	"transit_address": {"ipmi_transit_address", ipmiByte},
+	"target_channel":  {"ipmi_target_channel", ipmiByte},
+	"target_address":  {"ipmi_target_address", ipmiByte},
+}
+
+var redfishOptions = options{
+	"system_id": {"redfish_system_id", absolutePath},
+	"auth_type": {"redfish_auth_type", oneOf("basic", "session", "auto")},
+}
+
+var iDRACOptions = options{
+	"port":     {"drac_port", intRange(1, 65535)},
+	"protocol": {"drac_protocol", oneOf("http", "https")},
+	"path":     {"drac_path", absolutePath},
+}
+
+var iLOOptions = options{
+	"client_timeout": {"client_timeout", intRange(1, 3600)},
+	"console_port":   {"console_port", intRange(1, 65535)},
+}
+
+var iRMCOptions = options{
+	"auth_method":    {"irmc_auth_method", oneOf("basic", "digest")},
+	"client_timeout": {"irmc_client_timeout", intRange(1, 3600)},
+}
+
+// noOptions is used by the BMC types that take no options, so that
+// their addresses are still checked for query parameters.
+var noOptions = options{}
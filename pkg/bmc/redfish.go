@@ -9,19 +9,28 @@ func init() {
 	schemes := []string{"http", "https"}
 	RegisterFactory("redfish", newRedfishAccessDetails, schemes)
 	RegisterFactory("ilo5-redfish", newRedfishAccessDetails, schemes)
+	// The type of redfish-auto BMCs is detected by the operator,
+	// which uses the address of the detected type instead.
+	RegisterFactory("redfish-auto", newRedfishAccessDetails, schemes)
 }
 
-func redfishDetails(parsedURL *url.URL, disableCertificateVerification bool) *redfishAccessDetails {
+func redfishDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (*redfishAccessDetails, error) {
+	driverOptions, err := redfishOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
 	return &redfishAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		host:                           parsedURL.Host,
 		path:                           parsedURL.Path,
 		disableCertificateVerification: disableCertificateVerification,
-	}
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
+	}, nil
 }
 
-func newRedfishAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
-	return redfishDetails(parsedURL, disableCertificateVerification), nil
+func newRedfishAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	return redfishDetails(parsedURL, disableCertificateVerification, caFile)
 }
 
 type redfishAccessDetails struct {
@@ -29,6 +38,8 @@ type redfishAccessDetails struct {
 	host                           string
 	path                           string
 	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
 }
 
 const redfishDefaultScheme = "https"
@@ -81,6 +92,11 @@ func (a *redfishAccessDetails) DriverInfo(bmcCreds Credentials) map[string]inter
 
 	if a.disableCertificateVerification {
 		result["redfish_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["redfish_verify_ca"] = a.caFile
+	}
+	for key, value := range a.driverOptions {
+		result[key] = value
 	}
 
 	return result
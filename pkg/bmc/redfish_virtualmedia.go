@@ -10,12 +10,18 @@ func init() {
 	RegisterFactory("ilo5-virtualmedia", newRedfishVirtualMediaAccessDetails, schemes)
 }
 
-func newRedfishVirtualMediaAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+func newRedfishVirtualMediaAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := redfishOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
 	return &redfishVirtualMediaAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		host:                           parsedURL.Host,
 		path:                           parsedURL.Path,
 		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
 	}, nil
 }
 
@@ -24,6 +30,8 @@ type redfishVirtualMediaAccessDetails struct {
 	host                           string
 	path                           string
 	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
 }
 
 func (a *redfishVirtualMediaAccessDetails) Type() string {
@@ -61,6 +69,11 @@ func (a *redfishVirtualMediaAccessDetails) DriverInfo(bmcCreds Credentials) map[
 
 	if a.disableCertificateVerification {
 		result["redfish_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["redfish_verify_ca"] = a.caFile
+	}
+	for key, value := range a.driverOptions {
+		result[key] = value
 	}
 
 	return result
@@ -0,0 +1,122 @@
+package bmc
+
+import (
+	"net/url"
+	"strings"
+)
+
+func init() {
+	schemes := []string{"http", "https"}
+	RegisterFactory("supermicro", newSupermicroAccessDetails, schemes)
+	RegisterFactory("supermicro-virtualmedia", newSupermicroAccessDetails, schemes)
+}
+
+func newSupermicroAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := redfishOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
+	return &supermicroAccessDetails{
+		bmcType:                        parsedURL.Scheme,
+		host:                           parsedURL.Host,
+		path:                           parsedURL.Path,
+		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
+	}, nil
+}
+
+// supermicroAccessDetails drives Supermicro BMCs through Redfish.
+type supermicroAccessDetails struct {
+	bmcType                        string
+	host                           string
+	path                           string
+	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
+}
+
+// Supermicro BMCs manage a single system.
+const supermicroDefaultSystemID = "/redfish/v1/Systems/1"
+
+func (a *supermicroAccessDetails) Type() string {
+	return a.bmcType
+}
+
+// NeedsMAC returns true when the host is going to need a separate
+// port created rather than having it discovered.
+func (a *supermicroAccessDetails) NeedsMAC() bool {
+	// For the inspection to work, we need a MAC address
+	// https://github.com/metal3-io/baremetal-operator/pull/284#discussion_r317579040
+	return true
+}
+
+func (a *supermicroAccessDetails) Driver() string {
+	return "redfish"
+}
+
+func (a *supermicroAccessDetails) DisableCertificateVerification() bool {
+	return a.disableCertificateVerification
+}
+
+func (a *supermicroAccessDetails) virtualMedia() bool {
+	return strings.HasPrefix(a.bmcType, "supermicro-virtualmedia")
+}
+
+// DriverInfo returns a data structure to pass as the DriverInfo
+// parameter when creating a node in Ironic. The structure is
+// pre-populated with the access information, and the caller is
+// expected to add any other information that might be needed (such as
+// the kernel and ramdisk locations).
+func (a *supermicroAccessDetails) DriverInfo(bmcCreds Credentials) map[string]interface{} {
+	systemID := a.path
+	if systemID == "" || systemID == "/" {
+		systemID = supermicroDefaultSystemID
+	}
+	result := map[string]interface{}{
+		"redfish_system_id": systemID,
+		"redfish_username":  bmcCreds.Username,
+		"redfish_password":  bmcCreds.Password,
+		"redfish_address":   getRedfishAddress(a.bmcType, a.host),
+		// Supermicro BMCs only keep a few Redfish sessions, which the
+		// conductors use up, so basic authentication is used unless
+		// the address sets auth_type.
+		"redfish_auth_type": "basic",
+	}
+
+	if a.disableCertificateVerification {
+		result["redfish_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["redfish_verify_ca"] = a.caFile
+	}
+	for key, value := range a.driverOptions {
+		result[key] = value
+	}
+
+	return result
+}
+
+func (a *supermicroAccessDetails) BootInterface() string {
+	if a.virtualMedia() {
+		return "redfish-virtual-media"
+	}
+	return "ipxe"
+}
+
+func (a *supermicroAccessDetails) ManagementInterface() string {
+	return "redfish"
+}
+
+func (a *supermicroAccessDetails) PowerInterface() string {
+	return "redfish"
+}
+
+// RAIDInterface is disabled as the Redfish storage API of Supermicro
+// BMCs cannot create volumes.
+func (a *supermicroAccessDetails) RAIDInterface() string {
+	return "no-raid"
+}
+
+func (a *supermicroAccessDetails) VendorInterface() string {
+	return "no-vendor"
+}
@@ -0,0 +1,118 @@
+package bmc
+
+import (
+	"net/url"
+	"strings"
+)
+
+func init() {
+	schemes := []string{"http", "https"}
+	RegisterFactory("lenovo-xcc", newXClarityAccessDetails, schemes)
+	RegisterFactory("lenovo-xcc-virtualmedia", newXClarityAccessDetails, schemes)
+}
+
+func newXClarityAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (AccessDetails, error) {
+	driverOptions, err := redfishOptions.parse(parsedURL)
+	if err != nil {
+		return nil, err
+	}
+	return &xClarityAccessDetails{
+		bmcType:                        parsedURL.Scheme,
+		host:                           parsedURL.Host,
+		path:                           parsedURL.Path,
+		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
+		driverOptions:                  driverOptions,
+	}, nil
+}
+
+// xClarityAccessDetails drives Lenovo XClarity Controllers through
+// Redfish. The xclarity driver of ironic is not used, as it talks to
+// XClarity Administrator rather than to the BMCs.
+type xClarityAccessDetails struct {
+	bmcType                        string
+	host                           string
+	path                           string
+	disableCertificateVerification bool
+	caFile                         string
+	driverOptions                  map[string]interface{}
+}
+
+// XClarity Controllers manage a single system.
+const xClarityDefaultSystemID = "/redfish/v1/Systems/1"
+
+func (a *xClarityAccessDetails) Type() string {
+	return a.bmcType
+}
+
+// NeedsMAC returns true when the host is going to need a separate
+// port created rather than having it discovered.
+func (a *xClarityAccessDetails) NeedsMAC() bool {
+	// For the inspection to work, we need a MAC address
+	// https://github.com/metal3-io/baremetal-operator/pull/284#discussion_r317579040
+	return true
+}
+
+func (a *xClarityAccessDetails) Driver() string {
+	return "redfish"
+}
+
+func (a *xClarityAccessDetails) DisableCertificateVerification() bool {
+	return a.disableCertificateVerification
+}
+
+func (a *xClarityAccessDetails) virtualMedia() bool {
+	return strings.HasPrefix(a.bmcType, "lenovo-xcc-virtualmedia")
+}
+
+// DriverInfo returns a data structure to pass as the DriverInfo
+// parameter when creating a node in Ironic. The structure is
+// pre-populated with the access information, and the caller is
+// expected to add any other information that might be needed (such as
+// the kernel and ramdisk locations).
+func (a *xClarityAccessDetails) DriverInfo(bmcCreds Credentials) map[string]interface{} {
+	systemID := a.path
+	if systemID == "" || systemID == "/" {
+		systemID = xClarityDefaultSystemID
+	}
+	result := map[string]interface{}{
+		"redfish_system_id": systemID,
+		"redfish_username":  bmcCreds.Username,
+		"redfish_password":  bmcCreds.Password,
+		"redfish_address":   getRedfishAddress(a.bmcType, a.host),
+	}
+
+	if a.disableCertificateVerification {
+		result["redfish_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["redfish_verify_ca"] = a.caFile
+	}
+	for key, value := range a.driverOptions {
+		result[key] = value
+	}
+
+	return result
+}
+
+func (a *xClarityAccessDetails) BootInterface() string {
+	if a.virtualMedia() {
+		return "redfish-virtual-media"
+	}
+	return "ipxe"
+}
+
+func (a *xClarityAccessDetails) ManagementInterface() string {
+	return "redfish"
+}
+
+func (a *xClarityAccessDetails) PowerInterface() string {
+	return "redfish"
+}
+
+func (a *xClarityAccessDetails) RAIDInterface() string {
+	return "redfish"
+}
+
+func (a *xClarityAccessDetails) VendorInterface() string {
+	return "no-vendor"
+}
@@ -0,0 +1,319 @@
+// Package bmctrust provides the provisioning backend with what the
+// server certificates of BMCs are verified against, when it is not the
+// default CA bundle of the backend.
+//
+// The CA bundle of a host, or its pinned certificate, is written to a
+// directory shared with the backend, which only takes the path of a
+// file.
+package bmctrust
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// CAKey is the key of the CA bundle in the ConfigMaps.
+const CAKey = "ca.crt"
+
+// dialTimeout limits how long fetching the certificate of a BMC
+// takes.
+const dialTimeout = 10 * time.Second
+
+// Error reports a problem with the certificate settings of a host,
+// which is shown on the host.
+type Error struct {
+	message string
+}
+
+func (e Error) Error() string {
+	return e.message
+}
+
+// Options configure the Store.
+type Options struct {
+	// Dir is the directory the files are written to. The operator
+	// and the provisioning backend must both see it at this path.
+	Dir string
+	// DefaultBundle names the ConfigMap holding the CA bundle of the
+	// hosts of each namespace that set neither a CA bundle nor a
+	// fingerprint.
+	DefaultBundle string
+}
+
+// Store writes the CA bundles of the hosts to the directory shared
+// with the provisioning backend.
+type Store struct {
+	Client client.Reader
+	Options
+
+	// FetchCertificate returns the server certificate of the BMC at
+	// the address. It connects to the BMC by default.
+	FetchCertificate func(address string) (*x509.Certificate, error)
+}
+
+// Validate reports a problem with the options. The files are read by
+// the operator as well as by the backend, at the path recorded in the
+// status of the hosts, so the directory must be given as an absolute
+// path mounted at the same place in both.
+func (o Options) Validate() error {
+	if o.Dir != "" && !filepath.IsAbs(o.Dir) {
+		return fmt.Errorf("the BMC CA directory %s must be an absolute path, mounted at the same path for ironic", o.Dir)
+	}
+	return nil
+}
+
+// New returns a Store reading the ConfigMaps with the client.
+func New(c client.Reader, opts Options) *Store {
+	return &Store{Client: c, Options: opts}
+}
+
+// Update writes the CA bundle the certificate of the BMC of the host
+// is verified against, and returns the status recording it, or nil
+// when the default CA bundle of the backend is used.
+func (s *Store) Update(ctx context.Context, host *metal3v1alpha1.BareMetalHost) (*metal3v1alpha1.BMCCertificateStatus, error) {
+	bmcDetails := host.Spec.BMC
+	if bmcDetails.CABundleName != "" && bmcDetails.CertificateFingerprint != "" {
+		return nil, &Error{message: "only one of caBundleName and certificateFingerprint can be set"}
+	}
+	explicit := bmcDetails.CABundleName != "" || bmcDetails.CertificateFingerprint != ""
+	if bmcDetails.DisableCertificateVerification {
+		if explicit {
+			return nil, &Error{message: "certificate verification is disabled, but a CA bundle or fingerprint is set"}
+		}
+		return nil, s.Remove(host)
+	}
+	if !explicit && s.DefaultBundle == "" {
+		return nil, s.Remove(host)
+	}
+	if s.Dir == "" {
+		if !explicit {
+			return nil, nil
+		}
+		return nil, &Error{message: "BMC CA bundles and fingerprints are not enabled in the operator"}
+	}
+
+	var status metal3v1alpha1.BMCCertificateStatus
+	var content []byte
+	var err error
+	switch {
+	case bmcDetails.CertificateFingerprint != "":
+		status.Fingerprint = normalizeFingerprint(bmcDetails.CertificateFingerprint)
+		content, err = s.pinnedCertificate(host, status.Fingerprint)
+	case bmcDetails.CABundleName != "":
+		status.CABundle = bmcDetails.CABundleName
+		var found bool
+		content, found, err = s.caBundle(ctx, host.Namespace, bmcDetails.CABundleName)
+		if err == nil && !found {
+			err = &Error{message: fmt.Sprintf("the BMC CA bundle ConfigMap %s does not exist", bmcDetails.CABundleName)}
+		}
+	default:
+		// The namespaces without the default bundle use the one of
+		// the backend.
+		status.CABundle = s.DefaultBundle
+		var found bool
+		content, found, err = s.caBundle(ctx, host.Namespace, s.DefaultBundle)
+		if err == nil && !found {
+			return nil, s.Remove(host)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.write(host, content); err != nil {
+		return nil, err
+	}
+	status.CAFile = filepath.Join(s.Dir, fileName(host))
+	return &status, nil
+}
+
+// Remove deletes the file written for the host, if any.
+func (s *Store) Remove(host *metal3v1alpha1.BareMetalHost) error {
+	if s.Dir == "" {
+		return nil
+	}
+	err := os.Remove(filepath.Join(s.Dir, fileName(host)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func fileName(host *metal3v1alpha1.BareMetalHost) string {
+	return filepath.Join(host.Namespace, host.Name+".pem")
+}
+
+// write replaces the file of the host when its content changes. The
+// new file is renamed into place so that the backend never reads a
+// partial one.
+func (s *Store) write(host *metal3v1alpha1.BareMetalHost, content []byte) error {
+	path := filepath.Join(s.Dir, fileName(host))
+	if existing, err := ioutil.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+host.Name)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	// The certificates are public, and the backend may run as another
+	// user.
+	if err := os.Chmod(tmp.Name(), 0644); err != nil { // #nosec G302
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *Store) caBundle(ctx context.Context, namespace, name string) (content []byte, found bool, err error) {
+	configMap := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if err := s.Client.Get(ctx, key, configMap); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	content = []byte(configMap.Data[CAKey])
+	if len(parseCertificates(content)) == 0 {
+		return nil, true, &Error{message: fmt.Sprintf("the BMC CA bundle ConfigMap %s has no certificates under %s", name, CAKey)}
+	}
+	return content, true, nil
+}
+
+func parseCertificates(content []byte) (certs []*x509.Certificate) {
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// pinnedCertificate returns the certificate of the BMC in PEM form
+// after checking its fingerprint. The certificate written before is
+// reused while its fingerprint matches, rather than fetched again.
+//
+// The certificate is written as the CA bundle of the host, since the
+// backend only verifies certificates against a CA file, so it must be
+// self-signed and valid for the address of the BMC.
+func (s *Store) pinnedCertificate(host *metal3v1alpha1.BareMetalHost, fingerprint string) ([]byte, error) {
+	existing, err := ioutil.ReadFile(filepath.Join(s.Dir, fileName(host)))
+	if err == nil {
+		if certs := parseCertificates(existing); len(certs) == 1 && Fingerprint(certs[0]) == fingerprint {
+			return existing, checkPinnable(certs[0], host.Spec.BMC.Address)
+		}
+	}
+
+	fetch := s.FetchCertificate
+	if fetch == nil {
+		fetch = fetchCertificate
+	}
+	cert, err := fetch(host.Spec.BMC.Address)
+	if err != nil {
+		return nil, &Error{message: fmt.Sprintf("failed to fetch the certificate of the BMC: %s", err)}
+	}
+	if actual := Fingerprint(cert); actual != fingerprint {
+		return nil, &Error{message: fmt.Sprintf("the certificate of the BMC has fingerprint %s rather than the pinned %s", actual, fingerprint)}
+	}
+	if err := checkPinnable(cert, host.Spec.BMC.Address); err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), nil
+}
+
+// checkPinnable checks that the certificate is self-signed and valid
+// for the host name of the BMC address, so that it verifies against
+// itself when it is used as a CA bundle.
+func checkPinnable(cert *x509.Certificate, address string) error {
+	parsedURL, err := url.Parse(address)
+	if err != nil {
+		return &Error{message: fmt.Sprintf("failed to parse the BMC address %s", address)}
+	}
+	err = cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature)
+	if err == nil {
+		err = cert.VerifyHostname(parsedURL.Hostname())
+	}
+	if err != nil {
+		return &Error{message: fmt.Sprintf("the pinned certificate of the BMC cannot be used, only self-signed certificates "+
+			"valid for the BMC address can be pinned, use caBundleName for the others: %s", err)}
+	}
+	return nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the certificate in the
+// usual form, e.g. "AB:CD:...".
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return normalizeFingerprint(fmt.Sprintf("%X", sum))
+}
+
+func normalizeFingerprint(fingerprint string) string {
+	hex := strings.ToUpper(strings.ReplaceAll(fingerprint, ":", ""))
+	pairs := make([]string, 0, len(hex)/2)
+	for i := 0; i+1 < len(hex); i += 2 {
+		pairs = append(pairs, hex[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
+
+func fetchCertificate(address string) (*x509.Certificate, error) {
+	accessDetails, err := bmc.NewAccessDetails(address, false)
+	if err != nil {
+		return nil, err
+	}
+	parsedURL, err := url.Parse(address)
+	if err != nil || parsedURL.Host == "" || accessDetails.Driver() == "ipmi" || strings.HasSuffix(parsedURL.Scheme, "+http") {
+		return nil, fmt.Errorf("the BMC address %s does not use HTTPS", address)
+	}
+	port := parsedURL.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	// #nosec G402: the certificate is checked against the pinned
+	// fingerprint instead.
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp",
+		net.JoinHostPort(parsedURL.Hostname(), port), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
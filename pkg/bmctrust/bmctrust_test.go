@@ -0,0 +1,272 @@
+package bmctrust
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// bmcServer stands in for a BMC with a self-signed certificate.
+func bmcServer(t *testing.T) (server *httptest.Server, certPEM string) {
+	server = httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	cert := server.Certificate()
+	return server, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+func testHost(address string) *metal3v1alpha1.BareMetalHost {
+	return &metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{Name: "host-0", Namespace: "rack1"},
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			BMC: metal3v1alpha1.BMCDetails{Address: address},
+		},
+	}
+}
+
+func caBundleConfigMap(name, content string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "rack1"},
+		Data:       map[string]string{CAKey: content},
+	}
+}
+
+func newTestStore(t *testing.T, opts Options, objs ...runtime.Object) *Store {
+	if opts.Dir == "" {
+		opts.Dir = t.TempDir()
+	}
+	return New(fakeclient.NewFakeClientWithScheme(scheme.Scheme, objs...), opts)
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestCABundle(t *testing.T) {
+	_, bundle := bmcServer(t)
+	store := newTestStore(t, Options{}, caBundleConfigMap("rack1-ca", bundle))
+	host := testHost("redfish://192.168.122.1")
+	host.Spec.BMC.CABundleName = "rack1-ca"
+
+	status, err := store.Update(context.TODO(), host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &metal3v1alpha1.BMCCertificateStatus{
+		CABundle: "rack1-ca",
+		CAFile:   filepath.Join(store.Dir, "rack1", "host-0.pem"),
+	}, status)
+	assert.Equal(t, bundle, readFile(t, filepath.Join(store.Dir, "rack1", "host-0.pem")))
+
+	// Turning verification off removes the bundle.
+	host.Spec.BMC.CABundleName = ""
+	host.Spec.BMC.DisableCertificateVerification = true
+	status, err = store.Update(context.TODO(), host)
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+	_, err = os.Stat(filepath.Join(store.Dir, "rack1", "host-0.pem"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDefaultCABundle(t *testing.T) {
+	_, bundle := bmcServer(t)
+	store := newTestStore(t, Options{DefaultBundle: "bmc-ca"}, caBundleConfigMap("bmc-ca", bundle))
+	host := testHost("idrac://192.168.122.1")
+
+	status, err := store.Update(context.TODO(), host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "bmc-ca", status.CABundle)
+	assert.Equal(t, filepath.Join(store.Dir, "rack1", "host-0.pem"), status.CAFile)
+
+	// Namespaces without the ConfigMap keep the default CA bundle of
+	// the backend.
+	host.Namespace = "rack2"
+	status, err = store.Update(context.TODO(), host)
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+}
+
+func TestCertificateFingerprint(t *testing.T) {
+	server, certPEM := bmcServer(t)
+	fingerprint := Fingerprint(server.Certificate())
+	store := newTestStore(t, Options{})
+	host := testHost(strings.Replace(server.URL, "https://", "redfish://", 1) + "/redfish/v1/Systems/1")
+	// Fingerprints are accepted without colons and in lower case.
+	host.Spec.BMC.CertificateFingerprint = strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+
+	status, err := store.Update(context.TODO(), host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fingerprint, status.Fingerprint)
+	assert.Equal(t, certPEM, readFile(t, status.CAFile))
+
+	// The certificate written is reused rather than fetched again.
+	server.Close()
+	_, err = store.Update(context.TODO(), host)
+	assert.NoError(t, err)
+
+	host.Spec.BMC.CertificateFingerprint = strings.Repeat("AB:", 31) + "AB"
+	_, err = store.Update(context.TODO(), host)
+	assert.IsType(t, &Error{}, err)
+	assert.Contains(t, err.Error(), "failed to fetch the certificate of the BMC")
+}
+
+func TestCertificateFingerprintMismatch(t *testing.T) {
+	server, _ := bmcServer(t)
+	store := newTestStore(t, Options{})
+	host := testHost(strings.Replace(server.URL, "https://", "redfish://", 1))
+	host.Spec.BMC.CertificateFingerprint = strings.Repeat("AB:", 31) + "AB"
+
+	_, err := store.Update(context.TODO(), host)
+	assert.EqualError(t, err, "the certificate of the BMC has fingerprint "+Fingerprint(server.Certificate())+
+		" rather than the pinned "+host.Spec.BMC.CertificateFingerprint)
+
+	host.Spec.BMC.Address = "ipmi://192.168.122.1"
+	_, err = store.Update(context.TODO(), host)
+	assert.EqualError(t, err, "failed to fetch the certificate of the BMC: the BMC address ipmi://192.168.122.1 does not use HTTPS")
+}
+
+// newCertificate creates a certificate for the IP address, signed by
+// the parent or self-signed when parent is nil.
+func newCertificate(t *testing.T, ip string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: ip},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP(ip)},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestCertificateFingerprintNotPinnable(t *testing.T) {
+	ca, caKey := newCertificate(t, "192.168.122.100", nil, nil)
+	issued, _ := newCertificate(t, "192.168.122.1", ca, caKey)
+	otherAddress, _ := newCertificate(t, "192.168.122.2", nil, nil)
+	selfSigned, _ := newCertificate(t, "192.168.122.1", nil, nil)
+
+	for _, cert := range []*x509.Certificate{issued, otherAddress} {
+		store := newTestStore(t, Options{})
+		store.FetchCertificate = func(address string) (*x509.Certificate, error) {
+			return cert, nil
+		}
+		host := testHost("redfish://192.168.122.1/redfish/v1/Systems/1")
+		host.Spec.BMC.CertificateFingerprint = Fingerprint(cert)
+
+		_, err := store.Update(context.TODO(), host)
+		assert.IsType(t, &Error{}, err)
+		assert.Contains(t, err.Error(), "only self-signed certificates valid for the BMC address can be pinned")
+		_, err = os.Stat(filepath.Join(store.Dir, "rack1", "host-0.pem"))
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	store := newTestStore(t, Options{})
+	store.FetchCertificate = func(address string) (*x509.Certificate, error) {
+		return selfSigned, nil
+	}
+	host := testHost("redfish://192.168.122.1/redfish/v1/Systems/1")
+	host.Spec.BMC.CertificateFingerprint = Fingerprint(selfSigned)
+	_, err := store.Update(context.TODO(), host)
+	assert.NoError(t, err)
+}
+
+func TestValidateOptions(t *testing.T) {
+	assert.NoError(t, Options{}.Validate())
+	assert.NoError(t, Options{Dir: "/shared/bmc-ca"}.Validate())
+	assert.EqualError(t, Options{Dir: "bmc-ca"}.Validate(),
+		"the BMC CA directory bmc-ca must be an absolute path, mounted at the same path for ironic")
+}
+
+func TestUpdateErrors(t *testing.T) {
+	store := newTestStore(t, Options{}, caBundleConfigMap("empty", ""))
+	testCases := []struct {
+		Scenario string
+		BMC      metal3v1alpha1.BMCDetails
+		Error    string
+	}{
+		{
+			Scenario: "both set",
+			BMC:      metal3v1alpha1.BMCDetails{CABundleName: "rack1-ca", CertificateFingerprint: "AB"},
+			Error:    "only one of caBundleName and certificateFingerprint can be set",
+		},
+		{
+			Scenario: "verification disabled",
+			BMC:      metal3v1alpha1.BMCDetails{CABundleName: "rack1-ca", DisableCertificateVerification: true},
+			Error:    "certificate verification is disabled, but a CA bundle or fingerprint is set",
+		},
+		{
+			Scenario: "missing bundle",
+			BMC:      metal3v1alpha1.BMCDetails{CABundleName: "rack1-ca"},
+			Error:    "the BMC CA bundle ConfigMap rack1-ca does not exist",
+		},
+		{
+			Scenario: "empty bundle",
+			BMC:      metal3v1alpha1.BMCDetails{CABundleName: "empty"},
+			Error:    "the BMC CA bundle ConfigMap empty has no certificates under ca.crt",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := testHost("redfish://192.168.122.1")
+			host.Spec.BMC = tc.BMC
+			_, err := store.Update(context.TODO(), host)
+			assert.IsType(t, &Error{}, err)
+			assert.EqualError(t, err, tc.Error)
+		})
+	}
+
+	// Without a directory shared with the backend, the settings
+	// cannot be used.
+	host := testHost("redfish://192.168.122.1")
+	host.Spec.BMC.CABundleName = "rack1-ca"
+	_, err := New(store.Client, Options{}).Update(context.TODO(), host)
+	assert.EqualError(t, err, "BMC CA bundles and fingerprints are not enabled in the operator")
+}
@@ -0,0 +1,210 @@
+// Package credentials looks up the BMC credentials of hosts, which may
+// be kept in Kubernetes secrets or in sources outside of the cluster.
+//
+// The credentialsName of a host names where its credentials are. A
+// plain name is a secret in the namespace of the host, and a name
+// with a prefix, such as "vault:bmc/host-0", is looked up by the
+// resolver registered for the prefix, within the namespace of the host.
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// Resolved holds the credentials found for a host.
+type Resolved struct {
+	Credentials bmc.Credentials
+	// Fallbacks are more credentials to try, in order, when the first
+	// ones are rejected, such as the factory default account of new
+	// hosts.
+	Fallbacks []bmc.Credentials
+
+	// Name is the reference the credentials were found with.
+	Name string
+	// Namespace is the namespace the reference was resolved in.
+	Namespace string
+	// Version changes whenever the credentials change.
+	Version string
+
+	// Secret is the Kubernetes secret holding the credentials, when
+	// they come from one.
+	Secret *corev1.Secret
+}
+
+// AsSecret returns the secret holding the credentials or, when they
+// come from outside of the cluster, a secret without data standing
+// for them, to be recorded in the status of the host.
+func (r *Resolved) AsSecret() *corev1.Secret {
+	if r.Secret != nil {
+		return r.Secret
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            r.Name,
+			Namespace:       r.Namespace,
+			ResourceVersion: r.Version,
+		},
+	}
+}
+
+// Sets returns the credentials followed by the fallbacks.
+func (r *Resolved) Sets() []bmc.Credentials {
+	return append([]bmc.Credentials{r.Credentials}, r.Fallbacks...)
+}
+
+// readFallbacks collects the fallback credentials found by the lookup
+// function, from the username-1 and password-1 keys up to the first
+// missing username.
+func readFallbacks(lookup func(key string) (value string, found bool, err error)) ([]bmc.Credentials, error) {
+	var fallbacks []bmc.Credentials
+	for n := 1; ; n++ {
+		username, found, err := lookup(fmt.Sprintf("username-%d", n))
+		if err != nil || !found {
+			return fallbacks, err
+		}
+		password, _, err := lookup(fmt.Sprintf("password-%d", n))
+		if err != nil {
+			return nil, err
+		}
+		fallbacks = append(fallbacks, bmc.Credentials{Username: username, Password: password})
+	}
+}
+
+// Resolver looks up the BMC credentials of hosts.
+type Resolver interface {
+	// Resolve returns the credentials the reference names for the
+	// host. The reference is the credentialsName of the host, without
+	// the prefix selecting the resolver.
+	Resolve(ctx context.Context, host *metal3v1alpha1.BareMetalHost, ref string) (*Resolved, error)
+}
+
+// namespacedPath returns the path of the credentials a reference names
+// within the namespace of the host, so that hosts can only use the
+// credentials kept for their own namespace. References that are
+// absolute or climb out of the namespace are rejected.
+func namespacedPath(host *metal3v1alpha1.BareMetalHost, ref string) (string, error) {
+	if ref == "" {
+		return "", &EmptyReferenceError{}
+	}
+	if path.IsAbs(ref) {
+		return "", fmt.Errorf("BMC credentials reference %s must be relative to the namespace", ref)
+	}
+	for _, element := range strings.Split(ref, "/") {
+		if element == ".." {
+			return "", fmt.Errorf("BMC credentials reference %s must not contain ..", ref)
+		}
+	}
+	name := path.Clean(ref)
+	if name == "." {
+		return "", &EmptyReferenceError{}
+	}
+	return path.Join(host.Namespace, name), nil
+}
+
+// NotFoundError is returned when there are no credentials under the
+// reference. They may be created later.
+type NotFoundError struct {
+	Reference string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("BMC credentials %s do not exist", e.Reference)
+}
+
+// EmptyReferenceError is returned when the host does not say where its
+// credentials are.
+type EmptyReferenceError struct{}
+
+func (e EmptyReferenceError) Error() string {
+	return "the BMC credentials reference is empty"
+}
+
+// Mux passes each reference on to the resolver registered for its
+// prefix, or to the default resolver when it has none.
+type Mux struct {
+	Default  Resolver
+	prefixed map[string]Resolver
+}
+
+// NewMux returns a Mux resolving references without a prefix with the
+// default resolver.
+func NewMux(defaultResolver Resolver) *Mux {
+	return &Mux{Default: defaultResolver, prefixed: make(map[string]Resolver)}
+}
+
+// Register makes the resolver handle the references starting with the
+// prefix followed by a colon.
+func (m *Mux) Register(prefix string, resolver Resolver) *Mux {
+	m.prefixed[prefix] = resolver
+	return m
+}
+
+// Resolve implements Resolver. The name of the credentials found
+// keeps the prefix.
+func (m *Mux) Resolve(ctx context.Context, host *metal3v1alpha1.BareMetalHost, ref string) (*Resolved, error) {
+	if ref == "" {
+		return nil, &EmptyReferenceError{}
+	}
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) == 1 {
+		return m.Default.Resolve(ctx, host, ref)
+	}
+	resolver, ok := m.prefixed[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("no BMC credentials source %q configured for %s", parts[0], ref)
+	}
+	resolved, err := resolver.Resolve(ctx, host, parts[1])
+	if err != nil {
+		if notFound, ok := err.(*NotFoundError); ok {
+			notFound.Reference = ref
+		}
+		return nil, err
+	}
+	resolved.Name = ref
+	return resolved, nil
+}
+
+// Options select the sources of credentials used besides secrets.
+type Options struct {
+	// VaultAddress enables the "vault:" references.
+	VaultAddress   string
+	VaultToken     string
+	VaultTokenFile string
+	VaultMount     string
+	// Dir enables the "file:" references, read from this directory.
+	Dir string
+	// TemplateConfigMap names the ConfigMap holding the template used
+	// for the hosts without credentialsName in each namespace.
+	TemplateConfigMap string
+}
+
+// New returns the resolver reading secrets with the client and the
+// other sources enabled by the options.
+func New(c client.Reader, opts Options) Resolver {
+	mux := NewMux(&SecretResolver{Client: c})
+	if opts.VaultAddress != "" {
+		mux.Register("vault", &VaultResolver{
+			Address:   opts.VaultAddress,
+			Token:     opts.VaultToken,
+			TokenFile: opts.VaultTokenFile,
+			Mount:     opts.VaultMount,
+		})
+	}
+	if opts.Dir != "" {
+		mux.Register("file", &FileResolver{Dir: opts.Dir})
+	}
+	if opts.TemplateConfigMap != "" {
+		return &TemplateResolver{Client: c, ConfigMapName: opts.TemplateConfigMap, Next: mux}
+	}
+	return mux
+}
@@ -0,0 +1,302 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+func testHost() *metal3v1alpha1.BareMetalHost {
+	return &metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{Name: "host-0", Namespace: "rack1"},
+	}
+}
+
+// vaultServer stands in for the KV version 2 API of Vault, serving the
+// secrets under the "secret" mount.
+func vaultServer(t *testing.T, token string, secrets map[string]map[string]interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		data, ok := secrets[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		body := map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     data,
+				"metadata": map[string]interface{}{"version": 3},
+			},
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultResolver(t *testing.T) {
+	server := vaultServer(t, "s.token", map[string]map[string]interface{}{
+		"/v1/secret/data/rack1/bmc/host-0": {"username": "admin", "password": "pa55"},
+		"/v1/secret/data/rack2/bmc/host-0": {"username": "other", "password": "other"},
+	})
+
+	resolver := &VaultResolver{Address: server.URL, Token: "s.token"}
+	resolved, err := resolver.Resolve(context.TODO(), testHost(), "bmc/host-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bmc.Credentials{Username: "admin", Password: "pa55"}, resolved.Credentials)
+	assert.Equal(t, "3", resolved.Version)
+	assert.Nil(t, resolved.Secret)
+
+	resolved, err = resolver.Resolve(context.TODO(), testHost(), "bmc//./host-0/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "admin", resolved.Credentials.Username)
+
+	_, err = resolver.Resolve(context.TODO(), testHost(), "bmc/host-1")
+	assert.IsType(t, &NotFoundError{}, err)
+
+	// The secrets of other namespaces cannot be reached.
+	for _, ref := range []string{"../rack2/bmc/host-0", "bmc/../../rack2/bmc/host-0", "/rack2/bmc/host-0"} {
+		_, err = resolver.Resolve(context.TODO(), testHost(), ref)
+		if assert.Error(t, err, ref) {
+			assert.Contains(t, err.Error(), "BMC credentials reference", ref)
+		}
+	}
+
+	resolver.Token = "wrong"
+	_, err = resolver.Resolve(context.TODO(), testHost(), "bmc/host-0")
+	assert.EqualError(t, err, "failed to read BMC credentials rack1/bmc/host-0 from vault: 403 Forbidden permission denied")
+}
+
+func TestVaultResolverTokenFile(t *testing.T) {
+	server := vaultServer(t, "s.renewed", map[string]map[string]interface{}{
+		"/v1/kv/data/rack1/host-0": {"username": "admin", "password": "pa55"},
+	})
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("s.renewed\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := &VaultResolver{Address: server.URL, TokenFile: tokenFile, Mount: "kv"}
+	resolved, err := resolver.Resolve(context.TODO(), testHost(), "host-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "admin", resolved.Credentials.Username)
+}
+
+func writeCredentials(t *testing.T, dir, name, username, password string) {
+	if err := os.MkdirAll(filepath.Join(dir, name), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range map[string]string{"username": username, "password": password} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name, file), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	dir := t.TempDir()
+	writeCredentials(t, dir, "rack1/host-0", "admin\n", "pa55\n")
+	writeCredentials(t, dir, "rack2/host-0", "other\n", "other\n")
+	resolver := &FileResolver{Dir: dir}
+
+	resolved, err := resolver.Resolve(context.TODO(), testHost(), "host-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, bmc.Credentials{Username: "admin", Password: "pa55"}, resolved.Credentials)
+	version := resolved.Version
+
+	writeCredentials(t, dir, "rack1/host-0", "admin\n", "changed\n")
+	resolved, err = resolver.Resolve(context.TODO(), testHost(), "host-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, version, resolved.Version)
+
+	_, err = resolver.Resolve(context.TODO(), testHost(), "host-1")
+	assert.IsType(t, &NotFoundError{}, err)
+
+	for _, ref := range []string{"../rack2/host-0", "host-0/../../rack2/host-0", "/etc", ".", ".."} {
+		_, err = resolver.Resolve(context.TODO(), testHost(), ref)
+		assert.Error(t, err, ref)
+		assert.NotEqual(t, &NotFoundError{Reference: ref}, err, ref)
+	}
+}
+
+func testSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "host-0-bmc", Namespace: "rack1"},
+		Data: map[string][]byte{
+			"username": []byte("root"),
+			"password": []byte("calvin"),
+		},
+	}
+}
+
+func TestMux(t *testing.T) {
+	dir := t.TempDir()
+	writeCredentials(t, dir, "rack1/host-0", "admin", "pa55")
+	c := fakeclient.NewFakeClientWithScheme(scheme.Scheme, testSecret())
+	resolver := New(c, Options{Dir: dir})
+
+	resolved, err := resolver.Resolve(context.TODO(), testHost(), "host-0-bmc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "root", resolved.Credentials.Username)
+	assert.Equal(t, resolved.Secret, resolved.AsSecret())
+
+	resolved, err = resolver.Resolve(context.TODO(), testHost(), "file:host-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "admin", resolved.Credentials.Username)
+	secret := resolved.AsSecret()
+	assert.Equal(t, "file:host-0", secret.Name)
+	assert.Equal(t, "rack1", secret.Namespace)
+	assert.Equal(t, resolved.Version, secret.ResourceVersion)
+
+	_, err = resolver.Resolve(context.TODO(), testHost(), "file:host-1")
+	assert.EqualError(t, err, "BMC credentials file:host-1 do not exist")
+
+	_, err = resolver.Resolve(context.TODO(), testHost(), "missing")
+	assert.EqualError(t, err, "BMC credentials rack1/missing do not exist")
+
+	_, err = resolver.Resolve(context.TODO(), testHost(), "vault:host-0")
+	assert.EqualError(t, err, `no BMC credentials source "vault" configured for vault:host-0`)
+
+	_, err = resolver.Resolve(context.TODO(), testHost(), "")
+	assert.IsType(t, &EmptyReferenceError{}, err)
+}
+
+func TestTemplateResolver(t *testing.T) {
+	server := vaultServer(t, "s.token", map[string]map[string]interface{}{
+		"/v1/secret/data/rack1/bmc/host-0": {"username": "admin", "password": "pa55"},
+	})
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "bmc-credentials", Namespace: "rack1"},
+		Data:       map[string]string{TemplateKey: "vault:bmc/{{ .Name }}"},
+	}
+	objects := []runtime.Object{configMap, testSecret()}
+	c := fakeclient.NewFakeClientWithScheme(scheme.Scheme, objects...)
+	resolver := New(c, Options{
+		VaultAddress:      server.URL,
+		VaultToken:        "s.token",
+		TemplateConfigMap: "bmc-credentials",
+	})
+
+	resolved, err := resolver.Resolve(context.TODO(), testHost(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "vault:bmc/host-0", resolved.Name)
+	assert.Equal(t, "pa55", resolved.Credentials.Password)
+
+	// A credentialsName given by the host wins over the template.
+	resolved, err = resolver.Resolve(context.TODO(), testHost(), "host-0-bmc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "calvin", resolved.Credentials.Password)
+
+	// Namespaces without template have no default.
+	host := testHost()
+	host.Namespace = "rack2"
+	_, err = resolver.Resolve(context.TODO(), host, "")
+	assert.IsType(t, &EmptyReferenceError{}, err)
+
+	configMap.Data[TemplateKey] = "{{ .Spec.Missing }}"
+	c = fakeclient.NewFakeClientWithScheme(scheme.Scheme, configMap)
+	resolver = New(c, Options{TemplateConfigMap: "bmc-credentials"})
+	_, err = resolver.Resolve(context.TODO(), testHost(), "")
+	assert.Error(t, err)
+}
+
+func TestFallbacks(t *testing.T) {
+	expected := []bmc.Credentials{
+		{Username: "root", Password: "calvin"},
+		{Username: "ADMIN", Password: "ADMIN"},
+		{Username: "USERID", Password: "PASSW0RD"},
+	}
+
+	secret := testSecret()
+	secret.Data["username-1"] = []byte("ADMIN")
+	secret.Data["password-1"] = []byte("ADMIN")
+	secret.Data["username-2"] = []byte("USERID")
+	secret.Data["password-2"] = []byte("PASSW0RD")
+	// Sets after a missing one are ignored.
+	secret.Data["username-4"] = []byte("ignored")
+	c := fakeclient.NewFakeClientWithScheme(scheme.Scheme, secret)
+	resolved, err := (&SecretResolver{Client: c}).Resolve(context.TODO(), testHost(), "host-0-bmc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, resolved.Sets())
+
+	server := vaultServer(t, "s.token", map[string]map[string]interface{}{
+		"/v1/secret/data/rack1/host-0": {
+			"username": "root", "password": "calvin",
+			"username-1": "ADMIN", "password-1": "ADMIN",
+			"username-2": "USERID", "password-2": "PASSW0RD",
+		},
+	})
+	resolved, err = (&VaultResolver{Address: server.URL, Token: "s.token"}).Resolve(context.TODO(), testHost(), "host-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, resolved.Sets())
+
+	dir := t.TempDir()
+	writeCredentials(t, dir, "rack1/host-0", "root\n", "calvin\n")
+	for file, content := range map[string]string{
+		"username-1": "ADMIN\n", "password-1": "ADMIN\n",
+		"username-2": "USERID\n", "password-2": "PASSW0RD\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "rack1", "host-0", file), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolver := &FileResolver{Dir: dir}
+	resolved, err = resolver.Resolve(context.TODO(), testHost(), "host-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expected, resolved.Sets())
+
+	// The fallbacks are part of the version of the files.
+	version := resolved.Version
+	if err := ioutil.WriteFile(filepath.Join(dir, "rack1", "host-0", "password-2"), []byte("changed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	resolved, err = resolver.Resolve(context.TODO(), testHost(), "host-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, version, resolved.Version)
+}
@@ -0,0 +1,85 @@
+package credentials
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// FileResolver reads the credentials from files, such as the ones
+// mounted in the pod by the Secrets Store CSI driver. The credentials
+// of a reference are in the username and password files of the
+// directory of that name under the directory of the namespace of the
+// host, e.g. <Dir>/<namespace>/host-0, and the fallbacks in its
+// username-N and password-N files.
+type FileResolver struct {
+	Dir string
+}
+
+// Resolve implements Resolver.
+func (r *FileResolver) Resolve(ctx context.Context, host *metal3v1alpha1.BareMetalHost, ref string) (*Resolved, error) {
+	name, err := namespacedPath(host, ref)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(r.Dir, filepath.FromSlash(name))
+
+	read := func(key string) ([]byte, error) {
+		content, err := ioutil.ReadFile(filepath.Join(dir, key))
+		if os.IsNotExist(err) {
+			return nil, &NotFoundError{Reference: ref}
+		}
+		return content, err
+	}
+	username, err := read("username")
+	if err != nil {
+		return nil, err
+	}
+	password, err := read("password")
+	if err != nil {
+		return nil, err
+	}
+
+	fallbacks, err := readFallbacks(func(key string) (string, bool, error) {
+		content, err := read(key)
+		if _, missing := err.(*NotFoundError); missing {
+			return "", false, nil
+		}
+		return trimNewline(content), err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The files are replaced when the credentials change, so their
+	// content is what tells the versions apart.
+	hash := sha256.New()
+	hash.Write(username)
+	hash.Write([]byte{0})
+	hash.Write(password)
+	for _, fallback := range fallbacks {
+		fmt.Fprintf(hash, "\x00%s\x00%s", fallback.Username, fallback.Password)
+	}
+
+	return &Resolved{
+		Credentials: bmc.Credentials{
+			Username: trimNewline(username),
+			Password: trimNewline(password),
+		},
+		Fallbacks: fallbacks,
+		Name:      ref,
+		Namespace: host.Namespace,
+		Version:   fmt.Sprintf("%x", hash.Sum(nil))[:16],
+	}, nil
+}
+
+func trimNewline(content []byte) string {
+	return strings.TrimRight(string(content), "\r\n")
+}
@@ -0,0 +1,47 @@
+package credentials
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// SecretResolver reads the credentials from the username and password
+// keys of a secret in the namespace of the host, and the fallbacks from
+// the username-N and password-N keys.
+type SecretResolver struct {
+	Client client.Reader
+}
+
+// Resolve implements Resolver.
+func (r *SecretResolver) Resolve(ctx context.Context, host *metal3v1alpha1.BareMetalHost, ref string) (*Resolved, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: host.Namespace, Name: ref}
+	if err := r.Client.Get(ctx, key, secret); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, &NotFoundError{Reference: key.String()}
+		}
+		return nil, err
+	}
+	fallbacks, _ := readFallbacks(func(key string) (string, bool, error) {
+		value, found := secret.Data[key]
+		return string(value), found, nil
+	})
+	return &Resolved{
+		Credentials: bmc.Credentials{
+			Username: string(secret.Data["username"]),
+			Password: string(secret.Data["password"]),
+		},
+		Fallbacks: fallbacks,
+		Name:      secret.Name,
+		Namespace: secret.Namespace,
+		Version:   secret.ResourceVersion,
+		Secret:    secret,
+	}, nil
+}
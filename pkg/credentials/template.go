@@ -0,0 +1,64 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// TemplateKey is the key of the credentials template in the ConfigMap
+// read by TemplateResolver.
+const TemplateKey = "credentialsName"
+
+// TemplateResolver gives the hosts that do not say where their
+// credentials are a reference built from a template, so that all the
+// hosts of a namespace can follow the same convention, e.g.
+// "vault:bmc/{{ .Name }}". The template is the
+// credentialsName key of a ConfigMap in the namespace of the host and
+// is executed with the host. The reference is resolved by Next.
+type TemplateResolver struct {
+	Client        client.Reader
+	ConfigMapName string
+	Next          Resolver
+}
+
+// Resolve implements Resolver.
+func (r *TemplateResolver) Resolve(ctx context.Context, host *metal3v1alpha1.BareMetalHost, ref string) (*Resolved, error) {
+	if ref != "" {
+		return r.Next.Resolve(ctx, host, ref)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: host.Namespace, Name: r.ConfigMapName}
+	if err := r.Client.Get(ctx, key, configMap); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, &EmptyReferenceError{}
+		}
+		return nil, err
+	}
+	text, ok := configMap.Data[TemplateKey]
+	if !ok {
+		return nil, &EmptyReferenceError{}
+	}
+
+	tmpl, err := template.New(key.String()).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BMC credentials template in %s: %w", key, err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, host); err != nil {
+		return nil, fmt.Errorf("invalid BMC credentials template in %s: %w", key, err)
+	}
+	if rendered.Len() == 0 {
+		return nil, &EmptyReferenceError{}
+	}
+	return r.Next.Resolve(ctx, host, rendered.String())
+}
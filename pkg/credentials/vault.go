@@ -0,0 +1,121 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// DefaultVaultMount is the mount path of the KV secrets engine used
+// when none is given.
+const DefaultVaultMount = "secret"
+
+// VaultResolver reads the credentials from the username and password
+// fields, and the fallbacks from the username-N and password-N fields,
+// of a secret in a version 2 KV secrets engine of Vault, or of any
+// server implementing the same API.
+type VaultResolver struct {
+	// Address is the URL of the server, e.g. https://vault:8200.
+	Address string
+	// Token authenticates the requests.
+	Token string
+	// TokenFile, when set, holds the token instead. It is read for
+	// each request so that it can be renewed, e.g. by a Vault agent.
+	TokenFile string
+	// Mount is the mount path of the KV secrets engine.
+	Mount string
+	// Client sends the requests. http.DefaultClient is used when nil.
+	Client *http.Client
+}
+
+type vaultResponse struct {
+	Data struct {
+		Data     map[string]interface{} `json:"data"`
+		Metadata struct {
+			Version int `json:"version"`
+		} `json:"metadata"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// Resolve implements Resolver. The reference is the path of the secret
+// under the directory of the namespace of the host in the mount, e.g.
+// bmc/host-0 reads <mount>/data/<namespace>/bmc/host-0.
+func (r *VaultResolver) Resolve(ctx context.Context, host *metal3v1alpha1.BareMetalHost, ref string) (*Resolved, error) {
+	mount := r.Mount
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+	path, err := namespacedPath(host, ref)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s",
+		strings.TrimRight(r.Address, "/"), strings.Trim(mount, "/"), (&url.URL{Path: path}).EscapedPath())
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := r.Token
+	if r.TokenFile != "" {
+		content, err := ioutil.ReadFile(r.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the vault token: %w", err)
+		}
+		token = strings.TrimSpace(string(content))
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read BMC credentials %s from vault: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to parse BMC credentials %s from vault: %w", path, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, &NotFoundError{Reference: path}
+	default:
+		return nil, fmt.Errorf("failed to read BMC credentials %s from vault: %s %s",
+			path, resp.Status, strings.Join(body.Errors, ", "))
+	}
+
+	// A deleted version is returned without data.
+	if body.Data.Data == nil {
+		return nil, &NotFoundError{Reference: path}
+	}
+	username, _ := body.Data.Data["username"].(string)
+	password, _ := body.Data.Data["password"].(string)
+	fallbacks, _ := readFallbacks(func(key string) (string, bool, error) {
+		value, found := body.Data.Data[key]
+		text, _ := value.(string)
+		return text, found, nil
+	})
+	return &Resolved{
+		Credentials: bmc.Credentials{Username: username, Password: password},
+		Fallbacks:   fallbacks,
+		Name:        path,
+		Namespace:   host.Namespace,
+		Version:     fmt.Sprintf("%d", body.Data.Metadata.Version),
+	}, nil
+}
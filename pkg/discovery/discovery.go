@@ -0,0 +1,190 @@
+package discovery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxAddresses is the largest number of addresses scanned for a
+// discovery, to keep scans from running for days.
+const MaxAddresses = 65536
+
+// scanWorkers is the number of endpoints probed at the same time.
+const scanWorkers = 64
+
+// Protocol is a protocol the BMCs are probed with.
+type Protocol string
+
+const (
+	// Redfish BMCs answer HTTPS requests for /redfish/v1
+	Redfish Protocol = "redfish"
+	// IPMI BMCs answer RMCP presence pings
+	IPMI Protocol = "ipmi"
+)
+
+// Endpoint is an address and port where a BMC may answer.
+type Endpoint struct {
+	IP       string
+	Port     int
+	Protocol Protocol
+}
+
+func (e Endpoint) String() string {
+	return fmt.Sprintf("%s://%s", e.Protocol, net.JoinHostPort(e.IP, strconv.Itoa(e.Port)))
+}
+
+// Addresses lists the host addresses of the networks, leaving out the
+// network and broadcast addresses of IPv4 networks.
+func Addresses(networks []string) ([]string, error) {
+	addresses := []string{}
+	for _, network := range networks {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %s", network, err)
+		}
+		ones, bits := ipNet.Mask.Size()
+		if bits-ones > 16 || len(addresses)+(1<<uint(bits-ones)) > MaxAddresses {
+			return nil, fmt.Errorf("the networks hold more than %d addresses", MaxAddresses)
+		}
+
+		ip := ipNet.IP
+		if ip.To4() != nil {
+			ip = ip.To4()
+		}
+		first, last := 0, 1<<uint(bits-ones)
+		if bits == 32 && bits-ones > 1 {
+			first, last = 1, last-1
+		}
+		for i := 0; i < last; i++ {
+			if i >= first {
+				addresses = append(addresses, ip.String())
+			}
+			ip = next(ip)
+		}
+	}
+	return addresses, nil
+}
+
+// next returns the address following ip.
+func next(ip net.IP) net.IP {
+	result := make(net.IP, len(ip))
+	copy(result, ip)
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i]++
+		if result[i] != 0 {
+			break
+		}
+	}
+	return result
+}
+
+// Endpoints lists the endpoints probed on each address, the Redfish
+// ones first.
+func Endpoints(addresses []string, redfishPorts, ipmiPorts []int) []Endpoint {
+	endpoints := []Endpoint{}
+	for _, address := range addresses {
+		for _, port := range redfishPorts {
+			endpoints = append(endpoints, Endpoint{IP: address, Port: port, Protocol: Redfish})
+		}
+		for _, port := range ipmiPorts {
+			endpoints = append(endpoints, Endpoint{IP: address, Port: port, Protocol: IPMI})
+		}
+	}
+	return endpoints
+}
+
+// Prober tells whether the answer of an endpoint matches the
+// fingerprint of a BMC. No credentials are sent to the endpoint.
+type Prober func(endpoint Endpoint, timeout time.Duration) bool
+
+// Scan probes the endpoints concurrently, and returns those where a
+// BMC answers in the order they were given. progress, when set, is
+// called with the number of endpoints probed after each probe. The
+// scan stops early with the error of the context when it is done.
+func Scan(ctx context.Context, endpoints []Endpoint, probe Prober, timeout time.Duration, progress func(probed int)) ([]Endpoint, error) {
+	answered := make([]bool, len(endpoints))
+	indexes := make(chan int)
+	var lock sync.Mutex
+	probed := 0
+	var wg sync.WaitGroup
+	for w := 0; w < scanWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				answered[i] = probe(endpoints[i], timeout)
+				lock.Lock()
+				probed++
+				if progress != nil {
+					progress(probed)
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	var err error
+	for i := 0; i < len(endpoints) && err == nil; i++ {
+		select {
+		case <-ctx.Done():
+		case indexes <- i:
+		}
+		err = ctx.Err()
+	}
+	close(indexes)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	found := []Endpoint{}
+	for i, endpoint := range endpoints {
+		if answered[i] {
+			found = append(found, endpoint)
+		}
+	}
+	return found, nil
+}
+
+// HostName returns the name of the host of a system with the given
+// serial number, or an empty string when the serial number holds no
+// character usable in a name. When the serial number cannot be used
+// as it is, the name ends with a hash of it, so that serial numbers
+// differing only in the characters replaced or cut off do not get the
+// same name.
+func HostName(serialNumber string) string {
+	serialNumber = strings.TrimSpace(serialNumber)
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, serialNumber)
+	if strings.Trim(name, "-.") == "" {
+		return ""
+	}
+	if name == strings.ToLower(serialNumber) && len(name) <= 63 && strings.Trim(name, "-.") == name {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(serialNumber))
+	suffix := hex.EncodeToString(sum[:])[:8]
+	if len(name) > 63-len(suffix)-1 {
+		name = name[:63-len(suffix)-1]
+	}
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		return suffix
+	}
+	return name + "-" + suffix
+}
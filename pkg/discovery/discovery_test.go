@@ -0,0 +1,122 @@
+package discovery
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddresses(t *testing.T) {
+	testCases := []struct {
+		Scenario  string
+		Networks  []string
+		Addresses []string
+		Error     string
+	}{
+		{
+			Scenario:  "ipv4",
+			Networks:  []string{"192.168.111.0/30"},
+			Addresses: []string{"192.168.111.1", "192.168.111.2"},
+		},
+		{
+			Scenario:  "ipv4 point to point",
+			Networks:  []string{"192.168.111.8/31"},
+			Addresses: []string{"192.168.111.8", "192.168.111.9"},
+		},
+		{
+			Scenario:  "single address",
+			Networks:  []string{"192.168.111.20/32", "10.0.0.255/32"},
+			Addresses: []string{"192.168.111.20", "10.0.0.255"},
+		},
+		{
+			Scenario:  "across bytes",
+			Networks:  []string{"10.0.0.255/23"},
+			Addresses: nil,
+		},
+		{
+			Scenario:  "ipv6",
+			Networks:  []string{"fd00::fe/127"},
+			Addresses: []string{"fd00::fe", "fd00::ff"},
+		},
+		{
+			Scenario: "invalid",
+			Networks: []string{"192.168.111.0"},
+			Error:    `invalid network "192.168.111.0": invalid CIDR address: 192.168.111.0`,
+		},
+		{
+			Scenario: "too large",
+			Networks: []string{"10.0.0.0/16", "10.1.0.0/30"},
+			Error:    "the networks hold more than 65536 addresses",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			addresses, err := Addresses(tc.Networks)
+			if tc.Error != "" {
+				assert.EqualError(t, err, tc.Error)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.Addresses == nil {
+				assert.Len(t, addresses, 510)
+				assert.Equal(t, "10.0.0.1", addresses[0])
+				assert.Equal(t, "10.0.1.0", addresses[255])
+				assert.Equal(t, "10.0.1.254", addresses[509])
+				return
+			}
+			assert.Equal(t, tc.Addresses, addresses)
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	endpoints := Endpoints([]string{"192.168.111.1", "192.168.111.2", "192.168.111.3"}, []int{443}, []int{623})
+	assert.Len(t, endpoints, 6)
+	assert.Equal(t, "redfish://192.168.111.1:443", endpoints[0].String())
+	assert.Equal(t, "ipmi://192.168.111.1:623", endpoints[1].String())
+
+	probed := 0
+	found, err := Scan(context.TODO(), endpoints, func(endpoint Endpoint, timeout time.Duration) bool {
+		return endpoint.IP != "192.168.111.2" && (endpoint.Protocol == IPMI || endpoint.IP == "192.168.111.3")
+	}, time.Second, func(n int) { probed = n })
+	assert.NoError(t, err)
+	assert.Equal(t, 6, probed)
+	assert.Equal(t, []Endpoint{
+		{IP: "192.168.111.1", Port: 623, Protocol: IPMI},
+		{IP: "192.168.111.3", Port: 443, Protocol: Redfish},
+		{IP: "192.168.111.3", Port: 623, Protocol: IPMI},
+	}, found)
+}
+
+func TestScanCancel(t *testing.T) {
+	endpoints := Endpoints([]string{"192.168.111.1", "192.168.111.2"}, []int{443}, []int{623})
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	found, err := Scan(ctx, endpoints, func(endpoint Endpoint, timeout time.Duration) bool {
+		return true
+	}, time.Second, nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, found)
+}
+
+func TestHostName(t *testing.T) {
+	assert.Equal(t, "cz12345678", HostName("CZ12345678"))
+	assert.Equal(t, "cz12345678", HostName(" CZ12345678 "))
+	assert.Equal(t, "vmware-42-1d-9c-e7e25886", HostName(" VMware-42 1d 9c "))
+	assert.Equal(t, "s123-531a2595", HostName("#S123#"))
+	assert.Equal(t, "", HostName("  "))
+	assert.Equal(t, "", HostName("#"))
+
+	long := "A123456789012345678901234567890123456789012345678901234567890123456789"
+	assert.Len(t, HostName(long), 63)
+	assert.NotEqual(t, HostName(long), HostName(long+"0"), "truncated serial numbers collide")
+	assert.NotEqual(t, HostName("VMware-42 1d"), HostName("VMware-42#1d"), "replaced characters collide")
+	assert.Equal(t, "a3f16678", HostName(strings.Repeat("-", 72)+"A"))
+}
@@ -0,0 +1,130 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish"
+)
+
+// ipmitool is the command run to read the FRU data of IPMI BMCs.
+var ipmitool = "ipmitool"
+
+// Identity describes a BMC found by a scan and the system it manages.
+type Identity struct {
+	// Address is the BMC address of the host
+	Address        string
+	SerialNumber   string
+	Vendor         string
+	Model          string
+	BootMACAddress string
+}
+
+// Identifier logs into the BMC at an endpoint to identify it.
+type Identifier func(endpoint Endpoint, creds bmc.Credentials, disableCertificateVerification bool) (*Identity, error)
+
+// Identify is an Identifier detecting the type of Redfish BMCs from
+// their service root, and reading the FRU data of IPMI BMCs with
+// ipmitool.
+func Identify(endpoint Endpoint, creds bmc.Credentials, disableCertificateVerification bool) (*Identity, error) {
+	switch endpoint.Protocol {
+	case Redfish:
+		return identifyRedfish(endpoint, creds, disableCertificateVerification)
+	case IPMI:
+		return identifyIPMI(endpoint, creds)
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", endpoint.Protocol)
+	}
+}
+
+// address returns the BMC address of the endpoint for the BMC type,
+// leaving out the default port.
+func (e Endpoint) address(bmcType string, defaultPort int) string {
+	host := e.IP
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if e.Port != defaultPort {
+		host = net.JoinHostPort(e.IP, strconv.Itoa(e.Port))
+	}
+	return fmt.Sprintf("%s://%s", bmcType, host)
+}
+
+func identifyRedfish(endpoint Endpoint, creds bmc.Credentials, disableCertificateVerification bool) (*Identity, error) {
+	bmcType := redfish.AutoDetectType
+	if redfishScheme != "https" {
+		bmcType += "+" + redfishScheme
+	}
+	found, err := redfish.Identify(endpoint.address(bmcType, metal3v1alpha1.DefaultRedfishPort), creds, disableCertificateVerification, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		Address:        found.ResolvedAddress,
+		SerialNumber:   found.SerialNumber,
+		Vendor:         found.Vendor,
+		Model:          found.Model,
+		BootMACAddress: found.BootMACAddress,
+	}, nil
+}
+
+func identifyIPMI(endpoint Endpoint, creds bmc.Credentials) (*Identity, error) {
+	cmdArgs := []string{"-I", "lanplus", "-H", endpoint.IP, "-p", strconv.Itoa(endpoint.Port),
+		"-U", creds.Username, "-E", "fru", "print", "0"}
+
+	// #nosec G204: the arguments are not passed through a shell
+	cmd := exec.Command(ipmitool, cmdArgs...)
+	// The password is passed in the environment with -E rather than
+	// on the command line, where other processes could read it.
+	cmd.Env = append(os.Environ(), "IPMI_PASSWORD="+creds.Password)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ipmitool fru failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	fru := parseFRU(stdout.String())
+	return &Identity{
+		Address:      endpoint.address("ipmi", metal3v1alpha1.DefaultIPMIPort),
+		SerialNumber: firstOf(fru, "Product Serial", "Board Serial", "Chassis Serial"),
+		Vendor:       firstOf(fru, "Product Manufacturer", "Board Mfg"),
+		Model:        firstOf(fru, "Product Name", "Board Product"),
+	}, nil
+}
+
+// parseFRU reads the fields of the output of ipmitool fru print, e.g.
+//
+//	Board Mfg             : Supermicro
+//	Product Serial        : S12345678
+func parseFRU(output string) map[string]string {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		if _, ok := fields[key]; !ok {
+			fields[key] = strings.TrimSpace(parts[1])
+		}
+	}
+	return fields
+}
+
+func firstOf(fields map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := fields[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package discovery
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
+)
+
+const fruPrint = `FRU Device Description : Builtin FRU Device (ID 0)
+ Chassis Type          : Rack Mount Chassis
+ Chassis Serial        : C12345
+ Board Mfg             : Supermicro
+ Board Product         : X11DPi-N
+ Board Serial          : B67890
+ Product Manufacturer  : Supermicro
+ Product Name          : SYS-1029P-WTR
+ Product Serial        : S12345678
+`
+
+// fakeIPMITool installs a script standing in for ipmitool, which logs
+// its arguments to the returned file and prints the FRU data.
+func fakeIPMITool(t *testing.T) (logFile string) {
+	dir, err := ioutil.TempDir("", "ipmitool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	logFile = filepath.Join(dir, "log")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$IPMI_PASSWORD $*" >> %[1]s
+if [ "$IPMI_PASSWORD" != "password" ]; then
+  echo "Error: Unable to establish IPMI v2 / RMCP+ session" >&2
+  exit 1
+fi
+cat <<EOT
+%[2]sEOT
+`, logFile, fruPrint)
+	path := filepath.Join(dir, "ipmitool")
+	if err := ioutil.WriteFile(path, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	original := ipmitool
+	ipmitool = path
+	t.Cleanup(func() { ipmitool = original })
+	return logFile
+}
+
+func TestIdentifyIPMI(t *testing.T) {
+	logFile := fakeIPMITool(t)
+
+	identity, err := Identify(Endpoint{IP: "192.168.111.20", Port: 623, Protocol: IPMI},
+		bmc.Credentials{Username: "admin", Password: "password"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &Identity{
+		Address:      "ipmi://192.168.111.20",
+		SerialNumber: "S12345678",
+		Vendor:       "Supermicro",
+		Model:        "SYS-1029P-WTR",
+	}, identity)
+
+	identity, err = Identify(Endpoint{IP: "192.168.111.20", Port: 6230, Protocol: IPMI},
+		bmc.Credentials{Username: "admin", Password: "password"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "ipmi://192.168.111.20:6230", identity.Address)
+
+	_, err = Identify(Endpoint{IP: "192.168.111.20", Port: 623, Protocol: IPMI},
+		bmc.Credentials{Username: "admin", Password: "wrong"}, false)
+	assert.EqualError(t, err, "ipmitool fru failed: exit status 1: Error: Unable to establish IPMI v2 / RMCP+ session")
+
+	log, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	assert.Equal(t, "password -I lanplus -H 192.168.111.20 -p 623 -U admin -E fru print 0", lines[0])
+}
+
+func TestParseFRU(t *testing.T) {
+	fru := parseFRU("Board Mfg : Quanta\nBoard Serial : QTF1234\nBoard Extra : a:b\n")
+	assert.Equal(t, "QTF1234", firstOf(fru, "Product Serial", "Board Serial", "Chassis Serial"))
+	assert.Equal(t, "Quanta", firstOf(fru, "Product Manufacturer", "Board Mfg"))
+	assert.Equal(t, "a:b", fru["Board Extra"])
+	assert.Equal(t, "", firstOf(fru, "Product Name"))
+}
+
+func TestIdentifyRedfish(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+	mock.Vendor = "Dell"
+	redfishScheme = "http"
+	defer func() { redfishScheme = "https" }()
+
+	address := strings.TrimPrefix(mock.Address("redfish+http"), "redfish+http://")
+	endpoint := endpointOf(t, strings.SplitN(address, "/", 2)[0], Redfish)
+
+	identity, err := Identify(endpoint, bmc.Credentials{Username: "admin", Password: "password"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &Identity{
+		Address:        mock.Address("idrac-virtualmedia+http"),
+		SerialNumber:   "MS-0001",
+		Vendor:         "Dell",
+		BootMACAddress: "52:54:00:aa:bb:01",
+	}, identity)
+
+	_, err = Identify(endpoint, bmc.Credentials{Username: "admin", Password: "wrong"}, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package discovery
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// redfishScheme is the scheme of the Redfish services probed.
+var redfishScheme = "https"
+
+// rmcpPresencePing is an ASF presence ping carried by RMCP, which IPMI
+// BMCs answer with a presence pong.
+var rmcpPresencePing = []byte{
+	0x06, 0x00, 0xff, 0x06, // RMCP version 1.0, no ACK, ASF class
+	0x00, 0x00, 0x11, 0xbe, // ASF IANA enterprise number
+	0x80, 0x00, 0x00, 0x00, // presence ping, tag, reserved, no data
+}
+
+// asfPresencePong is the type of the ASF message answering a ping.
+const asfPresencePong = 0x40
+
+// ipmiSupported is the bit of the supported entities of a presence
+// pong set by IPMI BMCs, found after the IANA enterprise number and
+// OEM field of the pong.
+const (
+	supportedEntitiesOffset = 20
+	ipmiSupported           = 0x80
+)
+
+// Probe is a Prober probing Redfish endpoints with ProbeRedfish and
+// IPMI endpoints with ProbeIPMI.
+func Probe(endpoint Endpoint, timeout time.Duration) bool {
+	switch endpoint.Protocol {
+	case Redfish:
+		return ProbeRedfish(endpoint, timeout)
+	case IPMI:
+		return ProbeIPMI(endpoint, timeout)
+	default:
+		return false
+	}
+}
+
+// ProbeRedfish tells whether a Redfish service answers for the
+// service root at the endpoint. The service root is readable without
+// credentials, and has to report the Redfish version for the endpoint
+// to be taken for a BMC, so that credentials are not sent to other
+// HTTPS services.
+func ProbeRedfish(endpoint Endpoint, timeout time.Duration) bool {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Only the presence of the service is checked here, the
+	// certificate is verified when logging in.
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // #nosec
+	httpClient := &http.Client{Transport: transport, Timeout: timeout}
+	defer transport.CloseIdleConnections()
+
+	url := fmt.Sprintf("%s://%s/redfish/v1", redfishScheme,
+		net.JoinHostPort(endpoint.IP, strconv.Itoa(endpoint.Port)))
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	root := struct {
+		RedfishVersion string `json:"RedfishVersion"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&root); err != nil {
+		return false
+	}
+	return root.RedfishVersion != ""
+}
+
+// ProbeIPMI tells whether an IPMI BMC answers an RMCP presence ping at
+// the endpoint, with a pong reporting support for IPMI.
+func ProbeIPMI(endpoint Endpoint, timeout time.Duration) bool {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(endpoint.IP, strconv.Itoa(endpoint.Port)), timeout)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return false
+	}
+	if _, err := conn.Write(rmcpPresencePing); err != nil {
+		return false
+	}
+	pong := make([]byte, 64)
+	n, err := conn.Read(pong)
+	if err != nil || n <= supportedEntitiesOffset {
+		return false
+	}
+	return bytes.Equal(pong[:4], rmcpPresencePing[:4]) && pong[8] == asfPresencePong &&
+		pong[supportedEntitiesOffset]&ipmiSupported != 0
+}
@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// endpointOf returns the endpoint of the address a test server
+// listens on.
+func endpointOf(t *testing.T, address string, protocol Protocol) Endpoint {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Endpoint{IP: host, Port: portNum, Protocol: protocol}
+}
+
+func TestProbeRedfish(t *testing.T) {
+	var lock sync.Mutex
+	status, body := http.StatusOK, `{"@odata.id": "/redfish/v1", "RedfishVersion": "1.6.0"}`
+	answer := func(newStatus int, newBody string) {
+		lock.Lock()
+		defer lock.Unlock()
+		status, body = newStatus, newBody
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch req.URL.Path {
+		case "/redfish/v1":
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	endpoint := endpointOf(t, server.Listener.Addr().String(), Redfish)
+
+	assert.True(t, Probe(endpoint, time.Second))
+
+	// Services asking for credentials are not told apart from other
+	// HTTPS services.
+	answer(http.StatusUnauthorized, "")
+	assert.False(t, Probe(endpoint, time.Second))
+
+	answer(http.StatusOK, `{"name": "not a BMC"}`)
+	assert.False(t, Probe(endpoint, time.Second))
+
+	server.Close()
+	assert.False(t, Probe(endpoint, time.Second))
+}
+
+func TestProbeIPMI(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var lock sync.Mutex
+	supportedEntities := byte(0x81)
+	go func() {
+		ping := make([]byte, 64)
+		for {
+			n, addr, err := conn.ReadFrom(ping)
+			if err != nil {
+				return
+			}
+			if n == len(rmcpPresencePing) && ping[8] == 0x80 {
+				pong := append([]byte{}, ping[:8]...)
+				pong = append(pong, asfPresencePong, ping[9], 0x00, 0x10)
+				data := make([]byte, 16)
+				lock.Lock()
+				data[8] = supportedEntities
+				lock.Unlock()
+				pong = append(pong, data...)
+				conn.WriteTo(pong, addr)
+			}
+		}
+	}()
+	endpoint := endpointOf(t, conn.LocalAddr().String(), IPMI)
+
+	assert.True(t, Probe(endpoint, time.Second))
+
+	// ASF devices that do not support IPMI answer pings too.
+	lock.Lock()
+	supportedEntities = 0x01
+	lock.Unlock()
+	assert.False(t, Probe(endpoint, time.Second))
+
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	assert.False(t, Probe(endpointOf(t, silent.LocalAddr().String(), IPMI), 100*time.Millisecond))
+}
@@ -53,19 +53,50 @@ type demoProvisioner struct {
 	log logr.Logger
 	// an event publisher for recording significant events
 	publisher provisioner.EventPublisher
+	// the scenarios of the demo hosts
+	scenarios *Scenarios
+	// the scenario of the host, or nil for the fixed demo behaviour
+	scenario *Scenario
+	// the reason the scenario of the host could not be loaded
+	scenarioErr error
+	// the progress of the host through its scenario
+	state *hostState
 }
 
-// New returns a new Ironic Provisioner
+var defaultScenarios = &Scenarios{}
+
+// New returns a new demo Provisioner. Hosts with a scenario annotation
+// follow their scenario, the others the fixed demo behaviour.
 func New(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+	return defaultScenarios.New(host, bmcCreds, publisher)
+}
+
+// New returns a new demo Provisioner following the scenario of the
+// host. Hosts without a scenario get the fixed demo behaviour, based
+// on their name.
+func (s *Scenarios) New(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
 	p := &demoProvisioner{
 		host:      host,
 		bmcCreds:  bmcCreds,
 		log:       log.WithValues("host", host.Name),
 		publisher: publisher,
+		scenarios: s,
+	}
+	p.scenario, p.scenarioErr = s.scenarioFor(&host)
+	if p.scenario != nil {
+		s.lock.Lock()
+		p.state = s.stateFor(&host)
+		s.lock.Unlock()
 	}
 	return p, nil
 }
 
+// continuing returns the result for an operation that is still in
+// progress.
+func continuing(remaining time.Duration) provisioner.Result {
+	return provisioner.Result{Dirty: true, RequeueAfter: remaining}
+}
+
 func (m *demoProvisioner) HasProvisioningCapacity() (result bool, err error) {
 	return true, nil
 }
@@ -75,6 +106,24 @@ func (m *demoProvisioner) HasProvisioningCapacity() (result bool, err error) {
 func (p *demoProvisioner) ValidateManagementAccess(credentialsChanged, force bool) (result provisioner.Result, provID string, err error) {
 	p.log.Info("testing management access")
 
+	if p.scenarioErr != nil {
+		result.ErrorMessage = p.scenarioErr.Error()
+		return
+	}
+	if p.scenario != nil {
+		done, errorMessage, remaining := p.scenarios.progress(p.state, "registration",
+			p.scenario.Registration, credentialsChanged || force)
+		switch {
+		case !done:
+			result = continuing(remaining)
+		case errorMessage != "":
+			result.ErrorMessage = errorMessage
+		case p.host.Status.Provisioning.ID == "":
+			provID = p.host.ObjectMeta.Name
+		}
+		return
+	}
+
 	hostName := p.host.ObjectMeta.Name
 
 	switch hostName {
@@ -106,8 +155,26 @@ func (p *demoProvisioner) ValidateManagementAccess(credentialsChanged, force boo
 // details of devices discovered on the hardware. It may be called
 // multiple times, and should return true for its dirty flag until the
 // inspection is completed.
-func (p *demoProvisioner) InspectHardware(force bool) (result provisioner.Result, details *metal3v1alpha1.HardwareDetails, err error) {
-	p.log.Info("inspecting hardware", "status", p.host.OperationalStatus())
+func (p *demoProvisioner) InspectHardware(force, refresh bool) (result provisioner.Result, started bool, details *metal3v1alpha1.HardwareDetails, err error) {
+	p.log.Info("inspecting hardware", "status", p.host.OperationalStatus(), "refresh", refresh)
+
+	if p.scenario != nil {
+		done, errorMessage, remaining := p.scenarios.progress(p.state, "inspection",
+			p.scenario.Inspection, force)
+		switch {
+		case !done:
+			result = continuing(remaining)
+		case errorMessage != "":
+			result.ErrorMessage = errorMessage
+		case p.host.Status.HardwareDetails == nil || force || refresh:
+			details = p.scenario.HardwareDetails
+			if details == nil {
+				details = demoHardwareDetails()
+			}
+			p.publisher("InspectionComplete", "Hardware inspection completed")
+		}
+		return
+	}
 
 	hostName := p.host.ObjectMeta.Name
 
@@ -123,51 +190,9 @@ func (p *demoProvisioner) InspectHardware(force bool) (result provisioner.Result
 	// status for the server here until it is ready for us to get the
 	// inspection details. Simulate that for now by creating the
 	// hardware details struct as part of a second pass.
-	if p.host.Status.HardwareDetails == nil {
+	if p.host.Status.HardwareDetails == nil || refresh {
 		p.log.Info("continuing inspection by setting details")
-		details =
-			&metal3v1alpha1.HardwareDetails{
-				RAMMebibytes: 128 * 1024,
-				NIC: []metal3v1alpha1.NIC{
-					{
-						Name:      "nic-1",
-						Model:     "virt-io",
-						MAC:       "some:mac:address",
-						IP:        "192.168.100.1",
-						SpeedGbps: 1,
-						PXE:       true,
-					},
-					{
-						Name:      "nic-2",
-						Model:     "e1000",
-						MAC:       "some:other:mac:address",
-						IP:        "192.168.100.2",
-						SpeedGbps: 1,
-						PXE:       false,
-					},
-				},
-				Storage: []metal3v1alpha1.Storage{
-					{
-						Name:       "disk-1 (boot)",
-						Rotational: false,
-						SizeBytes:  metal3v1alpha1.TebiByte * 93,
-						Model:      "Dell CFJ61",
-					},
-					{
-						Name:       "disk-2",
-						Rotational: false,
-						SizeBytes:  metal3v1alpha1.TebiByte * 93,
-						Model:      "Dell CFJ61",
-					},
-				},
-				CPU: metal3v1alpha1.CPU{
-					Arch:           "x86_64",
-					Model:          "Core 2 Duo",
-					ClockMegahertz: 3.0 * metal3v1alpha1.GigaHertz,
-					Flags:          []string{"lm", "hypervisor", "vmx"},
-					Count:          1,
-				},
-			}
+		details = demoHardwareDetails()
 		p.publisher("InspectionComplete", "Hardware inspection completed")
 		p.host.SetOperationalStatus(metal3v1alpha1.OperationalStatusOK)
 	}
@@ -175,12 +200,63 @@ func (p *demoProvisioner) InspectHardware(force bool) (result provisioner.Result
 	return
 }
 
+// demoHardwareDetails returns the details of hosts without any of
+// their own.
+func demoHardwareDetails() *metal3v1alpha1.HardwareDetails {
+	return &metal3v1alpha1.HardwareDetails{
+		RAMMebibytes: 128 * 1024,
+		NIC: []metal3v1alpha1.NIC{
+			{
+				Name:      "nic-1",
+				Model:     "virt-io",
+				MAC:       "some:mac:address",
+				IP:        "192.168.100.1",
+				SpeedGbps: 1,
+				PXE:       true,
+			},
+			{
+				Name:      "nic-2",
+				Model:     "e1000",
+				MAC:       "some:other:mac:address",
+				IP:        "192.168.100.2",
+				SpeedGbps: 1,
+				PXE:       false,
+			},
+		},
+		Storage: []metal3v1alpha1.Storage{
+			{
+				Name:       "disk-1 (boot)",
+				Rotational: false,
+				SizeBytes:  metal3v1alpha1.TebiByte * 93,
+				Model:      "Dell CFJ61",
+			},
+			{
+				Name:       "disk-2",
+				Rotational: false,
+				SizeBytes:  metal3v1alpha1.TebiByte * 93,
+				Model:      "Dell CFJ61",
+			},
+		},
+		CPU: metal3v1alpha1.CPU{
+			Arch:           "x86_64",
+			Model:          "Core 2 Duo",
+			ClockMegahertz: 3.0 * metal3v1alpha1.GigaHertz,
+			Flags:          []string{"lm", "hypervisor", "vmx"},
+			Count:          1,
+		},
+	}
+}
+
 // UpdateHardwareState fetches the latest hardware state of the server
 // and updates the HardwareDetails field of the host with details. It
 // is expected to do this in the least expensive way possible, such as
 // reading from a cache.
 func (p *demoProvisioner) UpdateHardwareState() (hwState provisioner.HardwareState, err error) {
 	p.log.Info("updating hardware state")
+	if p.scenario != nil {
+		poweredOn := p.scenarios.poweredOn(p.state, p.scenario.Power)
+		hwState.PoweredOn = &poweredOn
+	}
 	return
 }
 
@@ -196,9 +272,23 @@ func (p *demoProvisioner) Adopt(force bool) (result provisioner.Result, err erro
 // until the deprovisioning operation is completed.
 func (p *demoProvisioner) Provision(hostConf provisioner.HostConfigData) (result provisioner.Result, err error) {
 
-	hostName := p.host.ObjectMeta.Name
 	p.log.Info("provisioning image to host", "state", p.host.Status.Provisioning.State)
 
+	if p.scenario != nil {
+		p.scenarios.reset(p.state, "deprovisioning")
+		done, errorMessage, remaining := p.scenarios.progress(p.state, "provisioning",
+			p.scenario.Provisioning, false)
+		switch {
+		case !done:
+			result = continuing(remaining)
+		case errorMessage != "":
+			result.ErrorMessage = errorMessage
+		}
+		return result, nil
+	}
+
+	hostName := p.host.ObjectMeta.Name
+
 	switch hostName {
 
 	case ValidationErrorHost:
@@ -222,6 +312,19 @@ func (p *demoProvisioner) Provision(hostConf provisioner.HostConfigData) (result
 // deprovisioning operation is completed.
 func (p *demoProvisioner) Deprovision(force bool) (result provisioner.Result, err error) {
 
+	if p.scenario != nil {
+		p.scenarios.reset(p.state, "provisioning")
+		done, errorMessage, remaining := p.scenarios.progress(p.state, "deprovisioning",
+			p.scenario.Deprovisioning, force)
+		switch {
+		case !done:
+			result = continuing(remaining)
+		case errorMessage != "":
+			result.ErrorMessage = errorMessage
+		}
+		return result, nil
+	}
+
 	hostName := p.host.ObjectMeta.Name
 	switch hostName {
 	default:
@@ -261,6 +364,9 @@ func (p *demoProvisioner) Deprovision(force bool) (result provisioner.Result, er
 // until the deprovisioning operation is completed.
 func (p *demoProvisioner) Delete() (result provisioner.Result, err error) {
 	p.log.Info("deleting host")
+	if p.scenario != nil {
+		p.scenarios.forget(&p.host)
+	}
 	return result, nil
 }
 
@@ -268,6 +374,10 @@ func (p *demoProvisioner) Delete() (result provisioner.Result, err error) {
 // provisioning operation.
 func (p *demoProvisioner) PowerOn() (result provisioner.Result, err error) {
 
+	if p.scenario != nil {
+		return p.changePower(true)
+	}
+
 	hostName := p.host.ObjectMeta.Name
 	switch hostName {
 	default:
@@ -287,7 +397,11 @@ func (p *demoProvisioner) PowerOn() (result provisioner.Result, err error) {
 
 // PowerOff ensures the server is powered off independently of any image
 // provisioning operation.
-func (p *demoProvisioner) PowerOff() (result provisioner.Result, err error) {
+func (p *demoProvisioner) PowerOff(rebootMode metal3v1alpha1.RebootMode) (result provisioner.Result, err error) {
+
+	if p.scenario != nil {
+		return p.changePower(false)
+	}
 
 	hostName := p.host.ObjectMeta.Name
 	switch hostName {
@@ -306,6 +420,18 @@ func (p *demoProvisioner) PowerOff() (result provisioner.Result, err error) {
 	// return result, nil
 }
 
+func (p *demoProvisioner) changePower(on bool) (result provisioner.Result, err error) {
+	p.log.Info("changing power state", "on", on)
+	done, remaining, err := p.scenarios.changePower(p.state, on, p.scenario.Power)
+	if err != nil {
+		return result, err
+	}
+	if !done {
+		result = continuing(remaining)
+	}
+	return result, nil
+}
+
 // IsReady always returns true for the demo provisioner
 func (p *demoProvisioner) IsReady() (result bool, err error) {
 	return true, nil
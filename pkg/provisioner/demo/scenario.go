@@ -0,0 +1,296 @@
+package demo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// ScenarioAnnotation holds a YAML scenario for a single host, taking
+// precedence over the scenarios file.
+const ScenarioAnnotation = "baremetalhost.metal3.io/demo-scenario"
+
+// Phase describes how one operation of the host behaves.
+type Phase struct {
+	// Duration is how long the operation takes.
+	Duration metav1.Duration `json:"duration,omitempty"`
+
+	// Error makes the operation fail with this message once Duration
+	// has passed.
+	Error string `json:"error,omitempty"`
+
+	// Failures limits the number of attempts that fail with Error, so
+	// the operation succeeds when it is retried. Zero means every
+	// attempt fails.
+	Failures int `json:"failures,omitempty"`
+}
+
+// PowerBehaviour describes how the host responds to power changes.
+type PowerBehaviour struct {
+	// Duration is how long a power change takes.
+	Duration metav1.Duration `json:"duration,omitempty"`
+
+	// FailureRate is the probability, between 0 and 1, of a power
+	// change request failing.
+	FailureRate float64 `json:"failureRate,omitempty"`
+
+	// Error is the message of failed power changes.
+	Error string `json:"error,omitempty"`
+
+	// InitiallyOn makes the host start powered on.
+	InitiallyOn bool `json:"initiallyOn,omitempty"`
+}
+
+// Scenario describes how a demo host behaves.
+type Scenario struct {
+	Registration   Phase          `json:"registration,omitempty"`
+	Inspection     Phase          `json:"inspection,omitempty"`
+	Provisioning   Phase          `json:"provisioning,omitempty"`
+	Deprovisioning Phase          `json:"deprovisioning,omitempty"`
+	Power          PowerBehaviour `json:"power,omitempty"`
+
+	// HardwareDetails is reported by the inspection. The demo details
+	// are used when it is not set.
+	HardwareDetails *metal3v1alpha1.HardwareDetails `json:"hardwareDetails,omitempty"`
+}
+
+// ScenarioFile is the content of a scenarios file, typically a
+// ConfigMap mounted into the operator pod.
+type ScenarioFile struct {
+	// Default applies to hosts without a scenario of their own.
+	Default *Scenario `json:"default,omitempty"`
+
+	// Hosts maps host names, or namespace/name, to their scenario.
+	Hosts map[string]Scenario `json:"hosts,omitempty"`
+}
+
+// phaseState tracks the progress of an operation of a host.
+type phaseState struct {
+	started  time.Time
+	attempts int
+	done     bool
+	failed   bool
+	// restart makes the next call start a new attempt
+	restart bool
+}
+
+// hostState is what the demo provisioner remembers about a host
+// between reconciles.
+type hostState struct {
+	phases    map[string]*phaseState
+	poweredOn *bool
+}
+
+// Scenarios loads the scenarios of demo hosts and keeps track of the
+// progress of each host through them.
+type Scenarios struct {
+	// Path is the scenarios file. It is read again when it changes.
+	Path string
+
+	lock     sync.Mutex
+	file     ScenarioFile
+	modTime  time.Time
+	hosts    map[string]*hostState
+	random   *rand.Rand
+	fileErr  error
+	fileRead bool
+}
+
+// scenarioFor returns the scenario of the host, or nil when it has
+// none and should get the fixed demo behaviour.
+func (s *Scenarios) scenarioFor(host *metal3v1alpha1.BareMetalHost) (*Scenario, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if value, ok := host.Annotations[ScenarioAnnotation]; ok {
+		scenario := &Scenario{}
+		if err := yaml.UnmarshalStrict([]byte(value), scenario); err != nil {
+			return nil, errors.Wrap(err, "invalid demo scenario annotation")
+		}
+		return scenario, nil
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if scenario, ok := s.file.Hosts[fmt.Sprintf("%s/%s", host.Namespace, host.Name)]; ok {
+		return &scenario, nil
+	}
+	if scenario, ok := s.file.Hosts[host.Name]; ok {
+		return &scenario, nil
+	}
+	return s.file.Default, nil
+}
+
+// load reads the scenarios file if it has changed since it was last
+// read. The caller must hold the lock.
+func (s *Scenarios) load() error {
+	if s.Path == "" {
+		return nil
+	}
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return errors.Wrap(err, "could not read demo scenarios")
+	}
+	if s.fileRead && info.ModTime().Equal(s.modTime) {
+		return s.fileErr
+	}
+
+	s.fileRead = true
+	s.modTime = info.ModTime()
+	s.file = ScenarioFile{}
+	s.fileErr = nil
+	content, err := ioutil.ReadFile(s.Path)
+	if err == nil {
+		err = yaml.UnmarshalStrict(content, &s.file)
+	}
+	if err != nil {
+		s.fileErr = errors.Wrap(err, "invalid demo scenarios file")
+		return s.fileErr
+	}
+	log.Info("loaded demo scenarios", "path", s.Path, "hosts", len(s.file.Hosts))
+	return nil
+}
+
+// stateFor returns the state of the host. The caller must hold the
+// lock.
+func (s *Scenarios) stateFor(host *metal3v1alpha1.BareMetalHost) *hostState {
+	if s.hosts == nil {
+		s.hosts = make(map[string]*hostState)
+	}
+	key := fmt.Sprintf("%s/%s", host.Namespace, host.Name)
+	state, ok := s.hosts[key]
+	if !ok {
+		state = &hostState{phases: make(map[string]*phaseState)}
+		s.hosts[key] = state
+	}
+	return state
+}
+
+// forget drops the state of the host.
+func (s *Scenarios) forget(host *metal3v1alpha1.BareMetalHost) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.hosts, fmt.Sprintf("%s/%s", host.Namespace, host.Name))
+}
+
+// progress advances the host through the phase. It reports whether
+// the phase is over, the error message if it failed, and how long is
+// left otherwise. A phase that failed starts a new attempt the next
+// time it is called, and one that succeeded starts again when restart
+// is set.
+func (s *Scenarios) progress(state *hostState, name string, phase Phase, restart bool) (done bool, errorMessage string, remaining time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ps := state.phases[name]
+	if ps == nil || ps.failed || ps.restart || (ps.done && restart) {
+		attempts := 0
+		if ps != nil {
+			attempts = ps.attempts
+		}
+		ps = &phaseState{started: time.Now(), attempts: attempts + 1}
+		state.phases[name] = ps
+	}
+
+	if !ps.done {
+		remaining = phase.Duration.Duration - time.Since(ps.started)
+		if remaining > 0 {
+			return false, "", remaining
+		}
+		ps.done = true
+		ps.failed = phase.Error != "" && (phase.Failures == 0 || ps.attempts <= phase.Failures)
+	}
+	if ps.failed {
+		return true, phase.Error, 0
+	}
+	return true, "", 0
+}
+
+// reset makes the phases start again the next time they are called,
+// keeping count of the attempts.
+func (s *Scenarios) reset(state *hostState, names ...string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, name := range names {
+		if ps, ok := state.phases[name]; ok {
+			ps.restart = true
+		}
+	}
+}
+
+// changePower moves the host to the requested power state. It
+// reports whether the host is in that state, and how long is left
+// otherwise. A flaky host may refuse the request.
+func (s *Scenarios) changePower(state *hostState, on bool, power PowerBehaviour) (done bool, remaining time.Duration, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if state.poweredOn == nil {
+		initial := power.InitiallyOn
+		state.poweredOn = &initial
+	}
+	name, other := "power-on", "power-off"
+	if !on {
+		name, other = other, name
+	}
+	if *state.poweredOn == on {
+		delete(state.phases, name)
+		return true, 0, nil
+	}
+
+	ps := state.phases[name]
+	if ps == nil {
+		if s.flaky(power) {
+			message := power.Error
+			if message == "" {
+				message = "demo BMC did not respond"
+			}
+			return false, 0, errors.New(message)
+		}
+		ps = &phaseState{started: time.Now()}
+		state.phases[name] = ps
+		delete(state.phases, other)
+	}
+
+	remaining = power.Duration.Duration - time.Since(ps.started)
+	if remaining > 0 {
+		return false, remaining, nil
+	}
+	*state.poweredOn = on
+	delete(state.phases, name)
+	return true, 0, nil
+}
+
+// poweredOn returns the power state of the host.
+func (s *Scenarios) poweredOn(state *hostState, power PowerBehaviour) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if state.poweredOn == nil {
+		return power.InitiallyOn
+	}
+	return *state.poweredOn
+}
+
+// flaky reports whether a power change request should fail. The
+// caller must hold the lock.
+func (s *Scenarios) flaky(power PowerBehaviour) bool {
+	if power.FailureRate <= 0 {
+		return false
+	}
+	if s.random == nil {
+		s.random = rand.New(rand.NewSource(time.Now().UnixNano())) // #nosec
+	}
+	return s.random.Float64() < power.FailureRate
+}
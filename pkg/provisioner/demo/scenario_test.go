@@ -0,0 +1,166 @@
+package demo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+)
+
+func nullEventPublisher(reason, message string) {}
+
+func makeHost(name string, annotations map[string]string) metal3v1alpha1.BareMetalHost {
+	return metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "myns",
+			Annotations: annotations,
+		},
+	}
+}
+
+func newProvisioner(t *testing.T, s *Scenarios, host metal3v1alpha1.BareMetalHost) provisioner.Provisioner {
+	prov, err := s.New(host, bmc.Credentials{}, nullEventPublisher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prov
+}
+
+func TestScenarioFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scenarios.yaml")
+
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`
+default:
+  registration:
+    error: default error
+hosts:
+  myns/host-0:
+    registration:
+      error: namespaced error
+  host-1:
+    registration:
+      error: named error
+`)
+
+	s := &Scenarios{Path: path}
+	for name, expected := range map[string]string{
+		"host-0": "namespaced error",
+		"host-1": "named error",
+		"host-2": "default error",
+	} {
+		result, _, err := newProvisioner(t, s, makeHost(name, nil)).ValidateManagementAccess(false, false)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result.ErrorMessage, name)
+	}
+
+	// The annotation takes precedence over the file.
+	host := makeHost("host-0", map[string]string{ScenarioAnnotation: "{}"})
+	result, provID, err := newProvisioner(t, s, host).ValidateManagementAccess(false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "", result.ErrorMessage)
+	assert.Equal(t, "host-0", provID)
+
+	// Changes to the file are picked up.
+	write("hosts: {host-3: {registration: {error: new error}}}")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	result, _, err = newProvisioner(t, s, makeHost("host-3", nil)).ValidateManagementAccess(false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "new error", result.ErrorMessage)
+
+	write("unknown: field")
+	later = later.Add(time.Minute)
+	os.Chtimes(path, later, later)
+	result, _, err = newProvisioner(t, s, makeHost("host-3", nil)).ValidateManagementAccess(false, false)
+	assert.NoError(t, err)
+	assert.Contains(t, result.ErrorMessage, "invalid demo scenarios file")
+}
+
+func TestScenarioPhases(t *testing.T) {
+	s := &Scenarios{}
+	host := makeHost("host-0", map[string]string{ScenarioAnnotation: `
+registration: {duration: 1h}
+provisioning: {error: disk full, failures: 1}
+`})
+
+	result, provID, err := newProvisioner(t, s, host).ValidateManagementAccess(false, false)
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	assert.True(t, result.RequeueAfter > 59*time.Minute, result.RequeueAfter)
+	assert.Equal(t, "", provID)
+
+	// The first attempt fails, and the retry succeeds.
+	prov := newProvisioner(t, s, host)
+	result, err = prov.Provision(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "disk full", result.ErrorMessage)
+	result, err = prov.Provision(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, provisioner.Result{}, result)
+
+	// Deprovisioning lets the host be provisioned again.
+	result, err = prov.Deprovision(false)
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+	result, err = prov.Provision(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "", result.ErrorMessage)
+}
+
+func TestScenarioPower(t *testing.T) {
+	s := &Scenarios{}
+	host := makeHost("host-0", map[string]string{ScenarioAnnotation: `
+power: {duration: 1h}
+`})
+	prov := newProvisioner(t, s, host)
+
+	hwState, err := prov.UpdateHardwareState()
+	assert.NoError(t, err)
+	if assert.NotNil(t, hwState.PoweredOn) {
+		assert.False(t, *hwState.PoweredOn)
+	}
+
+	result, err := prov.PowerOn()
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	hwState, _ = prov.UpdateHardwareState()
+	assert.False(t, *hwState.PoweredOn)
+
+	// Turning the power off again cancels the change.
+	result, err = prov.PowerOff(metal3v1alpha1.RebootModeSoft)
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+
+	flaky := makeHost("host-1", map[string]string{ScenarioAnnotation: `
+power: {failureRate: 1, error: BMC timed out}
+`})
+	_, err = newProvisioner(t, s, flaky).PowerOn()
+	assert.EqualError(t, err, "BMC timed out")
+
+	quick := makeHost("host-2", map[string]string{ScenarioAnnotation: "{}"})
+	prov = newProvisioner(t, s, quick)
+	result, err = prov.PowerOn()
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+	hwState, _ = prov.UpdateHardwareState()
+	assert.True(t, *hwState.PoweredOn)
+}
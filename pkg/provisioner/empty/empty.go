@@ -29,8 +29,8 @@ func (p *emptyProvisioner) ValidateManagementAccess(credentialsChanged, force bo
 // details of devices discovered on the hardware. It may be called
 // multiple times, and should return true for its dirty flag until the
 // inspection is completed.
-func (p *emptyProvisioner) InspectHardware(force bool) (provisioner.Result, *metal3v1alpha1.HardwareDetails, error) {
-	return provisioner.Result{}, nil, nil
+func (p *emptyProvisioner) InspectHardware(force, refresh bool) (provisioner.Result, bool, *metal3v1alpha1.HardwareDetails, error) {
+	return provisioner.Result{}, false, nil, nil
 }
 
 // UpdateHardwareState fetches the latest hardware state of the server
@@ -75,7 +75,7 @@ func (p *emptyProvisioner) PowerOn() (provisioner.Result, error) {
 
 // PowerOff ensures the server is powered off independently of any image
 // provisioning operation.
-func (p *emptyProvisioner) PowerOff() (provisioner.Result, error) {
+func (p *emptyProvisioner) PowerOff(rebootMode metal3v1alpha1.RebootMode) (provisioner.Result, error) {
 	return provisioner.Result{}, nil
 }
 
@@ -0,0 +1,98 @@
+package fixture
+
+import (
+	"time"
+
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+)
+
+// Method names a provisioner method that faults can be injected into.
+type Method string
+
+// The provisioner methods supporting faults.
+const (
+	MethodValidateManagementAccess Method = "ValidateManagementAccess"
+	MethodInspectHardware          Method = "InspectHardware"
+	MethodUpdateHardwareState      Method = "UpdateHardwareState"
+	MethodAdopt                    Method = "Adopt"
+	MethodProvision                Method = "Provision"
+	MethodDeprovision              Method = "Deprovision"
+	MethodDelete                   Method = "Delete"
+	MethodPowerOn                  Method = "PowerOn"
+	MethodPowerOff                 Method = "PowerOff"
+	MethodIsReady                  Method = "IsReady"
+	MethodHasProvisioningCapacity  Method = "HasProvisioningCapacity"
+)
+
+// Fault describes how a provisioner method misbehaves instead of
+// doing its work. Methods that do not return a Result only use Err,
+// except that IsReady and HasProvisioningCapacity return false for a
+// Dirty fault.
+type Fault struct {
+	// Err is returned as the error of the method.
+	Err error
+	// ErrorMessage is returned in the Result, reporting a failure of
+	// the operation.
+	ErrorMessage string
+	// Dirty reports the operation as still in progress.
+	Dirty bool
+	// RequeueAfter is returned in the Result.
+	RequeueAfter time.Duration
+	// Calls is the number of calls the fault lasts for. Zero means
+	// it never goes away.
+	Calls int
+}
+
+func (f Fault) result() provisioner.Result {
+	return provisioner.Result{
+		Dirty:        f.Dirty,
+		RequeueAfter: f.RequeueAfter,
+		ErrorMessage: f.ErrorMessage,
+	}
+}
+
+// faultPlan holds the faults waiting to be injected into each method
+// and counts the calls made to the methods.
+type faultPlan struct {
+	faults map[Method][]Fault
+	calls  map[Method]int
+}
+
+// Inject queues faults for the method. Each one is used for its
+// number of calls, in order, before the method behaves normally again.
+func (f *Fixture) Inject(method Method, faults ...Fault) *Fixture {
+	if f.plan.faults == nil {
+		f.plan.faults = make(map[Method][]Fault)
+	}
+	f.plan.faults[method] = append(f.plan.faults[method], faults...)
+	return f
+}
+
+// Calls returns how many times the method has been called, including
+// the calls that returned a fault.
+func (f *Fixture) Calls(method Method) int {
+	return f.plan.calls[method]
+}
+
+// fault records a call to the method and returns the fault to inject
+// into it, if there is one.
+func (f *Fixture) fault(method Method) (fault Fault, ok bool) {
+	if f.plan.calls == nil {
+		f.plan.calls = make(map[Method]int)
+	}
+	f.plan.calls[method]++
+
+	queue := f.plan.faults[method]
+	if len(queue) == 0 {
+		return
+	}
+	fault = queue[0]
+	if fault.Calls > 0 {
+		queue[0].Calls--
+		if queue[0].Calls == 0 {
+			f.plan.faults[method] = queue[1:]
+		}
+	}
+	log.Info("injecting fault", "method", method, "fault", fault)
+	return fault, true
+}
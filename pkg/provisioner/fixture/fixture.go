@@ -1,6 +1,7 @@
 package fixture
 
 import (
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -68,6 +69,10 @@ type Fixture struct {
 	image metal3v1alpha1.Image
 	// state to manage power
 	poweredOn bool
+	// the reboot mode of the last power off
+	RebootMode metal3v1alpha1.RebootMode
+	// faults to inject into the provisioner methods
+	plan faultPlan
 }
 
 // New returns a new Ironic FixtureProvisioner
@@ -82,7 +87,33 @@ func (f *Fixture) New(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credential
 	return p, nil
 }
 
+// Fixtures keeps a separate Fixture for each host, so the state of a
+// host is kept between the provisioners created for it.
+type Fixtures struct {
+	lock  sync.Mutex
+	hosts map[string]*Fixture
+}
+
+// New returns a new FixtureProvisioner using the state of the host
+func (f *Fixtures) New(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.hosts == nil {
+		f.hosts = map[string]*Fixture{}
+	}
+	key := host.Namespace + "/" + host.Name
+	state, ok := f.hosts[key]
+	if !ok {
+		state = &Fixture{}
+		f.hosts[key] = state
+	}
+	return state.New(host, bmcCreds, publisher)
+}
+
 func (p *fixtureProvisioner) HasProvisioningCapacity() (result bool, err error) {
+	if fault, ok := p.state.fault(MethodHasProvisioningCapacity); ok {
+		return !fault.Dirty, fault.Err
+	}
 	return true, nil
 }
 
@@ -91,6 +122,10 @@ func (p *fixtureProvisioner) HasProvisioningCapacity() (result bool, err error)
 func (p *fixtureProvisioner) ValidateManagementAccess(credentialsChanged, force bool) (result provisioner.Result, provID string, err error) {
 	p.log.Info("testing management access")
 
+	if fault, ok := p.state.fault(MethodValidateManagementAccess); ok {
+		return fault.result(), "", fault.Err
+	}
+
 	// Fill in the ID of the host in the provisioning system
 	if p.host.Status.Provisioning.ID == "" {
 		provID = "temporary-fake-id"
@@ -107,14 +142,18 @@ func (p *fixtureProvisioner) ValidateManagementAccess(credentialsChanged, force
 // details of devices discovered on the hardware. It may be called
 // multiple times, and should return true for its dirty flag until the
 // inspection is completed.
-func (p *fixtureProvisioner) InspectHardware(force bool) (result provisioner.Result, details *metal3v1alpha1.HardwareDetails, err error) {
-	p.log.Info("inspecting hardware", "status", p.host.OperationalStatus())
+func (p *fixtureProvisioner) InspectHardware(force, refresh bool) (result provisioner.Result, started bool, details *metal3v1alpha1.HardwareDetails, err error) {
+	p.log.Info("inspecting hardware", "status", p.host.OperationalStatus(), "refresh", refresh)
+
+	if fault, ok := p.state.fault(MethodInspectHardware); ok {
+		return fault.result(), false, nil, fault.Err
+	}
 
 	// The inspection is ongoing. We'll need to check the fixture
 	// status for the server here until it is ready for us to get the
 	// inspection details. Simulate that for now by creating the
 	// hardware details struct as part of a second pass.
-	if p.host.Status.HardwareDetails == nil {
+	if p.host.Status.HardwareDetails == nil || refresh {
 		p.log.Info("continuing inspection by setting details")
 		details =
 			&metal3v1alpha1.HardwareDetails{
@@ -170,6 +209,9 @@ func (p *fixtureProvisioner) InspectHardware(force bool) (result provisioner.Res
 // is expected to do this in the least expensive way possible, such as
 // reading from a cache.
 func (p *fixtureProvisioner) UpdateHardwareState() (hwState provisioner.HardwareState, err error) {
+	if fault, ok := p.state.fault(MethodUpdateHardwareState); ok {
+		return hwState, fault.Err
+	}
 	if !p.host.NeedsProvisioning() {
 		hwState.PoweredOn = &p.state.poweredOn
 		p.log.Info("updating hardware state")
@@ -180,6 +222,11 @@ func (p *fixtureProvisioner) UpdateHardwareState() (hwState provisioner.Hardware
 // Adopt allows an externally-provisioned server to be adopted.
 func (p *fixtureProvisioner) Adopt(force bool) (result provisioner.Result, err error) {
 	p.log.Info("adopting host")
+
+	if fault, ok := p.state.fault(MethodAdopt); ok {
+		return fault.result(), fault.Err
+	}
+
 	if p.host.Spec.ExternallyProvisioned && !p.state.adopted {
 		p.state.adopted = true
 		result.Dirty = true
@@ -195,6 +242,10 @@ func (p *fixtureProvisioner) Provision(hostConf provisioner.HostConfigData) (res
 	p.log.Info("provisioning image to host",
 		"state", p.host.Status.Provisioning.State)
 
+	if fault, ok := p.state.fault(MethodProvision); ok {
+		return fault.result(), fault.Err
+	}
+
 	if p.state.image.URL == "" {
 		p.publisher("ProvisioningComplete", "Image provisioning completed")
 		p.log.Info("moving to done")
@@ -212,6 +263,10 @@ func (p *fixtureProvisioner) Provision(hostConf provisioner.HostConfigData) (res
 func (p *fixtureProvisioner) Deprovision(force bool) (result provisioner.Result, err error) {
 	p.log.Info("ensuring host is deprovisioned")
 
+	if fault, ok := p.state.fault(MethodDeprovision); ok {
+		return fault.result(), fault.Err
+	}
+
 	result.RequeueAfter = deprovisionRequeueDelay
 
 	// NOTE(dhellmann): In order to simulate a multi-step process,
@@ -237,6 +292,10 @@ func (p *fixtureProvisioner) Deprovision(force bool) (result provisioner.Result,
 func (p *fixtureProvisioner) Delete() (result provisioner.Result, err error) {
 	p.log.Info("deleting host")
 
+	if fault, ok := p.state.fault(MethodDelete); ok {
+		return fault.result(), fault.Err
+	}
+
 	if !p.state.Deleted {
 		p.log.Info("clearing provisioning id")
 		p.state.Deleted = true
@@ -252,6 +311,10 @@ func (p *fixtureProvisioner) Delete() (result provisioner.Result, err error) {
 func (p *fixtureProvisioner) PowerOn() (result provisioner.Result, err error) {
 	p.log.Info("ensuring host is powered on")
 
+	if fault, ok := p.state.fault(MethodPowerOn); ok {
+		return fault.result(), fault.Err
+	}
+
 	if !p.state.poweredOn {
 		p.publisher("PowerOn", "Host powered on")
 		p.log.Info("changing status")
@@ -265,8 +328,13 @@ func (p *fixtureProvisioner) PowerOn() (result provisioner.Result, err error) {
 
 // PowerOff ensures the server is powered off independently of any image
 // provisioning operation.
-func (p *fixtureProvisioner) PowerOff() (result provisioner.Result, err error) {
-	p.log.Info("ensuring host is powered off")
+func (p *fixtureProvisioner) PowerOff(rebootMode metal3v1alpha1.RebootMode) (result provisioner.Result, err error) {
+	p.log.Info("ensuring host is powered off", "mode", rebootMode)
+	p.state.RebootMode = rebootMode
+
+	if fault, ok := p.state.fault(MethodPowerOff); ok {
+		return fault.result(), fault.Err
+	}
 
 	if p.state.poweredOn {
 		p.publisher("PowerOff", "Host powered off")
@@ -283,6 +351,10 @@ func (p *fixtureProvisioner) PowerOff() (result provisioner.Result, err error) {
 func (p *fixtureProvisioner) IsReady() (result bool, err error) {
 	p.log.Info("checking provisioner status")
 
+	if fault, ok := p.state.fault(MethodIsReady); ok {
+		return !fault.Dirty, fault.Err
+	}
+
 	if p.state.BecomeReadyCounter > 0 {
 		p.state.BecomeReadyCounter--
 	}
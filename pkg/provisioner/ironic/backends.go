@@ -0,0 +1,318 @@
+package ironic
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
+)
+
+const (
+	// BackendLabel is the label on a host naming the ironic backend
+	// that manages it. Hosts without the label use the default
+	// backend.
+	BackendLabel = "baremetalhost.metal3.io/ironic-backend"
+
+	// DefaultBackend is the name of the backend configured from the
+	// environment.
+	DefaultBackend = ""
+)
+
+// backend holds the settings of one ironic deployment, its auth
+// settings, and the clients for talking to it once they have been
+// created. The clients are shared by all of the provisioners using the
+// backend to reuse the connections between reconcilers.
+type backend struct {
+	config        Config
+	ironicAuth    clients.AuthConfig
+	inspectorAuth clients.AuthConfig
+	// rebuildLock lets a single caller at a time build new clients
+	// for the backend.
+	rebuildLock sync.Mutex
+	// clients is replaced, never modified, while holding
+	// backendsLock.
+	clients *clientSet
+}
+
+// clientSet holds the clients built for a backend.
+type clientSet struct {
+	ironic        *gophercloud.ServiceClient
+	inspector     *gophercloud.ServiceClient
+	ironicAuth    clients.AuthConfig
+	inspectorAuth clients.AuthConfig
+	// fingerprint identifies the contents of the CA bundle and auth
+	// files the clients were built from.
+	fingerprint string
+}
+
+var (
+	backendsLock sync.Mutex
+	backends     = map[string]*backend{}
+)
+
+// Configure validates the settings and makes them the ones used by
+// provisioners created from now on for hosts of the default backend.
+// Any named backends are removed. Provisioners that already exist
+// keep the settings they were created with.
+func Configure(c Config) error {
+	return ConfigureBackends(map[string]Config{DefaultBackend: c})
+}
+
+// ConfigureBackends replaces the full set of backends, keyed by
+// name, loading the auth settings of each from its AuthDir. Either all
+// of the settings are valid and applied, or none are.
+func ConfigureBackends(configs map[string]Config) error {
+	if _, ok := configs[DefaultBackend]; !ok {
+		return errors.New("no settings given for the default ironic backend")
+	}
+	newBackends := make(map[string]*backend, len(configs))
+	for name, c := range configs {
+		b := &backend{config: c}
+		err := validateBackendName(name)
+		if err == nil {
+			err = c.Validate()
+		}
+		if err == nil {
+			b.ironicAuth, b.inspectorAuth, err = clients.LoadAuthFrom(c.AuthDir)
+		}
+		if err != nil {
+			if name == DefaultBackend {
+				return err
+			}
+			return errors.Wrap(err, fmt.Sprintf("ironic backend %q", name))
+		}
+		newBackends[name] = b
+	}
+
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+
+	for name, b := range newBackends {
+		if old, ok := backends[name]; ok && old.config.sameEndpoints(b.config) {
+			b.clients = old.clients
+		}
+	}
+	backends = newBackends
+	return nil
+}
+
+// validateBackendName makes sure the name of a backend is a DNS label,
+// as it is used both in host labels and as the name of the auth
+// directory of the backend.
+func validateBackendName(name string) error {
+	if name == DefaultBackend {
+		return nil
+	}
+	if len(validation.IsDNS1123Label(name)) != 0 {
+		return errors.New("the name must be a DNS label")
+	}
+	return nil
+}
+
+// backendAuthTypes returns the auth types of the named backend.
+func backendAuthTypes(name string) (ironicType, inspectorType clients.AuthType) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	if b, ok := backends[name]; ok {
+		return b.ironicAuth.Type, b.inspectorAuth.Type
+	}
+	return "", ""
+}
+
+// CurrentConfig returns the settings of the default backend.
+func CurrentConfig() Config {
+	c, _ := BackendConfig(DefaultBackend)
+	return c
+}
+
+// BackendConfig returns the settings of the named backend, and
+// whether it exists.
+func BackendConfig(name string) (Config, bool) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	b, ok := backends[name]
+	if !ok {
+		return Config{}, false
+	}
+	return b.config, true
+}
+
+// BackendNames returns the names of the configured backends, in
+// order, starting with the default one.
+func BackendNames() []string {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// backendName returns the name of the backend managing the host.
+func backendName(host *metal3v1alpha1.BareMetalHost) string {
+	return host.Labels[BackendLabel]
+}
+
+// sharedClients returns the settings of the named backend together
+// with ironic and inspector clients built for them, creating the
+// clients if needed.
+//
+// The CA bundle and auth files are checked on every call, and new
+// clients are built when any of them has changed, for example because
+// a certificate was renewed. Provisioners already holding the old
+// clients keep using them until they finish, so reconciles that are
+// running are not disturbed.
+//
+// The files are read, and new clients built, without holding
+// backendsLock, since building them may mean authenticating with
+// Keystone. Only the swap to the new clients happens under the lock.
+func sharedClients(name string) (Config, *gophercloud.ServiceClient, *gophercloud.ServiceClient, error) {
+	backendsLock.Lock()
+	b, ok := backends[name]
+	backendsLock.Unlock()
+	if !ok {
+		if name == DefaultBackend {
+			return Config{}, nil, nil, errors.New("ironic provisioner is not configured")
+		}
+		return Config{}, nil, nil, fmt.Errorf("unknown ironic backend %q", name)
+	}
+
+	tlsConf := b.config.tlsConfig()
+	fingerprint := clients.Fingerprint(b.ironicAuth, tlsConf) +
+		clients.Fingerprint(b.inspectorAuth, tlsConf)
+	current := b.currentClients()
+	if current != nil && current.fingerprint == fingerprint {
+		return b.config, current.ironic, current.inspector, nil
+	}
+
+	b.rebuildLock.Lock()
+	defer b.rebuildLock.Unlock()
+
+	// Another caller may have built the clients while we waited.
+	current = b.currentClients()
+	if current != nil && current.fingerprint == fingerprint {
+		return b.config, current.ironic, current.inspector, nil
+	}
+
+	fresh, err := b.newClients(tlsConf, fingerprint)
+	if err != nil {
+		if current == nil {
+			return b.config, nil, nil, err
+		}
+		// The files may be part way through being rotated, so keep
+		// using the clients we have and try again next time.
+		log.Info("could not rebuild ironic clients, continuing with the previous ones",
+			"backend", name, "error", err.Error())
+		return b.config, current.ironic, current.inspector, nil
+	}
+	if current != nil {
+		log.Info("rebuilt ironic clients after TLS or auth files changed", "backend", name)
+	}
+
+	backendsLock.Lock()
+	b.clients = fresh
+	backendsLock.Unlock()
+	return b.config, fresh.ironic, fresh.inspector, nil
+}
+
+func (b *backend) currentClients() *clientSet {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	return b.clients
+}
+
+// newClients reloads the auth settings from their files and builds
+// new clients for the backend.
+func (b *backend) newClients(tlsConf clients.TLSConfig, fingerprint string) (*clientSet, error) {
+	ironicAuth, err := b.ironicAuth.Reload()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reload ironic auth settings")
+	}
+	inspectorAuth, err := b.inspectorAuth.Reload()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reload ironic-inspector auth settings")
+	}
+
+	clientIronic, err := clients.IronicClient(
+		b.config.IronicEndpoint, ironicAuth, tlsConf)
+	if err != nil {
+		return nil, err
+	}
+
+	clientInspector, err := clients.InspectorClient(
+		b.config.InspectorEndpoint, inspectorAuth, tlsConf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clientSet{
+		ironic:        clientIronic,
+		inspector:     clientInspector,
+		ironicAuth:    ironicAuth,
+		inspectorAuth: inspectorAuth,
+		fingerprint:   fingerprint,
+	}, nil
+}
+
+// ApplySettings configures the default backend from base with the
+// settings of a BareMetalOperatorConfig applied on top, and a named
+// backend for each entry in named. Named backends take any value they
+// do not set from the default backend, except for the endpoints and
+// the auth settings, which they read from their own directory under
+// backends/<name> in the auth directory of the default backend. It
+// returns the settings now in effect.
+func ApplySettings(base Config, settings *metal3v1alpha1.IronicSettings, named []metal3v1alpha1.IronicBackend) (*metal3v1alpha1.IronicSettings, []metal3v1alpha1.IronicBackend, error) {
+	defaultConfig := base.WithOverrides(settings)
+	configs := map[string]Config{DefaultBackend: defaultConfig}
+
+	for _, nb := range named {
+		switch {
+		case nb.Name == DefaultBackend:
+			return nil, nil, errors.New("ironic backends must have a name")
+		case nb.Endpoint == "" || nb.InspectorEndpoint == "":
+			return nil, nil, fmt.Errorf("ironic backend %q must set both endpoints", nb.Name)
+		}
+		if _, exists := configs[nb.Name]; exists {
+			return nil, nil, fmt.Errorf("ironic backend %q is defined more than once", nb.Name)
+		}
+		nbSettings := nb.IronicSettings
+		nbConfig := defaultConfig.WithOverrides(&nbSettings)
+		nbConfig.AuthDir = backendAuthDir(defaultConfig, nb.Name)
+		configs[nb.Name] = nbConfig
+	}
+
+	if err := ConfigureBackends(configs); err != nil {
+		return nil, nil, err
+	}
+
+	var effective []metal3v1alpha1.IronicBackend
+	for _, nb := range named {
+		effective = append(effective, metal3v1alpha1.IronicBackend{
+			Name:           nb.Name,
+			IronicSettings: *configs[nb.Name].Settings(),
+		})
+	}
+	return defaultConfig.Settings(), effective, nil
+}
+
+// backendAuthDir returns the auth directory of the named backend.
+func backendAuthDir(defaultConfig Config, name string) string {
+	return filepath.Join(defaultConfig.AuthDir, "backends", name)
+}
+
+func (c Config) sameEndpoints(other Config) bool {
+	return c.IronicEndpoint == other.IronicEndpoint &&
+		c.InspectorEndpoint == other.InspectorEndpoint &&
+		c.IronicTrustedCAFile == other.IronicTrustedCAFile &&
+		c.IronicInsecure == other.IronicInsecure &&
+		c.AuthDir == other.AuthDir
+}
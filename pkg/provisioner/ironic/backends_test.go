@@ -0,0 +1,374 @@
+package ironic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/testserver"
+)
+
+func backendSettings(name, endpoint string) metal3v1alpha1.IronicBackend {
+	return metal3v1alpha1.IronicBackend{
+		Name: name,
+		IronicSettings: metal3v1alpha1.IronicSettings{
+			Endpoint:          endpoint,
+			InspectorEndpoint: "http://inspector-" + name + ".test/v1/",
+		},
+	}
+}
+
+func TestApplySettings(t *testing.T) {
+	defer Configure(testConfig)
+
+	limit := 2
+	dc2 := backendSettings("dc2", "http://dc2.test/v1/")
+	dc2.ProvisioningLimit = &limit
+
+	effective, effectiveBackends, err := ApplySettings(testConfig, nil, []metal3v1alpha1.IronicBackend{dc2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, testConfig.IronicEndpoint, effective.Endpoint)
+	assert.Equal(t, 1, len(effectiveBackends))
+	assert.Equal(t, "dc2", effectiveBackends[0].Name)
+	assert.Equal(t, "http://dc2.test/v1/", effectiveBackends[0].Endpoint)
+	assert.Equal(t, testConfig.DeployKernelURL, effectiveBackends[0].DeployKernelURL)
+	assert.Equal(t, 2, *effectiveBackends[0].ProvisioningLimit)
+
+	assert.Equal(t, []string{DefaultBackend, "dc2"}, BackendNames())
+	dc2Config, ok := BackendConfig("dc2")
+	assert.True(t, ok)
+	assert.Equal(t, 2, dc2Config.MaxProvisioningHosts)
+	assert.Equal(t, testConfig, CurrentConfig())
+}
+
+func TestApplySettingsInvalid(t *testing.T) {
+	defer Configure(testConfig)
+
+	badLimit := 0
+	badLimitBackend := backendSettings("dc2", "http://dc2.test/v1/")
+	badLimitBackend.ProvisioningLimit = &badLimit
+
+	cases := []struct {
+		name          string
+		backends      []metal3v1alpha1.IronicBackend
+		expectedError string
+	}{
+		{
+			name:          "no-name",
+			backends:      []metal3v1alpha1.IronicBackend{backendSettings("", "http://dc2.test/v1/")},
+			expectedError: "ironic backends must have a name",
+		},
+		{
+			name:          "no-endpoint",
+			backends:      []metal3v1alpha1.IronicBackend{backendSettings("dc2", "")},
+			expectedError: "ironic backend \"dc2\" must set both endpoints",
+		},
+		{
+			name: "duplicate",
+			backends: []metal3v1alpha1.IronicBackend{
+				backendSettings("dc2", "http://dc2.test/v1/"),
+				backendSettings("dc2", "http://dc3.test/v1/"),
+			},
+			expectedError: "ironic backend \"dc2\" is defined more than once",
+		},
+		{
+			name:          "path-name",
+			backends:      []metal3v1alpha1.IronicBackend{backendSettings("../dc2", "http://dc2.test/v1/")},
+			expectedError: "ironic backend \"../dc2\": the name must be a DNS label",
+		},
+		{
+			name:          "invalid-setting",
+			backends:      []metal3v1alpha1.IronicBackend{badLimitBackend},
+			expectedError: "ironic backend \"dc2\": Invalid provisioning limit 0, must be at least 1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := ApplySettings(testConfig, nil, tc.backends)
+			assert.EqualError(t, err, tc.expectedError)
+			assert.Equal(t, []string{DefaultBackend}, BackendNames())
+		})
+	}
+}
+
+func TestNewSelectsBackend(t *testing.T) {
+	defer Configure(testConfig)
+
+	dc2Config := testConfig
+	dc2Config.IronicEndpoint = "http://dc2.test/v1/"
+	err := ConfigureBackends(map[string]Config{
+		DefaultBackend: testConfig,
+		"dc2":          dc2Config,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	host := makeHost()
+	prov, err := New(host, bmc.Credentials{}, nullEventPublisher)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+	assert.Equal(t, testConfig.IronicEndpoint, prov.(*ironicProvisioner).client.Endpoint)
+
+	host.Labels = map[string]string{BackendLabel: "dc2"}
+	prov, err = New(host, bmc.Credentials{}, nullEventPublisher)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+	assert.Equal(t, "http://dc2.test/v1/", prov.(*ironicProvisioner).client.Endpoint)
+
+	host.Labels[BackendLabel] = "dc3"
+	_, err = New(host, bmc.Credentials{}, nullEventPublisher)
+	assert.EqualError(t, err, "unknown ironic backend \"dc3\"")
+}
+
+func TestHasProvisioningCapacityPerBackend(t *testing.T) {
+	defer Configure(testConfig)
+
+	busyNodes := []nodes.Node{}
+	for n := 0; n < 3; n++ {
+		busyNodes = append(busyNodes, nodes.Node{
+			Name:           fmt.Sprintf("node-%d", n),
+			ProvisionState: string(nodes.Deploying),
+		})
+	}
+
+	busy := testserver.NewIronic(t).Nodes(busyNodes).Start()
+	defer busy.Stop()
+	idle := testserver.NewIronic(t).Nodes([]nodes.Node{}).Start()
+	defer idle.Stop()
+
+	busyConfig := testConfig
+	busyConfig.IronicEndpoint = busy.Endpoint()
+	busyConfig.MaxProvisioningHosts = 3
+	idleConfig := busyConfig
+	idleConfig.IronicEndpoint = idle.Endpoint()
+	err := ConfigureBackends(map[string]Config{
+		DefaultBackend: busyConfig,
+		"idle":         idleConfig,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	host := makeHost()
+	prov, err := New(host, bmc.Credentials{}, nullEventPublisher)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+	hasCapacity, err := prov.HasProvisioningCapacity()
+	assert.NoError(t, err)
+	assert.False(t, hasCapacity)
+
+	host.Labels = map[string]string{BackendLabel: "idle"}
+	prov, err = New(host, bmc.Credentials{}, nullEventPublisher)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+	hasCapacity, err = prov.HasProvisioningCapacity()
+	assert.NoError(t, err)
+	assert.True(t, hasCapacity)
+}
+
+// writeAuth writes HTTP basic auth settings for both clients in
+// authDir.
+func writeAuth(t *testing.T, authDir, password string) {
+	for _, dir := range []string{"ironic", "ironic-inspector"} {
+		writeAuthFiles(t, filepath.Join(authDir, dir),
+			map[string]string{"username": "user", "password": password})
+	}
+}
+
+func TestSharedClientsReloadAuth(t *testing.T) {
+	defer Configure(testConfig)
+
+	authRoot, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(authRoot)
+	writeAuth(t, authRoot, "old")
+
+	config := testConfig
+	config.AuthDir = authRoot
+	if err := Configure(config); err != nil {
+		t.Fatal(err)
+	}
+
+	_, firstIronic, firstInspector, err := sharedClients(DefaultBackend)
+	assert.NoError(t, err)
+	_, sameIronic, sameInspector, err := sharedClients(DefaultBackend)
+	assert.NoError(t, err)
+	assert.True(t, firstIronic == sameIronic, "ironic client rebuilt without changes")
+	assert.True(t, firstInspector == sameInspector, "inspector client rebuilt without changes")
+
+	writeAuth(t, authRoot, "new")
+	_, newIronic, newInspector, err := sharedClients(DefaultBackend)
+	assert.NoError(t, err)
+	assert.False(t, firstIronic == newIronic, "ironic client not rebuilt")
+	assert.False(t, firstInspector == newInspector, "inspector client not rebuilt")
+	assert.Equal(t, "new", backends[DefaultBackend].clients.ironicAuth.Password)
+	assert.Equal(t, "new", backends[DefaultBackend].clients.inspectorAuth.Password)
+
+	// A half written rotation keeps the current clients in use.
+	if err := os.Remove(filepath.Join(authRoot, "ironic", "password")); err != nil {
+		t.Fatal(err)
+	}
+	_, brokenIronic, _, err := sharedClients(DefaultBackend)
+	assert.NoError(t, err)
+	assert.True(t, newIronic == brokenIronic, "ironic client replaced with broken settings")
+	assert.Equal(t, "new", backends[DefaultBackend].clients.ironicAuth.Password)
+}
+
+func TestBackendAuthPerBackend(t *testing.T) {
+	defer Configure(testConfig)
+
+	authRoot, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(authRoot)
+	writeAuth(t, authRoot, "default-password")
+	writeAuth(t, filepath.Join(authRoot, "backends", "dc2"), "dc2-password")
+
+	base := testConfig
+	base.AuthDir = authRoot
+	_, _, err = ApplySettings(base, nil, []metal3v1alpha1.IronicBackend{
+		backendSettings("dc2", "http://dc2.test/v1/"),
+		backendSettings("dc3", "http://dc3.test/v1/"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	assert.Equal(t, "default-password", backends[DefaultBackend].ironicAuth.Password)
+	assert.Equal(t, "dc2-password", backends["dc2"].ironicAuth.Password)
+	assert.Equal(t, "dc2-password", backends["dc2"].inspectorAuth.Password)
+	// A backend without its own auth directory does not borrow the
+	// credentials of the default one.
+	assert.Equal(t, clients.NoAuth, backends["dc3"].ironicAuth.Type)
+
+	// Moving a backend to another auth directory builds new clients.
+	_, dc2Ironic, _, err := sharedClients("dc2")
+	assert.NoError(t, err)
+	writeAuth(t, filepath.Join(authRoot, "other", "backends", "dc2"), "other-password")
+	base.AuthDir = filepath.Join(authRoot, "other")
+	_, _, err = ApplySettings(base, nil, []metal3v1alpha1.IronicBackend{
+		backendSettings("dc2", "http://dc2.test/v1/"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, otherIronic, _, err := sharedClients("dc2")
+	assert.NoError(t, err)
+	assert.False(t, dc2Ironic == otherIronic, "clients reused with other auth settings")
+	assert.Equal(t, "other-password", backends["dc2"].clients.ironicAuth.Password)
+}
+
+func TestSharedClientsBuildOutsideLock(t *testing.T) {
+	defer Configure(testConfig)
+
+	authRequested := make(chan struct{}, 1)
+	release := make(chan struct{})
+	keystone := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authRequested <- struct{}{}
+		<-release
+		w.Header().Set("X-Subject-Token", "token")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"token": {"catalog": []}}`)
+	}))
+	defer keystone.Close()
+	var releaseOnce sync.Once
+	unblock := func() { releaseOnce.Do(func() { close(release) }) }
+	// Unblock the server on failure too, so that it can be closed.
+	defer unblock()
+
+	authRoot, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(authRoot)
+	for _, dir := range []string{"ironic", "ironic-inspector"} {
+		writeAuthFiles(t, filepath.Join(authRoot, dir), map[string]string{
+			"auth-url":                      keystone.URL + "/v3",
+			"application-credential-id":     "id",
+			"application-credential-secret": "secret",
+		})
+	}
+	config := testConfig
+	config.AuthDir = authRoot
+	if err := Configure(config); err != nil {
+		t.Fatal(err)
+	}
+
+	built := make(chan error)
+	go func() {
+		_, _, _, err := sharedClients(DefaultBackend)
+		built <- err
+	}()
+	<-authRequested
+
+	// The backends can be looked up while the clients are being
+	// authenticated.
+	looked := make(chan []string)
+	go func() { looked <- BackendNames() }()
+	select {
+	case names := <-looked:
+		assert.Equal(t, []string{DefaultBackend}, names)
+	case <-time.After(5 * time.Second):
+		t.Fatal("backends locked while authenticating with keystone")
+	}
+
+	unblock()
+	assert.NoError(t, <-built)
+}
+
+func writeAuthFiles(t *testing.T, dir string, files map[string]string) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChangedSettings(t *testing.T) {
+	defer Configure(testConfig)
+
+	changedSettings()
+	assert.Empty(t, changedSettings(), "settings logged again without a change")
+
+	_, _, err := ApplySettings(testConfig, nil, []metal3v1alpha1.IronicBackend{backendSettings("dc2", "http://dc2.test/v1/")})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	changed := changedSettings()
+	assert.Equal(t, 1, len(changed))
+	assert.Contains(t, changed, "dc2")
+	assert.Empty(t, changedSettings())
+
+	_, _, err = ApplySettings(testConfig, nil, []metal3v1alpha1.IronicBackend{backendSettings("dc2", "http://dc2-new.test/v1/")})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Contains(t, changedSettings(), "dc2")
+}
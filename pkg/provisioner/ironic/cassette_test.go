@@ -0,0 +1,124 @@
+package ironic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/fakeironic"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/testserver"
+)
+
+// registerAndInspect registers and inspects a new host through the
+// given servers, and returns the ID and the hardware details of the
+// host.
+func registerAndInspect(t *testing.T, ironicURL, inspectorURL string) (provID string, details *metal3v1alpha1.HardwareDetails) {
+	host := makeHost()
+	host.Status.Provisioning.ID = ""
+	host.Spec.BootMACAddress = "52:54:00:12:34:56"
+
+	auth := clients.AuthConfig{Type: clients.NoAuth}
+	newProvisioner := func() *ironicProvisioner {
+		prov, err := newProvisionerWithSettings(host, bmc.Credentials{Username: "admin", Password: "password"},
+			nullEventPublisher, ironicURL, auth, inspectorURL, auth)
+		if err != nil {
+			t.Fatalf("could not create provisioner: %s", err)
+		}
+		return prov
+	}
+
+	for i := 0; i < 20; i++ {
+		result, id, err := newProvisioner().ValidateManagementAccess(false, false)
+		if err != nil {
+			t.Fatalf("registration failed: %s", err)
+		}
+		if id != "" {
+			host.Status.Provisioning.ID = id
+		}
+		if !result.Dirty || result.ErrorMessage != "" {
+			assert.Equal(t, "", result.ErrorMessage)
+			break
+		}
+	}
+
+	for i := 0; i < 20 && details == nil; i++ {
+		result, _, hwDetails, err := newProvisioner().InspectHardware(false, false)
+		if err != nil {
+			t.Fatalf("inspection failed: %s", err)
+		}
+		assert.Equal(t, "", result.ErrorMessage)
+		details = hwDetails
+	}
+	return host.Status.Provisioning.ID, details
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassettes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ironicCassette := filepath.Join(dir, "ironic.yaml")
+	inspectorCassette := filepath.Join(dir, "inspector.yaml")
+
+	sim := fakeironic.New().Start()
+	defer sim.Stop()
+
+	ironic := testserver.NewIronic(t)
+	ironic.Record(sim.IronicEndpoint(), ironicCassette).Start()
+	inspector := testserver.NewInspector(t)
+	inspector.Record(sim.InspectorEndpoint(), inspectorCassette).Start()
+	recordedID, recordedDetails := registerAndInspect(t, ironic.Endpoint(), inspector.Endpoint())
+	ironic.Stop()
+	inspector.Stop()
+
+	if assert.NotNil(t, recordedDetails) {
+		assert.Equal(t, "52:54:00:12:34:56", recordedDetails.NIC[0].MAC)
+	}
+
+	recording, err := testserver.LoadCassette(ironicCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, interaction := range recording.Interactions {
+		assert.NotContains(t, interaction.Request, `"password"`)
+		assert.NotContains(t, interaction.Response, `"password"`)
+		assert.NotContains(t, interaction.Response, sim.IronicEndpoint())
+	}
+
+	ironic = testserver.NewIronic(t)
+	ironic.Replay(ironicCassette).Start()
+	defer ironic.Stop()
+	inspector = testserver.NewInspector(t)
+	inspector.Replay(inspectorCassette).Start()
+	defer inspector.Stop()
+	replayedID, replayedDetails := registerAndInspect(t, ironic.Endpoint(), inspector.Endpoint())
+
+	assert.Equal(t, recordedID, replayedID)
+	assert.Equal(t, recordedDetails, replayedDetails)
+}
+
+// TestCassetteRegisterAndInspect replays the registration and
+// inspection of a new host. Run it with IRONIC_RECORD_ENDPOINT and
+// INSPECTOR_RECORD_ENDPOINT set to record the cassettes again.
+func TestCassetteRegisterAndInspect(t *testing.T) {
+	ironic := testserver.NewIronic(t).Cassette("testdata/cassettes/register-and-inspect-ironic.yaml")
+	ironic.Start()
+	defer ironic.Stop()
+	inspector := testserver.NewInspector(t).Cassette("testdata/cassettes/register-and-inspect-inspector.yaml")
+	inspector.Start()
+	defer inspector.Stop()
+
+	provID, details := registerAndInspect(t, ironic.Endpoint(), inspector.Endpoint())
+	assert.NotEqual(t, "", provID)
+	if assert.NotNil(t, details) {
+		assert.Equal(t, "myhost", details.Hostname)
+		assert.Equal(t, "52:54:00:12:34:56", details.NIC[0].MAC)
+	}
+}
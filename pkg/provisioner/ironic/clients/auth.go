@@ -18,6 +18,14 @@ const (
 	NoAuth AuthType = "noauth"
 	// HTTPBasicAuth uses HTTP Basic Authentication
 	HTTPBasicAuth AuthType = "http_basic"
+	// BearerTokenAuth sends a token in the Authorization header
+	BearerTokenAuth AuthType = "bearer_token"
+	// ApplicationCredentialAuth uses a token obtained from Keystone
+	// with an application credential
+	ApplicationCredentialAuth AuthType = "application_credential"
+	// ClientCertAuth relies only on the client certificate presented
+	// when connecting
+	ClientCertAuth AuthType = "client_certificate"
 )
 
 // AuthConfig contains data needed to configure authentication in the client
@@ -25,9 +33,32 @@ type AuthConfig struct {
 	Type     AuthType
 	Username string
 	Password string
+
+	// TokenFile is the path of the file holding the bearer token. It
+	// is read for every request, so the token can be rotated.
+	TokenFile string
+
+	// AuthURL is the Keystone endpoint used to obtain tokens with
+	// the application credential.
+	AuthURL                     string
+	ApplicationCredentialID     string
+	ApplicationCredentialSecret string
+
+	// CertFile and KeyFile hold the client certificate presented to
+	// the server. They are read for every new connection, so the
+	// certificate can be rotated. They may be combined with any of
+	// the other auth types.
+	CertFile string
+	KeyFile  string
+
+	// authPath is the directory the settings were loaded from, if
+	// any, so they can be loaded again when they rotate.
+	authPath string
 }
 
-func authRoot() string {
+// AuthRoot returns the directory the auth settings are read from by
+// default, which holds a subdirectory for each client type.
+func AuthRoot() string {
 	env := os.Getenv("METAL3_AUTH_ROOT_DIR")
 	if env != "" {
 		return filepath.Clean(env)
@@ -40,8 +71,21 @@ func readAuthFile(filename string) (string, error) {
 	return strings.TrimSpace(string(content)), err
 }
 
-func load(clientType string) (auth AuthConfig, err error) {
-	authPath := path.Join(authRoot(), clientType)
+// authFiles lists the files in an auth directory that the settings
+// may be read from.
+var authFiles = []string{
+	"username", "password", "token", "auth-url",
+	"application-credential-id", "application-credential-secret",
+	"tls.crt", "tls.key",
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+func load(authDir, clientType string) (auth AuthConfig, err error) {
+	authPath := path.Join(authDir, clientType)
 
 	if _, err := os.Stat(authPath); err != nil {
 		if os.IsNotExist(err) {
@@ -50,33 +94,105 @@ func load(clientType string) (auth AuthConfig, err error) {
 		}
 		return auth, err
 	}
-	auth.Type = HTTPBasicAuth
+	return loadFrom(authPath)
+}
 
-	auth.Username, err = readAuthFile(path.Join(authPath, "username"))
-	if err != nil {
-		return
-	}
+// loadFrom reads the auth settings from the files in authPath. The
+// files present select the auth type.
+func loadFrom(authPath string) (auth AuthConfig, err error) {
+	auth.authPath = authPath
 
-	auth.Password, err = readAuthFile(path.Join(authPath, "password"))
-	if err != nil {
-		return
+	if certFile := path.Join(authPath, "tls.crt"); fileExists(certFile) {
+		auth.CertFile = certFile
+		auth.KeyFile = path.Join(authPath, "tls.key")
+		if !fileExists(auth.KeyFile) {
+			err = fmt.Errorf("Client certificate %s has no matching tls.key", certFile)
+			return
+		}
 	}
 
-	if auth.Username == "" {
-		err = fmt.Errorf("Empty HTTP Basic Auth username")
-	} else if auth.Password == "" {
-		err = fmt.Errorf("Empty HTTP Basic Auth password")
+	switch {
+	case fileExists(path.Join(authPath, "token")):
+		auth.Type = BearerTokenAuth
+		auth.TokenFile = path.Join(authPath, "token")
+		var token string
+		token, err = readAuthFile(auth.TokenFile)
+		if err == nil && token == "" {
+			err = fmt.Errorf("Empty bearer token")
+		}
+
+	case fileExists(path.Join(authPath, "application-credential-id")):
+		auth.Type = ApplicationCredentialAuth
+		auth.AuthURL, err = readAuthFile(path.Join(authPath, "auth-url"))
+		if err != nil {
+			return
+		}
+		auth.ApplicationCredentialID, err = readAuthFile(path.Join(authPath, "application-credential-id"))
+		if err != nil {
+			return
+		}
+		auth.ApplicationCredentialSecret, err = readAuthFile(path.Join(authPath, "application-credential-secret"))
+		if err != nil {
+			return
+		}
+
+		if auth.AuthURL == "" {
+			err = fmt.Errorf("Empty Keystone auth URL")
+		} else if auth.ApplicationCredentialID == "" {
+			err = fmt.Errorf("Empty application credential ID")
+		} else if auth.ApplicationCredentialSecret == "" {
+			err = fmt.Errorf("Empty application credential secret")
+		}
+
+	case auth.CertFile != "" && !fileExists(path.Join(authPath, "username")):
+		auth.Type = ClientCertAuth
+
+	default:
+		auth.Type = HTTPBasicAuth
+
+		auth.Username, err = readAuthFile(path.Join(authPath, "username"))
+		if err != nil {
+			return
+		}
+
+		auth.Password, err = readAuthFile(path.Join(authPath, "password"))
+		if err != nil {
+			return
+		}
+
+		if auth.Username == "" {
+			err = fmt.Errorf("Empty HTTP Basic Auth username")
+		} else if auth.Password == "" {
+			err = fmt.Errorf("Empty HTTP Basic Auth password")
+		}
 	}
 	return
 }
 
+// Reload returns the settings as they are now in the files they
+// were loaded from. Settings that did not come from files are
+// returned unchanged.
+func (auth AuthConfig) Reload() (AuthConfig, error) {
+	if auth.authPath == "" {
+		return auth, nil
+	}
+	return loadFrom(auth.authPath)
+}
+
 // LoadAuth loads the Ironic and Inspector configuration from the environment
 func LoadAuth() (ironicAuth, inspectorAuth AuthConfig, err error) {
-	ironicAuth, err = load("ironic")
+	return LoadAuthFrom(AuthRoot())
+}
+
+// LoadAuthFrom loads the Ironic and Inspector configuration from the
+// ironic and ironic-inspector subdirectories of authDir. Either one
+// that does not exist selects noauth.
+func LoadAuthFrom(authDir string) (ironicAuth, inspectorAuth AuthConfig, err error) {
+	ironicAuth, err = load(authDir, "ironic")
 	if err != nil {
 		return
 	}
-	inspectorAuth, err = load("ironic-inspector")
+	inspectorAuth, err = load(authDir, "ironic-inspector")
 	return
 }
 
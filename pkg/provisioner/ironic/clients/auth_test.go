@@ -1,6 +1,10 @@
 package clients
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -97,3 +101,105 @@ func TestConfigFromEndpointURL(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadFrom(t *testing.T) {
+	testCases := []struct {
+		Scenario     string
+		Files        map[string]string
+		ExpectedType AuthType
+		ExpectCert   bool
+		ExpectErr    string
+	}{
+		{
+			Scenario:     "basic auth",
+			Files:        map[string]string{"username": "user", "password": "pass"},
+			ExpectedType: HTTPBasicAuth,
+		},
+		{
+			Scenario:  "empty directory",
+			Files:     map[string]string{},
+			ExpectErr: "no such file or directory",
+		},
+		{
+			Scenario:     "bearer token",
+			Files:        map[string]string{"token": "secret-token\n"},
+			ExpectedType: BearerTokenAuth,
+		},
+		{
+			Scenario:  "empty bearer token",
+			Files:     map[string]string{"token": ""},
+			ExpectErr: "Empty bearer token",
+		},
+		{
+			Scenario: "application credential",
+			Files: map[string]string{
+				"auth-url":                      "https://keystone.test/v3",
+				"application-credential-id":     "id",
+				"application-credential-secret": "secret",
+			},
+			ExpectedType: ApplicationCredentialAuth,
+		},
+		{
+			Scenario: "application credential without secret",
+			Files: map[string]string{
+				"auth-url":                      "https://keystone.test/v3",
+				"application-credential-id":     "id",
+				"application-credential-secret": "",
+			},
+			ExpectErr: "Empty application credential secret",
+		},
+		{
+			Scenario:     "client certificate only",
+			Files:        map[string]string{"tls.crt": "cert", "tls.key": "key"},
+			ExpectedType: ClientCertAuth,
+			ExpectCert:   true,
+		},
+		{
+			Scenario:  "client certificate without key",
+			Files:     map[string]string{"tls.crt": "cert"},
+			ExpectErr: "has no matching tls.key",
+		},
+		{
+			Scenario: "client certificate and bearer token",
+			Files: map[string]string{
+				"tls.crt": "cert",
+				"tls.key": "key",
+				"token":   "secret-token",
+			},
+			ExpectedType: BearerTokenAuth,
+			ExpectCert:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "auth")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for name, content := range tc.Files {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			auth, err := loadFrom(dir)
+			if tc.ExpectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectErr) {
+					t.Errorf("Expected error containing '%s', got %v", tc.ExpectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error %s", err)
+			}
+			if auth.Type != tc.ExpectedType {
+				t.Errorf("Unexpected auth type %s", auth.Type)
+			}
+			if (auth.CertFile != "") != tc.ExpectCert {
+				t.Errorf("Unexpected client certificate '%s'", auth.CertFile)
+			}
+		})
+	}
+}
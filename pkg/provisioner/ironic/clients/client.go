@@ -1,16 +1,22 @@
 package clients
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path"
 	"time"
 
 	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/gophercloud/gophercloud/openstack/baremetal/httpbasic"
 	"github.com/gophercloud/gophercloud/openstack/baremetal/noauth"
 	httpbasicintrospection "github.com/gophercloud/gophercloud/openstack/baremetalintrospection/httpbasic"
 	noauthintrospection "github.com/gophercloud/gophercloud/openstack/baremetalintrospection/noauth"
+	"github.com/pkg/errors"
 	"go.etcd.io/etcd/pkg/transport"
 )
 
@@ -24,33 +30,145 @@ type TLSConfig struct {
 	InsecureSkipVerify bool
 }
 
-func updateHTTPClient(client *gophercloud.ServiceClient, tlsConf TLSConfig) (*gophercloud.ServiceClient, error) {
+// Fingerprint summarises the contents of the CA bundle and auth files
+// that clients built from the settings depend on. It changes whenever
+// one of the files is rotated, telling the caller to reload the auth
+// settings and build new clients.
+func Fingerprint(auth AuthConfig, tlsConf TLSConfig) string {
+	files := []string{tlsConf.TrustedCAFile}
+	if auth.authPath != "" {
+		for _, name := range authFiles {
+			files = append(files, path.Join(auth.authPath, name))
+		}
+	}
+
+	hash := sha256.New()
+	for _, filename := range files {
+		if filename == "" {
+			continue
+		}
+		// Missing files are not an error here; they only need to
+		// produce a different fingerprint from present ones.
+		content, err := ioutil.ReadFile(filename)
+		fmt.Fprintf(hash, "%s %t %d\n", filename, err == nil, len(content))
+		hash.Write(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// bearerTokenTransport adds the token found in a file to every
+// request, reading the file each time so the token can be rotated.
+type bearerTokenTransport struct {
+	tokenFile string
+	base      http.RoundTripper
+}
+
+func (t *bearerTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := readAuthFile(t.tokenFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read bearer token")
+	}
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+func newHTTPClient(auth AuthConfig, tlsConf TLSConfig) (c http.Client, err error) {
 	tlsInfo := transport.TLSInfo{
 		TrustedCAFile:      tlsConf.TrustedCAFile,
 		InsecureSkipVerify: tlsConf.InsecureSkipVerify,
+		CertFile:           auth.CertFile,
+		KeyFile:            auth.KeyFile,
 	}
 	if _, err := os.Stat(tlsConf.TrustedCAFile); err != nil {
 		if os.IsNotExist(err) {
 			tlsInfo.TrustedCAFile = ""
 		} else {
-			return client, err
+			return c, err
 		}
 	}
 	tlsTransport, err := transport.NewTransport(tlsInfo, tlsConnectionTimeout)
 	if err != nil {
-		return client, err
+		return c, err
+	}
+	c.Transport = tlsTransport
+	if auth.Type == BearerTokenAuth {
+		c.Transport = &bearerTokenTransport{
+			tokenFile: auth.TokenFile,
+			base:      tlsTransport,
+		}
 	}
-	c := http.Client{
-		Transport: tlsTransport,
+	return c, nil
+}
+
+func updateHTTPClient(client *gophercloud.ServiceClient, auth AuthConfig, tlsConf TLSConfig) (*gophercloud.ServiceClient, error) {
+	c, err := newHTTPClient(auth, tlsConf)
+	if err != nil {
+		return client, err
 	}
 	client.HTTPClient = c
 	return client, nil
 }
 
+func authenticateApplicationCredential(provider *gophercloud.ProviderClient, auth AuthConfig) error {
+	return openstack.AuthenticateV3(provider, &gophercloud.AuthOptions{
+		IdentityEndpoint:            auth.AuthURL,
+		ApplicationCredentialID:     auth.ApplicationCredentialID,
+		ApplicationCredentialSecret: auth.ApplicationCredentialSecret,
+	}, gophercloud.EndpointOpts{})
+}
+
+// applicationCredentialClient creates a client for endpoint that
+// authenticates with a token obtained from Keystone. When the token is
+// rejected, a new one is requested using the credentials as they are
+// in their files at that time.
+func applicationCredentialClient(endpoint string, auth AuthConfig, tlsConf TLSConfig) (*gophercloud.ServiceClient, error) {
+	httpClient, err := newHTTPClient(auth, tlsConf)
+	if err != nil {
+		return nil, err
+	}
+
+	newProvider := func(auth AuthConfig) (*gophercloud.ProviderClient, error) {
+		provider, err := openstack.NewClient(auth.AuthURL)
+		if err != nil {
+			return nil, err
+		}
+		provider.HTTPClient = httpClient
+		err = authenticateApplicationCredential(provider, auth)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to authenticate with keystone")
+		}
+		return provider, nil
+	}
+
+	provider, err := newProvider(auth)
+	if err != nil {
+		return nil, err
+	}
+	provider.ReauthFunc = func() error {
+		current, err := auth.Reload()
+		if err != nil {
+			return err
+		}
+		throwaway, err := newProvider(current)
+		if err != nil {
+			return err
+		}
+		provider.CopyTokenFrom(throwaway)
+		return nil
+	}
+
+	return &gophercloud.ServiceClient{
+		ProviderClient: provider,
+		Endpoint:       gophercloud.NormalizeURL(endpoint),
+	}, nil
+}
+
 // IronicClient creates a client for Ironic
 func IronicClient(ironicEndpoint string, auth AuthConfig, tls TLSConfig) (client *gophercloud.ServiceClient, err error) {
 	switch auth.Type {
-	case NoAuth:
+	case NoAuth, BearerTokenAuth, ClientCertAuth:
 		client, err = noauth.NewBareMetalNoAuth(noauth.EndpointOpts{
 			IronicEndpoint: ironicEndpoint,
 		})
@@ -60,19 +178,25 @@ func IronicClient(ironicEndpoint string, auth AuthConfig, tls TLSConfig) (client
 			IronicUser:         auth.Username,
 			IronicUserPassword: auth.Password,
 		})
+	case ApplicationCredentialAuth:
+		client, err = applicationCredentialClient(ironicEndpoint, auth, tls)
+		if err == nil {
+			client.Type = "baremetal"
+		}
+		return
 	default:
 		err = fmt.Errorf("Unknown auth type %s", auth.Type)
 	}
 	if err != nil {
 		return
 	}
-	return updateHTTPClient(client, tls)
+	return updateHTTPClient(client, auth, tls)
 }
 
 // InspectorClient creates a client for Ironic Inspector
 func InspectorClient(inspectorEndpoint string, auth AuthConfig, tls TLSConfig) (client *gophercloud.ServiceClient, err error) {
 	switch auth.Type {
-	case NoAuth:
+	case NoAuth, BearerTokenAuth, ClientCertAuth:
 		client, err = noauthintrospection.NewBareMetalIntrospectionNoAuth(
 			noauthintrospection.EndpointOpts{
 				IronicInspectorEndpoint: inspectorEndpoint,
@@ -83,11 +207,17 @@ func InspectorClient(inspectorEndpoint string, auth AuthConfig, tls TLSConfig) (
 			IronicInspectorUser:         auth.Username,
 			IronicInspectorUserPassword: auth.Password,
 		})
+	case ApplicationCredentialAuth:
+		client, err = applicationCredentialClient(inspectorEndpoint, auth, tls)
+		if err == nil {
+			client.Type = "baremetal-inspector"
+		}
+		return
 	default:
 		err = fmt.Errorf("Unknown auth type %s", auth.Type)
 	}
 	if err != nil {
 		return
 	}
-	return updateHTTPClient(client, tls)
+	return updateHTTPClient(client, auth, tls)
 }
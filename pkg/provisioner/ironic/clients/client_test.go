@@ -0,0 +1,255 @@
+package clients
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/drivers"
+)
+
+func writeAuthFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func tempAuthDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// listDrivers makes a simple request through the client and returns
+// any error.
+func listDrivers(t *testing.T, endpoint string, auth AuthConfig, tlsConf TLSConfig) func() error {
+	client, err := IronicClient(endpoint, auth, tlsConf)
+	if err != nil {
+		t.Fatalf("could not create client: %s", err)
+	}
+	return func() error {
+		_, err := drivers.ListDrivers(client, drivers.ListDriversOpts{}).AllPages()
+		return err
+	}
+}
+
+func driversHandler(check func(r *http.Request) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !check(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"drivers": []}`)
+	}
+}
+
+func TestBearerTokenRotation(t *testing.T) {
+	dir := tempAuthDir(t)
+	defer os.RemoveAll(dir)
+	writeAuthFiles(t, dir, map[string]string{"token": "first"})
+
+	var seen []string
+	server := httptest.NewServer(driversHandler(func(r *http.Request) bool {
+		seen = append(seen, r.Header.Get("Authorization"))
+		return true
+	}))
+	defer server.Close()
+
+	auth, err := loadFrom(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := listDrivers(t, server.URL+"/v1", auth, TLSConfig{})
+
+	if err := list(); err != nil {
+		t.Fatal(err)
+	}
+	writeAuthFiles(t, dir, map[string]string{"token": "second"})
+	if err := list(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 2 || seen[0] != "Bearer first" || seen[1] != "Bearer second" {
+		t.Errorf("Unexpected Authorization headers %v", seen)
+	}
+}
+
+func TestApplicationCredentialReauth(t *testing.T) {
+	dir := tempAuthDir(t)
+	defer os.RemoveAll(dir)
+
+	tokens := 0
+	validToken := ""
+	keystone := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Auth struct {
+				Identity struct {
+					ApplicationCredential struct {
+						ID     string `json:"id"`
+						Secret string `json:"secret"`
+					} `json:"application_credential"`
+				} `json:"identity"`
+			} `json:"auth"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		creds := body.Auth.Identity.ApplicationCredential
+		tokens++
+		validToken = fmt.Sprintf("token-%d-%s-%s", tokens, creds.ID, creds.Secret)
+		w.Header().Set("X-Subject-Token", validToken)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"token": {"catalog": []}}`)
+	}))
+	defer keystone.Close()
+
+	var seen []string
+	ironic := httptest.NewServer(driversHandler(func(r *http.Request) bool {
+		seen = append(seen, r.Header.Get("X-Auth-Token"))
+		return r.Header.Get("X-Auth-Token") == validToken
+	}))
+	defer ironic.Close()
+
+	writeAuthFiles(t, dir, map[string]string{
+		"auth-url":                      keystone.URL + "/v3",
+		"application-credential-id":     "id",
+		"application-credential-secret": "old",
+	})
+	auth, err := loadFrom(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := listDrivers(t, ironic.URL+"/v1", auth, TLSConfig{})
+
+	if err := list(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the secret and expire the token.
+	writeAuthFiles(t, dir, map[string]string{"application-credential-secret": "new"})
+	validToken = "expired"
+	if err := list(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"token-1-id-old", "token-1-id-old", "token-2-id-new"}
+	if fmt.Sprint(seen) != fmt.Sprint(expected) {
+		t.Errorf("Expected tokens %v, got %v", expected, seen)
+	}
+}
+
+// writeCert creates a certificate for name signed by the parent, or
+// self-signed when parent is nil, and writes it to dir.
+func writeCert(t *testing.T, dir, prefix, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeAuthFiles(t, dir, map[string]string{
+		prefix + ".crt": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		prefix + ".key": string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})),
+	})
+	return cert, key
+}
+
+func TestClientCertificateRotation(t *testing.T) {
+	dir := tempAuthDir(t)
+	defer os.RemoveAll(dir)
+
+	ca, caKey := writeCert(t, dir, "ca", "test-ca", nil, nil)
+	writeCert(t, dir, "server", "localhost", ca, caKey)
+	writeCert(t, dir, "tls", "first-client", ca, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	serverCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []string
+	server := httptest.NewUnstartedServer(driversHandler(func(r *http.Request) bool {
+		seen = append(seen, r.TLS.PeerCertificates[0].Subject.CommonName)
+		return true
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	auth, err := loadFrom(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Type != ClientCertAuth {
+		t.Fatalf("Unexpected auth type %s", auth.Type)
+	}
+	tlsConf := TLSConfig{TrustedCAFile: filepath.Join(dir, "ca.crt")}
+	list := listDrivers(t, server.URL+"/v1", auth, tlsConf)
+
+	if err := list(); err != nil {
+		t.Fatal(err)
+	}
+	writeCert(t, dir, "tls", "second-client", ca, caKey)
+	server.CloseClientConnections()
+	if err := list(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"first-client", "second-client"}
+	if fmt.Sprint(seen) != fmt.Sprint(expected) {
+		t.Errorf("Expected client certificates %v, got %v", expected, seen)
+	}
+}
@@ -0,0 +1,184 @@
+package ironic
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
+)
+
+// Config holds the settings used by the ironic provisioner.
+type Config struct {
+	DeployKernelURL           string
+	DeployRamdiskURL          string
+	IronicEndpoint            string
+	InspectorEndpoint         string
+	IronicTrustedCAFile       string
+	IronicInsecure            bool
+	AuthDir                   string
+	MaxProvisioningHosts      int
+	DeprovisionRequeueDelay   time.Duration
+	ProvisionRequeueDelay     time.Duration
+	PowerRequeueDelay         time.Duration
+	IntrospectionRequeueDelay time.Duration
+	SoftPowerOffTimeout       time.Duration
+}
+
+// DefaultConfig returns the settings used for any value that is not
+// given explicitly. The endpoints and deploy images have no default.
+func DefaultConfig() Config {
+	return Config{
+		IronicTrustedCAFile:       "/opt/metal3/certs/ca/crt",
+		AuthDir:                   clients.AuthRoot(),
+		MaxProvisioningHosts:      20,
+		DeprovisionRequeueDelay:   time.Second * 10,
+		ProvisionRequeueDelay:     time.Second * 10,
+		PowerRequeueDelay:         time.Second * 10,
+		IntrospectionRequeueDelay: time.Second * 15,
+		SoftPowerOffTimeout:       time.Second * 180,
+	}
+}
+
+// ConfigFromEnv returns the default settings updated with the values
+// of the environment variables the operator has always supported.
+func ConfigFromEnv() (Config, error) {
+	c := DefaultConfig()
+
+	c.DeployKernelURL = os.Getenv("DEPLOY_KERNEL_URL")
+	c.DeployRamdiskURL = os.Getenv("DEPLOY_RAMDISK_URL")
+	c.IronicEndpoint = os.Getenv("IRONIC_ENDPOINT")
+	c.InspectorEndpoint = os.Getenv("IRONIC_INSPECTOR_ENDPOINT")
+	if caFile := os.Getenv("IRONIC_CACERT_FILE"); caFile != "" {
+		c.IronicTrustedCAFile = caFile
+	}
+	if strings.ToLower(os.Getenv("IRONIC_INSECURE")) == "true" {
+		c.IronicInsecure = true
+	}
+
+	if maxHostsStr := os.Getenv("PROVISIONING_LIMIT"); maxHostsStr != "" {
+		value, err := strconv.Atoi(maxHostsStr)
+		if err != nil {
+			return c, fmt.Errorf("Invalid value set for variable PROVISIONING_LIMIT=%s", maxHostsStr)
+		}
+		c.MaxProvisioningHosts = value
+	}
+
+	return c, nil
+}
+
+// Validate reports the first problem found with the settings.
+func (c Config) Validate() error {
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"IRONIC_ENDPOINT", c.IronicEndpoint},
+		{"IRONIC_INSPECTOR_ENDPOINT", c.InspectorEndpoint},
+		{"DEPLOY_KERNEL_URL", c.DeployKernelURL},
+		{"DEPLOY_RAMDISK_URL", c.DeployRamdiskURL},
+	}
+	for _, setting := range required {
+		if setting.value == "" {
+			return fmt.Errorf("No %s variable set", setting.name)
+		}
+		if _, err := url.ParseRequestURI(setting.value); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Invalid value set for %s", setting.name))
+		}
+	}
+
+	if c.MaxProvisioningHosts < 1 {
+		return fmt.Errorf("Invalid provisioning limit %d, must be at least 1", c.MaxProvisioningHosts)
+	}
+
+	delays := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"deprovision requeue delay", c.DeprovisionRequeueDelay},
+		{"provision requeue delay", c.ProvisionRequeueDelay},
+		{"power requeue delay", c.PowerRequeueDelay},
+		{"introspection requeue delay", c.IntrospectionRequeueDelay},
+		{"soft power off timeout", c.SoftPowerOffTimeout},
+	}
+	for _, delay := range delays {
+		if delay.value <= 0 {
+			return fmt.Errorf("Invalid %s %s, must be positive", delay.name, delay.value)
+		}
+	}
+
+	return nil
+}
+
+// WithOverrides returns a copy of the settings with the values given
+// in a BareMetalOperatorConfig applied on top.
+func (c Config) WithOverrides(settings *metal3v1alpha1.IronicSettings) Config {
+	if settings == nil {
+		return c
+	}
+
+	overrideString := func(target *string, value string) {
+		if value != "" {
+			*target = value
+		}
+	}
+	overrideDuration := func(target *time.Duration, value *metav1.Duration) {
+		if value != nil {
+			*target = value.Duration
+		}
+	}
+
+	overrideString(&c.IronicEndpoint, settings.Endpoint)
+	overrideString(&c.InspectorEndpoint, settings.InspectorEndpoint)
+	overrideString(&c.IronicTrustedCAFile, settings.TrustedCAFile)
+	overrideString(&c.DeployKernelURL, settings.DeployKernelURL)
+	overrideString(&c.DeployRamdiskURL, settings.DeployRamdiskURL)
+	if settings.Insecure != nil {
+		c.IronicInsecure = *settings.Insecure
+	}
+	if settings.ProvisioningLimit != nil {
+		c.MaxProvisioningHosts = *settings.ProvisioningLimit
+	}
+	overrideDuration(&c.DeprovisionRequeueDelay, settings.DeprovisionRequeueDelay)
+	overrideDuration(&c.ProvisionRequeueDelay, settings.ProvisionRequeueDelay)
+	overrideDuration(&c.PowerRequeueDelay, settings.PowerRequeueDelay)
+	overrideDuration(&c.IntrospectionRequeueDelay, settings.IntrospectionRequeueDelay)
+	overrideDuration(&c.SoftPowerOffTimeout, settings.SoftPowerOffTimeout)
+
+	return c
+}
+
+// Settings returns the settings in the form reported in the status
+// of a BareMetalOperatorConfig.
+func (c Config) Settings() *metal3v1alpha1.IronicSettings {
+	insecure := c.IronicInsecure
+	limit := c.MaxProvisioningHosts
+	return &metal3v1alpha1.IronicSettings{
+		Endpoint:                  c.IronicEndpoint,
+		InspectorEndpoint:         c.InspectorEndpoint,
+		TrustedCAFile:             c.IronicTrustedCAFile,
+		Insecure:                  &insecure,
+		DeployKernelURL:           c.DeployKernelURL,
+		DeployRamdiskURL:          c.DeployRamdiskURL,
+		ProvisioningLimit:         &limit,
+		DeprovisionRequeueDelay:   &metav1.Duration{Duration: c.DeprovisionRequeueDelay},
+		ProvisionRequeueDelay:     &metav1.Duration{Duration: c.ProvisionRequeueDelay},
+		PowerRequeueDelay:         &metav1.Duration{Duration: c.PowerRequeueDelay},
+		IntrospectionRequeueDelay: &metav1.Duration{Duration: c.IntrospectionRequeueDelay},
+		SoftPowerOffTimeout:       &metav1.Duration{Duration: c.SoftPowerOffTimeout},
+	}
+}
+
+func (c Config) tlsConfig() clients.TLSConfig {
+	return clients.TLSConfig{
+		TrustedCAFile:      c.IronicTrustedCAFile,
+		InsecureSkipVerify: c.IronicInsecure,
+	}
+}
@@ -0,0 +1,141 @@
+package ironic
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		"DEPLOY_KERNEL_URL":         "http://env.test/kernel",
+		"DEPLOY_RAMDISK_URL":        "http://env.test/ramdisk",
+		"IRONIC_ENDPOINT":           "http://env.test:6385/v1/",
+		"IRONIC_INSPECTOR_ENDPOINT": "http://env.test:5050/v1/",
+		"IRONIC_CACERT_FILE":        "/tmp/ca.crt",
+		"IRONIC_INSECURE":           "True",
+		"PROVISIONING_LIMIT":        "5",
+	}
+	for key, value := range env {
+		if old, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, old)
+		} else {
+			defer os.Unsetenv(key)
+		}
+		os.Setenv(key, value)
+	}
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, "http://env.test/kernel", config.DeployKernelURL)
+	assert.Equal(t, "http://env.test/ramdisk", config.DeployRamdiskURL)
+	assert.Equal(t, "http://env.test:6385/v1/", config.IronicEndpoint)
+	assert.Equal(t, "http://env.test:5050/v1/", config.InspectorEndpoint)
+	assert.Equal(t, "/tmp/ca.crt", config.IronicTrustedCAFile)
+	assert.True(t, config.IronicInsecure)
+	assert.Equal(t, 5, config.MaxProvisioningHosts)
+	assert.NoError(t, config.Validate())
+
+	os.Setenv("PROVISIONING_LIMIT", "many")
+	_, err = ConfigFromEnv()
+	assert.EqualError(t, err, "Invalid value set for variable PROVISIONING_LIMIT=many")
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		name          string
+		modify        func(*Config)
+		expectedError string
+	}{
+		{
+			name:   "valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name:          "no-endpoint",
+			modify:        func(c *Config) { c.IronicEndpoint = "" },
+			expectedError: "No IRONIC_ENDPOINT variable set",
+		},
+		{
+			name:          "bad-kernel-url",
+			modify:        func(c *Config) { c.DeployKernelURL = "not a url" },
+			expectedError: "Invalid value set for DEPLOY_KERNEL_URL.*",
+		},
+		{
+			name:          "zero-limit",
+			modify:        func(c *Config) { c.MaxProvisioningHosts = 0 },
+			expectedError: "Invalid provisioning limit 0, must be at least 1",
+		},
+		{
+			name:          "negative-delay",
+			modify:        func(c *Config) { c.PowerRequeueDelay = -time.Second },
+			expectedError: "Invalid power requeue delay -1s, must be positive",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := testConfig
+			tc.modify(&config)
+			err := config.Validate()
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Regexp(t, tc.expectedError, err.Error())
+			}
+		})
+	}
+}
+
+func TestConfigWithOverrides(t *testing.T) {
+	insecure := true
+	limit := 3
+
+	config := testConfig.WithOverrides(&metal3v1alpha1.IronicSettings{
+		Endpoint:              "http://other.test/v1/",
+		Insecure:              &insecure,
+		ProvisioningLimit:     &limit,
+		ProvisionRequeueDelay: &metav1.Duration{Duration: time.Minute},
+	})
+
+	assert.Equal(t, "http://other.test/v1/", config.IronicEndpoint)
+	assert.Equal(t, testConfig.InspectorEndpoint, config.InspectorEndpoint)
+	assert.Equal(t, testConfig.DeployKernelURL, config.DeployKernelURL)
+	assert.True(t, config.IronicInsecure)
+	assert.Equal(t, 3, config.MaxProvisioningHosts)
+	assert.Equal(t, time.Minute, config.ProvisionRequeueDelay)
+	assert.Equal(t, testConfig.PowerRequeueDelay, config.PowerRequeueDelay)
+
+	assert.Equal(t, testConfig, testConfig.WithOverrides(nil))
+
+	settings := config.Settings()
+	assert.Equal(t, config, DefaultConfig().WithOverrides(settings))
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(testConfig)
+
+	config := testConfig
+	config.MaxProvisioningHosts = 0
+	assert.Error(t, Configure(config))
+	assert.Equal(t, testConfig, CurrentConfig())
+
+	config.MaxProvisioningHosts = 7
+	assert.NoError(t, Configure(config))
+	assert.Equal(t, config, CurrentConfig())
+
+	prov, err := New(makeHost(), bmc.Credentials{}, nullEventPublisher)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+	assert.Equal(t, 7, prov.(*ironicProvisioner).config.MaxProvisioningHosts)
+}
@@ -50,7 +50,7 @@ func TestDelete(t *testing.T) {
 				},
 			).DeleteError(nodeUUID, http.StatusConflict),
 			expectedDirty:        true,
-			expectedRequestAfter: provisionRequeueDelay,
+			expectedRequestAfter: testConfig.ProvisionRequeueDelay,
 		},
 		{
 			name: "delete-host-not-found",
@@ -97,7 +97,7 @@ func TestDelete(t *testing.T) {
 				},
 			),
 			expectedDirty:        true,
-			expectedRequestAfter: provisionRequeueDelay,
+			expectedRequestAfter: testConfig.ProvisionRequeueDelay,
 		},
 		{
 			name: "not-in-maintenance-update-fail",
@@ -122,7 +122,7 @@ func TestDelete(t *testing.T) {
 			).NodeUpdateError(nodeUUID, http.StatusConflict),
 
 			expectedDirty:        true,
-			expectedRequestAfter: provisionRequeueDelay,
+			expectedRequestAfter: testConfig.ProvisionRequeueDelay,
 		},
 		{
 			name: "not-in-maintenance-update",
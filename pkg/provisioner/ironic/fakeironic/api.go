@@ -0,0 +1,412 @@
+package fakeironic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/drivers"
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/ports"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// apiError is an error reported to the client with an HTTP status.
+type apiError struct {
+	code    int
+	message string
+}
+
+func (e apiError) Error() string {
+	return e.message
+}
+
+func errorf(code int, format string, args ...interface{}) error {
+	return apiError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if body != nil {
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	if apiErr, ok := err.(apiError); ok {
+		code = apiErr.code
+	}
+	log.Info("request failed", "code", code, "error", err.Error())
+	writeJSON(w, code, map[string]string{"error_message": err.Error()})
+}
+
+func readJSON(r *http.Request, out interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		return errorf(http.StatusBadRequest, "invalid request body: %s", err)
+	}
+	return nil
+}
+
+func (i *Ironic) handleRoot(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1" && r.URL.Path != "/v1/" {
+		writeError(w, errorf(http.StatusNotFound, "unknown resource %s", r.URL.Path))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id": "v1"})
+}
+
+func (i *Ironic) handleDrivers(w http.ResponseWriter, r *http.Request) {
+	names := []string{"fake-hardware", "idrac", "ilo", "ipmi", "irmc", "redfish"}
+	result := []drivers.Driver{}
+	for _, name := range names {
+		result = append(result, drivers.Driver{Name: name, Hosts: []string{"fake-ironic"}, Type: "dynamic"})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"drivers": result})
+}
+
+// findNode returns the node with the given UUID or name.
+func (i *Ironic) findNode(ident string) *node {
+	if n, ok := i.nodes[ident]; ok {
+		return n
+	}
+	for _, n := range i.nodes {
+		if n.name() == ident && ident != "" {
+			return n
+		}
+	}
+	return nil
+}
+
+func (i *Ironic) handleNodes(w http.ResponseWriter, r *http.Request) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		result := []map[string]interface{}{}
+		for _, n := range i.nodes {
+			n.advance(i.StepDelay)
+			result = append(result, n.data)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"nodes": result})
+
+	case http.MethodPost:
+		body := map[string]interface{}{}
+		if err := readJSON(r, &body); err != nil {
+			writeError(w, err)
+			return
+		}
+		n, err := i.createNode(body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, n.data)
+
+	default:
+		writeError(w, errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+	}
+}
+
+func (i *Ironic) createNode(body map[string]interface{}) (*node, error) {
+	if driver, _ := body["driver"].(string); driver == "" {
+		return nil, errorf(http.StatusBadRequest, "a driver is required")
+	}
+	if name, _ := body["name"].(string); name != "" && i.findNode(name) != nil {
+		return nil, errorf(http.StatusConflict, "a node with name %s already exists", name)
+	}
+
+	n := &node{data: body}
+	id, _ := body["uuid"].(string)
+	if id == "" {
+		id = string(uuid.NewUUID())
+	}
+	if _, exists := i.nodes[id]; exists {
+		return nil, errorf(http.StatusConflict, "a node with UUID %s already exists", id)
+	}
+
+	defaults := map[string]interface{}{
+		"uuid":                   id,
+		"target_provision_state": nil,
+		"power_state":            nil,
+		"target_power_state":     nil,
+		"maintenance":            false,
+		"last_error":             nil,
+		"automated_clean":        nil,
+		"instance_uuid":          nil,
+		"created_at":             time.Now().UTC().Format(time.RFC3339),
+	}
+	for _, section := range []string{"driver_info", "driver_internal_info", "instance_info", "properties", "extra"} {
+		defaults[section] = map[string]interface{}{}
+	}
+	for key, value := range defaults {
+		if n.data[key] == nil {
+			n.data[key] = value
+		}
+	}
+	n.setProvisionState(nodes.Enroll)
+
+	i.nodes[id] = n
+	log.Info("created node", "uuid", id, "name", n.name())
+	return n, nil
+}
+
+func (i *Ironic) handleNode(w http.ResponseWriter, r *http.Request) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/nodes/"), "/")
+	n := i.findNode(parts[0])
+	if n == nil {
+		writeError(w, errorf(http.StatusNotFound, "node %s could not be found", parts[0]))
+		return
+	}
+	action := strings.Join(parts[1:], "/")
+
+	var err error
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		n.advance(i.StepDelay)
+		writeJSON(w, http.StatusOK, n.data)
+		return
+
+	case action == "" && r.Method == http.MethodPatch:
+		var patch []patchOperation
+		if err = readJSON(r, &patch); err == nil {
+			err = n.applyPatch(patch)
+		}
+		if err == nil {
+			writeJSON(w, http.StatusOK, n.data)
+			return
+		}
+
+	case action == "" && r.Method == http.MethodDelete:
+		if err = i.deleteNode(n); err == nil {
+			writeJSON(w, http.StatusNoContent, nil)
+			return
+		}
+
+	case action == "validate" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, n.validate())
+		return
+
+	case action == "states/provision" && r.Method == http.MethodPut:
+		opts := nodes.ProvisionStateOpts{}
+		if err = readJSON(r, &opts); err == nil {
+			err = i.changeProvisionState(n, opts)
+		}
+		if err == nil {
+			writeJSON(w, http.StatusAccepted, nil)
+			return
+		}
+
+	case action == "states/power" && r.Method == http.MethodPut:
+		opts := nodes.PowerStateOpts{}
+		if err = readJSON(r, &opts); err == nil {
+			err = i.changePowerState(n, opts)
+		}
+		if err == nil {
+			writeJSON(w, http.StatusAccepted, nil)
+			return
+		}
+
+	default:
+		err = errorf(http.StatusNotFound, "unknown resource %s", r.URL.Path)
+	}
+	writeError(w, err)
+}
+
+func (i *Ironic) deleteNode(n *node) error {
+	switch n.provisionState() {
+	case nodes.Enroll, nodes.Manageable, nodes.AdoptFail:
+	default:
+		if maintenance, _ := n.data["maintenance"].(bool); !maintenance {
+			return errorf(http.StatusConflict,
+				"node %s can not be deleted while it is in state %q", n.uuid(), n.provisionState())
+		}
+	}
+	for id, port := range i.ports {
+		if port.NodeUUID == n.uuid() {
+			delete(i.ports, id)
+		}
+	}
+	delete(i.nodes, n.uuid())
+	log.Info("deleted node", "uuid", n.uuid(), "name", n.name())
+	return nil
+}
+
+func (i *Ironic) changeProvisionState(n *node, opts nodes.ProvisionStateOpts) error {
+	if target := n.str("target_provision_state"); target != "" {
+		return errorf(http.StatusConflict,
+			"node %s is locked while it is moving to %q", n.uuid(), target)
+	}
+
+	trans, ok := transitions[opts.Target]
+	if !ok {
+		return errorf(http.StatusBadRequest, "unsupported provision target %q", opts.Target)
+	}
+	allowed := false
+	for _, state := range trans.from {
+		allowed = allowed || state == n.provisionState()
+	}
+	if !allowed {
+		return errorf(http.StatusBadRequest,
+			"the requested action %q can not be performed on node %s while it is in state %q",
+			opts.Target, n.uuid(), n.provisionState())
+	}
+
+	failMessage, _ := i.takeFailure(n, opts.Target)
+	var settled func(n *node)
+
+	switch opts.Target {
+	case nodes.TargetManage:
+		if n.provisionState() == nodes.Enroll {
+			if validation := n.validate(); !validation.Power.Result && failMessage == "" {
+				failMessage = fmt.Sprintf("Failed to get power state for node %s: %s",
+					n.uuid(), validation.Power.Reason)
+			}
+			settled = func(n *node) {
+				n.data["power_state"] = powerOff
+			}
+		}
+
+	case nodes.TargetInspect:
+		n.startIntrospection()
+		settled = func(n *node) {
+			n.finishIntrospection(i.bootMAC(n))
+			n.data["power_state"] = powerOff
+		}
+
+	case nodes.TargetProvide:
+		if clean, ok := n.data["automated_clean"].(bool); ok && !clean {
+			trans.through = trans.through[len(trans.through)-1:]
+		}
+		settled = func(n *node) {
+			n.data["power_state"] = powerOff
+		}
+
+	case nodes.TargetActive:
+		validation := n.validate()
+		for _, result := range []nodes.DriverValidation{validation.Deploy, validation.Boot, validation.Power} {
+			if !result.Result {
+				return errorf(http.StatusBadRequest, "node %s failed validation: %s", n.uuid(), result.Reason)
+			}
+		}
+		if opts.ConfigDrive != nil {
+			n.section("instance_info")["configdrive"] = opts.ConfigDrive
+		}
+		settled = func(n *node) {
+			n.data["power_state"] = powerOn
+		}
+
+	case nodes.TargetDeleted:
+		settled = func(n *node) {
+			delete(n.section("instance_info"), "configdrive")
+			n.data["power_state"] = powerOff
+		}
+
+	case nodes.TargetAdopt:
+		settled = func(n *node) {
+			n.data["power_state"] = powerOn
+		}
+	}
+
+	log.Info("changing provision state", "uuid", n.uuid(), "name", n.name(),
+		"current", n.provisionState(), "target", opts.Target, "willFail", failMessage != "")
+	n.startTransition(trans, failMessage, settled)
+	return nil
+}
+
+func (i *Ironic) changePowerState(n *node, opts nodes.PowerStateOpts) error {
+	if target := n.str("target_provision_state"); target != "" {
+		return errorf(http.StatusConflict,
+			"node %s is locked while it is moving to %q", n.uuid(), target)
+	}
+	if n.str("target_power_state") != "" {
+		return errorf(http.StatusConflict, "node %s is already changing its power state", n.uuid())
+	}
+
+	switch opts.Target {
+	case nodes.PowerOn, nodes.Rebooting, nodes.SoftRebooting:
+		n.data["target_power_state"] = powerOn
+	case nodes.PowerOff, nodes.SoftPowerOff:
+		n.data["target_power_state"] = powerOff
+	default:
+		return errorf(http.StatusBadRequest, "unsupported power target %q", opts.Target)
+	}
+	n.data["last_error"] = nil
+	n.changed = time.Now()
+	log.Info("changing power state", "uuid", n.uuid(), "name", n.name(), "target", opts.Target)
+	return nil
+}
+
+// nodePorts returns the ports of the node.
+func (i *Ironic) nodePorts(n *node) (result []*ports.Port) {
+	for _, port := range i.ports {
+		if port.NodeUUID == n.uuid() {
+			result = append(result, port)
+		}
+	}
+	return
+}
+
+func (i *Ironic) handlePorts(w http.ResponseWriter, r *http.Request) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		address := strings.ToLower(r.URL.Query().Get("address"))
+		nodeUUID := r.URL.Query().Get("node_uuid")
+		result := []*ports.Port{}
+		for _, port := range i.ports {
+			if (address == "" || port.Address == address) && (nodeUUID == "" || port.NodeUUID == nodeUUID) {
+				result = append(result, port)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ports": result})
+
+	case http.MethodPost:
+		opts := ports.CreateOpts{}
+		if err := readJSON(r, &opts); err != nil {
+			writeError(w, err)
+			return
+		}
+		port, err := i.createPort(opts)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, port)
+
+	default:
+		writeError(w, errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+	}
+}
+
+func (i *Ironic) createPort(opts ports.CreateOpts) (*ports.Port, error) {
+	if _, exists := i.nodes[opts.NodeUUID]; !exists {
+		return nil, errorf(http.StatusBadRequest, "node %s could not be found", opts.NodeUUID)
+	}
+	address := strings.ToLower(opts.Address)
+	for _, port := range i.ports {
+		if port.Address == address {
+			return nil, errorf(http.StatusConflict, "a port with MAC address %s already exists", address)
+		}
+	}
+
+	port := &ports.Port{
+		UUID:       string(uuid.NewUUID()),
+		Address:    address,
+		NodeUUID:   opts.NodeUUID,
+		PXEEnabled: opts.PXEEnabled == nil || *opts.PXEEnabled,
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+	}
+	i.ports[port.UUID] = port
+	return port, nil
+}
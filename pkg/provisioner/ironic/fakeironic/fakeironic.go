@@ -0,0 +1,151 @@
+// Package fakeironic is an in-memory simulation of the parts of the
+// Ironic and Ironic Inspector APIs used by the ironic provisioner.
+//
+// Unlike the mocks in the testserver package, which replay canned
+// responses, the simulator keeps the nodes and ports created through
+// the API and moves the nodes through the provision and power state
+// machines the way a conductor would. It can be embedded in unit tests
+// or run on its own by cmd/fake-ironic.
+package fakeironic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/ports"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("fakeironic")
+
+// DefaultStepDelay is a step delay slow enough to follow the progress
+// of a host when running the simulator on its own.
+const DefaultStepDelay = time.Second * 5
+
+// Ironic simulates an Ironic conductor and the matching Inspector
+// service. The zero value is not usable, call New.
+type Ironic struct {
+	// StepDelay is how long a node stays in each intermediate
+	// provision state and how long power changes take. With no delay
+	// a node moves on by one state every time it is read, which keeps
+	// tests deterministic.
+	StepDelay time.Duration
+
+	lock     sync.Mutex
+	nodes    map[string]*node
+	ports    map[string]*ports.Port
+	failures map[string]map[nodes.TargetProvisionState]string
+
+	ironicServer    *httptest.Server
+	inspectorServer *httptest.Server
+}
+
+// New returns a simulator without any nodes.
+func New() *Ironic {
+	return &Ironic{
+		nodes:    make(map[string]*node),
+		ports:    make(map[string]*ports.Port),
+		failures: make(map[string]map[nodes.TargetProvisionState]string),
+	}
+}
+
+// FailNext makes the next provision state change of the node with the
+// given name or UUID to the target fail with the message, leaving the
+// node in the matching failure state. The node does not have to exist
+// yet.
+func (i *Ironic) FailNext(ident string, target nodes.TargetProvisionState, message string) *Ironic {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.failures[ident] == nil {
+		i.failures[ident] = make(map[nodes.TargetProvisionState]string)
+	}
+	i.failures[ident][target] = message
+	return i
+}
+
+// takeFailure returns and forgets the failure registered for the
+// change of the node to the target, if there is one.
+func (i *Ironic) takeFailure(n *node, target nodes.TargetProvisionState) (message string, ok bool) {
+	for _, ident := range []string{n.uuid(), n.name()} {
+		if message, ok = i.failures[ident][target]; ok {
+			delete(i.failures[ident], target)
+			return
+		}
+	}
+	return
+}
+
+// Node returns the current state of the node with the given name or
+// UUID, without moving it on.
+func (i *Ironic) Node(ident string) (result nodes.Node, ok bool) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	n := i.findNode(ident)
+	if n == nil {
+		return
+	}
+	if err := n.decode(&result); err != nil {
+		return
+	}
+	return result, true
+}
+
+// IronicHandler returns the handler serving the Ironic API.
+func (i *Ironic) IronicHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1", i.handleRoot)
+	mux.HandleFunc("/v1/", i.handleRoot)
+	mux.HandleFunc("/v1/drivers", i.handleDrivers)
+	mux.HandleFunc("/v1/nodes", i.handleNodes)
+	mux.HandleFunc("/v1/nodes/", i.handleNode)
+	mux.HandleFunc("/v1/ports", i.handlePorts)
+	// Nodes and ports are always listed with all their fields.
+	mux.HandleFunc("/v1/nodes/detail", i.handleNodes)
+	mux.HandleFunc("/v1/ports/detail", i.handlePorts)
+	return logRequests("ironic", mux)
+}
+
+// InspectorHandler returns the handler serving the Ironic Inspector
+// API.
+func (i *Ironic) InspectorHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1", i.handleRoot)
+	mux.HandleFunc("/v1/", i.handleRoot)
+	mux.HandleFunc("/v1/introspection/", i.handleIntrospection)
+	return logRequests("inspector", mux)
+}
+
+// Start runs the Ironic and Inspector APIs on local test servers.
+func (i *Ironic) Start() *Ironic {
+	i.ironicServer = httptest.NewServer(i.IronicHandler())
+	i.inspectorServer = httptest.NewServer(i.InspectorHandler())
+	return i
+}
+
+// Stop shuts down the servers run by Start.
+func (i *Ironic) Stop() {
+	i.ironicServer.Close()
+	i.inspectorServer.Close()
+}
+
+// IronicEndpoint returns the URL of the Ironic API run by Start.
+func (i *Ironic) IronicEndpoint() string {
+	return i.ironicServer.URL + "/v1/"
+}
+
+// InspectorEndpoint returns the URL of the Inspector API run by Start.
+func (i *Ironic) InspectorEndpoint() string {
+	return i.inspectorServer.URL + "/v1/"
+}
+
+func logRequests(name string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.V(1).Info("request", "api", name, "method", r.Method, "url", r.URL.String())
+		handler.ServeHTTP(w, r)
+	})
+}
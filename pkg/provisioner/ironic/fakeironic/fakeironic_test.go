@@ -0,0 +1,90 @@
+package fakeironic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func newNode(t *testing.T, sim *Ironic) *node {
+	n, err := sim.createNode(map[string]interface{}{
+		"name":   "node-0",
+		"driver": "ipmi",
+		"driver_info": map[string]interface{}{
+			"ipmi_address": "192.168.111.1",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestApplyPatch(t *testing.T) {
+	n := newNode(t, New())
+
+	err := n.applyPatch([]patchOperation{
+		{Op: "add", Path: "/instance_info/image_source", Value: "http://images.test/image.qcow2"},
+		{Op: "replace", Path: "/properties/capabilities", Value: "boot_mode:uefi"},
+		{Op: "remove", Path: "/driver_info/ipmi_address"},
+		{Op: "replace", Path: "/maintenance", Value: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://images.test/image.qcow2", n.section("instance_info")["image_source"])
+	assert.Equal(t, "boot_mode:uefi", n.section("properties")["capabilities"])
+	assert.NotContains(t, n.section("driver_info"), "ipmi_address")
+	assert.Equal(t, true, n.data["maintenance"])
+
+	// A failing operation leaves the node unchanged.
+	err = n.applyPatch([]patchOperation{
+		{Op: "add", Path: "/name", Value: "renamed"},
+		{Op: "remove", Path: "/driver_info/missing"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "node-0", n.name())
+
+	err = n.applyPatch([]patchOperation{{Op: "replace", Path: "/provision_state", Value: "active"}})
+	assert.Error(t, err)
+}
+
+func TestProvisionStateMachine(t *testing.T) {
+	sim := New()
+	n := newNode(t, sim)
+
+	err := sim.changeProvisionState(n, nodes.ProvisionStateOpts{Target: nodes.TargetProvide})
+	assert.Equal(t, apiError{http.StatusBadRequest,
+		"the requested action \"provide\" can not be performed on node " + n.uuid() + " while it is in state \"enroll\""}, err)
+
+	assert.NoError(t, sim.changeProvisionState(n, nodes.ProvisionStateOpts{Target: nodes.TargetManage}))
+	assert.Equal(t, nodes.Verifying, n.provisionState())
+
+	// The node is locked until it settles.
+	err = sim.changeProvisionState(n, nodes.ProvisionStateOpts{Target: nodes.TargetInspect})
+	assert.Equal(t, http.StatusConflict, err.(apiError).code)
+	err = sim.changePowerState(n, nodes.PowerStateOpts{Target: nodes.PowerOn})
+	assert.Equal(t, http.StatusConflict, err.(apiError).code)
+
+	n.advance(0)
+	assert.Equal(t, nodes.Manageable, n.provisionState())
+	assert.Equal(t, powerOff, n.str("power_state"))
+	assert.Equal(t, "", n.str("target_provision_state"))
+
+	sim.FailNext("node-0", nodes.TargetProvide, "cleaning timed out")
+	assert.NoError(t, sim.changeProvisionState(n, nodes.ProvisionStateOpts{Target: nodes.TargetProvide}))
+	var states []nodes.ProvisionState
+	for n.str("target_provision_state") != "" {
+		n.advance(0)
+		states = append(states, n.provisionState())
+	}
+	assert.Equal(t, []nodes.ProvisionState{nodes.CleanWait, nodes.CleanFail}, states)
+	assert.Equal(t, "cleaning timed out", n.str("last_error"))
+	assert.Equal(t, true, n.data["maintenance"])
+
+	assert.NoError(t, sim.changePowerState(n, nodes.PowerStateOpts{Target: nodes.PowerOn}))
+	assert.Equal(t, powerOff, n.str("power_state"))
+	n.advance(0)
+	assert.Equal(t, powerOn, n.str("power_state"))
+	assert.Equal(t, "", n.str("target_power_state"))
+}
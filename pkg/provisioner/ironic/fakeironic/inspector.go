@@ -0,0 +1,185 @@
+package fakeironic
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/ports"
+	"github.com/gophercloud/gophercloud/openstack/baremetalintrospection/v1/introspection"
+)
+
+func (n *node) startIntrospection() {
+	n.introspection = &introspection.Introspection{
+		UUID:      n.uuid(),
+		State:     "waiting",
+		StartedAt: time.Now().UTC(),
+	}
+	n.introspectionData = nil
+}
+
+// finishIntrospection records the data found on the node. The data
+// is generated from the node UUID so it stays the same across runs.
+func (n *node) finishIntrospection(bootMAC string) {
+	sum := fnv.New32a()
+	sum.Write([]byte(n.uuid()))
+	seed := sum.Sum32()
+
+	otherMAC := macAddress(seed + 1)
+	bootIP := fmt.Sprintf("192.168.111.%d", 20+seed%200)
+
+	bootMode := "bios"
+	if capabilities, _ := n.section("properties")["capabilities"].(string); strings.Contains(capabilities, "boot_mode:uefi") {
+		bootMode = "uefi"
+	}
+
+	n.introspectionData = &introspection.Data{
+		BootInterface: "01-" + strings.Replace(bootMAC, ":", "-", -1),
+		CPUArch:       "x86_64",
+		CPUs:          8,
+		MemoryMB:      16384,
+		LocalGB:       199,
+		MACs:          []string{bootMAC, otherMAC},
+		AllInterfaces: map[string]introspection.BaseInterfaceType{
+			"eth0": {IP: bootIP, MAC: bootMAC, PXE: true},
+			"eth1": {MAC: otherMAC},
+		},
+		Inventory: introspection.InventoryType{
+			Boot: introspection.BootInfoType{
+				CurrentBootMode: bootMode,
+				PXEInterface:    bootMAC,
+			},
+			CPU: introspection.CPUType{
+				Architecture: "x86_64",
+				Count:        8,
+				Flags:        []string{"aes", "avx2", "sse4_2", "vmx"},
+				Frequency:    "2400.000",
+				ModelName:    "Fake Ironic CPU",
+			},
+			Disks: []introspection.RootDiskType{
+				{
+					Name:   "/dev/sda",
+					Model:  "Fake SSD",
+					Vendor: "Metal3",
+					Serial: fmt.Sprintf("SSD-%08x", seed),
+					Size:   214748364800,
+				},
+				{
+					Name:       "/dev/sdb",
+					Model:      "Fake HDD",
+					Vendor:     "Metal3",
+					Serial:     fmt.Sprintf("HDD-%08x", seed),
+					Size:       1000204886016,
+					Rotational: true,
+				},
+			},
+			Interfaces: []introspection.InterfaceType{
+				{Name: "eth0", MACAddress: bootMAC, IPV4Address: bootIP, HasCarrier: true, Vendor: "0x1af4", Product: "0x0001"},
+				{Name: "eth1", MACAddress: otherMAC, HasCarrier: true, Vendor: "0x1af4", Product: "0x0001"},
+			},
+			Memory: introspection.MemoryType{
+				PhysicalMb: 16384,
+				Total:      17179869184,
+			},
+			SystemVendor: introspection.SystemVendorType{
+				Manufacturer: "Metal3",
+				ProductName:  "Fake Ironic Node",
+				SerialNumber: fmt.Sprintf("FI-%08x", seed),
+			},
+			Hostname: n.name(),
+		},
+		Extra: introspection.ExtraHardwareDataType{
+			Firmware: introspection.ExtraHardwareDataSection{
+				"bios": {"vendor": "Metal3", "version": "1.0.0", "date": "01/01/2021"},
+			},
+			Network: introspection.ExtraHardwareDataSection{
+				"eth0": {"speed": "10Gbps"},
+				"eth1": {"speed": "1Gbps"},
+			},
+		},
+	}
+
+	n.introspection.Finished = true
+	n.introspection.State = "finished"
+	n.introspection.FinishedAt = time.Now().UTC()
+}
+
+// macAddress builds a locally administered MAC address from the seed.
+func macAddress(seed uint32) string {
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", byte(seed>>16), byte(seed>>8), byte(seed))
+}
+
+// bootMAC returns the address of the port the node boots from. Like
+// Inspector, it creates a port for the node if it has none.
+func (i *Ironic) bootMAC(n *node) string {
+	var found *ports.Port
+	for _, port := range i.nodePorts(n) {
+		if found == nil || port.PXEEnabled && !found.PXEEnabled {
+			found = port
+		}
+	}
+	if found != nil {
+		return found.Address
+	}
+
+	sum := fnv.New32a()
+	sum.Write([]byte(n.uuid()))
+	pxe := true
+	port, err := i.createPort(ports.CreateOpts{
+		NodeUUID:   n.uuid(),
+		Address:    macAddress(sum.Sum32()),
+		PXEEnabled: &pxe,
+	})
+	if err != nil {
+		log.Error(err, "could not create a port for the inspected node", "uuid", n.uuid())
+		return macAddress(sum.Sum32())
+	}
+	return port.Address
+}
+
+func (i *Ironic) handleIntrospection(w http.ResponseWriter, r *http.Request) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/introspection/"), "/")
+	n := i.findNode(parts[0])
+	if r.Method != http.MethodGet {
+		writeError(w, errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+		return
+	}
+	if n == nil || n.introspection == nil {
+		writeError(w, errorf(http.StatusNotFound, "introspection data not found for node %s", parts[0]))
+		return
+	}
+
+	switch strings.Join(parts[1:], "/") {
+	case "":
+		status := map[string]interface{}{
+			"uuid":       n.introspection.UUID,
+			"finished":   n.introspection.Finished,
+			"state":      n.introspection.State,
+			"error":      nil,
+			"started_at": n.introspection.StartedAt.Format(time.RFC3339),
+			"links":      []interface{}{},
+		}
+		if n.introspection.Error != "" {
+			status["error"] = n.introspection.Error
+		}
+		if n.introspection.Finished {
+			status["finished_at"] = n.introspection.FinishedAt.Format(time.RFC3339)
+		}
+		writeJSON(w, http.StatusOK, status)
+
+	case "data":
+		if n.introspectionData == nil {
+			writeError(w, errorf(http.StatusNotFound, "introspection data not found for node %s", parts[0]))
+			return
+		}
+		writeJSON(w, http.StatusOK, n.introspectionData)
+
+	default:
+		writeError(w, errorf(http.StatusNotFound, "unknown resource %s", r.URL.Path))
+	}
+}
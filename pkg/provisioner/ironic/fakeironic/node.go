@@ -0,0 +1,248 @@
+package fakeironic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/gophercloud/gophercloud/openstack/baremetalintrospection/v1/introspection"
+)
+
+const (
+	powerOn  = "power on"
+	powerOff = "power off"
+)
+
+// node is a node known to the simulator. The fields returned by the
+// API are kept as a JSON document so that updates can be applied as
+// JSON patches without the simulator knowing about every field.
+type node struct {
+	data map[string]interface{}
+
+	// changed is the time of the last state change, used to pace the
+	// progress through the pending states.
+	changed time.Time
+	// pending lists the provision states the node still has to pass
+	// through before it settles in the last one.
+	pending []nodes.ProvisionState
+	// failState replaces the last pending state when the current
+	// operation is going to fail with failMessage.
+	failState   nodes.ProvisionState
+	failMessage string
+	// settled is called once the last pending state is reached.
+	settled func(n *node)
+
+	introspection     *introspection.Introspection
+	introspectionData *introspection.Data
+}
+
+func (n *node) str(key string) string {
+	value, _ := n.data[key].(string)
+	return value
+}
+
+func (n *node) uuid() string {
+	return n.str("uuid")
+}
+
+func (n *node) name() string {
+	return n.str("name")
+}
+
+func (n *node) provisionState() nodes.ProvisionState {
+	return nodes.ProvisionState(n.str("provision_state"))
+}
+
+func (n *node) section(key string) map[string]interface{} {
+	value, _ := n.data[key].(map[string]interface{})
+	return value
+}
+
+func (n *node) decode(out interface{}) error {
+	content, err := json.Marshal(n.data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, out)
+}
+
+func (n *node) setProvisionState(state nodes.ProvisionState) {
+	n.data["provision_state"] = string(state)
+	n.data["provision_updated_at"] = time.Now().UTC().Format(time.RFC3339)
+	n.changed = time.Now()
+}
+
+// transition describes how a node moves to a requested target.
+type transition struct {
+	// from lists the states the change may be requested in
+	from []nodes.ProvisionState
+	// through lists the states the node passes through, the last
+	// being where it settles
+	through []nodes.ProvisionState
+	// fail is the state the node settles in if the change fails
+	fail nodes.ProvisionState
+}
+
+var transitions = map[nodes.TargetProvisionState]transition{
+	nodes.TargetManage: {
+		from: []nodes.ProvisionState{nodes.Enroll, nodes.Manageable, nodes.Available,
+			nodes.CleanFail, nodes.InspectFail, nodes.AdoptFail},
+		through: []nodes.ProvisionState{nodes.Manageable},
+		fail:    nodes.Enroll,
+	},
+	nodes.TargetInspect: {
+		from:    []nodes.ProvisionState{nodes.Manageable, nodes.InspectFail},
+		through: []nodes.ProvisionState{nodes.Inspecting, nodes.InspectWait, nodes.Manageable},
+		fail:    nodes.InspectFail,
+	},
+	nodes.TargetProvide: {
+		from:    []nodes.ProvisionState{nodes.Manageable},
+		through: []nodes.ProvisionState{nodes.Cleaning, nodes.CleanWait, nodes.Available},
+		fail:    nodes.CleanFail,
+	},
+	nodes.TargetActive: {
+		from:    []nodes.ProvisionState{nodes.Available, nodes.DeployFail},
+		through: []nodes.ProvisionState{nodes.Deploying, nodes.DeployWait, nodes.Deploying, nodes.Active},
+		fail:    nodes.DeployFail,
+	},
+	nodes.TargetDeleted: {
+		from:    []nodes.ProvisionState{nodes.Active, nodes.DeployFail, nodes.Error},
+		through: []nodes.ProvisionState{nodes.Deleting, nodes.Cleaning, nodes.CleanWait, nodes.Available},
+		fail:    nodes.Error,
+	},
+	nodes.TargetAdopt: {
+		from:    []nodes.ProvisionState{nodes.Manageable, nodes.AdoptFail},
+		through: []nodes.ProvisionState{nodes.Adopting, nodes.Active},
+		fail:    nodes.AdoptFail,
+	},
+}
+
+// startTransition moves the node to the first state on the way to the
+// target. The request has already been validated.
+func (n *node) startTransition(trans transition, failMessage string, settled func(n *node)) {
+	through := trans.through
+	if n.provisionState() == nodes.Enroll {
+		// Moving out of enroll verifies the BMC settings first.
+		through = append([]nodes.ProvisionState{nodes.Verifying}, through...)
+	}
+
+	n.pending = through[1:]
+	n.failState = ""
+	n.failMessage = ""
+	if failMessage != "" {
+		n.failState = trans.fail
+		n.failMessage = failMessage
+	}
+	n.settled = settled
+
+	n.data["last_error"] = nil
+	n.data["target_provision_state"] = string(through[len(through)-1])
+	n.setProvisionState(through[0])
+	if len(n.pending) == 0 {
+		n.settle()
+	}
+}
+
+// settle finishes the current operation in the state it was heading to.
+func (n *node) settle() {
+	n.data["target_provision_state"] = nil
+	if n.failState != "" {
+		n.setProvisionState(n.failState)
+		n.data["last_error"] = n.failMessage
+		if n.failState == nodes.CleanFail {
+			n.data["maintenance"] = true
+			n.data["maintenance_reason"] = n.failMessage
+		}
+		if n.introspection != nil && !n.introspection.Finished {
+			n.introspection.Finished = true
+			n.introspection.State = "error"
+			n.introspection.Error = n.failMessage
+			n.introspection.FinishedAt = time.Now().UTC()
+		}
+		n.failState = ""
+		n.failMessage = ""
+		n.settled = nil
+		return
+	}
+	if n.settled != nil {
+		n.settled(n)
+		n.settled = nil
+	}
+}
+
+// advance moves the node on to its next provision state and completes
+// any pending power change, once the step delay has passed.
+func (n *node) advance(delay time.Duration) {
+	if time.Since(n.changed) < delay {
+		return
+	}
+
+	if target := n.str("target_power_state"); target != "" {
+		n.data["power_state"] = target
+		n.data["target_power_state"] = nil
+		n.changed = time.Now()
+	}
+
+	if len(n.pending) == 0 {
+		return
+	}
+	next := n.pending[0]
+	n.pending = n.pending[1:]
+	if len(n.pending) != 0 || n.failState == "" {
+		n.setProvisionState(next)
+	}
+	if len(n.pending) == 0 {
+		n.settle()
+	}
+}
+
+// validate reports whether the node has the settings each interface
+// needs, in the format of the validate API.
+func (n *node) validate() nodes.NodeValidation {
+	driverInfo := n.section("driver_info")
+	instanceInfo := n.section("instance_info")
+
+	ok := nodes.DriverValidation{Result: true}
+	result := nodes.NodeValidation{
+		Boot:       ok,
+		Deploy:     ok,
+		Inspect:    ok,
+		Management: ok,
+		Power:      ok,
+	}
+
+	hasAddress := false
+	for key, value := range driverInfo {
+		if address, _ := value.(string); strings.HasSuffix(key, "_address") && address != "" {
+			hasAddress = true
+		}
+	}
+	if !hasAddress {
+		result.Power = nodes.DriverValidation{
+			Reason: fmt.Sprintf("Missing the BMC address in the driver_info of node %s", n.uuid()),
+		}
+		result.Management = result.Power
+	}
+
+	if driverInfo["deploy_kernel"] == nil || driverInfo["deploy_ramdisk"] == nil {
+		result.Boot = nodes.DriverValidation{
+			Reason: "Cannot validate driver deploy. Some parameters were missing in node's driver_info. Missing are: ['deploy_kernel', 'deploy_ramdisk']",
+		}
+	}
+
+	if n.str("deploy_interface") == "ramdisk" {
+		if instanceInfo["boot_iso"] == nil {
+			result.Deploy = nodes.DriverValidation{
+				Reason: "Cannot validate ramdisk deploy. Missing are: ['boot_iso']",
+			}
+		}
+	} else if instanceInfo["image_source"] == nil {
+		result.Deploy = nodes.DriverValidation{
+			Reason: "Cannot validate image information for node because one or more parameters are missing from its instance_info. Missing are: ['image_source']",
+		}
+	}
+
+	return result
+}
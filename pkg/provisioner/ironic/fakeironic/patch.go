@@ -0,0 +1,79 @@
+package fakeironic
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// patchOperation is one operation of a JSON patch.
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// readOnlyFields may not be changed through the API.
+var readOnlyFields = map[string]bool{
+	"uuid":                   true,
+	"provision_state":        true,
+	"target_provision_state": true,
+	"power_state":            true,
+	"target_power_state":     true,
+	"last_error":             true,
+	"created_at":             true,
+}
+
+// applyPatch applies the operations to the node. Like Ironic, and
+// unlike strict JSON patch, replacing a missing value adds it. Either
+// all of the operations are applied or none are.
+func (n *node) applyPatch(patch []patchOperation) error {
+	content, err := json.Marshal(n.data)
+	if err != nil {
+		return err
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return err
+	}
+
+	for _, op := range patch {
+		keys := strings.Split(strings.TrimPrefix(op.Path, "/"), "/")
+		if keys[0] == "" || readOnlyFields[keys[0]] {
+			return errorf(http.StatusBadRequest, "the path %q can not be changed", op.Path)
+		}
+
+		parent := data
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := parent[key].(map[string]interface{})
+			if !ok {
+				if op.Op == "remove" {
+					return errorf(http.StatusBadRequest, "can't remove non-existent object %q", op.Path)
+				}
+				child = map[string]interface{}{}
+				parent[key] = child
+			}
+			parent = child
+		}
+		last := keys[len(keys)-1]
+
+		switch op.Op {
+		case "add", "replace":
+			parent[last] = op.Value
+		case "remove":
+			if _, ok := parent[last]; !ok {
+				return errorf(http.StatusBadRequest, "can't remove non-existent object %q", op.Path)
+			}
+			if len(keys) == 1 {
+				// Top level fields are reset rather than removed.
+				parent[last] = nil
+			} else {
+				delete(parent, last)
+			}
+		default:
+			return errorf(http.StatusBadRequest, "unsupported patch operation %q", op.Op)
+		}
+	}
+	n.data = data
+	return nil
+}
@@ -22,7 +22,9 @@ func TestInspectHardware(t *testing.T) {
 		name      string
 		ironic    *testserver.IronicMock
 		inspector *testserver.InspectorMock
+		refresh   bool
 
+		expectedStarted      bool
 		expectedDirty        bool
 		expectedRequestAfter int
 		expectedResultError  string
@@ -39,10 +41,54 @@ func TestInspectHardware(t *testing.T) {
 			}),
 			inspector: testserver.NewInspector(t).Ready().WithIntrospectionFailed(nodeUUID, http.StatusNotFound),
 
+			expectedStarted:      true,
 			expectedDirty:        true,
 			expectedRequestAfter: 10,
 			expectedPublish:      "InspectionStarted Hardware inspection started",
 		},
+		{
+			name: "refresh-manageable",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				UUID:           nodeUUID,
+				ProvisionState: "manageable",
+			}),
+			inspector: testserver.NewInspector(t).Ready().WithIntrospection(nodeUUID, introspection.Introspection{
+				Finished: true,
+			}),
+			refresh: true,
+
+			expectedStarted:      true,
+			expectedDirty:        true,
+			expectedRequestAfter: 10,
+			expectedPublish:      "InspectionStarted Hardware inspection started",
+		},
+		{
+			name: "refresh-available",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				UUID:           nodeUUID,
+				ProvisionState: "available",
+			}),
+			inspector: testserver.NewInspector(t).Ready().WithIntrospection(nodeUUID, introspection.Introspection{
+				Finished: true,
+			}),
+			refresh: true,
+
+			expectedDirty:        true,
+			expectedRequestAfter: 10,
+		},
+		{
+			name: "refresh-waiting-for-new-status",
+			ironic: testserver.NewIronic(t).Ready().Node(nodes.Node{
+				UUID:           nodeUUID,
+				ProvisionState: "inspecting",
+			}),
+			inspector: testserver.NewInspector(t).Ready().WithIntrospection(nodeUUID, introspection.Introspection{
+				Finished: true,
+			}),
+
+			expectedDirty:        true,
+			expectedRequestAfter: 15,
+		},
 		{
 			name:   "introspection-data-failed",
 			ironic: testserver.NewIronic(t).WithDefaultResponses(),
@@ -152,8 +198,9 @@ func TestInspectHardware(t *testing.T) {
 			}
 
 			prov.status.ID = nodeUUID
-			result, details, err := prov.InspectHardware(false)
+			result, started, details, err := prov.InspectHardware(false, tc.refresh)
 
+			assert.Equal(t, tc.expectedStarted, started)
 			assert.Equal(t, tc.expectedDirty, result.Dirty)
 			assert.Equal(t, time.Second*time.Duration(tc.expectedRequestAfter), result.RequeueAfter)
 			assert.Equal(t, tc.expectedResultError, result.ErrorMessage)
@@ -2,10 +2,9 @@ package ironic
 
 import (
 	"fmt"
-	"os"
-	"strconv"
+	"reflect"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/gophercloud/gophercloud"
@@ -25,29 +24,7 @@ import (
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/hardwaredetails"
 )
 
-var (
-	log                       = logf.Log.WithName("provisioner").WithName("ironic")
-	deprovisionRequeueDelay   = time.Second * 10
-	provisionRequeueDelay     = time.Second * 10
-	powerRequeueDelay         = time.Second * 10
-	introspectionRequeueDelay = time.Second * 15
-	softPowerOffTimeout       = time.Second * 180
-	deployKernelURL           string
-	deployRamdiskURL          string
-	ironicEndpoint            string
-	inspectorEndpoint         string
-	ironicTrustedCAFile       string
-	ironicInsecure            bool
-	ironicAuth                clients.AuthConfig
-	inspectorAuth             clients.AuthConfig
-	maxProvisioningHosts      int = 20
-
-	// Keep pointers to ironic and inspector clients configured with
-	// the global auth settings to reuse the connection between
-	// reconcilers.
-	clientIronicSingleton    *gophercloud.ServiceClient
-	clientInspectorSingleton *gophercloud.ServiceClient
-)
+var log = logf.Log.WithName("provisioner").WithName("ironic")
 
 const (
 	// See nodes.Node.PowerState for details
@@ -75,56 +52,6 @@ func NewMacAddressConflictError(address, node string) error {
 	return macAddressConflictError{Address: address, ExistingNode: node}
 }
 
-func init() {
-	// NOTE(dhellmann): Use Fprintf() to report errors instead of
-	// logging, because logging is not configured yet in init().
-
-	var authErr error
-	ironicAuth, inspectorAuth, authErr = clients.LoadAuth()
-	if authErr != nil {
-		fmt.Fprintf(os.Stderr, "Cannot start: %s\n", authErr)
-		os.Exit(1)
-	}
-
-	deployKernelURL = os.Getenv("DEPLOY_KERNEL_URL")
-	if deployKernelURL == "" {
-		fmt.Fprintf(os.Stderr, "Cannot start: No DEPLOY_KERNEL_URL variable set\n")
-		os.Exit(1)
-	}
-	deployRamdiskURL = os.Getenv("DEPLOY_RAMDISK_URL")
-	if deployRamdiskURL == "" {
-		fmt.Fprintf(os.Stderr, "Cannot start: No DEPLOY_RAMDISK_URL variable set\n")
-		os.Exit(1)
-	}
-	ironicEndpoint = os.Getenv("IRONIC_ENDPOINT")
-	if ironicEndpoint == "" {
-		fmt.Fprintf(os.Stderr, "Cannot start: No IRONIC_ENDPOINT variable set\n")
-		os.Exit(1)
-	}
-	inspectorEndpoint = os.Getenv("IRONIC_INSPECTOR_ENDPOINT")
-	if inspectorEndpoint == "" {
-		fmt.Fprintf(os.Stderr, "Cannot start: No IRONIC_INSPECTOR_ENDPOINT variable set\n")
-		os.Exit(1)
-	}
-	ironicTrustedCAFile = os.Getenv("IRONIC_CACERT_FILE")
-	if ironicTrustedCAFile == "" {
-		ironicTrustedCAFile = "/opt/metal3/certs/ca/crt"
-	}
-	ironicInsecureStr := os.Getenv("IRONIC_INSECURE")
-	if strings.ToLower(ironicInsecureStr) == "true" {
-		ironicInsecure = true
-	}
-
-	if maxHostsStr := os.Getenv("PROVISIONING_LIMIT"); maxHostsStr != "" {
-		value, err := strconv.Atoi(maxHostsStr)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Cannot start: Invalid value set for variable PROVISIONING_LIMIT=%s", maxHostsStr)
-			os.Exit(1)
-		}
-		maxProvisioningHosts = value
-	}
-}
-
 // Provisioner implements the provisioning.Provisioner interface
 // and uses Ironic to manage the host.
 type ironicProvisioner struct {
@@ -144,28 +71,57 @@ type ironicProvisioner struct {
 	log logr.Logger
 	// an event publisher for recording significant events
 	publisher provisioner.EventPublisher
+	// the settings in effect when the provisioner was created
+	config Config
 }
 
-// LogStartup produces useful logging information that we only want to
-// emit once on startup but that is interal to this package.
-func LogStartup() {
-	log.Info("ironic settings",
-		"endpoint", ironicEndpoint,
-		"ironicAuthType", ironicAuth.Type,
-		"inspectorEndpoint", inspectorEndpoint,
-		"inspectorAuthType", inspectorAuth.Type,
-		"deployKernelURL", deployKernelURL,
-		"deployRamdiskURL", deployRamdiskURL,
-	)
+var (
+	loggedSettingsLock sync.Mutex
+	loggedSettings     = map[string][]interface{}{}
+)
+
+// LogSettings logs the settings of each backend whose settings changed
+// since they were last logged, so that they show up once per change
+// rather than every time they are applied.
+func LogSettings() {
+	for name, settings := range changedSettings() {
+		log.Info("ironic settings", append([]interface{}{"backend", name}, settings...)...)
+	}
+}
+
+// changedSettings returns the settings to log, keyed by backend name,
+// and remembers them as logged.
+func changedSettings() map[string][]interface{} {
+	loggedSettingsLock.Lock()
+	defer loggedSettingsLock.Unlock()
+
+	current := map[string][]interface{}{}
+	changed := map[string][]interface{}{}
+	for _, name := range BackendNames() {
+		config, _ := BackendConfig(name)
+		ironicAuthType, inspectorAuthType := backendAuthTypes(name)
+		settings := []interface{}{
+			"endpoint", config.IronicEndpoint,
+			"ironicAuthType", ironicAuthType,
+			"inspectorEndpoint", config.InspectorEndpoint,
+			"inspectorAuthType", inspectorAuthType,
+			"deployKernelURL", config.DeployKernelURL,
+			"deployRamdiskURL", config.DeployRamdiskURL,
+		}
+		current[name] = settings
+		if !reflect.DeepEqual(loggedSettings[name], settings) {
+			changed[name] = settings
+		}
+	}
+	loggedSettings = current
+	return changed
 }
 
 // A private function to construct an ironicProvisioner (rather than a
 // Provisioner interface) in a consistent way for tests.
 func newProvisionerWithSettings(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher, ironicURL string, ironicAuthSettings clients.AuthConfig, inspectorURL string, inspectorAuthSettings clients.AuthConfig) (*ironicProvisioner, error) {
-	tlsConf := clients.TLSConfig{
-		TrustedCAFile:      ironicTrustedCAFile,
-		InsecureSkipVerify: ironicInsecure,
-	}
+	config := CurrentConfig()
+	tlsConf := config.tlsConfig()
 	clientIronic, err := clients.IronicClient(ironicURL, ironicAuthSettings, tlsConf)
 	if err != nil {
 		return nil, err
@@ -177,12 +133,18 @@ func newProvisionerWithSettings(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.
 	}
 
 	return newProvisionerWithIronicClients(host, bmcCreds, publisher,
-		clientIronic, clientInspector)
+		config, clientIronic, clientInspector)
 }
 
-func newProvisionerWithIronicClients(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher, clientIronic *gophercloud.ServiceClient, clientInspector *gophercloud.ServiceClient) (*ironicProvisioner, error) {
+func newProvisionerWithIronicClients(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher, config Config, clientIronic *gophercloud.ServiceClient, clientInspector *gophercloud.ServiceClient) (*ironicProvisioner, error) {
 
-	bmcAccess, err := bmc.NewAccessDetails(host.Spec.BMC.Address, host.Spec.BMC.DisableCertificateVerification)
+	var bmcAccess bmc.AccessDetails
+	var err error
+	if cert := host.Status.BMCCertificate; cert != nil && cert.CAFile != "" && !host.Spec.BMC.DisableCertificateVerification {
+		bmcAccess, err = bmc.NewAccessDetailsWithCAFile(host.Spec.BMC.Address, cert.CAFile)
+	} else {
+		bmcAccess, err = bmc.NewAccessDetails(host.Spec.BMC.Address, host.Spec.BMC.DisableCertificateVerification)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse BMC address information")
 	}
@@ -199,34 +161,22 @@ func newProvisionerWithIronicClients(host metal3v1alpha1.BareMetalHost, bmcCreds
 		inspector: clientInspector,
 		log:       log.WithValues("host", host.Name),
 		publisher: publisher,
+		config:    config,
 	}
 
 	return p, nil
 }
 
-// New returns a new Ironic Provisioner using the global configuration
-// for finding the Ironic services.
+// New returns a new Ironic Provisioner using the configuration of the
+// backend selected by the host's labels for finding the Ironic
+// services.
 func New(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
-	var err error
-	if clientIronicSingleton == nil || clientInspectorSingleton == nil {
-		tlsConf := clients.TLSConfig{
-			TrustedCAFile:      ironicTrustedCAFile,
-			InsecureSkipVerify: ironicInsecure,
-		}
-		clientIronicSingleton, err = clients.IronicClient(
-			ironicEndpoint, ironicAuth, tlsConf)
-		if err != nil {
-			return nil, err
-		}
-
-		clientInspectorSingleton, err = clients.InspectorClient(
-			inspectorEndpoint, inspectorAuth, tlsConf)
-		if err != nil {
-			return nil, err
-		}
+	config, clientIronic, clientInspector, err := sharedClients(backendName(&host))
+	if err != nil {
+		return nil, err
 	}
 	return newProvisionerWithIronicClients(host, bmcCreds, publisher,
-		clientIronicSingleton, clientInspectorSingleton)
+		config, clientIronic, clientInspector)
 }
 
 func (p *ironicProvisioner) validateNode(ironicNode *nodes.Node) (errorMessage string, err error) {
@@ -359,6 +309,27 @@ func (p *ironicProvisioner) findExistingHost() (ironicNode *nodes.Node, err erro
 //
 // FIXME(dhellmann): We should rename this method to describe what it
 // actually does.
+// certificateSettingsChanged reports whether the settings verifying the
+// certificate of the BMC differ between the driver info of the node and
+// the one wanted. Unlike the credentials, they are not secret and so
+// are returned by ironic.
+func certificateSettingsChanged(current, wanted map[string]interface{}) bool {
+	isCertificateSetting := func(key string) bool {
+		return strings.HasSuffix(key, "_verify_ca") || strings.HasSuffix(key, "_ca_file")
+	}
+	for key, value := range wanted {
+		if isCertificateSetting(key) && current[key] != value {
+			return true
+		}
+	}
+	for key := range current {
+		if _, found := wanted[key]; isCertificateSetting(key) && !found {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *ironicProvisioner) ValidateManagementAccess(credentialsChanged, force bool) (result provisioner.Result, provID string, err error) {
 	var ironicNode *nodes.Node
 
@@ -385,10 +356,10 @@ func (p *ironicProvisioner) ValidateManagementAccess(credentialsChanged, force b
 	}
 
 	driverInfo := p.bmcAccess.DriverInfo(p.bmcCreds)
-	// FIXME(dhellmann): We need to get our IP on the
-	// provisioning network from somewhere.
-	driverInfo["deploy_kernel"] = deployKernelURL
-	driverInfo["deploy_ramdisk"] = deployRamdiskURL
+	deployImageInfo := p.deployImageInfo()
+	for key, value := range deployImageInfo {
+		driverInfo[key] = value
+	}
 
 	result, err = operationComplete()
 
@@ -467,7 +438,7 @@ func (p *ironicProvisioner) ValidateManagementAccess(credentialsChanged, force b
 				case nil:
 				case gophercloud.ErrDefault409:
 					p.log.Info("could not update host settings in ironic, busy")
-					result, err = retryAfterDelay(provisionRequeueDelay)
+					result, err = retryAfterDelay(p.config.ProvisionRequeueDelay)
 					return
 				default:
 					result, err = transientError(errors.Wrap(err, "failed to update host settings in ironic"))
@@ -495,7 +466,7 @@ func (p *ironicProvisioner) ValidateManagementAccess(credentialsChanged, force b
 			case nil:
 			case gophercloud.ErrDefault409:
 				p.log.Info("could not update ironic node name, busy")
-				result, err = retryAfterDelay(provisionRequeueDelay)
+				result, err = retryAfterDelay(p.config.ProvisionRequeueDelay)
 				return
 			default:
 				result, err = transientError(errors.Wrap(err, "failed to update ironc node name"))
@@ -506,8 +477,9 @@ func (p *ironicProvisioner) ValidateManagementAccess(credentialsChanged, force b
 		}
 
 		// Look for the case where we previously enrolled this node
-		// and now the credentials have changed.
-		if credentialsChanged {
+		// and now the credentials, or the CA bundle the certificate
+		// of the BMC is verified against, have changed.
+		if credentialsChanged || certificateSettingsChanged(ironicNode.DriverInfo, driverInfo) {
 			updates := nodes.UpdateOpts{
 				nodes.UpdateOperation{
 					Op:    nodes.ReplaceOp,
@@ -520,7 +492,7 @@ func (p *ironicProvisioner) ValidateManagementAccess(credentialsChanged, force b
 			case nil:
 			case gophercloud.ErrDefault409:
 				p.log.Info("could not update host driver settings, busy")
-				result, err = retryAfterDelay(provisionRequeueDelay)
+				result, err = retryAfterDelay(p.config.ProvisionRequeueDelay)
 				return
 			default:
 				result, err = transientError(errors.Wrap(err, "failed to update host driver settings"))
@@ -530,6 +502,21 @@ func (p *ironicProvisioner) ValidateManagementAccess(credentialsChanged, force b
 			// We don't return here because we also have to set the
 			// target provision state to manageable, which happens
 			// below.
+		} else if updates := p.getDeployImageUpdateOptsForNode(ironicNode, deployImageInfo); len(updates) != 0 {
+			// The deploy images are not secret, so unlike the
+			// credentials we can tell when they have changed.
+			ironicNode, err = nodes.Update(p.client, ironicNode.UUID, updates).Extract()
+			switch err.(type) {
+			case nil:
+			case gophercloud.ErrDefault409:
+				p.log.Info("could not update host deploy images, busy")
+				result, err = retryAfterDelay(p.config.ProvisionRequeueDelay)
+				return
+			default:
+				result, err = transientError(errors.Wrap(err, "failed to update host deploy images"))
+				return
+			}
+			p.log.Info("updated host deploy images")
 		}
 	}
 
@@ -558,7 +545,7 @@ func (p *ironicProvisioner) ValidateManagementAccess(credentialsChanged, force b
 		if ironicNode.TargetProvisionState == string(nodes.TargetManage) {
 			// We have already tried to manage the node and did not
 			// get an error, so do nothing and keep trying.
-			result, err = operationContinuing(provisionRequeueDelay)
+			result, err = operationContinuing(p.config.ProvisionRequeueDelay)
 			return
 		}
 
@@ -572,7 +559,7 @@ func (p *ironicProvisioner) ValidateManagementAccess(credentialsChanged, force b
 		// If we're still waiting for the state to change in Ironic,
 		// return true to indicate that we're dirty and need to be
 		// reconciled again.
-		result, err = operationContinuing(provisionRequeueDelay)
+		result, err = operationContinuing(p.config.ProvisionRequeueDelay)
 		return
 
 	case nodes.Manageable:
@@ -608,7 +595,7 @@ func (p *ironicProvisioner) tryChangeNodeProvisionState(ironicNode *nodes.Node,
 		success = true
 	case gophercloud.ErrDefault409:
 		p.log.Info("could not change state of host, busy")
-		result, err = retryAfterDelay(provisionRequeueDelay)
+		result, err = retryAfterDelay(p.config.ProvisionRequeueDelay)
 		return
 	default:
 		result, err = transientError(errors.Wrap(changeResult.Err,
@@ -616,7 +603,7 @@ func (p *ironicProvisioner) tryChangeNodeProvisionState(ironicNode *nodes.Node,
 		return
 	}
 
-	result, err = operationContinuing(provisionRequeueDelay)
+	result, err = operationContinuing(p.config.ProvisionRequeueDelay)
 	return
 }
 
@@ -625,12 +612,48 @@ func (p *ironicProvisioner) changeNodeProvisionState(ironicNode *nodes.Node, opt
 	return
 }
 
+// startInspection sets the boot mode of a node and starts the hardware
+// inspection of it.
+func (p *ironicProvisioner) startInspection(ironicNode *nodes.Node) (result provisioner.Result, started bool, err error) {
+	p.log.Info("updating boot mode before hardware inspection")
+	op, value := buildCapabilitiesValue(ironicNode, p.host.Status.Provisioning.BootMode)
+	updates := nodes.UpdateOpts{
+		nodes.UpdateOperation{
+			Op:    op,
+			Path:  "/properties/capabilities",
+			Value: value,
+		},
+	}
+	_, err = nodes.Update(p.client, ironicNode.UUID, updates).Extract()
+	switch err.(type) {
+	case nil:
+	case gophercloud.ErrDefault409:
+		p.log.Info("could not update host settings in ironic, busy")
+		result, err = retryAfterDelay(p.config.ProvisionRequeueDelay)
+		return
+	default:
+		result, err = transientError(errors.Wrap(err, "failed to update host boot mode settings in ironic"))
+		return
+	}
+
+	p.log.Info("starting new hardware inspection")
+	started, result, err = p.tryChangeNodeProvisionState(
+		ironicNode,
+		nodes.ProvisionStateOpts{Target: nodes.TargetInspect},
+	)
+	if started {
+		p.publisher("InspectionStarted", "Hardware inspection started")
+	}
+	return
+}
+
 // InspectHardware updates the HardwareDetails field of the host with
 // details of devices discovered on the hardware. It may be called
 // multiple times, and should return true for its dirty flag until the
-// inspection is completed.
-func (p *ironicProvisioner) InspectHardware(force bool) (result provisioner.Result, details *metal3v1alpha1.HardwareDetails, err error) {
-	p.log.Info("inspecting hardware", "status", p.host.OperationalStatus())
+// inspection is completed. When refresh is set, a new inspection is
+// started even though an earlier one has finished.
+func (p *ironicProvisioner) InspectHardware(force, refresh bool) (result provisioner.Result, started bool, details *metal3v1alpha1.HardwareDetails, err error) {
+	p.log.Info("inspecting hardware", "status", p.host.OperationalStatus(), "refresh", refresh)
 
 	ironicNode, err := p.findExistingHost()
 	if err != nil {
@@ -642,13 +665,29 @@ func (p *ironicProvisioner) InspectHardware(force bool) (result provisioner.Resu
 		return
 	}
 
+	if refresh {
+		switch nodes.ProvisionState(ironicNode.ProvisionState) {
+		case nodes.Available:
+			// Nodes are only inspected from the manageable state.
+			p.log.Info("making the host manageable before hardware inspection")
+			result, err = p.changeNodeProvisionState(
+				ironicNode,
+				nodes.ProvisionStateOpts{Target: nodes.TargetManage},
+			)
+			return
+		case nodes.Manageable, nodes.InspectFail:
+			result, started, err = p.startInspection(ironicNode)
+			return
+		}
+	}
+
 	status, err := introspection.GetIntrospectionStatus(p.inspector, ironicNode.UUID).Extract()
 	if err != nil {
 		if _, isNotFound := err.(gophercloud.ErrDefault404); isNotFound {
 			switch nodes.ProvisionState(ironicNode.ProvisionState) {
 			case nodes.Inspecting, nodes.InspectWait:
 				p.log.Info("inspection already started")
-				result, err = operationContinuing(introspectionRequeueDelay)
+				result, err = operationContinuing(p.config.IntrospectionRequeueDelay)
 				return
 			default:
 				if nodes.ProvisionState(ironicNode.ProvisionState) == nodes.InspectFail && !force {
@@ -660,45 +699,25 @@ func (p *ironicProvisioner) InspectHardware(force bool) (result provisioner.Resu
 					}
 					err = nil
 				}
-				p.log.Info("updating boot mode before hardware inspection")
-				op, value := buildCapabilitiesValue(ironicNode, p.host.Status.Provisioning.BootMode)
-				updates := nodes.UpdateOpts{
-					nodes.UpdateOperation{
-						Op:    op,
-						Path:  "/properties/capabilities",
-						Value: value,
-					},
-				}
-				_, err = nodes.Update(p.client, ironicNode.UUID, updates).Extract()
-				switch err.(type) {
-				case nil:
-				case gophercloud.ErrDefault409:
-					p.log.Info("could not update host settings in ironic, busy")
-					result, err = retryAfterDelay(provisionRequeueDelay)
-					return
-				default:
-					result, err = transientError(errors.Wrap(err, "failed to update host boot mode settings in ironic"))
-					return
-				}
-
-				p.log.Info("starting new hardware inspection")
-				var success bool
-				success, result, err = p.tryChangeNodeProvisionState(
-					ironicNode,
-					nodes.ProvisionStateOpts{Target: nodes.TargetInspect},
-				)
-				if success {
-					p.publisher("InspectionStarted", "Hardware inspection started")
-				}
+				result, started, err = p.startInspection(ironicNode)
 				return
 			}
 		}
 		result, err = transientError(errors.Wrap(err, "failed to extract hardware inspection status"))
 		return
 	}
+	if status.Finished {
+		switch nodes.ProvisionState(ironicNode.ProvisionState) {
+		case nodes.Inspecting, nodes.InspectWait:
+			// The status is still the one of an earlier inspection.
+			p.log.Info("inspection started", "finished_at", status.FinishedAt)
+			result, err = operationContinuing(p.config.IntrospectionRequeueDelay)
+			return
+		}
+	}
 	if !status.Finished {
 		p.log.Info("inspection in progress", "started_at", status.StartedAt)
-		result, err = operationContinuing(introspectionRequeueDelay)
+		result, err = operationContinuing(p.config.IntrospectionRequeueDelay)
 		return
 	}
 	if status.Error != "" {
@@ -749,6 +768,85 @@ func (p *ironicProvisioner) UpdateHardwareState() (hwState provisioner.HardwareS
 	default:
 		p.log.Info("unknown power state", "value", ironicNode.PowerState)
 	}
+
+	if agentVersion, ok := ironicNode.DriverInternalInfo["agent_version"].(string); ok {
+		hwState.AgentVersion = agentVersion
+	}
+	return
+}
+
+// deployImageInfo returns the driver_info settings for the deploy
+// ramdisk, using the host's override where it has one and the
+// global settings otherwise.
+func (p *ironicProvisioner) deployImageInfo() map[string]interface{} {
+	// FIXME(dhellmann): We need to get our IP on the
+	// provisioning network from somewhere.
+	info := map[string]interface{}{
+		"deploy_kernel":  p.config.DeployKernelURL,
+		"deploy_ramdisk": p.config.DeployRamdiskURL,
+	}
+
+	override := p.host.Spec.DeployRamdisk
+	if override == nil {
+		return info
+	}
+	if override.Kernel != "" {
+		info["deploy_kernel"] = override.Kernel
+	}
+	if override.Ramdisk != "" {
+		info["deploy_ramdisk"] = override.Ramdisk
+	}
+	if override.ExtraKernelParams != "" {
+		info["kernel_append_params"] = override.ExtraKernelParams
+	}
+	return info
+}
+
+// appliedByOverride reports whether a deploy image setting of a node
+// holds the value set from the override last applied to the host, as
+// recorded in its status. Only the settings without a default are
+// concerned, the others are put back to their default.
+func (p *ironicProvisioner) appliedByOverride(key string, current interface{}) bool {
+	applied := p.host.Status.Provisioning.DeployRamdisk
+	return applied != nil && key == "kernel_append_params" &&
+		applied.ExtraKernelParams != "" && current == applied.ExtraKernelParams
+}
+
+// getDeployImageUpdateOptsForNode returns the operations needed to
+// bring the deploy image settings of an existing node in line with
+// the host. Settings missing from a node are only filled in when the
+// host overrides the defaults, and settings without a default are only
+// removed when the host overrides the defaults without them, or when
+// they were set from an override that was removed since, so nodes
+// enrolled by other means are left alone.
+func (p *ironicProvisioner) getDeployImageUpdateOptsForNode(ironicNode *nodes.Node, deployImageInfo map[string]interface{}) (updates nodes.UpdateOpts) {
+	for _, key := range []string{"deploy_kernel", "deploy_ramdisk", "kernel_append_params"} {
+		current, isSet := ironicNode.DriverInfo[key]
+		desired, wanted := deployImageInfo[key]
+		switch {
+		case !wanted && isSet && p.appliedByOverride(key, current):
+			p.log.Info("removing deploy image setting of the previous override", "setting", key)
+			updates = append(updates, nodes.UpdateOperation{
+				Op:   nodes.RemoveOp,
+				Path: "/driver_info/" + key,
+			})
+		case !isSet && p.host.Spec.DeployRamdisk == nil:
+			continue
+		case wanted && current != desired:
+			p.log.Info("updating deploy image setting", "setting", key, "value", desired)
+			updates = append(updates, nodes.UpdateOperation{
+				Op:    nodes.AddOp,
+				Path:  "/driver_info/" + key,
+				Value: desired,
+			})
+		case !wanted && isSet && p.host.Spec.DeployRamdisk != nil:
+			p.log.Info("removing deploy image setting", "setting", key)
+			updates = append(updates, nodes.UpdateOperation{
+				Op:   nodes.RemoveOp,
+				Path: "/driver_info/" + key,
+			})
+		}
+	}
 	return
 }
 
@@ -1079,7 +1177,7 @@ func (p *ironicProvisioner) setUpForProvisioning(ironicNode *nodes.Node, hostCon
 	case nil:
 	case gophercloud.ErrDefault409:
 		p.log.Info("could not update host settings in ironic, busy")
-		return retryAfterDelay(provisionRequeueDelay)
+		return retryAfterDelay(p.config.ProvisionRequeueDelay)
 	default:
 		return transientError(errors.Wrap(err, "failed to update host settings in ironic"))
 	}
@@ -1091,7 +1189,7 @@ func (p *ironicProvisioner) setUpForProvisioning(ironicNode *nodes.Node, hostCon
 	case nil:
 	case gophercloud.ErrDefault409:
 		p.log.Info("could not validate host during registration, busy")
-		return retryAfterDelay(provisionRequeueDelay)
+		return retryAfterDelay(p.config.ProvisionRequeueDelay)
 	default:
 		return transientError(errors.Wrap(err, "failed to validate host during registration"))
 	}
@@ -1156,7 +1254,7 @@ func (p *ironicProvisioner) Adopt(force bool) (result provisioner.Result, err er
 			},
 		)
 	case nodes.Adopting:
-		return operationContinuing(provisionRequeueDelay)
+		return operationContinuing(p.config.ProvisionRequeueDelay)
 	case nodes.AdoptFail:
 		if force {
 			return p.changeNodeProvisionState(
@@ -1336,7 +1434,7 @@ func (p *ironicProvisioner) Provision(hostConf provisioner.HostConfigData) (resu
 		p.log.Info("waiting for host to become available",
 			"state", ironicNode.ProvisionState,
 			"deploy step", ironicNode.DeployStep)
-		return operationContinuing(provisionRequeueDelay)
+		return operationContinuing(p.config.ProvisionRequeueDelay)
 	}
 }
 
@@ -1356,7 +1454,7 @@ func (p *ironicProvisioner) setMaintenanceFlag(ironicNode *nodes.Node, value boo
 	case nil:
 	case gophercloud.ErrDefault409:
 		p.log.Info("could not set host maintenance flag, busy")
-		return retryAfterDelay(provisionRequeueDelay)
+		return retryAfterDelay(p.config.ProvisionRequeueDelay)
 	default:
 		return transientError(errors.Wrap(err, "failed to set host maintenance flag"))
 	}
@@ -1434,16 +1532,16 @@ func (p *ironicProvisioner) Deprovision(force bool) (result provisioner.Result,
 	case nodes.Deleting:
 		p.log.Info("deleting")
 		// Transitions to Cleaning upon completion
-		return operationContinuing(deprovisionRequeueDelay)
+		return operationContinuing(p.config.DeprovisionRequeueDelay)
 
 	case nodes.Cleaning:
 		p.log.Info("cleaning")
 		// Transitions to Available upon completion
-		return operationContinuing(deprovisionRequeueDelay)
+		return operationContinuing(p.config.DeprovisionRequeueDelay)
 
 	case nodes.CleanWait:
 		p.log.Info("cleaning")
-		return operationContinuing(deprovisionRequeueDelay)
+		return operationContinuing(p.config.DeprovisionRequeueDelay)
 
 	case nodes.Active, nodes.DeployFail:
 		p.log.Info("starting deprovisioning")
@@ -1512,7 +1610,7 @@ func (p *ironicProvisioner) Delete() (result provisioner.Result, err error) {
 		p.log.Info("removed")
 	case gophercloud.ErrDefault409:
 		p.log.Info("could not remove host, busy")
-		return retryAfterDelay(provisionRequeueDelay)
+		return retryAfterDelay(p.config.ProvisionRequeueDelay)
 	case gophercloud.ErrDefault404:
 		p.log.Info("did not find host to delete, OK")
 	default:
@@ -1530,14 +1628,14 @@ func (p *ironicProvisioner) changePower(ironicNode *nodes.Node, target nodes.Tar
 			"state", ironicNode.ProvisionState,
 			"target state", ironicNode.TargetProvisionState,
 		)
-		return operationContinuing(powerRequeueDelay)
+		return operationContinuing(p.config.PowerRequeueDelay)
 	}
 
 	powerStateOpts := nodes.PowerStateOpts{
 		Target: target,
 	}
 	if target == softPowerOff {
-		powerStateOpts.Timeout = int(softPowerOffTimeout.Seconds())
+		powerStateOpts.Timeout = int(p.config.SoftPowerOffTimeout.Seconds())
 	}
 
 	changeResult := nodes.ChangePowerState(
@@ -1550,8 +1648,8 @@ func (p *ironicProvisioner) changePower(ironicNode *nodes.Node, target nodes.Tar
 		p.log.Info("power change OK")
 		return operationContinuing(0)
 	case gophercloud.ErrDefault409:
-		p.log.Info("host is locked, trying again after delay", "delay", powerRequeueDelay)
-		result, _ = retryAfterDelay(powerRequeueDelay)
+		p.log.Info("host is locked, trying again after delay", "delay", p.config.PowerRequeueDelay)
+		result, _ = retryAfterDelay(p.config.PowerRequeueDelay)
 		return result, HostLockedError{Address: p.host.Spec.BMC.Address}
 	case gophercloud.ErrDefault400:
 		// Error 400 Bad Request means target power state is not supported by vendor driver
@@ -1580,7 +1678,7 @@ func (p *ironicProvisioner) PowerOn() (result provisioner.Result, err error) {
 	if ironicNode.PowerState != powerOn {
 		if ironicNode.TargetPowerState == powerOn {
 			p.log.Info("waiting for power status to change")
-			return operationContinuing(powerRequeueDelay)
+			return operationContinuing(p.config.PowerRequeueDelay)
 		}
 		result, err = p.changePower(ironicNode, nodes.PowerOn)
 		switch err.(type) {
@@ -1597,9 +1695,12 @@ func (p *ironicProvisioner) PowerOn() (result provisioner.Result, err error) {
 
 // PowerOff ensures the server is powered off independently of any image
 // provisioning operation.
-func (p *ironicProvisioner) PowerOff() (result provisioner.Result, err error) {
-	p.log.Info("ensuring host is powered off")
+func (p *ironicProvisioner) PowerOff(rebootMode metal3v1alpha1.RebootMode) (result provisioner.Result, err error) {
+	p.log.Info("ensuring host is powered off", "mode", rebootMode)
 
+	if rebootMode == metal3v1alpha1.RebootModeHard {
+		return p.hardPowerOff()
+	}
 	result, err = p.softPowerOff()
 	if err != nil {
 		switch err.(type) {
@@ -1608,7 +1709,7 @@ func (p *ironicProvisioner) PowerOff() (result provisioner.Result, err error) {
 		case SoftPowerOffUnsupportedError, SoftPowerOffFailed:
 			return p.hardPowerOff()
 		case HostLockedError:
-			return retryAfterDelay(powerRequeueDelay)
+			return retryAfterDelay(p.config.PowerRequeueDelay)
 		default:
 			return transientError(err)
 		}
@@ -1628,7 +1729,7 @@ func (p *ironicProvisioner) hardPowerOff() (result provisioner.Result, err error
 	if ironicNode.PowerState != powerOff {
 		if ironicNode.TargetPowerState == powerOff {
 			p.log.Info("waiting for power status to change")
-			return operationContinuing(powerRequeueDelay)
+			return operationContinuing(p.config.PowerRequeueDelay)
 		}
 		result, err = p.changePower(ironicNode, nodes.PowerOff)
 		if err != nil {
@@ -1659,7 +1760,7 @@ func (p *ironicProvisioner) softPowerOff() (result provisioner.Result, err error
 		// If the target state is either powerOff or softPowerOff, then we should wait
 		if targetState == powerOff || targetState == softPowerOff {
 			p.log.Info("waiting for power status to change")
-			return operationContinuing(powerRequeueDelay)
+			return operationContinuing(p.config.PowerRequeueDelay)
 		}
 		// If the target state is unset while the last error is set,
 		// then the last execution of soft power off has failed.
@@ -1697,7 +1798,7 @@ func (p *ironicProvisioner) HasProvisioningCapacity() (result bool, err error) {
 		return true, nil
 	}
 
-	return len(hosts) < maxProvisioningHosts, nil
+	return len(hosts) < p.config.MaxProvisioningHosts, nil
 }
 
 func (p *ironicProvisioner) loadProvisioningHosts() (hosts map[string]struct{}, err error) {
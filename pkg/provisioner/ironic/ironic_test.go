@@ -12,8 +12,19 @@ import (
 	_ "github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/testbmc"
 )
 
+var testConfig Config
+
 func init() {
 	logf.SetLogger(logf.ZapLogger(true))
+
+	testConfig = DefaultConfig()
+	testConfig.DeployKernelURL = "http://deploy.test/ironic-python-agent.kernel"
+	testConfig.DeployRamdiskURL = "http://deploy.test/ironic-python-agent.initramfs"
+	testConfig.IronicEndpoint = "http://ironic.test/v1/"
+	testConfig.InspectorEndpoint = "http://inspector.test/v1/"
+	if err := Configure(testConfig); err != nil {
+		panic(err)
+	}
 }
 
 func makeHost() metal3v1alpha1.BareMetalHost {
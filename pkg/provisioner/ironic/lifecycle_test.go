@@ -0,0 +1,196 @@
+package ironic
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/fakeironic"
+)
+
+// lifecycle drives a host through the provisioner the way the
+// controller does, against the simulated Ironic.
+type lifecycle struct {
+	t    *testing.T
+	sim  *fakeironic.Ironic
+	host metal3v1alpha1.BareMetalHost
+}
+
+func newLifecycle(t *testing.T) *lifecycle {
+	host := makeHost()
+	host.Status.Provisioning.ID = ""
+	host.Spec.BootMACAddress = "52:54:00:12:34:56"
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:      "http://images.test/image.qcow2",
+		Checksum: "http://images.test/image.qcow2.md5sum",
+	}
+	return &lifecycle{
+		t:    t,
+		sim:  fakeironic.New().Start(),
+		host: host,
+	}
+}
+
+func (l *lifecycle) provisioner() *ironicProvisioner {
+	auth := clients.AuthConfig{Type: clients.NoAuth}
+	prov, err := newProvisionerWithSettings(l.host, bmc.Credentials{Username: "admin", Password: "password"},
+		nullEventPublisher, l.sim.IronicEndpoint(), auth, l.sim.InspectorEndpoint(), auth)
+	if err != nil {
+		l.t.Fatalf("could not create provisioner: %s", err)
+	}
+	return prov
+}
+
+// run repeats the step until it is no longer dirty, and returns the
+// last result.
+func (l *lifecycle) run(name string, step func(p *ironicProvisioner) (provisioner.Result, error)) provisioner.Result {
+	for i := 0; i < 20; i++ {
+		result, err := step(l.provisioner())
+		if err != nil {
+			l.t.Fatalf("%s failed: %s", name, err)
+		}
+		if !result.Dirty || result.ErrorMessage != "" {
+			return result
+		}
+	}
+	l.t.Fatalf("%s did not finish", name)
+	return provisioner.Result{}
+}
+
+func (l *lifecycle) register() {
+	result := l.run("registration", func(p *ironicProvisioner) (result provisioner.Result, err error) {
+		var provID string
+		result, provID, err = p.ValidateManagementAccess(false, false)
+		l.host.Status.Provisioning.ID = provID
+		return
+	})
+	assert.Equal(l.t, "", result.ErrorMessage)
+}
+
+func (l *lifecycle) node() nodes.Node {
+	node, ok := l.sim.Node(l.host.Name)
+	if !ok {
+		l.t.Fatalf("node %s not found", l.host.Name)
+	}
+	return node
+}
+
+func TestLifecycle(t *testing.T) {
+	l := newLifecycle(t)
+	defer l.sim.Stop()
+
+	l.register()
+	assert.Equal(t, string(nodes.Manageable), l.node().ProvisionState)
+	assert.Equal(t, l.node().UUID, l.host.Status.Provisioning.ID)
+
+	var details *metal3v1alpha1.HardwareDetails
+	result := l.run("inspection", func(p *ironicProvisioner) (result provisioner.Result, err error) {
+		result, _, details, err = p.InspectHardware(false, false)
+		return
+	})
+	assert.Equal(t, "", result.ErrorMessage)
+	if assert.NotNil(t, details) {
+		assert.Equal(t, "myhost", details.Hostname)
+		assert.Equal(t, 16384, details.RAMMebibytes)
+		assert.Equal(t, 8, details.CPU.Count)
+		assert.Equal(t, "52:54:00:12:34:56", details.NIC[0].MAC)
+		assert.True(t, details.NIC[0].PXE)
+		assert.Equal(t, 2, len(details.Storage))
+	}
+
+	hostConf := fixture.NewHostConfigData("#cloud-config", "", "")
+	result = l.run("provisioning", func(p *ironicProvisioner) (provisioner.Result, error) {
+		return p.Provision(hostConf)
+	})
+	assert.Equal(t, "", result.ErrorMessage)
+	node := l.node()
+	assert.Equal(t, string(nodes.Active), node.ProvisionState)
+	assert.Equal(t, powerOn, node.PowerState)
+	assert.Equal(t, "http://images.test/image.qcow2", node.InstanceInfo["image_source"])
+	assert.NotNil(t, node.InstanceInfo["configdrive"])
+
+	l.run("power off", func(p *ironicProvisioner) (provisioner.Result, error) {
+		return p.PowerOff(metal3v1alpha1.RebootModeSoft)
+	})
+	assert.Equal(t, powerOff, l.node().PowerState)
+
+	result = l.run("deprovisioning", func(p *ironicProvisioner) (provisioner.Result, error) {
+		return p.Deprovision(false)
+	})
+	assert.Equal(t, "", result.ErrorMessage)
+	assert.Equal(t, string(nodes.Available), l.node().ProvisionState)
+
+	l.run("deletion", func(p *ironicProvisioner) (provisioner.Result, error) {
+		return p.Delete()
+	})
+	_, found := l.sim.Node(l.host.Name)
+	assert.False(t, found)
+}
+
+func TestLifecycleInspectionFailure(t *testing.T) {
+	l := newLifecycle(t)
+	defer l.sim.Stop()
+
+	l.sim.FailNext(l.host.Name, nodes.TargetInspect, "no agent callback")
+	l.register()
+
+	result := l.run("inspection", func(p *ironicProvisioner) (result provisioner.Result, err error) {
+		result, _, _, err = p.InspectHardware(false, false)
+		return
+	})
+	assert.Equal(t, "no agent callback", result.ErrorMessage)
+	assert.Equal(t, string(nodes.InspectFail), l.node().ProvisionState)
+}
+
+func TestLifecycleDeployFailure(t *testing.T) {
+	l := newLifecycle(t)
+	defer l.sim.Stop()
+
+	l.sim.FailNext(l.host.Name, nodes.TargetActive, "disk full")
+	l.register()
+	l.run("inspection", func(p *ironicProvisioner) (result provisioner.Result, err error) {
+		result, _, _, err = p.InspectHardware(false, false)
+		return
+	})
+
+	result := l.run("provisioning", func(p *ironicProvisioner) (provisioner.Result, error) {
+		return p.Provision(fixture.NewHostConfigData("", "", ""))
+	})
+	assert.Equal(t, "Image provisioning failed: disk full", result.ErrorMessage)
+	assert.Equal(t, string(nodes.DeployFail), l.node().ProvisionState)
+
+	// The failure is only injected once, so the host can be cleaned
+	// up and provisioned again.
+	result = l.run("deprovisioning", func(p *ironicProvisioner) (provisioner.Result, error) {
+		return p.Deprovision(false)
+	})
+	assert.Equal(t, "", result.ErrorMessage)
+	assert.Equal(t, string(nodes.Available), l.node().ProvisionState)
+
+	result = l.run("provisioning again", func(p *ironicProvisioner) (provisioner.Result, error) {
+		return p.Provision(fixture.NewHostConfigData("", "", ""))
+	})
+	assert.Equal(t, "", result.ErrorMessage)
+	assert.Equal(t, string(nodes.Active), l.node().ProvisionState)
+}
+
+func TestLifecycleBadBMC(t *testing.T) {
+	l := newLifecycle(t)
+	defer l.sim.Stop()
+
+	l.host.Spec.BMC.Address = "test://"
+	result := l.run("registration", func(p *ironicProvisioner) (result provisioner.Result, err error) {
+		var provID string
+		result, provID, err = p.ValidateManagementAccess(false, false)
+		l.host.Status.Provisioning.ID = provID
+		return
+	})
+	assert.Contains(t, result.ErrorMessage, "Failed to get power state")
+	assert.Equal(t, string(nodes.Enroll), l.node().ProvisionState)
+}
@@ -1,6 +1,7 @@
 package ironic
 
 import (
+	"encoding/json"
 	"net/http"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/gophercloud/gophercloud/openstack/baremetalintrospection/v1/introspection"
 	"github.com/stretchr/testify/assert"
 
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	"github.com/metal3-io/baremetal-operator/pkg/bmc"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/testserver"
@@ -117,12 +119,14 @@ func TestPowerOff(t *testing.T) {
 
 	nodeUUID := "33ce8659-7400-4c68-9535-d10766f07a58"
 	cases := []struct {
-		name   string
-		ironic *testserver.IronicMock
+		name       string
+		ironic     *testserver.IronicMock
+		rebootMode metal3v1alpha1.RebootMode
 
 		expectedDirty        bool
 		expectedError        bool
 		expectedRequestAfter int
+		expectedTarget       nodes.TargetPowerState
 	}{
 		{
 			name: "node-already-power-off",
@@ -149,7 +153,20 @@ func TestPowerOff(t *testing.T) {
 				TargetProvisionState: "",
 				UUID:                 nodeUUID,
 			}),
-			expectedDirty: true,
+			expectedDirty:  true,
+			expectedTarget: nodes.SoftPowerOff,
+		},
+		{
+			name: "power-off hard",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				PowerState:           powerOn,
+				TargetPowerState:     powerOn,
+				TargetProvisionState: "",
+				UUID:                 nodeUUID,
+			}),
+			rebootMode:     metal3v1alpha1.RebootModeHard,
+			expectedDirty:  true,
+			expectedTarget: nodes.PowerOff,
 		},
 		{
 			name: "power-off wait for Provisioning state",
@@ -199,7 +216,11 @@ func TestPowerOff(t *testing.T) {
 			}
 
 			prov.status.ID = nodeUUID
-			result, err := prov.PowerOff()
+			rebootMode := tc.rebootMode
+			if rebootMode == "" {
+				rebootMode = metal3v1alpha1.RebootModeSoft
+			}
+			result, err := prov.PowerOff(rebootMode)
 
 			assert.Equal(t, tc.expectedDirty, result.Dirty)
 			assert.Equal(t, time.Second*time.Duration(tc.expectedRequestAfter), result.RequeueAfter)
@@ -208,6 +229,13 @@ func TestPowerOff(t *testing.T) {
 			} else {
 				assert.Error(t, err)
 			}
+			if tc.expectedTarget != "" {
+				body, _ := tc.ironic.GetLastRequestFor("/v1/nodes/"+nodeUUID+"/states/power", http.MethodPut)
+				opts := nodes.PowerStateOpts{}
+				if assert.NoError(t, json.Unmarshal([]byte(body), &opts)) {
+					assert.Equal(t, tc.expectedTarget, opts.Target)
+				}
+			}
 		})
 	}
 }
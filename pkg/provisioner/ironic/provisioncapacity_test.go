@@ -78,14 +78,13 @@ func TestHasProvisioningCapacity(t *testing.T) {
 
 			auth := clients.AuthConfig{Type: clients.NoAuth}
 
-			maxProvisioningHosts = tc.provisioningLimit
-
 			prov, err := newProvisionerWithSettings(host, bmc.Credentials{}, nullEventPublisher,
 				ironic.Endpoint(), auth, inspector.Endpoint(), auth,
 			)
 			if err != nil {
 				t.Fatalf("could not create provisioner: %s", err)
 			}
+			prov.config.MaxProvisioningHosts = tc.provisioningLimit
 
 			result, err := prov.HasProvisioningCapacity()
 
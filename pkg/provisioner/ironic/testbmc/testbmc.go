@@ -11,11 +11,12 @@ func init() {
 	bmc.RegisterFactory("test-needs-mac", newTestBMCAccessDetails, []string{})
 }
 
-func newTestBMCAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (bmc.AccessDetails, error) {
+func newTestBMCAccessDetails(parsedURL *url.URL, disableCertificateVerification bool, caFile string) (bmc.AccessDetails, error) {
 	return &testAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		hostname:                       parsedURL.Hostname(),
 		disableCertificateVerification: disableCertificateVerification,
+		caFile:                         caFile,
 	}, nil
 }
 
@@ -23,6 +24,7 @@ type testAccessDetails struct {
 	bmcType                        string
 	hostname                       string
 	disableCertificateVerification bool
+	caFile                         string
 }
 
 func (a *testAccessDetails) Type() string {
@@ -58,6 +60,8 @@ func (a *testAccessDetails) DriverInfo(bmcCreds bmc.Credentials) map[string]inte
 
 	if a.disableCertificateVerification {
 		result["test_verify_ca"] = false
+	} else if a.caFile != "" {
+		result["test_verify_ca"] = a.caFile
 	}
 	return result
 }
@@ -0,0 +1,244 @@
+package testserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+// endpointPlaceholder replaces the URL of the recorded server in the
+// responses, so that links point back to the mock server on replay.
+const endpointPlaceholder = "{endpoint}"
+
+// redacted replaces the passwords sent to the recorded server.
+const redacted = "******"
+
+// Interaction is a request received by a server and the response it
+// sent back.
+type Interaction struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Query    string `json:"query,omitempty"`
+	Request  string `json:"request,omitempty"`
+	Status   int    `json:"status"`
+	Response string `json:"response,omitempty"`
+}
+
+// Cassette is a recording of the traffic with a server, in the order
+// the requests were received.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cassette := &Cassette{}
+	if err := yaml.UnmarshalStrict(content, cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return cassette, nil
+}
+
+// Save writes the cassette to a file, creating its directory if
+// needed.
+func (c *Cassette) Save(path string) error {
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644) // #nosec
+}
+
+// tape is the cassette used by a MockServer in record or replay mode.
+type tape struct {
+	lock     sync.Mutex
+	path     string
+	target   string
+	cassette Cassette
+	next     int
+}
+
+// Record makes the server pass all requests on to the server at the
+// target URL, such as a real Ironic, and record them. The cassette is
+// written to path when the server is stopped. The responses
+// configured on the server are not used.
+func (m *MockServer) Record(target string, path string) *MockServer {
+	target = strings.TrimSuffix(strings.TrimSuffix(target, "/"), "/v1")
+	m.t.Logf("%s: recording requests to %s in %s", m.name, target, path)
+	m.tape = &tape{path: path, target: target}
+	return m
+}
+
+// Replay makes the server answer requests with the responses recorded
+// in the cassette at path. The requests must arrive in the order they
+// were recorded, and all of them must have been received when the
+// server is stopped. The responses configured on the server are not
+// used.
+func (m *MockServer) Replay(path string) *MockServer {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		m.t.Fatalf("%s: %s", m.name, err)
+	}
+	m.t.Logf("%s: replaying %d interactions from %s", m.name, len(cassette.Interactions), path)
+	m.tape = &tape{path: path, cassette: *cassette}
+	return m
+}
+
+// Cassette records the requests into the cassette at path when the
+// environment variable recordEnv holds the URL of a server to record,
+// and replays the cassette otherwise.
+func (m *MockServer) Cassette(path string, recordEnv string) *MockServer {
+	if target := os.Getenv(recordEnv); target != "" {
+		return m.Record(target, path)
+	}
+	return m.Replay(path)
+}
+
+func (m *MockServer) tapeHandler(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if m.tape.target != "" {
+		m.recordRequest(w, r, body)
+	} else {
+		m.replayRequest(w, r)
+	}
+}
+
+func (m *MockServer) recordRequest(w http.ResponseWriter, r *http.Request, body []byte) {
+	request, err := http.NewRequest(r.Method, m.tape.target+r.URL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		m.t.Errorf("%s: %s", m.name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	request.Header = r.Header.Clone()
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		m.t.Errorf("%s: could not record %s %s: %s", m.name, r.Method, r.URL, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+	payload, _ := ioutil.ReadAll(response.Body)
+
+	m.tape.lock.Lock()
+	m.tape.cassette.Interactions = append(m.tape.cassette.Interactions, Interaction{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Query:    r.URL.RawQuery,
+		Request:  redact(string(body)),
+		Status:   response.StatusCode,
+		Response: strings.ReplaceAll(redact(string(payload)), m.tape.target, endpointPlaceholder),
+	})
+	m.tape.lock.Unlock()
+
+	m.logRequest(r, string(payload))
+	for key, values := range response.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(response.StatusCode)
+	w.Write(payload)
+}
+
+func (m *MockServer) replayRequest(w http.ResponseWriter, r *http.Request) {
+	m.tape.lock.Lock()
+	defer m.tape.lock.Unlock()
+
+	if m.tape.next >= len(m.tape.cassette.Interactions) {
+		m.t.Errorf("%s: unexpected request %s %s after the end of %s",
+			m.name, r.Method, r.URL, m.tape.path)
+		m.logRequest(r, "")
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+		return
+	}
+
+	interaction := m.tape.cassette.Interactions[m.tape.next]
+	if interaction.Method != r.Method || interaction.Path != r.URL.Path || interaction.Query != r.URL.RawQuery {
+		expected := interaction.Path
+		if interaction.Query != "" {
+			expected += "?" + interaction.Query
+		}
+		m.t.Errorf("%s: request %d is %s %s, %s expects %s %s",
+			m.name, m.tape.next, r.Method, r.URL, m.tape.path, interaction.Method, expected)
+		m.logRequest(r, "")
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+		return
+	}
+	m.tape.next++
+
+	payload := strings.ReplaceAll(interaction.Response, endpointPlaceholder, m.server.URL)
+	m.sendData(w, r, interaction.Status, payload)
+}
+
+// stopTape saves the recording, or checks that the whole cassette has
+// been replayed.
+func (m *MockServer) stopTape() {
+	m.tape.lock.Lock()
+	defer m.tape.lock.Unlock()
+
+	if m.tape.target != "" {
+		if err := m.tape.cassette.Save(m.tape.path); err != nil {
+			m.t.Errorf("%s: could not save %s: %s", m.name, m.tape.path, err)
+		}
+		return
+	}
+	if remaining := len(m.tape.cassette.Interactions) - m.tape.next; remaining != 0 {
+		next := m.tape.cassette.Interactions[m.tape.next]
+		m.t.Errorf("%s: %d requests of %s were not received, starting with %s %s",
+			m.name, remaining, m.tape.path, next.Method, next.Path)
+	}
+}
+
+// redact hides the passwords in a JSON body, either as the value of a
+// password field or as the value of a JSON patch operation on one.
+func redact(body string) string {
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return body
+	}
+	content, err := json.Marshal(redactValue(data))
+	if err != nil {
+		return body
+	}
+	return string(content)
+}
+
+func redactValue(data interface{}) interface{} {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		if path, ok := value["path"].(string); ok && strings.Contains(path, "password") {
+			if _, ok := value["value"]; ok {
+				value["value"] = redacted
+			}
+		}
+		for key, field := range value {
+			if _, ok := field.(string); ok && strings.Contains(key, "password") {
+				value[key] = redacted
+				continue
+			}
+			value[key] = redactValue(field)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactValue(item)
+		}
+	}
+	return data
+}
@@ -62,3 +62,11 @@ func (m *InspectorMock) WithIntrospectionDataFailed(nodeUUID string, errorCode i
 	m.ErrorResponse("/v1/introspection/"+nodeUUID+"/data", errorCode)
 	return m
 }
+
+// Cassette records the requests into the cassette at path when
+// INSPECTOR_RECORD_ENDPOINT holds the URL of an Ironic Inspector API,
+// and replays the cassette otherwise.
+func (m *InspectorMock) Cassette(path string) *InspectorMock {
+	m.MockServer.Cassette(path, "INSPECTOR_RECORD_ENDPOINT")
+	return m
+}
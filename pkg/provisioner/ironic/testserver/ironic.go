@@ -268,3 +268,11 @@ func (m *IronicMock) Nodes(allNodes []nodes.Node) *IronicMock {
 	m.ResponseJSON(m.buildURL("/v1/nodes", http.MethodGet), resp)
 	return m
 }
+
+// Cassette records the requests into the cassette at path when
+// IRONIC_RECORD_ENDPOINT holds the URL of an Ironic API, and replays
+// the cassette otherwise.
+func (m *IronicMock) Cassette(path string) *IronicMock {
+	m.MockServer.Cassette(path, "IRONIC_RECORD_ENDPOINT")
+	return m
+}
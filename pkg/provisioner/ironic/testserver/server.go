@@ -51,6 +51,7 @@ type MockServer struct {
 	FullRequests []simpleRequest
 	server       *httptest.Server
 	errorCode    int
+	tape         *tape
 
 	responsesByMethod map[string]map[string]response
 	defaultResponses  []defaultResponse
@@ -163,6 +164,10 @@ func (m *MockServer) ErrorResponse(pattern string, errorCode int) *MockServer {
 
 // Start runs the server
 func (m *MockServer) Start() *MockServer {
+	if m.tape != nil {
+		m.server = httptest.NewServer(http.HandlerFunc(m.tapeHandler))
+		return m
+	}
 	m.server = httptest.NewServer(m.mux)
 	//catch all handler
 	m.mux.HandleFunc("/", m.defaultHandler)
@@ -172,6 +177,9 @@ func (m *MockServer) Start() *MockServer {
 // Stop closes the server down
 func (m *MockServer) Stop() {
 	m.server.Close()
+	if m.tape != nil {
+		m.stopTape()
+	}
 }
 
 // AddDefaultResponseJSON adds a default response for the specified pattern
@@ -24,6 +24,7 @@ func TestUpdateHardwareState(t *testing.T) {
 		hostName             string
 
 		expectUnreadablePower bool
+		expectedAgentVersion  string
 
 		expectedPublish string
 		expectedError   string
@@ -76,6 +77,18 @@ func TestUpdateHardwareState(t *testing.T) {
 			hostCurrentlyPowered:  true,
 			expectUnreadablePower: true,
 		},
+		{
+			name: "agent-version",
+			ironic: testserver.NewIronic(t).Ready().Node(nodes.Node{
+				UUID:       nodeUUID,
+				PowerState: "power on",
+				DriverInternalInfo: map[string]interface{}{
+					"agent_version": "6.5.1",
+				},
+			}),
+			hostCurrentlyPowered: true,
+			expectedAgentVersion: "6.5.1",
+		},
 		{
 			name: "node-not-found",
 
@@ -140,6 +153,7 @@ func TestUpdateHardwareState(t *testing.T) {
 			hwStatus, err := prov.UpdateHardwareState()
 
 			assert.Equal(t, tc.expectUnreadablePower, hwStatus.PoweredOn == nil)
+			assert.Equal(t, tc.expectedAgentVersion, hwStatus.AgentVersion)
 
 			assert.Equal(t, tc.expectedPublish, publishedMsg)
 			if tc.expectedError == "" {
@@ -454,6 +454,99 @@ func TestValidateManagementAccessNewCredentials(t *testing.T) {
 	assert.Equal(t, "test.bmc", newValues["test_address"])
 }
 
+func TestValidateManagementAccessNewCAFile(t *testing.T) {
+	host := makeHost()
+	host.Spec.BootMACAddress = ""
+	host.Status.Provisioning.ID = "" // so we don't lookup by uuid
+	host.Status.BMCCertificate = &metal3v1alpha1.BMCCertificateStatus{
+		CABundle: "rack1-ca",
+		CAFile:   "/shared/bmc-ca/myns/myhost.pem",
+	}
+
+	ironic := testserver.NewIronic(t).
+		Node(
+			nodes.Node{
+				Name: host.Name,
+				UUID: "uuid",
+				DriverInfo: map[string]interface{}{
+					"test_address": "test.bmc",
+				},
+			}).
+		NodeUpdate(
+			nodes.Node{
+				Name: host.Name,
+				UUID: "uuid",
+			})
+	ironic.Start()
+	defer ironic.Stop()
+
+	auth := clients.AuthConfig{Type: clients.NoAuth}
+	prov, err := newProvisionerWithSettings(host, bmc.Credentials{}, nullEventPublisher,
+		ironic.Endpoint(), auth, testserver.NewInspector(t).Endpoint(), auth,
+	)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+
+	// The credentials are unchanged, but the driver info is updated
+	// with the new CA file.
+	result, _, err := prov.ValidateManagementAccess(false, false)
+	if err != nil {
+		t.Fatalf("error from ValidateManagementAccess: %s", err)
+	}
+	assert.Equal(t, "", result.ErrorMessage)
+
+	updates := ironic.GetLastNodeUpdateRequestFor("uuid")
+	if assert.Len(t, updates, 1) {
+		assert.Equal(t, "/driver_info", updates[0].Path)
+		newValues := updates[0].Value.(map[string]interface{})
+		assert.Equal(t, "/shared/bmc-ca/myns/myhost.pem", newValues["test_verify_ca"])
+	}
+}
+
+func TestCertificateSettingsChanged(t *testing.T) {
+	testCases := []struct {
+		Scenario string
+		Current  map[string]interface{}
+		Wanted   map[string]interface{}
+		Expected bool
+	}{
+		{
+			Scenario: "none",
+			Current:  map[string]interface{}{"redfish_address": "https://bmc"},
+			Wanted:   map[string]interface{}{"redfish_address": "https://bmc", "redfish_password": "secret"},
+		},
+		{
+			Scenario: "unchanged",
+			Current:  map[string]interface{}{"redfish_verify_ca": "/ca.pem"},
+			Wanted:   map[string]interface{}{"redfish_verify_ca": "/ca.pem"},
+		},
+		{
+			Scenario: "added",
+			Current:  map[string]interface{}{},
+			Wanted:   map[string]interface{}{"ilo_ca_file": "/ca.pem"},
+			Expected: true,
+		},
+		{
+			Scenario: "removed",
+			Current:  map[string]interface{}{"redfish_verify_ca": "/ca.pem"},
+			Wanted:   map[string]interface{}{},
+			Expected: true,
+		},
+		{
+			Scenario: "verification disabled",
+			Current:  map[string]interface{}{"redfish_verify_ca": "/ca.pem"},
+			Wanted:   map[string]interface{}{"redfish_verify_ca": false},
+			Expected: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, certificateSettingsChanged(tc.Current, tc.Wanted))
+		})
+	}
+}
+
 func TestValidateManagementAccessLinkExistingIronicNodeByMAC(t *testing.T) {
 	// Create an Ironic node, and then create a host with a matching MAC
 	// Test to see if the node was found, and if the link is made
@@ -620,3 +713,176 @@ func TestValidateManagementAccessAddTwoHostsWithSameMAC(t *testing.T) {
 	assert.Equal(t, "", result.ErrorMessage)
 	assert.NotEqual(t, "", provID)
 }
+
+func TestValidateManagementAccessCreateWithDeployRamdisk(t *testing.T) {
+	host := makeHost()
+	host.Spec.BootMACAddress = ""
+	host.Spec.Image = nil
+	host.Spec.DeployRamdisk = &metal3v1alpha1.DeployRamdisk{
+		Ramdisk:           "http://example.test/patched-ipa.initramfs",
+		ExtraKernelParams: "console=ttyS0",
+	}
+	host.Status.Provisioning.ID = "" // so we don't lookup by uuid
+
+	var createdNode *nodes.Node
+
+	createCallback := func(node nodes.Node) {
+		createdNode = &node
+	}
+
+	ironic := testserver.NewIronic(t).Ready().CreateNodes(createCallback).NoNode(host.Name)
+	ironic.Start()
+	defer ironic.Stop()
+
+	auth := clients.AuthConfig{Type: clients.NoAuth}
+	prov, err := newProvisionerWithSettings(host, bmc.Credentials{}, nullEventPublisher,
+		ironic.Endpoint(), auth, testserver.NewInspector(t).Endpoint(), auth,
+	)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+
+	result, _, err := prov.ValidateManagementAccess(false, false)
+	if err != nil {
+		t.Fatalf("error from ValidateManagementAccess: %s", err)
+	}
+	assert.Equal(t, "", result.ErrorMessage)
+	assert.Equal(t, testConfig.DeployKernelURL, createdNode.DriverInfo["deploy_kernel"])
+	assert.Equal(t, "http://example.test/patched-ipa.initramfs", createdNode.DriverInfo["deploy_ramdisk"])
+	assert.Equal(t, "console=ttyS0", createdNode.DriverInfo["kernel_append_params"])
+}
+
+func TestValidateManagementAccessUpdateDeployRamdisk(t *testing.T) {
+	cases := []struct {
+		name            string
+		deployRamdisk   *metal3v1alpha1.DeployRamdisk
+		appliedRamdisk  *metal3v1alpha1.DeployRamdisk
+		driverInfo      map[string]interface{}
+		expectedUpdates []nodes.UpdateOperation
+	}{
+		{
+			name: "no-override-no-settings",
+		},
+		{
+			name: "no-override-defaults",
+			driverInfo: map[string]interface{}{
+				"deploy_kernel":  testConfig.DeployKernelURL,
+				"deploy_ramdisk": testConfig.DeployRamdiskURL,
+			},
+		},
+		{
+			name: "override-added",
+			deployRamdisk: &metal3v1alpha1.DeployRamdisk{
+				Kernel:  "http://example.test/kernel",
+				Ramdisk: "http://example.test/ramdisk",
+			},
+			driverInfo: map[string]interface{}{
+				"deploy_kernel":  testConfig.DeployKernelURL,
+				"deploy_ramdisk": testConfig.DeployRamdiskURL,
+			},
+			expectedUpdates: []nodes.UpdateOperation{
+				{
+					Op:    nodes.AddOp,
+					Path:  "/driver_info/deploy_kernel",
+					Value: "http://example.test/kernel",
+				},
+				{
+					Op:    nodes.AddOp,
+					Path:  "/driver_info/deploy_ramdisk",
+					Value: "http://example.test/ramdisk",
+				},
+			},
+		},
+		{
+			name: "override-removed",
+			driverInfo: map[string]interface{}{
+				"deploy_kernel":        "http://example.test/kernel",
+				"deploy_ramdisk":       testConfig.DeployRamdiskURL,
+				"kernel_append_params": "console=ttyS0",
+			},
+			expectedUpdates: []nodes.UpdateOperation{
+				{
+					Op:    nodes.AddOp,
+					Path:  "/driver_info/deploy_kernel",
+					Value: testConfig.DeployKernelURL,
+				},
+			},
+		},
+		{
+			name:           "applied-override-removed",
+			appliedRamdisk: &metal3v1alpha1.DeployRamdisk{Kernel: "http://example.test/kernel", ExtraKernelParams: "console=ttyS0"},
+			driverInfo: map[string]interface{}{
+				"deploy_kernel":        "http://example.test/kernel",
+				"deploy_ramdisk":       testConfig.DeployRamdiskURL,
+				"kernel_append_params": "console=ttyS0",
+			},
+			expectedUpdates: []nodes.UpdateOperation{
+				{
+					Op:    nodes.AddOp,
+					Path:  "/driver_info/deploy_kernel",
+					Value: testConfig.DeployKernelURL,
+				},
+				{
+					Op:   nodes.RemoveOp,
+					Path: "/driver_info/kernel_append_params",
+				},
+			},
+		},
+		{
+			name:           "applied-override-removed-params-changed",
+			appliedRamdisk: &metal3v1alpha1.DeployRamdisk{ExtraKernelParams: "console=ttyS0"},
+			driverInfo: map[string]interface{}{
+				"deploy_kernel":        testConfig.DeployKernelURL,
+				"deploy_ramdisk":       testConfig.DeployRamdiskURL,
+				"kernel_append_params": "console=tty1",
+			},
+		},
+		{
+			name:          "override-params-removed",
+			deployRamdisk: &metal3v1alpha1.DeployRamdisk{},
+			driverInfo: map[string]interface{}{
+				"deploy_kernel":        testConfig.DeployKernelURL,
+				"deploy_ramdisk":       testConfig.DeployRamdiskURL,
+				"kernel_append_params": "console=ttyS0",
+			},
+			expectedUpdates: []nodes.UpdateOperation{
+				{
+					Op:   nodes.RemoveOp,
+					Path: "/driver_info/kernel_append_params",
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			host := makeHost()
+			host.Spec.DeployRamdisk = tc.deployRamdisk
+			host.Status.Provisioning.DeployRamdisk = tc.appliedRamdisk
+
+			node := nodes.Node{
+				Name:       host.Name,
+				UUID:       host.Status.Provisioning.ID,
+				DriverInfo: tc.driverInfo,
+			}
+			ironic := testserver.NewIronic(t).Ready().Node(node).NodeUpdate(node)
+			ironic.Start()
+			defer ironic.Stop()
+
+			auth := clients.AuthConfig{Type: clients.NoAuth}
+			prov, err := newProvisionerWithSettings(host, bmc.Credentials{}, nullEventPublisher,
+				ironic.Endpoint(), auth, testserver.NewInspector(t).Endpoint(), auth,
+			)
+			if err != nil {
+				t.Fatalf("could not create provisioner: %s", err)
+			}
+
+			result, _, err := prov.ValidateManagementAccess(false, false)
+			if err != nil {
+				t.Fatalf("error from ValidateManagementAccess: %s", err)
+			}
+			assert.Equal(t, "", result.ErrorMessage)
+			assert.Equal(t, tc.expectedUpdates, ironic.GetLastNodeUpdateRequestFor(node.UUID))
+		})
+	}
+}
@@ -0,0 +1,461 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ProvisionerClient is the client API for Provisioner service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProvisionerClient interface {
+	ValidateManagementAccess(ctx context.Context, in *ValidateManagementAccessRequest, opts ...grpc.CallOption) (*ValidateManagementAccessResponse, error)
+	InspectHardware(ctx context.Context, in *InspectHardwareRequest, opts ...grpc.CallOption) (*InspectHardwareResponse, error)
+	UpdateHardwareState(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*UpdateHardwareStateResponse, error)
+	Adopt(ctx context.Context, in *ForceRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	Provision(ctx context.Context, in *ProvisionRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	Deprovision(ctx context.Context, in *ForceRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	Delete(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	PowerOn(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	PowerOff(ctx context.Context, in *PowerOffRequest, opts ...grpc.CallOption) (*ResultResponse, error)
+	IsReady(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*BoolResponse, error)
+	HasProvisioningCapacity(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*BoolResponse, error)
+}
+
+type provisionerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProvisionerClient(cc grpc.ClientConnInterface) ProvisionerClient {
+	return &provisionerClient{cc}
+}
+
+func (c *provisionerClient) ValidateManagementAccess(ctx context.Context, in *ValidateManagementAccessRequest, opts ...grpc.CallOption) (*ValidateManagementAccessResponse, error) {
+	out := new(ValidateManagementAccessResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/ValidateManagementAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) InspectHardware(ctx context.Context, in *InspectHardwareRequest, opts ...grpc.CallOption) (*InspectHardwareResponse, error) {
+	out := new(InspectHardwareResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/InspectHardware", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) UpdateHardwareState(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*UpdateHardwareStateResponse, error) {
+	out := new(UpdateHardwareStateResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/UpdateHardwareState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) Adopt(ctx context.Context, in *ForceRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/Adopt", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) Provision(ctx context.Context, in *ProvisionRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/Provision", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) Deprovision(ctx context.Context, in *ForceRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/Deprovision", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) Delete(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) PowerOn(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/PowerOn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) PowerOff(ctx context.Context, in *PowerOffRequest, opts ...grpc.CallOption) (*ResultResponse, error) {
+	out := new(ResultResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/PowerOff", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) IsReady(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*BoolResponse, error) {
+	out := new(BoolResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/IsReady", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionerClient) HasProvisioningCapacity(ctx context.Context, in *HostRequest, opts ...grpc.CallOption) (*BoolResponse, error) {
+	out := new(BoolResponse)
+	err := c.cc.Invoke(ctx, "/metal3.provisioner.v1alpha1.Provisioner/HasProvisioningCapacity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProvisionerServer is the server API for Provisioner service.
+// All implementations must embed UnimplementedProvisionerServer
+// for forward compatibility
+type ProvisionerServer interface {
+	ValidateManagementAccess(context.Context, *ValidateManagementAccessRequest) (*ValidateManagementAccessResponse, error)
+	InspectHardware(context.Context, *InspectHardwareRequest) (*InspectHardwareResponse, error)
+	UpdateHardwareState(context.Context, *HostRequest) (*UpdateHardwareStateResponse, error)
+	Adopt(context.Context, *ForceRequest) (*ResultResponse, error)
+	Provision(context.Context, *ProvisionRequest) (*ResultResponse, error)
+	Deprovision(context.Context, *ForceRequest) (*ResultResponse, error)
+	Delete(context.Context, *HostRequest) (*ResultResponse, error)
+	PowerOn(context.Context, *HostRequest) (*ResultResponse, error)
+	PowerOff(context.Context, *PowerOffRequest) (*ResultResponse, error)
+	IsReady(context.Context, *HostRequest) (*BoolResponse, error)
+	HasProvisioningCapacity(context.Context, *HostRequest) (*BoolResponse, error)
+	mustEmbedUnimplementedProvisionerServer()
+}
+
+// UnimplementedProvisionerServer must be embedded to have forward compatible implementations.
+type UnimplementedProvisionerServer struct {
+}
+
+func (UnimplementedProvisionerServer) ValidateManagementAccess(context.Context, *ValidateManagementAccessRequest) (*ValidateManagementAccessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateManagementAccess not implemented")
+}
+func (UnimplementedProvisionerServer) InspectHardware(context.Context, *InspectHardwareRequest) (*InspectHardwareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InspectHardware not implemented")
+}
+func (UnimplementedProvisionerServer) UpdateHardwareState(context.Context, *HostRequest) (*UpdateHardwareStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateHardwareState not implemented")
+}
+func (UnimplementedProvisionerServer) Adopt(context.Context, *ForceRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Adopt not implemented")
+}
+func (UnimplementedProvisionerServer) Provision(context.Context, *ProvisionRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Provision not implemented")
+}
+func (UnimplementedProvisionerServer) Deprovision(context.Context, *ForceRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deprovision not implemented")
+}
+func (UnimplementedProvisionerServer) Delete(context.Context, *HostRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedProvisionerServer) PowerOn(context.Context, *HostRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PowerOn not implemented")
+}
+func (UnimplementedProvisionerServer) PowerOff(context.Context, *PowerOffRequest) (*ResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PowerOff not implemented")
+}
+func (UnimplementedProvisionerServer) IsReady(context.Context, *HostRequest) (*BoolResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsReady not implemented")
+}
+func (UnimplementedProvisionerServer) HasProvisioningCapacity(context.Context, *HostRequest) (*BoolResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HasProvisioningCapacity not implemented")
+}
+func (UnimplementedProvisionerServer) mustEmbedUnimplementedProvisionerServer() {}
+
+// UnsafeProvisionerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProvisionerServer will
+// result in compilation errors.
+type UnsafeProvisionerServer interface {
+	mustEmbedUnimplementedProvisionerServer()
+}
+
+func RegisterProvisionerServer(s grpc.ServiceRegistrar, srv ProvisionerServer) {
+	s.RegisterService(&Provisioner_ServiceDesc, srv)
+}
+
+func _Provisioner_ValidateManagementAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateManagementAccessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).ValidateManagementAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/ValidateManagementAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).ValidateManagementAccess(ctx, req.(*ValidateManagementAccessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_InspectHardware_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InspectHardwareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).InspectHardware(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/InspectHardware",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).InspectHardware(ctx, req.(*InspectHardwareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_UpdateHardwareState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).UpdateHardwareState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/UpdateHardwareState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).UpdateHardwareState(ctx, req.(*HostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_Adopt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).Adopt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/Adopt",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).Adopt(ctx, req.(*ForceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_Provision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProvisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).Provision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/Provision",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).Provision(ctx, req.(*ProvisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_Deprovision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).Deprovision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/Deprovision",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).Deprovision(ctx, req.(*ForceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).Delete(ctx, req.(*HostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_PowerOn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).PowerOn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/PowerOn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).PowerOn(ctx, req.(*HostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_PowerOff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PowerOffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).PowerOff(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/PowerOff",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).PowerOff(ctx, req.(*PowerOffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_IsReady_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).IsReady(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/IsReady",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).IsReady(ctx, req.(*HostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provisioner_HasProvisioningCapacity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionerServer).HasProvisioningCapacity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/metal3.provisioner.v1alpha1.Provisioner/HasProvisioningCapacity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionerServer).HasProvisioningCapacity(ctx, req.(*HostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Provisioner_ServiceDesc is the grpc.ServiceDesc for Provisioner service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Provisioner_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "metal3.provisioner.v1alpha1.Provisioner",
+	HandlerType: (*ProvisionerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ValidateManagementAccess",
+			Handler:    _Provisioner_ValidateManagementAccess_Handler,
+		},
+		{
+			MethodName: "InspectHardware",
+			Handler:    _Provisioner_InspectHardware_Handler,
+		},
+		{
+			MethodName: "UpdateHardwareState",
+			Handler:    _Provisioner_UpdateHardwareState_Handler,
+		},
+		{
+			MethodName: "Adopt",
+			Handler:    _Provisioner_Adopt_Handler,
+		},
+		{
+			MethodName: "Provision",
+			Handler:    _Provisioner_Provision_Handler,
+		},
+		{
+			MethodName: "Deprovision",
+			Handler:    _Provisioner_Deprovision_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Provisioner_Delete_Handler,
+		},
+		{
+			MethodName: "PowerOn",
+			Handler:    _Provisioner_PowerOn_Handler,
+		},
+		{
+			MethodName: "PowerOff",
+			Handler:    _Provisioner_PowerOff_Handler,
+		},
+		{
+			MethodName: "IsReady",
+			Handler:    _Provisioner_IsReady_Handler,
+		},
+		{
+			MethodName: "HasProvisioningCapacity",
+			Handler:    _Provisioner_HasProvisioningCapacity_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "provisioner.proto",
+}
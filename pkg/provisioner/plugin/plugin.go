@@ -0,0 +1,322 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/plugin/api"
+)
+
+/*
+Package plugin implements a provisioner that forwards calls to an
+external plugin over gRPC, and the server side of the protocol for
+plugins written in Go.
+*/
+
+// Label is the label on a host naming the plugin that manages it.
+// Hosts without the label are managed by the fallback provisioner.
+const Label = "baremetalhost.metal3.io/provisioner-plugin"
+
+var log = logf.Log.WithName("provisioner").WithName("plugin")
+
+// callTimeout bounds how long a single call to a plugin may take.
+var callTimeout = time.Minute
+
+// ParsePlugins parses a comma-separated list of name=address pairs
+// into a map of plugin addresses keyed by name. Addresses use the gRPC
+// name syntax, such as "unix:///run/plugin.sock" or "dns:///host:port".
+func ParsePlugins(spec string) (map[string]string, error) {
+	plugins := map[string]string{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid provisioner plugin %q, expected name=address", item)
+		}
+		if _, exists := plugins[parts[0]]; exists {
+			return nil, fmt.Errorf("provisioner plugin %q is defined more than once", parts[0])
+		}
+		plugins[parts[0]] = parts[1]
+	}
+	return plugins, nil
+}
+
+// NewFactory returns a provisioner.Factory that creates provisioners
+// talking to the plugin named by the Label of each host, or uses the
+// fallback factory for hosts without the label. Connections to the
+// plugins are established in the background and shared by all hosts.
+//
+// Plugins listening on a unix socket are reached without TLS. Any
+// other plugin is only reached over TLS, so a CA file must be given in
+// tlsOptions to use it.
+func NewFactory(plugins map[string]string, tlsOptions TLSOptions, fallback provisioner.Factory) (provisioner.Factory, error) {
+	creds, err := tlsOptions.clientCredentials()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set up TLS for provisioner plugins")
+	}
+
+	clients := make(map[string]api.ProvisionerClient, len(plugins))
+	for name, address := range plugins {
+		var transport grpc.DialOption
+		switch {
+		case isLocal(address):
+			transport = grpc.WithInsecure()
+		case creds != nil:
+			transport = grpc.WithTransportCredentials(creds)
+		default:
+			return nil, fmt.Errorf("provisioner plugin %q at %s is not on a unix socket and needs a CA file to connect with TLS", name, address)
+		}
+		conn, err := grpc.Dial(address, transport)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to connect to provisioner plugin %q", name))
+		}
+		clients[name] = api.NewProvisionerClient(conn)
+		log.Info("using provisioner plugin", "plugin", name, "address", address)
+	}
+
+	return func(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+		name, ok := host.Labels[Label]
+		if !ok {
+			return fallback(host, bmcCreds, publisher)
+		}
+		client, ok := clients[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown provisioner plugin %q", name)
+		}
+		return newProvisioner(host, bmcCreds, publisher, name, client)
+	}, nil
+}
+
+// pluginProvisioner implements the provisioner.Provisioner interface
+// by forwarding each call to a plugin.
+type pluginProvisioner struct {
+	// the host to be managed by this provisioner, as sent to the plugin
+	host *api.Host
+	// the plugin to forward calls to
+	client api.ProvisionerClient
+	// a logger configured for this host
+	log logr.Logger
+	// an event publisher for recording significant events
+	publisher provisioner.EventPublisher
+}
+
+func newProvisioner(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher, name string, client api.ProvisionerClient) (*pluginProvisioner, error) {
+	encoded, err := json.Marshal(&host)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode host")
+	}
+	return &pluginProvisioner{
+		host: &api.Host{
+			BareMetalHost: encoded,
+			BmcCredentials: &api.Credentials{
+				Username: bmcCreds.Username,
+				Password: bmcCreds.Password,
+			},
+		},
+		client:    client,
+		log:       log.WithValues("host", host.Name, "plugin", name),
+		publisher: publisher,
+	}, nil
+}
+
+func (p *pluginProvisioner) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), callTimeout)
+}
+
+// publish records the events returned by the plugin.
+func (p *pluginProvisioner) publish(events []*api.Event) {
+	for _, event := range events {
+		p.publisher(event.Reason, event.Message)
+	}
+}
+
+// callError converts an error returned by the plugin back into the
+// error the provisioner returned.
+func callError(err error) error {
+	if status.Code(err) == codes.FailedPrecondition {
+		return errors.Wrap(provisioner.NeedsRegistration, status.Convert(err).Message())
+	}
+	return errors.Wrap(err, "provisioner plugin call failed")
+}
+
+func resultFromAPI(r *api.Result) (result provisioner.Result) {
+	if r == nil {
+		return
+	}
+	result.Dirty = r.Dirty
+	if r.RequeueAfter != nil {
+		result.RequeueAfter = r.RequeueAfter.AsDuration()
+	}
+	result.ErrorMessage = r.ErrorMessage
+	return
+}
+
+func resultToAPI(result provisioner.Result) *api.Result {
+	return &api.Result{
+		Dirty:        result.Dirty,
+		RequeueAfter: durationpb.New(result.RequeueAfter),
+		ErrorMessage: result.ErrorMessage,
+	}
+}
+
+// resultCall makes a call returning only a Result.
+func (p *pluginProvisioner) resultCall(call func(ctx context.Context) (*api.ResultResponse, error)) (result provisioner.Result, err error) {
+	ctx, cancel := p.context()
+	defer cancel()
+	resp, err := call(ctx)
+	if err != nil {
+		return result, callError(err)
+	}
+	p.publish(resp.Events)
+	return resultFromAPI(resp.Result), nil
+}
+
+// boolCall makes a call returning only a bool.
+func (p *pluginProvisioner) boolCall(call func(ctx context.Context) (*api.BoolResponse, error)) (bool, error) {
+	ctx, cancel := p.context()
+	defer cancel()
+	resp, err := call(ctx)
+	if err != nil {
+		return false, callError(err)
+	}
+	p.publish(resp.Events)
+	return resp.Value, nil
+}
+
+// ValidateManagementAccess tests the connection information for the
+// host to verify that the location and credentials work.
+func (p *pluginProvisioner) ValidateManagementAccess(credentialsChanged, force bool) (result provisioner.Result, provID string, err error) {
+	ctx, cancel := p.context()
+	defer cancel()
+	resp, err := p.client.ValidateManagementAccess(ctx, &api.ValidateManagementAccessRequest{
+		Host:               p.host,
+		CredentialsChanged: credentialsChanged,
+		Force:              force,
+	})
+	if err != nil {
+		return result, "", callError(err)
+	}
+	p.publish(resp.Events)
+	return resultFromAPI(resp.Result), resp.ProvisioningId, nil
+}
+
+// InspectHardware updates the HardwareDetails field of the host with
+// details of devices discovered on the hardware.
+func (p *pluginProvisioner) InspectHardware(force, refresh bool) (result provisioner.Result, started bool, details *metal3v1alpha1.HardwareDetails, err error) {
+	ctx, cancel := p.context()
+	defer cancel()
+	resp, err := p.client.InspectHardware(ctx, &api.InspectHardwareRequest{Host: p.host, Force: force, Refresh: refresh})
+	if err != nil {
+		return result, false, nil, callError(err)
+	}
+	if len(resp.HardwareDetails) != 0 {
+		details = &metal3v1alpha1.HardwareDetails{}
+		if err = json.Unmarshal(resp.HardwareDetails, details); err != nil {
+			return result, false, nil, errors.Wrap(err, "invalid hardware details from provisioner plugin")
+		}
+	}
+	p.publish(resp.Events)
+	return resultFromAPI(resp.Result), resp.Started, details, nil
+}
+
+// UpdateHardwareState fetches the latest hardware state of the server.
+func (p *pluginProvisioner) UpdateHardwareState() (hwState provisioner.HardwareState, err error) {
+	ctx, cancel := p.context()
+	defer cancel()
+	resp, err := p.client.UpdateHardwareState(ctx, &api.HostRequest{Host: p.host})
+	if err != nil {
+		return hwState, callError(err)
+	}
+	p.publish(resp.Events)
+	hwState.PoweredOn = resp.PoweredOn
+	hwState.AgentVersion = resp.AgentVersion
+	return hwState, nil
+}
+
+// Adopt brings an externally-provisioned host under management.
+func (p *pluginProvisioner) Adopt(force bool) (result provisioner.Result, err error) {
+	return p.resultCall(func(ctx context.Context) (*api.ResultResponse, error) {
+		return p.client.Adopt(ctx, &api.ForceRequest{Host: p.host, Force: force})
+	})
+}
+
+// Provision writes the image from the host spec to the host. The
+// configuration data is retrieved before the call so the plugin does
+// not need access to the cluster.
+func (p *pluginProvisioner) Provision(configData provisioner.HostConfigData) (result provisioner.Result, err error) {
+	apiConfigData := &api.HostConfigData{}
+	if configData != nil {
+		if apiConfigData.UserData, err = configData.UserData(); err != nil {
+			return result, errors.Wrap(err, "could not retrieve user data")
+		}
+		if apiConfigData.NetworkData, err = configData.NetworkData(); err != nil {
+			return result, errors.Wrap(err, "could not retrieve network data")
+		}
+		if apiConfigData.MetaData, err = configData.MetaData(); err != nil {
+			return result, errors.Wrap(err, "could not retrieve metadata")
+		}
+	}
+	return p.resultCall(func(ctx context.Context) (*api.ResultResponse, error) {
+		return p.client.Provision(ctx, &api.ProvisionRequest{Host: p.host, ConfigData: apiConfigData})
+	})
+}
+
+// Deprovision removes the image from the host.
+func (p *pluginProvisioner) Deprovision(force bool) (result provisioner.Result, err error) {
+	return p.resultCall(func(ctx context.Context) (*api.ResultResponse, error) {
+		return p.client.Deprovision(ctx, &api.ForceRequest{Host: p.host, Force: force})
+	})
+}
+
+// Delete removes the host from the provisioning system.
+func (p *pluginProvisioner) Delete() (result provisioner.Result, err error) {
+	return p.resultCall(func(ctx context.Context) (*api.ResultResponse, error) {
+		return p.client.Delete(ctx, &api.HostRequest{Host: p.host})
+	})
+}
+
+// PowerOn ensures the server is powered on.
+func (p *pluginProvisioner) PowerOn() (result provisioner.Result, err error) {
+	return p.resultCall(func(ctx context.Context) (*api.ResultResponse, error) {
+		return p.client.PowerOn(ctx, &api.HostRequest{Host: p.host})
+	})
+}
+
+// PowerOff ensures the server is powered off.
+func (p *pluginProvisioner) PowerOff(rebootMode metal3v1alpha1.RebootMode) (result provisioner.Result, err error) {
+	return p.resultCall(func(ctx context.Context) (*api.ResultResponse, error) {
+		return p.client.PowerOff(ctx, &api.PowerOffRequest{Host: p.host, Mode: string(rebootMode)})
+	})
+}
+
+// IsReady checks if the plugin is available to accept requests.
+func (p *pluginProvisioner) IsReady() (result bool, err error) {
+	return p.boolCall(func(ctx context.Context) (*api.BoolResponse, error) {
+		return p.client.IsReady(ctx, &api.HostRequest{Host: p.host})
+	})
+}
+
+// HasProvisioningCapacity checks if the plugin has a free provisioning
+// slot for the host.
+func (p *pluginProvisioner) HasProvisioningCapacity() (result bool, err error) {
+	return p.boolCall(func(ctx context.Context) (*api.BoolResponse, error) {
+		return p.client.HasProvisioningCapacity(ctx, &api.HostRequest{Host: p.host})
+	})
+}
@@ -0,0 +1,230 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
+)
+
+// unregisteredProvisioner reports every host as not registered.
+type unregisteredProvisioner struct {
+	provisioner.Provisioner
+}
+
+func (p unregisteredProvisioner) ValidateManagementAccess(credentialsChanged, force bool) (provisioner.Result, string, error) {
+	return provisioner.Result{}, "", provisioner.NeedsRegistration
+}
+
+// recordingProvisioner records the reboot modes and refresh flags it
+// is called with.
+type recordingProvisioner struct {
+	provisioner.Provisioner
+	modes     *[]metal3v1alpha1.RebootMode
+	refreshes *[]bool
+}
+
+func (p recordingProvisioner) PowerOff(rebootMode metal3v1alpha1.RebootMode) (provisioner.Result, error) {
+	*p.modes = append(*p.modes, rebootMode)
+	return p.Provisioner.PowerOff(rebootMode)
+}
+
+func (p recordingProvisioner) InspectHardware(force, refresh bool) (provisioner.Result, bool, *metal3v1alpha1.HardwareDetails, error) {
+	*p.refreshes = append(*p.refreshes, refresh)
+	return p.Provisioner.InspectHardware(force, refresh)
+}
+
+type event struct {
+	reason  string
+	message string
+}
+
+// startPlugin serves the factory on a unix socket and returns a
+// Factory using it as the plugin called "test".
+func startPlugin(t *testing.T, factory provisioner.Factory) (provisioner.Factory, func()) {
+	dir, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "plugin.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(factory)
+	go server.Serve(listener)
+
+	fallback := func(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+		return nil, errors.New("fallback used")
+	}
+	pluginFactory, err := NewFactory(map[string]string{"test": "unix://" + socket}, TLSOptions{}, fallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pluginFactory, func() {
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func makeHost(plugin string) metal3v1alpha1.BareMetalHost {
+	host := metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myhost",
+			Namespace: "myns",
+		},
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			Image: &metal3v1alpha1.Image{URL: "http://images.test/image.qcow2"},
+		},
+	}
+	if plugin != "" {
+		host.Labels = map[string]string{Label: plugin}
+	}
+	return host
+}
+
+func TestParsePlugins(t *testing.T) {
+	plugins, err := ParsePlugins("a=unix:///run/a.sock, b=dns:///b:50051,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "unix:///run/a.sock", "b": "dns:///b:50051"}, plugins)
+
+	for _, spec := range []string{"a", "=addr", "a=", "a=x,a=y"} {
+		_, err := ParsePlugins(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestFactorySelectsPlugin(t *testing.T) {
+	fixtures := &fixture.Fixtures{}
+	factory, stop := startPlugin(t, fixtures.New)
+	defer stop()
+
+	_, err := factory(makeHost(""), bmc.Credentials{}, nil)
+	assert.EqualError(t, err, "fallback used")
+
+	_, err = factory(makeHost("other"), bmc.Credentials{}, nil)
+	assert.EqualError(t, err, "unknown provisioner plugin \"other\"")
+
+	_, err = factory(makeHost("test"), bmc.Credentials{}, nil)
+	assert.NoError(t, err)
+}
+
+func TestFixturePlugin(t *testing.T) {
+	fixtures := &fixture.Fixtures{}
+	factory, stop := startPlugin(t, fixtures.New)
+	defer stop()
+
+	var events []event
+	publisher := func(reason, message string) {
+		events = append(events, event{reason, message})
+	}
+	host := makeHost("test")
+	prov, err := factory(host, bmc.Credentials{Username: "u", Password: "p"}, publisher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, provID, err := prov.ValidateManagementAccess(false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "temporary-fake-id", provID)
+	assert.True(t, result.Dirty)
+	assert.NotZero(t, result.RequeueAfter)
+	assert.Equal(t, []event{{"Registered", "Registered new host"}}, events)
+
+	_, _, details, err := prov.InspectHardware(false, false)
+	assert.NoError(t, err)
+	if assert.NotNil(t, details) {
+		assert.Equal(t, "FancyPants CPU", details.CPU.Model)
+		assert.Equal(t, 2, len(details.NIC))
+	}
+
+	host.Status.HardwareDetails = details
+	prov, err = factory(host, bmc.Credentials{}, publisher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, details, err = prov.InspectHardware(false, false)
+	assert.NoError(t, err)
+	assert.Nil(t, details)
+
+	// The state of the host is kept by the plugin between provisioners.
+	result, err = prov.PowerOn()
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	result, err = prov.PowerOn()
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+
+	hwState, err := prov.UpdateHardwareState()
+	assert.NoError(t, err)
+	if assert.NotNil(t, hwState.PoweredOn) {
+		assert.True(t, *hwState.PoweredOn)
+	}
+
+	result, err = prov.Provision(fixture.NewHostConfigData("user", "network", "meta"))
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+
+	ready, err := prov.IsReady()
+	assert.NoError(t, err)
+	assert.True(t, ready)
+}
+
+func TestPluginRebootModeAndRefresh(t *testing.T) {
+	fixtures := &fixture.Fixtures{}
+	var modes []metal3v1alpha1.RebootMode
+	var refreshes []bool
+	factory, stop := startPlugin(t, func(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+		prov, err := fixtures.New(host, bmcCreds, publisher)
+		return recordingProvisioner{Provisioner: prov, modes: &modes, refreshes: &refreshes}, err
+	})
+	defer stop()
+
+	prov, err := factory(makeHost("test"), bmc.Credentials{}, func(reason, message string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = prov.PowerOff(metal3v1alpha1.RebootModeHard)
+	assert.NoError(t, err)
+	_, err = prov.PowerOff(metal3v1alpha1.RebootModeSoft)
+	assert.NoError(t, err)
+	assert.Equal(t, []metal3v1alpha1.RebootMode{metal3v1alpha1.RebootModeHard, metal3v1alpha1.RebootModeSoft}, modes)
+
+	_, _, _, err = prov.InspectHardware(false, true)
+	assert.NoError(t, err)
+	_, _, _, err = prov.InspectHardware(false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false}, refreshes)
+}
+
+func TestPluginNeedsRegistration(t *testing.T) {
+	fixtures := &fixture.Fixtures{}
+	factory, stop := startPlugin(t, func(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+		prov, err := fixtures.New(host, bmcCreds, publisher)
+		return unregisteredProvisioner{prov}, err
+	})
+	defer stop()
+
+	prov, err := factory(makeHost("test"), bmc.Credentials{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = prov.ValidateManagementAccess(false, false)
+	assert.True(t, errors.Is(err, provisioner.NeedsRegistration), "unexpected error %v", err)
+
+	// Other calls are passed through.
+	result, err := prov.Delete()
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+}
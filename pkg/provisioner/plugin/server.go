@@ -0,0 +1,229 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/plugin/api"
+)
+
+// server implements the plugin side of the protocol by creating a
+// provisioner from a Factory for every call.
+type server struct {
+	api.UnimplementedProvisionerServer
+	factory provisioner.Factory
+}
+
+// NewServer returns a gRPC server offering the provisioners created by
+// factory as a plugin. Options such as ServerCredentials are passed on
+// to the gRPC server.
+func NewServer(factory provisioner.Factory, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	api.RegisterProvisionerServer(s, &server{factory: factory})
+	return s
+}
+
+// Serve offers the provisioners created by factory as a plugin on the
+// listener. It returns when the listener fails or is closed.
+func Serve(listener net.Listener, factory provisioner.Factory, opts ...grpc.ServerOption) error {
+	return NewServer(factory, opts...).Serve(listener)
+}
+
+// eventRecorder collects the events published during a call so they
+// can be returned to the operator.
+type eventRecorder struct {
+	events []*api.Event
+}
+
+func (r *eventRecorder) publish(reason, message string) {
+	r.events = append(r.events, &api.Event{Reason: reason, Message: message})
+}
+
+// provisioner creates a provisioner for the host in a request.
+func (s *server) provisioner(host *api.Host) (provisioner.Provisioner, *eventRecorder, error) {
+	if host == nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "no host given")
+	}
+	bmh := metal3v1alpha1.BareMetalHost{}
+	if err := json.Unmarshal(host.BareMetalHost, &bmh); err != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "invalid host: %s", err)
+	}
+	var creds bmc.Credentials
+	if host.BmcCredentials != nil {
+		creds.Username = host.BmcCredentials.Username
+		creds.Password = host.BmcCredentials.Password
+	}
+
+	recorder := &eventRecorder{}
+	prov, err := s.factory(bmh, creds, recorder.publish)
+	if err != nil {
+		return nil, nil, serverError(err)
+	}
+	return prov, recorder, nil
+}
+
+// serverError converts an error returned by a provisioner into a gRPC
+// status the operator can convert back.
+func serverError(err error) error {
+	if errors.Is(err, provisioner.NeedsRegistration) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+func (s *server) ValidateManagementAccess(ctx context.Context, req *api.ValidateManagementAccessRequest) (*api.ValidateManagementAccessResponse, error) {
+	prov, recorder, err := s.provisioner(req.Host)
+	if err != nil {
+		return nil, err
+	}
+	result, provID, err := prov.ValidateManagementAccess(req.CredentialsChanged, req.Force)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	return &api.ValidateManagementAccessResponse{
+		Result:         resultToAPI(result),
+		ProvisioningId: provID,
+		Events:         recorder.events,
+	}, nil
+}
+
+func (s *server) InspectHardware(ctx context.Context, req *api.InspectHardwareRequest) (*api.InspectHardwareResponse, error) {
+	prov, recorder, err := s.provisioner(req.Host)
+	if err != nil {
+		return nil, err
+	}
+	result, started, details, err := prov.InspectHardware(req.Force, req.Refresh)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	resp := &api.InspectHardwareResponse{
+		Result:  resultToAPI(result),
+		Events:  recorder.events,
+		Started: started,
+	}
+	if details != nil {
+		if resp.HardwareDetails, err = json.Marshal(details); err != nil {
+			return nil, status.Errorf(codes.Internal, "could not encode hardware details: %s", err)
+		}
+	}
+	return resp, nil
+}
+
+func (s *server) UpdateHardwareState(ctx context.Context, req *api.HostRequest) (*api.UpdateHardwareStateResponse, error) {
+	prov, recorder, err := s.provisioner(req.Host)
+	if err != nil {
+		return nil, err
+	}
+	hwState, err := prov.UpdateHardwareState()
+	if err != nil {
+		return nil, serverError(err)
+	}
+	return &api.UpdateHardwareStateResponse{
+		PoweredOn:    hwState.PoweredOn,
+		AgentVersion: hwState.AgentVersion,
+		Events:       recorder.events,
+	}, nil
+}
+
+// resultCall handles a call returning only a Result.
+func (s *server) resultCall(host *api.Host, call func(prov provisioner.Provisioner) (provisioner.Result, error)) (*api.ResultResponse, error) {
+	prov, recorder, err := s.provisioner(host)
+	if err != nil {
+		return nil, err
+	}
+	result, err := call(prov)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	return &api.ResultResponse{Result: resultToAPI(result), Events: recorder.events}, nil
+}
+
+// boolCall handles a call returning only a bool.
+func (s *server) boolCall(host *api.Host, call func(prov provisioner.Provisioner) (bool, error)) (*api.BoolResponse, error) {
+	prov, recorder, err := s.provisioner(host)
+	if err != nil {
+		return nil, err
+	}
+	value, err := call(prov)
+	if err != nil {
+		return nil, serverError(err)
+	}
+	return &api.BoolResponse{Value: value, Events: recorder.events}, nil
+}
+
+func (s *server) Adopt(ctx context.Context, req *api.ForceRequest) (*api.ResultResponse, error) {
+	return s.resultCall(req.Host, func(prov provisioner.Provisioner) (provisioner.Result, error) {
+		return prov.Adopt(req.Force)
+	})
+}
+
+func (s *server) Provision(ctx context.Context, req *api.ProvisionRequest) (*api.ResultResponse, error) {
+	configData := req.ConfigData
+	if configData == nil {
+		configData = &api.HostConfigData{}
+	}
+	return s.resultCall(req.Host, func(prov provisioner.Provisioner) (provisioner.Result, error) {
+		return prov.Provision(hostConfigData{configData})
+	})
+}
+
+func (s *server) Deprovision(ctx context.Context, req *api.ForceRequest) (*api.ResultResponse, error) {
+	return s.resultCall(req.Host, func(prov provisioner.Provisioner) (provisioner.Result, error) {
+		return prov.Deprovision(req.Force)
+	})
+}
+
+func (s *server) Delete(ctx context.Context, req *api.HostRequest) (*api.ResultResponse, error) {
+	return s.resultCall(req.Host, provisioner.Provisioner.Delete)
+}
+
+func (s *server) PowerOn(ctx context.Context, req *api.HostRequest) (*api.ResultResponse, error) {
+	return s.resultCall(req.Host, provisioner.Provisioner.PowerOn)
+}
+
+// PowerOff powers off soft unless the hard reboot mode is asked for,
+// so that operators sending no mode keep the graceful shutdown.
+func (s *server) PowerOff(ctx context.Context, req *api.PowerOffRequest) (*api.ResultResponse, error) {
+	mode := metal3v1alpha1.RebootModeSoft
+	if metal3v1alpha1.RebootMode(req.Mode) == metal3v1alpha1.RebootModeHard {
+		mode = metal3v1alpha1.RebootModeHard
+	}
+	return s.resultCall(req.Host, func(prov provisioner.Provisioner) (provisioner.Result, error) {
+		return prov.PowerOff(mode)
+	})
+}
+
+func (s *server) IsReady(ctx context.Context, req *api.HostRequest) (*api.BoolResponse, error) {
+	return s.boolCall(req.Host, provisioner.Provisioner.IsReady)
+}
+
+func (s *server) HasProvisioningCapacity(ctx context.Context, req *api.HostRequest) (*api.BoolResponse, error) {
+	return s.boolCall(req.Host, provisioner.Provisioner.HasProvisioningCapacity)
+}
+
+// hostConfigData implements provisioner.HostConfigData with the data
+// sent by the operator.
+type hostConfigData struct {
+	data *api.HostConfigData
+}
+
+func (d hostConfigData) UserData() (string, error) {
+	return d.data.UserData, nil
+}
+
+func (d hostConfigData) NetworkData() (string, error) {
+	return d.data.NetworkData, nil
+}
+
+func (d hostConfigData) MetaData() (string, error) {
+	return d.data.MetaData, nil
+}
@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSOptions holds the files used to secure the connections to
+// plugins that are not reached over a unix socket.
+type TLSOptions struct {
+	// CAFile holds the CA certificates the certificates of the
+	// plugins are verified against.
+	CAFile string
+	// CertFile and KeyFile hold the client certificate presented to
+	// the plugins, if any. They are read for every new connection, so
+	// the certificate can be rotated.
+	CertFile string
+	KeyFile  string
+}
+
+// isLocal reports whether a plugin address names a unix socket, which
+// only processes on the same host can reach.
+func isLocal(address string) bool {
+	return strings.HasPrefix(address, "unix:")
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(filepath.Clean(caFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read CA file")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+	}
+	return pool, nil
+}
+
+// keyPairLoader returns a function loading the certificate from its
+// files each time it is called.
+func keyPairLoader(certFile, keyFile string) func() (*tls.Certificate, error) {
+	return func() (*tls.Certificate, error) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load certificate")
+		}
+		return &cert, nil
+	}
+}
+
+// clientCredentials returns the transport credentials for connecting
+// to plugins over the network. There are none without a CA file,
+// since the credentials sent to plugins must not cross the network
+// unencrypted.
+func (o TLSOptions) clientCredentials() (credentials.TransportCredentials, error) {
+	if o.CAFile == "" {
+		return nil, nil
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, errors.New("the client certificate and key of provisioner plugins must be given together")
+	}
+	pool, err := loadCertPool(o.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	if o.CertFile != "" {
+		load := keyPairLoader(o.CertFile, o.KeyFile)
+		if _, err := load(); err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return load()
+		}
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// ServerCredentials returns the option serving a plugin over TLS with
+// the certificate in certFile and keyFile, which are read for every new
+// connection. When clientCAFile is set, the operator must present a
+// client certificate signed by one of the CAs it holds.
+func ServerCredentials(certFile, keyFile, clientCAFile string) (grpc.ServerOption, error) {
+	load := keyPairLoader(certFile, keyFile)
+	if _, err := load(); err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return load()
+		},
+		MinVersion: tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return grpc.Creds(credentials.NewTLS(config)), nil
+}
@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
+)
+
+// writeCert creates a certificate for localhost signed by the parent,
+// or a CA when parent is nil, and writes it to dir as prefix.crt and
+// prefix.key.
+func writeCert(t *testing.T, dir, prefix string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: prefix},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		prefix + ".crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		prefix + ".key": pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cert, key
+}
+
+// startTLSPlugin serves the fixture provisioner over TLS on a local
+// port, requiring a client certificate signed by the CA in dir, and
+// returns its address.
+func startTLSPlugin(t *testing.T, dir string) (string, func()) {
+	creds, err := ServerCredentials(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"),
+		filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := &fixture.Fixtures{}
+	server := NewServer(fixtures.New, creds)
+	go server.Serve(listener)
+	return listener.Addr().String(), server.Stop
+}
+
+func TestPluginOverTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca, caKey := writeCert(t, dir, "ca", nil, nil)
+	writeCert(t, dir, "server", ca, caKey)
+	writeCert(t, dir, "client", ca, caKey)
+	writeCert(t, dir, "other-ca", nil, nil)
+
+	address, stop := startTLSPlugin(t, dir)
+	defer stop()
+
+	fallback := func(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+		return nil, errors.New("fallback used")
+	}
+	powerOn := func(opts TLSOptions) error {
+		factory, err := NewFactory(map[string]string{"test": address}, opts, fallback)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prov, err := factory(makeHost("test"), bmc.Credentials{}, func(reason, message string) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = prov.PowerOn()
+		return err
+	}
+
+	assert.NoError(t, powerOn(TLSOptions{
+		CAFile:   filepath.Join(dir, "ca.crt"),
+		CertFile: filepath.Join(dir, "client.crt"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+	}))
+
+	// The certificate of the plugin is not signed by the CA.
+	assert.Error(t, powerOn(TLSOptions{
+		CAFile:   filepath.Join(dir, "other-ca.crt"),
+		CertFile: filepath.Join(dir, "client.crt"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+	}))
+
+	// The plugin requires a client certificate.
+	assert.Error(t, powerOn(TLSOptions{CAFile: filepath.Join(dir, "ca.crt")}))
+}
+
+func TestNewFactoryRequiresTLS(t *testing.T) {
+	_, err := NewFactory(map[string]string{"remote": "dns:///plugin:50051"}, TLSOptions{}, nil)
+	assert.EqualError(t, err, "provisioner plugin \"remote\" at dns:///plugin:50051 is not on a unix socket and needs a CA file to connect with TLS")
+
+	_, err = NewFactory(map[string]string{"local": "unix:///run/plugin.sock"}, TLSOptions{}, nil)
+	assert.NoError(t, err)
+
+	_, err = NewFactory(map[string]string{}, TLSOptions{CAFile: "/ca.crt", CertFile: "/tls.crt"}, nil)
+	assert.EqualError(t, err, "failed to set up TLS for provisioner plugins: the client certificate and key of provisioner plugins must be given together")
+}
@@ -48,8 +48,10 @@ type Provisioner interface {
 	// InspectHardware updates the HardwareDetails field of the host with
 	// details of devices discovered on the hardware. It may be called
 	// multiple times, and should return true for its dirty flag until the
-	// inspection is completed.
-	InspectHardware(force bool) (result Result, details *metal3v1alpha1.HardwareDetails, err error)
+	// inspection is completed. When refresh is set, a new inspection is
+	// started even though an earlier one has finished, and started
+	// tells when it is.
+	InspectHardware(force, refresh bool) (result Result, started bool, details *metal3v1alpha1.HardwareDetails, err error)
 
 	// UpdateHardwareState fetches the latest hardware state of the
 	// server and updates the HardwareDetails field of the host with
@@ -81,8 +83,9 @@ type Provisioner interface {
 	PowerOn() (result Result, err error)
 
 	// PowerOff ensures the server is powered off independently of any image
-	// provisioning operation.
-	PowerOff() (result Result, err error)
+	// provisioning operation. The reboot mode tells whether the
+	// server may be shut down gracefully or has to be forced off.
+	PowerOff(rebootMode metal3v1alpha1.RebootMode) (result Result, err error)
 
 	// IsReady checks if the provisioning backend is available to accept
 	// all the incoming requests.
@@ -109,6 +112,10 @@ type HardwareState struct {
 	// PoweredOn is a pointer to a bool indicating whether the Host is currently
 	// powered on. The value is nil if the power state cannot be determined.
 	PoweredOn *bool
+
+	// AgentVersion is the version of the provisioning agent last
+	// reported by the deploy ramdisk, or empty if it is not known.
+	AgentVersion string
 }
 
 var NeedsRegistration = errors.New("Host not registered")
@@ -0,0 +1,69 @@
+package redfish
+
+import (
+	"fmt"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+type accountService struct {
+	Accounts odataID `json:"Accounts"`
+}
+
+type account struct {
+	UserName string `json:"UserName"`
+}
+
+// SupportsAccounts returns whether the BMC of the host can be reached
+// over Redfish to manage its accounts.
+func SupportsAccounts(host metal3v1alpha1.BareMetalHost) bool {
+	bmcAccess, err := bmc.NewAccessDetails(host.Spec.BMC.Address, host.Spec.BMC.DisableCertificateVerification)
+	if err != nil {
+		return false
+	}
+	_, ok := bmcAccess.DriverInfo(bmc.Credentials{})["redfish_address"]
+	return ok
+}
+
+// ChangePassword sets the password of the BMC account the credentials
+// belong to through the Redfish AccountService.
+func ChangePassword(host metal3v1alpha1.BareMetalHost, creds bmc.Credentials, password string) error {
+	bmcAccess, err := AccessDetails(host)
+	if err != nil {
+		return err
+	}
+	c, err := newDriverClient(bmcAccess.DriverInfo(creds), creds)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return fmt.Errorf("BMC type %s does not support Redfish", bmcAccess.Type())
+	}
+
+	service := accountService{}
+	if err := c.get("/redfish/v1/AccountService", &service); err != nil {
+		return err
+	}
+	accountPath := ""
+	err = c.members(service.Accounts.ID, func(path string) error {
+		if accountPath != "" {
+			return nil
+		}
+		acct := account{}
+		if err := c.get(path, &acct); err != nil {
+			return err
+		}
+		if acct.UserName == creds.Username {
+			accountPath = path
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if accountPath == "" {
+		return fmt.Errorf("BMC has no account named %s", creds.Username)
+	}
+	return c.patch(accountPath, map[string]string{"Password": password})
+}
@@ -0,0 +1,38 @@
+package redfish
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
+)
+
+func TestChangePassword(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+	host := makeHost(mock.Address("redfish+http"))
+
+	assert.True(t, SupportsAccounts(host))
+	if err := ChangePassword(host, creds, "n3w-Password"); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "n3w-Password", mock.Password)
+
+	// The previous password no longer works.
+	err := ChangePassword(host, creds, "other")
+	assert.EqualError(t, err, "BMC rejected the credentials: 401 Unauthorized")
+
+	newCreds := bmc.Credentials{Username: "admin", Password: "n3w-Password"}
+	prov := newTestProvisioner(t, host, newCreds)
+	result, _, err := prov.ValidateManagementAccess(true, false)
+	assert.NoError(t, err)
+	assert.Empty(t, result.ErrorMessage)
+}
+
+func TestChangePasswordUnsupported(t *testing.T) {
+	host := makeHost("ipmi://192.168.122.1:6233")
+	assert.False(t, SupportsAccounts(host))
+	assert.EqualError(t, ChangePassword(host, creds, "n3w-Password"), "BMC type ipmi does not support Redfish")
+}
@@ -0,0 +1,357 @@
+package redfish
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+var requestTimeout = time.Second * 60
+
+// odataID is a link to another resource.
+type odataID struct {
+	ID string `json:"@odata.id"`
+}
+
+type collection struct {
+	Members []odataID `json:"Members"`
+}
+
+type action struct {
+	Target string `json:"target"`
+}
+
+// resetAction is the reset action of a system, with the types of
+// reset it supports when the BMC lists them.
+type resetAction struct {
+	Target          string   `json:"target"`
+	AllowableValues []string `json:"ResetType@Redfish.AllowableValues"`
+}
+
+// allows reports whether the reset type is supported, assuming that
+// it is when the BMC does not list the supported types.
+func (a resetAction) allows(resetType string) bool {
+	if len(a.AllowableValues) == 0 {
+		return true
+	}
+	for _, value := range a.AllowableValues {
+		if value == resetType {
+			return true
+		}
+	}
+	return false
+}
+
+type computerSystem struct {
+	ID           string `json:"Id"`
+	Manufacturer string `json:"Manufacturer"`
+	Model        string `json:"Model"`
+	SerialNumber string `json:"SerialNumber"`
+	BiosVersion  string `json:"BiosVersion"`
+	HostName     string `json:"HostName"`
+	PowerState   string `json:"PowerState"`
+
+	ProcessorSummary struct {
+		Count                 int    `json:"Count"`
+		LogicalProcessorCount int    `json:"LogicalProcessorCount"`
+		Model                 string `json:"Model"`
+	} `json:"ProcessorSummary"`
+
+	MemorySummary struct {
+		TotalSystemMemoryGiB float64 `json:"TotalSystemMemoryGiB"`
+	} `json:"MemorySummary"`
+
+	Boot struct {
+		BootSourceOverrideEnabled string `json:"BootSourceOverrideEnabled"`
+		BootSourceOverrideTarget  string `json:"BootSourceOverrideTarget"`
+	} `json:"Boot"`
+
+	Processors         odataID `json:"Processors"`
+	EthernetInterfaces odataID `json:"EthernetInterfaces"`
+	Storage            odataID `json:"Storage"`
+
+	Links struct {
+		ManagedBy []odataID `json:"ManagedBy"`
+	} `json:"Links"`
+
+	Actions struct {
+		Reset resetAction `json:"#ComputerSystem.Reset"`
+	} `json:"Actions"`
+}
+
+type processor struct {
+	ProcessorArchitecture string `json:"ProcessorArchitecture"`
+	InstructionSet        string `json:"InstructionSet"`
+	MaxSpeedMHz           int    `json:"MaxSpeedMHz"`
+}
+
+type ethernetInterface struct {
+	ID            string `json:"Id"`
+	Name          string `json:"Name"`
+	MACAddress    string `json:"MACAddress"`
+	SpeedMbps     int    `json:"SpeedMbps"`
+	IPv4Addresses []struct {
+		Address string `json:"Address"`
+	} `json:"IPv4Addresses"`
+	IPv6Addresses []struct {
+		Address string `json:"Address"`
+	} `json:"IPv6Addresses"`
+}
+
+type storage struct {
+	Drives []odataID `json:"Drives"`
+}
+
+type drive struct {
+	ID            string `json:"Id"`
+	Name          string `json:"Name"`
+	Manufacturer  string `json:"Manufacturer"`
+	Model         string `json:"Model"`
+	SerialNumber  string `json:"SerialNumber"`
+	CapacityBytes int64  `json:"CapacityBytes"`
+	MediaType     string `json:"MediaType"`
+}
+
+type manager struct {
+	Model        string  `json:"Model"`
+	VirtualMedia odataID `json:"VirtualMedia"`
+}
+
+type serviceRoot struct {
+	Vendor  string                     `json:"Vendor"`
+	Product string                     `json:"Product"`
+	Oem     map[string]json.RawMessage `json:"Oem"`
+}
+
+type virtualMedia struct {
+	ID         string   `json:"Id"`
+	MediaTypes []string `json:"MediaTypes"`
+	Image      string   `json:"Image"`
+	Inserted   bool     `json:"Inserted"`
+
+	Actions struct {
+		InsertMedia action `json:"#VirtualMedia.InsertMedia"`
+		EjectMedia  action `json:"#VirtualMedia.EjectMedia"`
+	} `json:"Actions"`
+}
+
+// redfishError is the body of an error response.
+type redfishError struct {
+	Error struct {
+		Message      string `json:"message"`
+		ExtendedInfo []struct {
+			Message string `json:"Message"`
+		} `json:"@Message.ExtendedInfo"`
+	} `json:"error"`
+}
+
+// client makes requests to a Redfish service.
+type client struct {
+	httpClient *http.Client
+	address    string
+	username   string
+	password   string
+}
+
+// authError is returned when the BMC rejects the credentials.
+type authError struct {
+	message string
+}
+
+func (e authError) Error() string {
+	return e.message
+}
+
+// newTLSConfig returns the TLS settings matching the verify_ca setting
+// of the Ironic driver: false to skip the verification of the BMC
+// certificate, or the path of the CA file to verify it against. The
+// system CAs are used when it is not set.
+func newTLSConfig(verifyCA interface{}) (*tls.Config, error) {
+	switch verify := verifyCA.(type) {
+	case bool:
+		if !verify {
+			return &tls.Config{InsecureSkipVerify: true}, nil // #nosec
+		}
+	case string:
+		pem, err := ioutil.ReadFile(filepath.Clean(verify))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the CA file of the BMC")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in the CA file %s of the BMC", verify)
+		}
+		return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+	}
+	return nil, nil
+}
+
+// newDriverClient returns a client for the Redfish endpoint in the
+// Ironic driver settings of a BMC, or nil when there is none.
+func newDriverClient(driverInfo map[string]interface{}, creds bmc.Credentials) (*client, error) {
+	address, ok := driverInfo["redfish_address"].(string)
+	if !ok {
+		return nil, nil
+	}
+	tlsConfig, err := newTLSConfig(driverInfo["redfish_verify_ca"])
+	if err != nil {
+		return nil, err
+	}
+	return newClient(address, creds.Username, creds.Password, tlsConfig), nil
+}
+
+func newClient(address, username, password string, tlsConfig *tls.Config) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &client{
+		httpClient: &http.Client{Transport: transport, Timeout: requestTimeout},
+		address:    strings.TrimSuffix(address, "/"),
+		username:   username,
+		password:   password,
+	}
+}
+
+func (c *client) do(method, path string, body interface{}, out interface{}) error {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		reqBody, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.address+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s %s failed", method, path))
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to read response to %s %s", method, path))
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return authError{fmt.Sprintf("BMC rejected the credentials: %s", resp.Status)}
+	}
+	if resp.StatusCode >= 400 {
+		message := resp.Status
+		redfishErr := redfishError{}
+		if json.Unmarshal(respBody, &redfishErr) == nil {
+			if len(redfishErr.Error.ExtendedInfo) != 0 {
+				message = redfishErr.Error.ExtendedInfo[0].Message
+			} else if redfishErr.Error.Message != "" {
+				message = redfishErr.Error.Message
+			}
+		}
+		return fmt.Errorf("%s %s failed: %s", method, path, message)
+	}
+
+	if out != nil && len(respBody) != 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("invalid response to %s %s", method, path))
+		}
+	}
+	return nil
+}
+
+func (c *client) get(path string, out interface{}) error {
+	return c.do(http.MethodGet, path, nil, out)
+}
+
+func (c *client) post(path string, body interface{}) error {
+	return c.do(http.MethodPost, path, body, nil)
+}
+
+func (c *client) patch(path string, body interface{}) error {
+	return c.do(http.MethodPatch, path, body, nil)
+}
+
+// members fetches every resource in a collection.
+func (c *client) members(path string, each func(path string) error) error {
+	if path == "" {
+		return nil
+	}
+	coll := collection{}
+	if err := c.get(path, &coll); err != nil {
+		return err
+	}
+	for _, member := range coll.Members {
+		if err := each(member.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// systemPath returns the path of the system, finding the only one
+// managed by the BMC when no path is given.
+func (c *client) systemPath(systemID string) (string, error) {
+	if systemID != "" && systemID != "/" {
+		return systemID, nil
+	}
+	coll := collection{}
+	if err := c.get("/redfish/v1/Systems", &coll); err != nil {
+		return "", err
+	}
+	if len(coll.Members) != 1 {
+		return "", fmt.Errorf("BMC manages %d systems, the address must include the path of one of them",
+			len(coll.Members))
+	}
+	return coll.Members[0].ID, nil
+}
+
+// virtualMedia returns the path and state of the virtual media device
+// of the system able to hold a CD or DVD image.
+func (c *client) virtualMedia(system *computerSystem) (path string, media virtualMedia, err error) {
+	for _, managerLink := range system.Links.ManagedBy {
+		mgr := manager{}
+		if err = c.get(managerLink.ID, &mgr); err != nil {
+			return
+		}
+		err = c.members(mgr.VirtualMedia.ID, func(memberPath string) error {
+			if path != "" {
+				return nil
+			}
+			candidate := virtualMedia{}
+			if err := c.get(memberPath, &candidate); err != nil {
+				return err
+			}
+			for _, mediaType := range candidate.MediaTypes {
+				if mediaType == "CD" || mediaType == "DVD" {
+					path, media = memberPath, candidate
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil || path != "" {
+			return
+		}
+	}
+	err = errors.New("BMC has no virtual media device for CD or DVD images")
+	return
+}
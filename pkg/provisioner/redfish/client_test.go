@@ -0,0 +1,141 @@
+package redfish
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
+)
+
+// newCA creates a private CA, writes its certificate to caFile and
+// returns a server certificate for 127.0.0.1 signed by it.
+func newCA(t *testing.T, caFile string) tls.Certificate {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "BMC CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDer}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "bmc"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caTemplate, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestCertificateVerification(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redfish-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.crt")
+	otherCAFile := filepath.Join(dir, "other-ca.crt")
+	mock := testserver.NewRedfish(t).StartTLS(newCA(t, caFile))
+	defer mock.Stop()
+	newCA(t, otherCAFile)
+
+	cases := []struct {
+		name          string
+		caFile        string
+		disable       bool
+		expectedError string
+	}{
+		{
+			name:   "private CA",
+			caFile: caFile,
+		},
+		{
+			name:          "system CAs",
+			expectedError: "certificate signed by unknown authority",
+		},
+		{
+			name:          "other CA",
+			caFile:        otherCAFile,
+			expectedError: "certificate signed by unknown authority",
+		},
+		{
+			name:    "verification disabled",
+			caFile:  otherCAFile,
+			disable: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			host := makeHost(mock.Address("redfish"))
+			host.Spec.BMC.DisableCertificateVerification = tc.disable
+			if tc.caFile != "" {
+				host.Status.BMCCertificate = &metal3v1alpha1.BMCCertificateStatus{CAFile: tc.caFile}
+			}
+
+			prov := newTestProvisioner(t, host, creds)
+			result, _, err := prov.ValidateManagementAccess(false, false)
+			assert.NoError(t, err)
+			err = ChangePassword(host, creds, creds.Password)
+			if tc.expectedError == "" {
+				assert.Empty(t, result.ErrorMessage)
+				assert.NoError(t, err)
+			} else {
+				assert.Contains(t, result.ErrorMessage, tc.expectedError)
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.expectedError)
+				}
+			}
+		})
+	}
+}
+
+func TestMissingCAFile(t *testing.T) {
+	host := makeHost("redfish://127.0.0.1/redfish/v1/Systems/1")
+	host.Status.BMCCertificate = &metal3v1alpha1.BMCCertificateStatus{CAFile: "/nonexistent/ca.crt"}
+
+	_, err := New(host, creds, nullEventPublisher)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed to read the CA file of the BMC")
+	}
+}
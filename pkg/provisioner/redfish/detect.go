@@ -0,0 +1,227 @@
+package redfish
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// AutoDetectType is the BMC type of addresses whose actual type is
+// detected from the Redfish service of the BMC.
+const AutoDetectType = "redfish-auto"
+
+// IsAutoDetect returns whether the BMC type of the address is to be
+// detected.
+func IsAutoDetect(address string) bool {
+	parsedURL, err := url.Parse(address)
+	if err != nil {
+		return false
+	}
+	return parsedURL.Scheme == AutoDetectType || strings.HasPrefix(parsedURL.Scheme, AutoDetectType+"+")
+}
+
+// vendorTypes lists the BMC types used for the BMCs of a vendor, with
+// and without virtual media.
+type vendorTypes struct {
+	virtualMedia string
+	pxe          string
+}
+
+var (
+	genericTypes    = vendorTypes{virtualMedia: "redfish-virtualmedia", pxe: "redfish"}
+	dellTypes       = vendorTypes{virtualMedia: "idrac-virtualmedia", pxe: "redfish"}
+	ilo4Types       = vendorTypes{virtualMedia: "ilo4", pxe: "ilo4"}
+	ilo5Types       = vendorTypes{virtualMedia: "ilo5-virtualmedia", pxe: "ilo5"}
+	supermicroTypes = vendorTypes{virtualMedia: "supermicro-virtualmedia", pxe: "supermicro"}
+	lenovoTypes     = vendorTypes{virtualMedia: "lenovo-xcc-virtualmedia", pxe: "lenovo-xcc"}
+	huaweiTypes     = vendorTypes{virtualMedia: "ibmc-virtualmedia", pxe: "ibmc"}
+	fujitsuTypes    = vendorTypes{virtualMedia: "irmc-virtualmedia", pxe: "irmc"}
+)
+
+// oemVendors maps the Oem sections of service roots to vendors, for
+// BMCs too old to report a Vendor.
+var oemVendors = map[string]string{
+	"Dell":       "Dell",
+	"Hpe":        "HPE",
+	"Hp":         "HPE",
+	"Supermicro": "Supermicro",
+	"Lenovo":     "Lenovo",
+	"Huawei":     "Huawei",
+	"ts_fujitsu": "Fujitsu",
+}
+
+// typesWithoutSystemPath lists the BMC types whose addresses do not
+// take the path of the system.
+var typesWithoutSystemPath = map[string]bool{
+	"ilo4":              true,
+	"ilo5":              true,
+	"irmc":              true,
+	"irmc-virtualmedia": true,
+	"ibmc":              true,
+}
+
+func vendorOf(root *serviceRoot) string {
+	if root.Vendor != "" {
+		return root.Vendor
+	}
+	for oem, vendor := range oemVendors {
+		if _, ok := root.Oem[oem]; ok {
+			return vendor
+		}
+	}
+	return ""
+}
+
+func typesFor(vendor, model string) vendorTypes {
+	vendor = strings.ToLower(vendor)
+	switch {
+	case strings.Contains(vendor, "dell"):
+		return dellTypes
+	case strings.Contains(vendor, "hpe"), strings.Contains(vendor, "hewlett"):
+		switch {
+		case strings.Contains(model, "iLO 5"):
+			return ilo5Types
+		case strings.Contains(model, "iLO 4"):
+			return ilo4Types
+		}
+	case strings.Contains(vendor, "supermicro"):
+		return supermicroTypes
+	case strings.Contains(vendor, "lenovo"):
+		return lenovoTypes
+	case strings.Contains(vendor, "huawei"):
+		return huaweiTypes
+	case strings.Contains(vendor, "fujitsu"):
+		return fujitsuTypes
+	}
+	return genericTypes
+}
+
+// Detect finds the vendor and model of the BMC at a redfish-auto
+// address, and the system it manages, and resolves the address to the
+// BMC type best suited to them. Virtual media types are preferred when
+// the BMC supports virtual media. The certificate of the BMC is verified
+// against caFile when it is set.
+func Detect(address string, creds bmc.Credentials, disableCertificateVerification bool, caFile string) (*metal3v1alpha1.DetectedBMC, error) {
+	identity, err := Identify(address, creds, disableCertificateVerification, caFile)
+	if err != nil {
+		return nil, err
+	}
+	return &identity.DetectedBMC, nil
+}
+
+// Identity describes the BMC at a redfish-auto address and the system
+// it manages.
+type Identity struct {
+	metal3v1alpha1.DetectedBMC
+
+	// SerialNumber is the serial number of the system
+	SerialNumber string
+	// BootMACAddress is the MAC address of the first network
+	// interface of the system
+	BootMACAddress string
+}
+
+// Identify detects the type of the BMC at a redfish-auto address like
+// Detect, and reads the serial number and first MAC address of the
+// system it manages.
+func Identify(address string, creds bmc.Credentials, disableCertificateVerification bool, caFile string) (*Identity, error) {
+	parsedURL, err := url.Parse(address)
+	if err != nil || !IsAutoDetect(address) {
+		return nil, fmt.Errorf("the BMC address %s does not use the %s type", address, AutoDetectType)
+	}
+	bmcAccess, err := accessDetails(address, disableCertificateVerification, caFile)
+	if err != nil {
+		return nil, err
+	}
+	driverInfo := bmcAccess.DriverInfo(creds)
+	redfishAddress, _ := driverInfo["redfish_address"].(string)
+	tlsConfig, err := newTLSConfig(driverInfo["redfish_verify_ca"])
+	if err != nil {
+		return nil, err
+	}
+	c := newClient(redfishAddress, creds.Username, creds.Password, tlsConfig)
+
+	root := serviceRoot{}
+	if err := c.get("/redfish/v1", &root); err != nil {
+		return nil, err
+	}
+	systemID, _ := driverInfo["redfish_system_id"].(string)
+	systemPath, err := c.systemPath(systemID)
+	if err != nil {
+		return nil, err
+	}
+	system := computerSystem{}
+	if err := c.get(systemPath, &system); err != nil {
+		return nil, err
+	}
+	mgr := manager{}
+	if len(system.Links.ManagedBy) != 0 {
+		if err := c.get(system.Links.ManagedBy[0].ID, &mgr); err != nil {
+			return nil, err
+		}
+	}
+
+	identity := &Identity{
+		DetectedBMC: metal3v1alpha1.DetectedBMC{
+			Address: address,
+			Vendor:  vendorOf(&root),
+			Model:   mgr.Model,
+		},
+		SerialNumber: system.SerialNumber,
+	}
+	types := typesFor(identity.Vendor, identity.Model)
+	resolved, err := resolveAddress(parsedURL, types, mgr.VirtualMedia.ID != "", systemPath, disableCertificateVerification, caFile)
+	if _, unknown := err.(*bmc.UnknownBMCTypeError); unknown {
+		// The type of the vendor does not support plain HTTP.
+		resolved, err = resolveAddress(parsedURL, genericTypes, mgr.VirtualMedia.ID != "", systemPath, disableCertificateVerification, caFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	identity.ResolvedAddress = resolved.String()
+	identity.Type = strings.SplitN(resolved.Scheme, "+", 2)[0]
+
+	err = c.members(system.EthernetInterfaces.ID, func(path string) error {
+		if identity.BootMACAddress != "" {
+			return nil
+		}
+		iface := ethernetInterface{}
+		if err := c.get(path, &iface); err != nil {
+			return err
+		}
+		identity.BootMACAddress = strings.ToLower(iface.MACAddress)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func resolveAddress(parsedURL *url.URL, types vendorTypes, virtualMedia bool, systemPath string, disableCertificateVerification bool, caFile string) (*url.URL, error) {
+	bmcType := types.pxe
+	if virtualMedia {
+		bmcType = types.virtualMedia
+	}
+	resolved := *parsedURL
+	resolved.Scheme = strings.Replace(parsedURL.Scheme, AutoDetectType, bmcType, 1)
+	resolved.Path = systemPath
+	if typesWithoutSystemPath[bmcType] {
+		resolved.Path = ""
+	}
+	_, err := accessDetails(resolved.String(), disableCertificateVerification, caFile)
+	return &resolved, err
+}
+
+// accessDetails returns the access details of a BMC whose certificate
+// is verified against caFile, when it is set and verification is not
+// disabled.
+func accessDetails(address string, disableCertificateVerification bool, caFile string) (bmc.AccessDetails, error) {
+	if caFile != "" && !disableCertificateVerification {
+		return bmc.NewAccessDetailsWithCAFile(address, caFile)
+	}
+	return bmc.NewAccessDetails(address, disableCertificateVerification)
+}
@@ -0,0 +1,154 @@
+package redfish
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
+)
+
+func TestIsAutoDetect(t *testing.T) {
+	assert.True(t, IsAutoDetect("redfish-auto://192.168.122.1"))
+	assert.True(t, IsAutoDetect("redfish-auto+http://192.168.122.1/redfish/v1/Systems/1"))
+	assert.False(t, IsAutoDetect("redfish://192.168.122.1"))
+	assert.False(t, IsAutoDetect("192.168.122.1:6233"))
+}
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		Scenario       string
+		Vendor         string
+		Model          string
+		NoVirtualMedia bool
+		Type           string
+		Path           string
+	}{
+		{
+			Scenario: "generic",
+			Vendor:   "Metal3",
+			Type:     "redfish-virtualmedia",
+			Path:     "/redfish/v1/Systems/1",
+		},
+		{
+			Scenario:       "generic without virtual media",
+			Vendor:         "Metal3",
+			NoVirtualMedia: true,
+			Type:           "redfish",
+			Path:           "/redfish/v1/Systems/1",
+		},
+		{
+			Scenario: "dell",
+			Vendor:   "Dell",
+			Model:    "14G Monolithic",
+			Type:     "idrac-virtualmedia",
+			Path:     "/redfish/v1/Systems/1",
+		},
+		{
+			Scenario: "supermicro",
+			Vendor:   "Supermicro",
+			Type:     "supermicro-virtualmedia",
+			Path:     "/redfish/v1/Systems/1",
+		},
+		{
+			Scenario: "lenovo",
+			Vendor:   "Lenovo",
+			Model:    "Lenovo XClarity Controller",
+			Type:     "lenovo-xcc-virtualmedia",
+			Path:     "/redfish/v1/Systems/1",
+		},
+		{
+			Scenario: "hpe",
+			Vendor:   "HPE",
+			Model:    "iLO 5",
+			Type:     "ilo5-virtualmedia",
+			Path:     "/redfish/v1/Systems/1",
+		},
+		{
+			// iRMC cannot be driven over plain HTTP, so Redfish is
+			// used.
+			Scenario: "fujitsu over http",
+			Vendor:   "Fujitsu",
+			Type:     "redfish-virtualmedia",
+			Path:     "/redfish/v1/Systems/1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			mock := testserver.NewRedfish(t).Start()
+			defer mock.Stop()
+			mock.Vendor = tc.Vendor
+			mock.ManagerModel = tc.Model
+			mock.NoVirtualMedia = tc.NoVirtualMedia
+			// Without the path of the system, the only one is used.
+			address := strings.TrimSuffix(mock.Address("redfish-auto+http"), "/redfish/v1/Systems/1")
+
+			detected, err := Detect(address, creds, false, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			hostPort := strings.TrimPrefix(address, "redfish-auto+http://")
+			assert.Equal(t, &metal3v1alpha1.DetectedBMC{
+				Address:         address,
+				Type:            tc.Type,
+				ResolvedAddress: tc.Type + "+http://" + hostPort + tc.Path,
+				Vendor:          tc.Vendor,
+				Model:           tc.Model,
+			}, detected)
+		})
+	}
+}
+
+func TestDetectKeepsOptions(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+	mock.Vendor = "Supermicro"
+
+	detected, err := Detect(mock.Address("redfish-auto+http")+"?auth_type=session", creds, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, mock.Address("supermicro-virtualmedia+http")+"?auth_type=session", detected.ResolvedAddress)
+}
+
+func TestIdentify(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+
+	identity, err := Identify(mock.Address("redfish-auto+http"), creds, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "redfish-virtualmedia", identity.Type)
+	assert.Equal(t, mock.Address("redfish-virtualmedia+http"), identity.ResolvedAddress)
+	assert.Equal(t, "MS-0001", identity.SerialNumber)
+	assert.Equal(t, "52:54:00:aa:bb:01", identity.BootMACAddress)
+}
+
+func TestDetectHPEVendorTypes(t *testing.T) {
+	assert.Equal(t, ilo5Types, typesFor("HPE", "iLO 5"))
+	assert.Equal(t, ilo4Types, typesFor("HPE", "iLO 4"))
+	assert.Equal(t, genericTypes, typesFor("HPE", "iLO 6"))
+	assert.Equal(t, dellTypes, vendorTypesOfOem(t, "Dell"))
+	assert.Equal(t, fujitsuTypes, vendorTypesOfOem(t, "ts_fujitsu"))
+}
+
+func vendorTypesOfOem(t *testing.T, oem string) vendorTypes {
+	root := serviceRoot{Oem: map[string]json.RawMessage{oem: json.RawMessage("{}")}}
+	return typesFor(vendorOf(&root), "")
+}
+
+func TestDetectErrors(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+
+	_, err := Detect(mock.Address("redfish+http"), creds, false, "")
+	assert.EqualError(t, err, "the BMC address "+mock.Address("redfish+http")+" does not use the redfish-auto type")
+
+	mock.Password = "changed"
+	_, err = Detect(mock.Address("redfish-auto+http"), creds, false, "")
+	assert.EqualError(t, err, "BMC rejected the credentials: 401 Unauthorized")
+}
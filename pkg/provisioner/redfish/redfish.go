@@ -0,0 +1,566 @@
+package redfish
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+)
+
+var log = logf.Log.WithName("provisioner").WithName("redfish")
+var provisionRequeueDelay = time.Second * 10
+var powerRequeueDelay = time.Second * 10
+
+// softPowerOffTimeout is how long a host is given to shut down after a
+// graceful shutdown before it is forced off, as ironic does.
+var softPowerOffTimeout = time.Second * 180
+
+const (
+	powerOn  = "On"
+	powerOff = "Off"
+
+	bootTargetCd   = "Cd"
+	bootTargetNone = "None"
+	bootOnce       = "Once"
+	bootContinuous = "Continuous"
+
+	liveISO = "live-iso"
+)
+
+// redfishProvisioner implements the provisioner.Provisioner interface
+// by talking to the BMC of the host directly over Redfish, without
+// Ironic. Images are booted from virtual media, so only ISO images
+// are supported.
+type redfishProvisioner struct {
+	// the host to be managed by this provisioner
+	host metal3v1alpha1.BareMetalHost
+	// access parameters for the BMC
+	bmcAccess bmc.AccessDetails
+	// a client for talking to the BMC, or nil if the BMC address
+	// cannot be used with Redfish
+	client *client
+	// the path of the system in the Redfish API, if given in the BMC
+	// address
+	systemID string
+	// a logger configured for this host
+	log logr.Logger
+	// an event publisher for recording significant events
+	publisher provisioner.EventPublisher
+}
+
+// New returns a new Redfish Provisioner
+func New(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+	bmcAccess, err := AccessDetails(host)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &redfishProvisioner{
+		host:      host,
+		bmcAccess: bmcAccess,
+		log:       log.WithValues("host", host.Name),
+		publisher: publisher,
+	}
+
+	// The Ironic driver settings hold the Redfish endpoint for every
+	// BMC type that supports it.
+	driverInfo := bmcAccess.DriverInfo(bmcCreds)
+	p.client, err = newDriverClient(driverInfo, bmcCreds)
+	if err != nil {
+		return nil, err
+	}
+	p.systemID, _ = driverInfo["redfish_system_id"].(string)
+	return p, nil
+}
+
+// AccessDetails returns the access details of the BMC of a host, with
+// its certificate verified against the CA file recorded in the status
+// of the host when there is one.
+func AccessDetails(host metal3v1alpha1.BareMetalHost) (bmc.AccessDetails, error) {
+	if cert := host.Status.BMCCertificate; cert != nil && cert.CAFile != "" && !host.Spec.BMC.DisableCertificateVerification {
+		return bmc.NewAccessDetailsWithCAFile(host.Spec.BMC.Address, cert.CAFile)
+	}
+	return bmc.NewAccessDetails(host.Spec.BMC.Address, host.Spec.BMC.DisableCertificateVerification)
+}
+
+func operationContinuing(delay time.Duration) (provisioner.Result, error) {
+	return provisioner.Result{
+		Dirty:        true,
+		RequeueAfter: delay,
+	}, nil
+}
+
+func operationComplete() (provisioner.Result, error) {
+	return provisioner.Result{}, nil
+}
+
+func operationFailed(message string) (provisioner.Result, error) {
+	return provisioner.Result{ErrorMessage: message}, nil
+}
+
+func transientError(err error) (provisioner.Result, error) {
+	return provisioner.Result{}, err
+}
+
+// system fetches the system managed through the BMC.
+func (p *redfishProvisioner) system() (path string, system computerSystem, err error) {
+	if p.client == nil {
+		err = fmt.Errorf("BMC type %s does not support Redfish", p.bmcAccess.Type())
+		return
+	}
+	path, err = p.client.systemPath(p.systemID)
+	if err != nil {
+		return
+	}
+	err = p.client.get(path, &system)
+	return
+}
+
+func (p *redfishProvisioner) HasProvisioningCapacity() (result bool, err error) {
+	return true, nil
+}
+
+// ValidateManagementAccess tests the connection information for the
+// host to verify that the location and credentials work.
+func (p *redfishProvisioner) ValidateManagementAccess(credentialsChanged, force bool) (result provisioner.Result, provID string, err error) {
+	p.log.Info("testing management access")
+
+	if p.client == nil {
+		result, err = operationFailed(fmt.Sprintf(
+			"BMC type %s is not supported by the redfish provisioner", p.bmcAccess.Type()))
+		return
+	}
+
+	path, _, err := p.system()
+	if err != nil {
+		result, err = operationFailed(fmt.Sprintf("Failed to access the BMC: %s", err))
+		return
+	}
+
+	if p.host.Status.Provisioning.ID != path {
+		p.log.Info("found system", "system", path)
+		p.publisher("Registered", "Registered new host")
+	}
+	return result, path, nil
+}
+
+// InspectHardware updates the HardwareDetails field of the host with
+// details of devices discovered on the hardware. The details are read
+// from the inventory kept by the BMC, so inspection completes in a
+// single call and is always a refresh.
+func (p *redfishProvisioner) InspectHardware(force, refresh bool) (result provisioner.Result, started bool, details *metal3v1alpha1.HardwareDetails, err error) {
+	p.log.Info("inspecting hardware", "status", p.host.OperationalStatus(), "refresh", refresh)
+
+	_, system, err := p.system()
+	if err != nil {
+		result, err = transientError(err)
+		return
+	}
+
+	details = &metal3v1alpha1.HardwareDetails{
+		SystemVendor: metal3v1alpha1.HardwareSystemVendor{
+			Manufacturer: system.Manufacturer,
+			ProductName:  system.Model,
+			SerialNumber: system.SerialNumber,
+		},
+		Firmware: metal3v1alpha1.Firmware{
+			BIOS: metal3v1alpha1.BIOS{Version: system.BiosVersion},
+		},
+		RAMMebibytes: int(system.MemorySummary.TotalSystemMemoryGiB * 1024),
+		CPU: metal3v1alpha1.CPU{
+			Model: system.ProcessorSummary.Model,
+			Count: system.ProcessorSummary.LogicalProcessorCount,
+		},
+		Hostname: system.HostName,
+	}
+	if details.CPU.Count == 0 {
+		details.CPU.Count = system.ProcessorSummary.Count
+	}
+
+	err = p.client.members(system.Processors.ID, func(path string) error {
+		cpu := processor{}
+		if err := p.client.get(path, &cpu); err != nil {
+			return err
+		}
+		if details.CPU.Arch == "" {
+			details.CPU.Arch = cpuArch(cpu)
+		}
+		if metal3v1alpha1.ClockSpeed(cpu.MaxSpeedMHz) > details.CPU.ClockMegahertz {
+			details.CPU.ClockMegahertz = metal3v1alpha1.ClockSpeed(cpu.MaxSpeedMHz)
+		}
+		return nil
+	})
+	if err != nil {
+		result, err = transientError(err)
+		return
+	}
+
+	err = p.client.members(system.EthernetInterfaces.ID, func(path string) error {
+		iface := ethernetInterface{}
+		if err := p.client.get(path, &iface); err != nil {
+			return err
+		}
+		nic := metal3v1alpha1.NIC{
+			Name:      iface.ID,
+			MAC:       strings.ToLower(iface.MACAddress),
+			SpeedGbps: iface.SpeedMbps / 1000,
+			PXE:       strings.EqualFold(iface.MACAddress, p.host.Spec.BootMACAddress),
+		}
+		if len(iface.IPv4Addresses) != 0 {
+			nic.IP = iface.IPv4Addresses[0].Address
+		} else if len(iface.IPv6Addresses) != 0 {
+			nic.IP = iface.IPv6Addresses[0].Address
+		}
+		details.NIC = append(details.NIC, nic)
+		return nil
+	})
+	if err != nil {
+		result, err = transientError(err)
+		return
+	}
+
+	err = p.client.members(system.Storage.ID, func(path string) error {
+		controller := storage{}
+		if err := p.client.get(path, &controller); err != nil {
+			return err
+		}
+		for _, driveLink := range controller.Drives {
+			disk := drive{}
+			if err := p.client.get(driveLink.ID, &disk); err != nil {
+				return err
+			}
+			name := disk.Name
+			if name == "" {
+				name = disk.ID
+			}
+			details.Storage = append(details.Storage, metal3v1alpha1.Storage{
+				Name:         name,
+				Rotational:   disk.MediaType == "HDD",
+				SizeBytes:    metal3v1alpha1.Capacity(disk.CapacityBytes),
+				Vendor:       disk.Manufacturer,
+				Model:        disk.Model,
+				SerialNumber: disk.SerialNumber,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		result, err = transientError(err)
+		return
+	}
+
+	p.publisher("InspectionComplete", "Hardware inspection completed")
+	return
+}
+
+// cpuArch converts the Redfish processor architecture to the name
+// used in HardwareDetails.
+func cpuArch(cpu processor) string {
+	switch cpu.InstructionSet {
+	case "x86-64":
+		return "x86_64"
+	case "ARM-A64":
+		return "aarch64"
+	case "PowerISA":
+		return "ppc64le"
+	case "x86":
+		return "i686"
+	}
+	return strings.ToLower(cpu.ProcessorArchitecture)
+}
+
+// UpdateHardwareState fetches the latest hardware state of the server
+// from the BMC.
+func (p *redfishProvisioner) UpdateHardwareState() (hwState provisioner.HardwareState, err error) {
+	_, system, err := p.system()
+	if err != nil {
+		return hwState, err
+	}
+	switch system.PowerState {
+	case powerOn:
+		poweredOn := true
+		hwState.PoweredOn = &poweredOn
+	case powerOff:
+		poweredOn := false
+		hwState.PoweredOn = &poweredOn
+	default:
+		// Powering on or off, report it once the change is done.
+	}
+	return hwState, nil
+}
+
+// Adopt brings an externally-provisioned host under management. There
+// is nothing to register without Ironic.
+func (p *redfishProvisioner) Adopt(force bool) (result provisioner.Result, err error) {
+	return operationComplete()
+}
+
+// Provision boots the host from the ISO image in the host spec using
+// virtual media. A live ISO is booted every time the host starts,
+// while an installer ISO is booted only once, after which the host
+// boots from the disk it installed to. Provisioning is complete once
+// the image has been inserted and the host restarted from it.
+func (p *redfishProvisioner) Provision(configData provisioner.HostConfigData) (result provisioner.Result, err error) {
+	image := p.host.Spec.Image
+	if image == nil || image.URL == "" {
+		return operationFailed("no image given to provision")
+	}
+	if image.DiskFormat != nil && *image.DiskFormat != liveISO {
+		return operationFailed(fmt.Sprintf(
+			"the redfish provisioner can only boot ISO images, not %s images", *image.DiskFormat))
+	}
+	p.log.Info("provisioning image to host", "image", image.URL)
+
+	systemPath, system, err := p.system()
+	if err != nil {
+		return transientError(err)
+	}
+	mediaPath, media, err := p.client.virtualMedia(&system)
+	if err != nil {
+		return operationFailed(err.Error())
+	}
+
+	if media.Inserted && media.Image == image.URL {
+		p.log.Info("image is inserted, provisioning complete")
+		return operationComplete()
+	}
+
+	if media.Inserted {
+		p.log.Info("ejecting previous image", "image", media.Image)
+		if err = p.ejectMedia(mediaPath, media); err != nil {
+			return transientError(err)
+		}
+	}
+
+	p.log.Info("inserting image")
+	err = p.client.post(actionTarget(media.Actions.InsertMedia.Target, mediaPath, "VirtualMedia.InsertMedia"),
+		map[string]interface{}{
+			"Image":          image.URL,
+			"Inserted":       true,
+			"WriteProtected": true,
+		})
+	if err != nil {
+		return transientError(err)
+	}
+
+	bootOverride := bootOnce
+	if image.DiskFormat != nil && *image.DiskFormat == liveISO {
+		bootOverride = bootContinuous
+	}
+	if err = p.setBootOverride(systemPath, bootTargetCd, bootOverride); err != nil {
+		return transientError(err)
+	}
+
+	resetType := "On"
+	if system.PowerState != powerOff {
+		resetType = "ForceRestart"
+	}
+	if err = p.reset(systemPath, system, resetType); err != nil {
+		return transientError(err)
+	}
+
+	p.publisher("ProvisioningStarted", fmt.Sprintf("Booting image %s from virtual media", image.URL))
+	return operationContinuing(provisionRequeueDelay)
+}
+
+// Deprovision ejects the image and powers off the host. It may be
+// called multiple times, and returns true for its dirty flag until
+// the deprovisioning operation is completed.
+func (p *redfishProvisioner) Deprovision(force bool) (result provisioner.Result, err error) {
+	p.log.Info("ensuring host is deprovisioned")
+
+	systemPath, system, err := p.system()
+	if err != nil {
+		return transientError(err)
+	}
+	mediaPath, media, err := p.client.virtualMedia(&system)
+	if err != nil {
+		return operationFailed(err.Error())
+	}
+
+	if media.Inserted {
+		p.log.Info("ejecting image", "image", media.Image)
+		p.publisher("DeprovisionStarted", "Image deprovisioning started")
+		if err = p.ejectMedia(mediaPath, media); err != nil {
+			return transientError(err)
+		}
+		return operationContinuing(provisionRequeueDelay)
+	}
+
+	if system.Boot.BootSourceOverrideTarget == bootTargetCd {
+		p.log.Info("clearing boot override")
+		if err = p.setBootOverride(systemPath, bootTargetNone, "Disabled"); err != nil {
+			return transientError(err)
+		}
+		return operationContinuing(provisionRequeueDelay)
+	}
+
+	if system.PowerState != powerOff {
+		p.log.Info("powering off")
+		if err = p.reset(systemPath, system, "ForceOff"); err != nil {
+			return transientError(err)
+		}
+		return operationContinuing(powerRequeueDelay)
+	}
+
+	p.publisher("DeprovisionComplete", "Image deprovisioning completed")
+	return operationComplete()
+}
+
+// Delete removes the host from the provisioning system. Nothing is
+// registered for the host without Ironic, so there is nothing to do.
+func (p *redfishProvisioner) Delete() (result provisioner.Result, err error) {
+	p.log.Info("deleting host")
+	return operationComplete()
+}
+
+// PowerOn ensures the server is powered on independently of any image
+// provisioning operation.
+func (p *redfishProvisioner) PowerOn() (result provisioner.Result, err error) {
+	p.log.Info("ensuring host is powered on")
+	p.forgetSoftPowerOff()
+	return p.changePower(powerOn, "On", "PowerOn", "Host powered on")
+}
+
+// softPowerOff records a graceful shutdown requested for a host.
+type softPowerOff struct {
+	started time.Time
+	// forced is set once the host has been forced off after not
+	// shutting down in time.
+	forced bool
+}
+
+// softPowerOffs holds the graceful shutdowns under way, keyed by host,
+// since nothing else remembers them between reconciles. A shutdown
+// forgotten on a restart of the operator is requested again.
+var softPowerOffs = struct {
+	sync.Mutex
+	hosts map[string]softPowerOff
+}{hosts: map[string]softPowerOff{}}
+
+func (p *redfishProvisioner) hostKey() string {
+	return p.host.Namespace + "/" + p.host.Name
+}
+
+func (p *redfishProvisioner) forgetSoftPowerOff() {
+	softPowerOffs.Lock()
+	defer softPowerOffs.Unlock()
+	delete(softPowerOffs.hosts, p.hostKey())
+}
+
+// PowerOff ensures the server is powered off independently of any image
+// provisioning operation. In soft mode the operating system is asked to
+// shut down first, and the system is forced off when the BMC does not
+// support that or the host is still on after softPowerOffTimeout.
+func (p *redfishProvisioner) PowerOff(rebootMode metal3v1alpha1.RebootMode) (result provisioner.Result, err error) {
+	p.log.Info("ensuring host is powered off", "mode", rebootMode)
+	if rebootMode == metal3v1alpha1.RebootModeHard {
+		p.forgetSoftPowerOff()
+		return p.changePower(powerOff, "ForceOff", "PowerOff", "Host powered off")
+	}
+
+	systemPath, system, err := p.system()
+	if err != nil {
+		return transientError(err)
+	}
+	if system.PowerState == powerOff {
+		p.forgetSoftPowerOff()
+		return operationComplete()
+	}
+	if system.PowerState != powerOn {
+		p.log.Info("waiting for power state change", "state", system.PowerState)
+		return operationContinuing(powerRequeueDelay)
+	}
+
+	softPowerOffs.Lock()
+	defer softPowerOffs.Unlock()
+	request, requested := softPowerOffs.hosts[p.hostKey()]
+	switch {
+	case request.forced:
+		p.log.Info("waiting for power state change", "state", system.PowerState)
+	case !system.Actions.Reset.allows("GracefulShutdown"):
+		p.log.Info("graceful shutdown not supported, forcing power off")
+		if err = p.reset(systemPath, system, "ForceOff"); err != nil {
+			return transientError(err)
+		}
+		p.publisher("PowerOff", "Host powered off")
+	case !requested:
+		if err = p.reset(systemPath, system, "GracefulShutdown"); err != nil {
+			return transientError(err)
+		}
+		softPowerOffs.hosts[p.hostKey()] = softPowerOff{started: time.Now()}
+		p.publisher("PowerOff", "Host soft powered off")
+	case time.Since(request.started) < softPowerOffTimeout:
+		p.log.Info("waiting for the host to shut down")
+	default:
+		p.log.Info("host did not shut down in time, forcing power off",
+			"timeout", softPowerOffTimeout)
+		if err = p.reset(systemPath, system, "ForceOff"); err != nil {
+			return transientError(err)
+		}
+		request.forced = true
+		softPowerOffs.hosts[p.hostKey()] = request
+		p.publisher("PowerOff", "Host powered off")
+	}
+	return operationContinuing(powerRequeueDelay)
+}
+
+func (p *redfishProvisioner) changePower(target, resetType, reason, message string) (result provisioner.Result, err error) {
+	systemPath, system, err := p.system()
+	if err != nil {
+		return transientError(err)
+	}
+	switch system.PowerState {
+	case target:
+		return operationComplete()
+	case powerOn, powerOff:
+		if err = p.reset(systemPath, system, resetType); err != nil {
+			return transientError(err)
+		}
+		p.publisher(reason, message)
+	default:
+		p.log.Info("waiting for power state change", "state", system.PowerState)
+	}
+	return operationContinuing(powerRequeueDelay)
+}
+
+// IsReady returns the current availability status of the provisioner.
+// There is no provisioning service to wait for.
+func (p *redfishProvisioner) IsReady() (result bool, err error) {
+	return true, nil
+}
+
+// actionTarget returns the target of an action, falling back to the
+// path defined by the standard when the resource does not give one.
+func actionTarget(target, resourcePath, action string) string {
+	if target != "" {
+		return target
+	}
+	return fmt.Sprintf("%s/Actions/%s", resourcePath, action)
+}
+
+func (p *redfishProvisioner) reset(systemPath string, system computerSystem, resetType string) error {
+	return p.client.post(actionTarget(system.Actions.Reset.Target, systemPath, "ComputerSystem.Reset"),
+		map[string]string{"ResetType": resetType})
+}
+
+func (p *redfishProvisioner) ejectMedia(mediaPath string, media virtualMedia) error {
+	return p.client.post(actionTarget(media.Actions.EjectMedia.Target, mediaPath, "VirtualMedia.EjectMedia"),
+		map[string]string{})
+}
+
+func (p *redfishProvisioner) setBootOverride(systemPath, target, enabled string) error {
+	return p.client.patch(systemPath, map[string]interface{}{
+		"Boot": map[string]string{
+			"BootSourceOverrideTarget":  target,
+			"BootSourceOverrideEnabled": enabled,
+		},
+	})
+}
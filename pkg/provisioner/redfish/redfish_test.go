@@ -0,0 +1,323 @@
+package redfish
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
+)
+
+func nullEventPublisher(reason, message string) {}
+
+func makeHost(address string) metal3v1alpha1.BareMetalHost {
+	return metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myhost",
+			Namespace: "myns",
+		},
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			BMC: metal3v1alpha1.BMCDetails{
+				Address:         address,
+				CredentialsName: "bmc-creds-valid",
+			},
+			BootMACAddress: "52:54:00:aa:bb:01",
+			Image: &metal3v1alpha1.Image{
+				URL: "http://images.test/installer.iso",
+			},
+		},
+	}
+}
+
+var creds = bmc.Credentials{Username: "admin", Password: "password"}
+
+func newTestProvisioner(t *testing.T, host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials) provisioner.Provisioner {
+	prov, err := New(host, bmcCreds, nullEventPublisher)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+	return prov
+}
+
+func TestValidateManagementAccess(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+
+	cases := []struct {
+		name          string
+		address       string
+		creds         bmc.Credentials
+		expectedID    string
+		expectedError string
+	}{
+		{
+			name:       "system path",
+			address:    mock.Address("redfish+http"),
+			creds:      creds,
+			expectedID: "/redfish/v1/Systems/1",
+		},
+		{
+			name:       "virtualmedia",
+			address:    mock.Address("redfish-virtualmedia+http"),
+			creds:      creds,
+			expectedID: "/redfish/v1/Systems/1",
+		},
+		{
+			name:       "only system",
+			address:    strings.TrimSuffix(mock.Address("redfish+http"), "/redfish/v1/Systems/1"),
+			creds:      creds,
+			expectedID: "/redfish/v1/Systems/1",
+		},
+		{
+			name:          "bad credentials",
+			address:       mock.Address("redfish+http"),
+			creds:         bmc.Credentials{Username: "admin", Password: "wrong"},
+			expectedError: "Failed to access the BMC: BMC rejected the credentials: 401 Unauthorized",
+		},
+		{
+			name:          "not redfish",
+			address:       "ipmi://192.168.122.1:6233",
+			creds:         creds,
+			expectedError: "BMC type ipmi is not supported by the redfish provisioner",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := newTestProvisioner(t, makeHost(tc.address), tc.creds)
+			result, provID, err := prov.ValidateManagementAccess(false, false)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedError, result.ErrorMessage)
+			assert.Equal(t, tc.expectedID, provID)
+		})
+	}
+}
+
+func TestInspectHardware(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+
+	prov := newTestProvisioner(t, makeHost(mock.Address("redfish+http")), creds)
+	result, _, details, err := prov.InspectHardware(false, false)
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+	if !assert.NotNil(t, details) {
+		return
+	}
+
+	assert.Equal(t, metal3v1alpha1.HardwareSystemVendor{
+		Manufacturer: "Metal3",
+		ProductName:  "Mock Server",
+		SerialNumber: "MS-0001",
+	}, details.SystemVendor)
+	assert.Equal(t, "1.2.3", details.Firmware.BIOS.Version)
+	assert.Equal(t, 64*1024, details.RAMMebibytes)
+	assert.Equal(t, "mock-host", details.Hostname)
+	assert.Equal(t, metal3v1alpha1.CPU{
+		Arch:           "x86_64",
+		Model:          "Mock CPU",
+		ClockMegahertz: 3200,
+		Count:          16,
+	}, details.CPU)
+	assert.Equal(t, []metal3v1alpha1.NIC{
+		{Name: "NIC1", MAC: "52:54:00:aa:bb:01", IP: "192.168.111.20", SpeedGbps: 10, PXE: true},
+		{Name: "NIC2", MAC: "52:54:00:aa:bb:02", SpeedGbps: 1},
+	}, details.NIC)
+	assert.Equal(t, []metal3v1alpha1.Storage{
+		{Name: "sda", SizeBytes: 480103981056, Vendor: "Mock", Model: "Fast SSD", SerialNumber: "SSD-1"},
+		{Name: "sdb", Rotational: true, SizeBytes: 4000787030016, Vendor: "Mock", Model: "Big HDD", SerialNumber: "HDD-1"},
+	}, details.Storage)
+}
+
+func TestPower(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+
+	prov := newTestProvisioner(t, makeHost(mock.Address("redfish+http")), creds)
+
+	hwState, err := prov.UpdateHardwareState()
+	assert.NoError(t, err)
+	if assert.NotNil(t, hwState.PoweredOn) {
+		assert.False(t, *hwState.PoweredOn)
+	}
+
+	result, err := prov.PowerOn()
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	result, err = prov.PowerOn()
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+
+	hwState, err = prov.UpdateHardwareState()
+	assert.NoError(t, err)
+	if assert.NotNil(t, hwState.PoweredOn) {
+		assert.True(t, *hwState.PoweredOn)
+	}
+
+	result, err = prov.PowerOff(metal3v1alpha1.RebootModeSoft)
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	result, err = prov.PowerOff(metal3v1alpha1.RebootModeSoft)
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+
+	assert.Equal(t, []string{"On", "GracefulShutdown"}, mock.Resets)
+}
+
+func TestPowerOffModes(t *testing.T) {
+	defer func(timeout time.Duration) { softPowerOffTimeout = timeout }(softPowerOffTimeout)
+
+	powerOff := func(prov provisioner.Provisioner, mode metal3v1alpha1.RebootMode) bool {
+		result, err := prov.PowerOff(mode)
+		assert.NoError(t, err)
+		return result.Dirty
+	}
+
+	cases := []struct {
+		name           string
+		mode           metal3v1alpha1.RebootMode
+		resetTypes     []string
+		ignoreShutdown bool
+		expectedResets []string
+	}{
+		{
+			name:           "soft",
+			mode:           metal3v1alpha1.RebootModeSoft,
+			expectedResets: []string{"GracefulShutdown"},
+		},
+		{
+			name:           "hard",
+			mode:           metal3v1alpha1.RebootModeHard,
+			expectedResets: []string{"ForceOff"},
+		},
+		{
+			name:           "soft-unsupported",
+			mode:           metal3v1alpha1.RebootModeSoft,
+			resetTypes:     []string{"On", "ForceOff", "ForceRestart"},
+			expectedResets: []string{"ForceOff"},
+		},
+		{
+			name:           "soft-timeout",
+			mode:           metal3v1alpha1.RebootModeSoft,
+			ignoreShutdown: true,
+			expectedResets: []string{"GracefulShutdown", "ForceOff"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			softPowerOffTimeout = time.Hour
+			mock := testserver.NewRedfish(t).Start()
+			defer mock.Stop()
+			mock.PowerState = "On"
+			mock.ResetTypes = tc.resetTypes
+			mock.IgnoreShutdown = tc.ignoreShutdown
+
+			prov := newTestProvisioner(t, makeHost(mock.Address("redfish+http")), creds)
+			assert.True(t, powerOff(prov, tc.mode))
+			if tc.ignoreShutdown {
+				// The host is given time to shut down before it is
+				// forced off.
+				assert.True(t, powerOff(prov, tc.mode))
+				assert.Equal(t, []string{"GracefulShutdown"}, mock.Resets)
+				softPowerOffTimeout = 0
+				assert.True(t, powerOff(prov, tc.mode))
+			}
+			assert.False(t, powerOff(prov, tc.mode))
+			assert.Equal(t, tc.expectedResets, mock.Resets)
+		})
+	}
+}
+
+func TestProvisionInstallerISO(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+	mock.PowerState = "On"
+	mock.MediaInserted = true
+	mock.MediaImage = "http://images.test/old.iso"
+
+	host := makeHost(mock.Address("redfish+http"))
+	prov := newTestProvisioner(t, host, creds)
+
+	result, err := prov.Provision(nil)
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	assert.Equal(t, "http://images.test/installer.iso", mock.MediaImage)
+	assert.Equal(t, []string{"ForceRestart"}, mock.Resets)
+	// The one time boot override was used by the restart.
+	assert.Equal(t, "None", mock.BootTarget)
+
+	result, err = prov.Provision(nil)
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+	assert.Equal(t, "", result.ErrorMessage)
+
+	result, err = prov.Deprovision(false)
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	assert.False(t, mock.MediaInserted)
+
+	result, err = prov.Deprovision(false)
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	assert.Equal(t, "Off", mock.PowerState)
+
+	result, err = prov.Deprovision(false)
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+}
+
+func TestProvisionLiveISO(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+
+	liveISOFormat := "live-iso"
+	host := makeHost(mock.Address("redfish+http"))
+	host.Spec.Image.URL = "http://images.test/live.iso"
+	host.Spec.Image.DiskFormat = &liveISOFormat
+	prov := newTestProvisioner(t, host, creds)
+
+	result, err := prov.Provision(nil)
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	assert.Equal(t, []string{"On"}, mock.Resets)
+	assert.Equal(t, "Cd", mock.BootTarget)
+	assert.Equal(t, "Continuous", mock.BootEnabled)
+
+	result, err = prov.Provision(nil)
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+
+	// Ejecting the media, clearing the boot override and powering off
+	// each take a step.
+	for i := 0; i < 3; i++ {
+		result, err = prov.Deprovision(false)
+		assert.NoError(t, err)
+		assert.True(t, result.Dirty)
+	}
+	assert.Equal(t, "None", mock.BootTarget)
+	result, err = prov.Deprovision(false)
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+}
+
+func TestProvisionDiskImage(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+
+	qcow2 := "qcow2"
+	host := makeHost(mock.Address("redfish+http"))
+	host.Spec.Image.DiskFormat = &qcow2
+	prov := newTestProvisioner(t, host, creds)
+
+	result, err := prov.Provision(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "the redfish provisioner can only boot ISO images, not qcow2 images", result.ErrorMessage)
+	assert.False(t, mock.MediaInserted)
+}
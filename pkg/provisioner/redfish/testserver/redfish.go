@@ -0,0 +1,341 @@
+package testserver
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	systemPath   = "/redfish/v1/Systems/1"
+	cdPath       = "/redfish/v1/Managers/1/VirtualMedia/Cd"
+	accountsPath = "/redfish/v1/AccountService/Accounts"
+)
+
+// Redfish is a mock Redfish service managing a single system with a
+// virtual CD drive. It keeps the power, boot and media state changed
+// by requests so tests can follow multi-step operations.
+type Redfish struct {
+	t      *testing.T
+	server *httptest.Server
+	lock   sync.Mutex
+
+	// Credentials accepted by the service, the password can be
+	// changed through the AccountService
+	Username string
+	Password string
+
+	// Vendor and ManagerModel describe the BMC
+	Vendor       string
+	ManagerModel string
+	// NoVirtualMedia hides the virtual media of the manager
+	NoVirtualMedia bool
+
+	PowerState    string
+	BootTarget    string
+	BootEnabled   string
+	MediaImage    string
+	MediaInserted bool
+
+	// ResetTypes lists the supported reset types, all of them when
+	// empty
+	ResetTypes []string
+	// IgnoreShutdown leaves the system on after a graceful shutdown,
+	// as an operating system that does not respond would
+	IgnoreShutdown bool
+
+	// Resets records the ResetType of every reset request
+	Resets []string
+}
+
+// NewRedfish returns a Redfish mock with a powered off system.
+func NewRedfish(t *testing.T) *Redfish {
+	return &Redfish{
+		t:           t,
+		Username:    "admin",
+		Password:    "password",
+		Vendor:      "Metal3",
+		PowerState:  "Off",
+		BootTarget:  "None",
+		BootEnabled: "Disabled",
+	}
+}
+
+// Start runs the server
+func (r *Redfish) Start() *Redfish {
+	r.server = httptest.NewServer(http.HandlerFunc(r.handle))
+	return r
+}
+
+// StartTLS runs the server over HTTPS with the given certificate
+func (r *Redfish) StartTLS(certificate tls.Certificate) *Redfish {
+	r.server = httptest.NewUnstartedServer(http.HandlerFunc(r.handle))
+	r.server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	r.server.StartTLS()
+	return r
+}
+
+// Stop shuts down the server
+func (r *Redfish) Stop() {
+	r.server.Close()
+}
+
+// Address returns the BMC address of the system for the given BMC type,
+// such as "redfish+http".
+func (r *Redfish) Address(bmcType string) string {
+	hostPort := strings.TrimPrefix(strings.TrimPrefix(r.server.URL, "http://"), "https://")
+	return fmt.Sprintf("%s://%s%s", bmcType, hostPort, systemPath)
+}
+
+func link(path string) map[string]string {
+	return map[string]string{"@odata.id": path}
+}
+
+func members(paths ...string) map[string]interface{} {
+	links := []map[string]string{}
+	for _, path := range paths {
+		links = append(links, link(path))
+	}
+	return map[string]interface{}{"Members": links}
+}
+
+func (r *Redfish) resetAction() map[string]interface{} {
+	action := map[string]interface{}{
+		"target": systemPath + "/Actions/ComputerSystem.Reset",
+	}
+	if len(r.ResetTypes) != 0 {
+		action["ResetType@Redfish.AllowableValues"] = r.ResetTypes
+	}
+	return action
+}
+
+func (r *Redfish) resources() map[string]interface{} {
+	mgr := map[string]interface{}{
+		"Model": r.ManagerModel,
+	}
+	if !r.NoVirtualMedia {
+		mgr["VirtualMedia"] = link("/redfish/v1/Managers/1/VirtualMedia")
+	}
+	return map[string]interface{}{
+		"/redfish/v1": map[string]interface{}{
+			"Vendor":         r.Vendor,
+			"Systems":        link("/redfish/v1/Systems"),
+			"Managers":       link("/redfish/v1/Managers"),
+			"AccountService": link("/redfish/v1/AccountService"),
+		},
+		"/redfish/v1/Systems": members(systemPath),
+		systemPath: map[string]interface{}{
+			"Id":           "1",
+			"Manufacturer": "Metal3",
+			"Model":        "Mock Server",
+			"SerialNumber": "MS-0001",
+			"BiosVersion":  "1.2.3",
+			"HostName":     "mock-host",
+			"PowerState":   r.PowerState,
+			"ProcessorSummary": map[string]interface{}{
+				"Count":                 2,
+				"LogicalProcessorCount": 16,
+				"Model":                 "Mock CPU",
+			},
+			"MemorySummary": map[string]interface{}{
+				"TotalSystemMemoryGiB": 64,
+			},
+			"Boot": map[string]interface{}{
+				"BootSourceOverrideTarget":  r.BootTarget,
+				"BootSourceOverrideEnabled": r.BootEnabled,
+			},
+			"Processors":         link(systemPath + "/Processors"),
+			"EthernetInterfaces": link(systemPath + "/EthernetInterfaces"),
+			"Storage":            link(systemPath + "/Storage"),
+			"Links": map[string]interface{}{
+				"ManagedBy": []map[string]string{link("/redfish/v1/Managers/1")},
+			},
+			"Actions": map[string]interface{}{
+				"#ComputerSystem.Reset": r.resetAction(),
+			},
+		},
+		systemPath + "/Processors": members(systemPath + "/Processors/CPU1"),
+		systemPath + "/Processors/CPU1": map[string]interface{}{
+			"ProcessorArchitecture": "x86",
+			"InstructionSet":        "x86-64",
+			"MaxSpeedMHz":           3200,
+		},
+		systemPath + "/EthernetInterfaces": members(
+			systemPath+"/EthernetInterfaces/NIC1", systemPath+"/EthernetInterfaces/NIC2"),
+		systemPath + "/EthernetInterfaces/NIC1": map[string]interface{}{
+			"Id":            "NIC1",
+			"MACAddress":    "52:54:00:AA:BB:01",
+			"SpeedMbps":     10000,
+			"IPv4Addresses": []map[string]string{{"Address": "192.168.111.20"}},
+		},
+		systemPath + "/EthernetInterfaces/NIC2": map[string]interface{}{
+			"Id":         "NIC2",
+			"MACAddress": "52:54:00:AA:BB:02",
+			"SpeedMbps":  1000,
+		},
+		systemPath + "/Storage": members(systemPath + "/Storage/1"),
+		systemPath + "/Storage/1": map[string]interface{}{
+			"Drives": []map[string]string{
+				link(systemPath + "/Storage/1/Drives/Disk1"),
+				link(systemPath + "/Storage/1/Drives/Disk2"),
+			},
+		},
+		systemPath + "/Storage/1/Drives/Disk1": map[string]interface{}{
+			"Id":            "Disk1",
+			"Name":          "sda",
+			"Manufacturer":  "Mock",
+			"Model":         "Fast SSD",
+			"SerialNumber":  "SSD-1",
+			"CapacityBytes": 480103981056,
+			"MediaType":     "SSD",
+		},
+		systemPath + "/Storage/1/Drives/Disk2": map[string]interface{}{
+			"Id":            "Disk2",
+			"Name":          "sdb",
+			"Manufacturer":  "Mock",
+			"Model":         "Big HDD",
+			"SerialNumber":  "HDD-1",
+			"CapacityBytes": 4000787030016,
+			"MediaType":     "HDD",
+		},
+		"/redfish/v1/AccountService": map[string]interface{}{
+			"Accounts": link(accountsPath),
+		},
+		accountsPath: members(accountsPath+"/1", accountsPath+"/2"),
+		accountsPath + "/1": map[string]interface{}{
+			"Id":       "1",
+			"UserName": "operator",
+			"RoleId":   "Operator",
+		},
+		accountsPath + "/2": map[string]interface{}{
+			"Id":       "2",
+			"UserName": r.Username,
+			"RoleId":   "Administrator",
+		},
+		"/redfish/v1/Managers":   members("/redfish/v1/Managers/1"),
+		"/redfish/v1/Managers/1": mgr,
+		"/redfish/v1/Managers/1/VirtualMedia": members(
+			"/redfish/v1/Managers/1/VirtualMedia/Floppy", cdPath),
+		"/redfish/v1/Managers/1/VirtualMedia/Floppy": map[string]interface{}{
+			"Id":         "Floppy",
+			"MediaTypes": []string{"Floppy", "USBStick"},
+		},
+		cdPath: map[string]interface{}{
+			"Id":         "Cd",
+			"MediaTypes": []string{"CD", "DVD"},
+			"Image":      r.MediaImage,
+			"Inserted":   r.MediaInserted,
+			"Actions": map[string]interface{}{
+				"#VirtualMedia.InsertMedia": map[string]string{
+					"target": cdPath + "/Actions/VirtualMedia.InsertMedia",
+				},
+				"#VirtualMedia.EjectMedia": map[string]string{
+					"target": cdPath + "/Actions/VirtualMedia.EjectMedia",
+				},
+			},
+		},
+	}
+}
+
+func (r *Redfish) fail(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": message},
+	})
+}
+
+func (r *Redfish) handle(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.t.Logf("redfish: %s %s", req.Method, req.URL.Path)
+
+	username, password, ok := req.BasicAuth()
+	if !ok || username != r.Username || password != r.Password {
+		r.fail(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+
+	body := map[string]interface{}{}
+	if req.Method != http.MethodGet {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			r.fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	switch {
+	case req.Method == http.MethodGet:
+		resource, ok := r.resources()[path]
+		if !ok {
+			r.fail(w, http.StatusNotFound, "no such resource")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resource)
+		return
+
+	case req.Method == http.MethodPatch && path == systemPath:
+		boot, _ := body["Boot"].(map[string]interface{})
+		if target, ok := boot["BootSourceOverrideTarget"].(string); ok {
+			r.BootTarget = target
+		}
+		if enabled, ok := boot["BootSourceOverrideEnabled"].(string); ok {
+			r.BootEnabled = enabled
+		}
+
+	case req.Method == http.MethodPost && path == systemPath+"/Actions/ComputerSystem.Reset":
+		resetType, _ := body["ResetType"].(string)
+		r.Resets = append(r.Resets, resetType)
+		switch resetType {
+		case "On", "ForceRestart", "GracefulRestart":
+			r.PowerState = "On"
+		case "ForceOff":
+			r.PowerState = "Off"
+		case "GracefulShutdown":
+			if !r.IgnoreShutdown {
+				r.PowerState = "Off"
+			}
+		default:
+			r.fail(w, http.StatusBadRequest, "unsupported reset type "+resetType)
+			return
+		}
+		if r.PowerState == "On" && r.BootEnabled == "Once" {
+			// The override is used up by the boot.
+			r.BootEnabled = "Disabled"
+			r.BootTarget = "None"
+		}
+
+	case req.Method == http.MethodPost && path == cdPath+"/Actions/VirtualMedia.InsertMedia":
+		if r.MediaInserted {
+			r.fail(w, http.StatusConflict, "media already inserted")
+			return
+		}
+		r.MediaImage, _ = body["Image"].(string)
+		r.MediaInserted = true
+
+	case req.Method == http.MethodPatch && path == accountsPath+"/2":
+		password, ok := body["Password"].(string)
+		if !ok || password == "" {
+			r.fail(w, http.StatusBadRequest, "invalid password")
+			return
+		}
+		r.Password = password
+
+	case req.Method == http.MethodPost && path == cdPath+"/Actions/VirtualMedia.EjectMedia":
+		r.MediaImage = ""
+		r.MediaInserted = false
+
+	default:
+		r.fail(w, http.StatusMethodNotAllowed, "not supported")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
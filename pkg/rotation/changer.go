@@ -0,0 +1,41 @@
+package rotation
+
+import (
+	"fmt"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish"
+)
+
+// PasswordChanger sets the password of the BMC account of a host,
+// logging in with the given credentials.
+type PasswordChanger func(host metal3v1alpha1.BareMetalHost, creds bmc.Credentials, password string) error
+
+// UnsupportedError is returned for hosts with BMCs whose passwords
+// cannot be changed.
+type UnsupportedError struct {
+	BMCType string
+}
+
+func (e UnsupportedError) Error() string {
+	return fmt.Sprintf("changing the password of %s BMCs is not supported", e.BMCType)
+}
+
+// ChangePassword is a PasswordChanger using the Redfish AccountService
+// when the BMC supports Redfish, or the IPMI user commands for IPMI
+// BMCs.
+func ChangePassword(host metal3v1alpha1.BareMetalHost, creds bmc.Credentials, password string) error {
+	accessDetails, err := redfish.AccessDetails(host)
+	if err != nil {
+		return err
+	}
+	switch {
+	case redfish.SupportsAccounts(host):
+		return redfish.ChangePassword(host, creds, password)
+	case accessDetails.Driver() == "ipmi":
+		return changeIPMIPassword(accessDetails, creds, password)
+	default:
+		return &UnsupportedError{BMCType: accessDetails.Type()}
+	}
+}
@@ -0,0 +1,117 @@
+package rotation
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+// ipmitool is the command run to manage IPMI accounts.
+var ipmitool = "ipmitool"
+
+// ipmiChannel is the LAN channel the accounts are listed for.
+const ipmiChannel = "1"
+
+// maxIPMIPasswordLength is the size of the longest passwords IPMI 2.0
+// stores.
+const maxIPMIPasswordLength = 20
+
+// runIPMITool runs ipmitool against the BMC, with the privilege level
+// and cipher suite given as options of its address.
+func runIPMITool(driverInfo map[string]interface{}, creds bmc.Credentials, args ...string) (string, error) {
+	address, _ := driverInfo["ipmi_address"].(string)
+	port, _ := driverInfo["ipmi_port"].(string)
+	cmdArgs := []string{"-I", "lanplus", "-H", address, "-p", port, "-U", creds.Username, "-E"}
+	if privLevel, ok := driverInfo["ipmi_priv_level"]; ok {
+		cmdArgs = append(cmdArgs, "-L", fmt.Sprint(privLevel))
+	}
+	if cipherSuite, ok := driverInfo["ipmi_cipher_suite"]; ok {
+		cmdArgs = append(cmdArgs, "-C", fmt.Sprint(cipherSuite))
+	}
+	cmdArgs = append(cmdArgs, args...)
+
+	// #nosec G204: the arguments are not passed through a shell
+	cmd := exec.Command(ipmitool, cmdArgs...)
+	// The password is passed in the environment with -E rather than
+	// on the command line, where other processes could read it.
+	cmd.Env = append(os.Environ(), "IPMI_PASSWORD="+creds.Password)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("ipmitool %s failed: %s: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// ipmiUserID finds the ID of the user in the output of ipmitool user
+// list, e.g.
+//
+//	ID  Name             Callin  Link Auth  IPMI Msg   Channel Priv Limit
+//	1                    true    false      false      NO ACCESS
+//	2   admin            true    true       true       ADMINISTRATOR
+func ipmiUserID(list, username string) (string, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	// Unnamed users leave the Name column blank, so it is cut out at
+	// the offsets of the header rather than split on spaces.
+	var nameStart, nameEnd int
+	for scanner.Scan() {
+		line := scanner.Text()
+		if nameEnd == 0 {
+			nameStart = strings.Index(line, "Name")
+			nameEnd = strings.Index(line, "Callin")
+			if nameStart < 0 || nameEnd <= nameStart {
+				return "", false
+			}
+			continue
+		}
+		if len(line) < nameEnd {
+			continue
+		}
+		if strings.TrimSpace(line[nameStart:nameEnd]) == username {
+			return strings.TrimSpace(line[:nameStart]), true
+		}
+	}
+	return "", false
+}
+
+func changeIPMIPassword(accessDetails bmc.AccessDetails, creds bmc.Credentials, password string) error {
+	if len(password) > maxIPMIPasswordLength {
+		return fmt.Errorf("IPMI passwords are at most %d characters long", maxIPMIPasswordLength)
+	}
+	if strings.ContainsAny(password, " \t\n\"'\\") {
+		return fmt.Errorf("IPMI passwords cannot contain spaces, quotes or backslashes")
+	}
+	driverInfo := accessDetails.DriverInfo(creds)
+
+	list, err := runIPMITool(driverInfo, creds, "user", "list", ipmiChannel)
+	if err != nil {
+		return err
+	}
+	id, ok := ipmiUserID(list, creds.Username)
+	if !ok {
+		return fmt.Errorf("BMC has no account named %s", creds.Username)
+	}
+
+	// The new password is read from a batch file rather than given on
+	// the command line.
+	batch, err := ioutil.TempFile("", "ipmi-password-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(batch.Name())
+	_, err = fmt.Fprintf(batch, "user set password %s %s %d\n", id, password, maxIPMIPasswordLength)
+	if closeErr := batch.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	_, err = runIPMITool(driverInfo, creds, "exec", batch.Name())
+	return err
+}
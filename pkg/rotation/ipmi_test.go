@@ -0,0 +1,135 @@
+package rotation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/bmc"
+)
+
+const userList = `ID  Name             Callin  Link Auth  IPMI Msg   Channel Priv Limit
+1                    true    false      false      NO ACCESS
+2   admin            true    true       true       ADMINISTRATOR
+3   operator         true    true       true       OPERATOR
+`
+
+func TestIPMIUserID(t *testing.T) {
+	id, ok := ipmiUserID(userList, "operator")
+	assert.True(t, ok)
+	assert.Equal(t, "3", id)
+
+	_, ok = ipmiUserID(userList, "true")
+	assert.False(t, ok)
+}
+
+// fakeIPMITool installs a script standing in for ipmitool, which logs
+// its arguments and the commands of batch files to the returned file.
+func fakeIPMITool(t *testing.T) (logFile string) {
+	dir, err := ioutil.TempDir("", "ipmitool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	logFile = filepath.Join(dir, "log")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$IPMI_PASSWORD $*" >> %[1]s
+for last; do :; done
+case "$*" in
+*"user list"*) cat <<EOT
+%[2]sEOT
+;;
+*exec*) cat "$last" >> %[1]s ;;
+esac
+`, logFile, userList)
+	path := filepath.Join(dir, "ipmitool")
+	if err := ioutil.WriteFile(path, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	original := ipmitool
+	ipmitool = path
+	t.Cleanup(func() { ipmitool = original })
+	return logFile
+}
+
+func TestChangeIPMIPassword(t *testing.T) {
+	logFile := fakeIPMITool(t)
+	host := metal3v1alpha1.BareMetalHost{
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			BMC: metal3v1alpha1.BMCDetails{Address: "ipmi://192.168.122.1:6233"},
+		},
+	}
+	creds := bmc.Credentials{Username: "admin", Password: "old"}
+
+	if err := ChangePassword(host, creds, "n3w-Password"); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, "old -I lanplus -H 192.168.122.1 -p 6233 -U admin -E user list 1", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "old -I lanplus -H 192.168.122.1 -p 6233 -U admin -E exec "))
+		assert.Equal(t, "user set password 2 n3w-Password 20", lines[2])
+	}
+}
+
+func TestChangeIPMIPasswordOptions(t *testing.T) {
+	logFile := fakeIPMITool(t)
+	accessDetails, err := bmc.NewAccessDetails("ipmi://192.168.122.1?priv_level=operator&cipher_suite=3", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := changeIPMIPassword(accessDetails, bmc.Credentials{Username: "admin", Password: "old"}, "n3w-Password"); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, "old -I lanplus -H 192.168.122.1 -p 623 -U admin -E -L OPERATOR -C 3 user list 1", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "old -I lanplus -H 192.168.122.1 -p 623 -U admin -E -L OPERATOR -C 3 exec "))
+	}
+}
+
+func TestChangeIPMIPasswordErrors(t *testing.T) {
+	fakeIPMITool(t)
+	accessDetails, err := bmc.NewAccessDetails("ipmi://192.168.122.1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = changeIPMIPassword(accessDetails, bmc.Credentials{Username: "admin"}, strings.Repeat("x", 21))
+	assert.EqualError(t, err, "IPMI passwords are at most 20 characters long")
+
+	err = changeIPMIPassword(accessDetails, bmc.Credentials{Username: "admin"}, "with space")
+	assert.EqualError(t, err, "IPMI passwords cannot contain spaces, quotes or backslashes")
+
+	err = changeIPMIPassword(accessDetails, bmc.Credentials{Username: "nobody"}, "n3w-Password")
+	assert.EqualError(t, err, "BMC has no account named nobody")
+}
+
+func TestChangePasswordUnsupported(t *testing.T) {
+	host := metal3v1alpha1.BareMetalHost{
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			BMC: metal3v1alpha1.BMCDetails{Address: "irmc://192.168.122.1"},
+		},
+	}
+	err := ChangePassword(host, bmc.Credentials{}, "n3w-Password")
+	assert.IsType(t, &UnsupportedError{}, err)
+}
@@ -0,0 +1,100 @@
+// Package rotation changes the passwords of BMC accounts, for the
+// BMCCredentialRotation policies.
+package rotation
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+const (
+	lowercase = "abcdefghijklmnopqrstuvwxyz"
+	uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits    = "0123456789"
+)
+
+// ValidatePolicy returns an error when no password can follow the
+// policy.
+func ValidatePolicy(policy metal3v1alpha1.PasswordPolicy) error {
+	_, _, err := policyClasses(policy)
+	return err
+}
+
+func policyClasses(policy metal3v1alpha1.PasswordPolicy) (length int, classes map[string]int, err error) {
+	length = policy.Length
+	if length == 0 {
+		length = metal3v1alpha1.DefaultPasswordLength
+	}
+	symbols := metal3v1alpha1.DefaultPasswordSymbols
+	if policy.Symbols != nil {
+		symbols = *policy.Symbols
+	}
+
+	if policy.MinSymbols > 0 && symbols == "" {
+		return 0, nil, fmt.Errorf("passwords need %d symbols but none are allowed", policy.MinSymbols)
+	}
+	required := policy.MinLowercase + policy.MinUppercase + policy.MinDigits + policy.MinSymbols
+	if required > length {
+		return 0, nil, fmt.Errorf("passwords need %d characters of given kinds but are %d characters long",
+			required, length)
+	}
+
+	classes = map[string]int{
+		lowercase: policy.MinLowercase,
+		uppercase: policy.MinUppercase,
+		digits:    policy.MinDigits,
+	}
+	if symbols != "" {
+		classes[symbols] = policy.MinSymbols
+	}
+	return length, classes, nil
+}
+
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+// GeneratePassword returns a random password following the policy.
+func GeneratePassword(policy metal3v1alpha1.PasswordPolicy) (string, error) {
+	length, classes, err := policyClasses(policy)
+	if err != nil {
+		return "", err
+	}
+
+	password := make([]byte, 0, length)
+	all := ""
+	for class, minimum := range classes {
+		all += class
+		for i := 0; i < minimum; i++ {
+			index, err := randomIndex(len(class))
+			if err != nil {
+				return "", err
+			}
+			password = append(password, class[index])
+		}
+	}
+	for len(password) < length {
+		index, err := randomIndex(len(all))
+		if err != nil {
+			return "", err
+		}
+		password = append(password, all[index])
+	}
+
+	// Shuffle so that the required characters are not always first.
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,103 @@
+package rotation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+func count(password, class string) (n int) {
+	for _, c := range password {
+		if strings.ContainsRune(class, c) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGeneratePassword(t *testing.T) {
+	symbols := "*"
+	policy := metal3v1alpha1.PasswordPolicy{
+		Length:       20,
+		MinLowercase: 2,
+		MinUppercase: 3,
+		MinDigits:    4,
+		MinSymbols:   5,
+		Symbols:      &symbols,
+	}
+	for i := 0; i < 20; i++ {
+		password, err := GeneratePassword(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, password, 20)
+		assert.GreaterOrEqual(t, count(password, lowercase), 2)
+		assert.GreaterOrEqual(t, count(password, uppercase), 3)
+		assert.GreaterOrEqual(t, count(password, digits), 4)
+		assert.GreaterOrEqual(t, count(password, symbols), 5)
+		assert.Equal(t, 20, count(password, lowercase+uppercase+digits+symbols))
+	}
+}
+
+func TestGeneratePasswordDefaults(t *testing.T) {
+	password, err := GeneratePassword(metal3v1alpha1.PasswordPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, password, metal3v1alpha1.DefaultPasswordLength)
+
+	other, err := GeneratePassword(metal3v1alpha1.PasswordPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, password, other)
+}
+
+func TestGeneratePasswordNoSymbols(t *testing.T) {
+	none := ""
+	password, err := GeneratePassword(metal3v1alpha1.PasswordPolicy{Length: 40, Symbols: &none})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 40, count(password, lowercase+uppercase+digits))
+}
+
+func TestValidatePolicy(t *testing.T) {
+	none := ""
+	testCases := []struct {
+		Scenario string
+		Policy   metal3v1alpha1.PasswordPolicy
+		Error    string
+	}{
+		{
+			Scenario: "defaults",
+		},
+		{
+			Scenario: "minimums fill the length",
+			Policy:   metal3v1alpha1.PasswordPolicy{Length: 4, MinLowercase: 1, MinUppercase: 1, MinDigits: 1, MinSymbols: 1},
+		},
+		{
+			Scenario: "minimums exceed the default length",
+			Policy:   metal3v1alpha1.PasswordPolicy{MinDigits: 17},
+			Error:    "passwords need 17 characters of given kinds but are 16 characters long",
+		},
+		{
+			Scenario: "symbols required but none allowed",
+			Policy:   metal3v1alpha1.PasswordPolicy{MinSymbols: 1, Symbols: &none},
+			Error:    "passwords need 1 symbols but none are allowed",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			err := ValidatePolicy(tc.Policy)
+			if tc.Error == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.Error)
+			}
+		})
+	}
+}
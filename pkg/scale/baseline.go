@@ -0,0 +1,81 @@
+package scale
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Load reads a result saved with Save.
+func Load(path string) (*Result, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{}
+	if err := json.Unmarshal(content, result); err != nil {
+		return nil, fmt.Errorf("invalid result in %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// Save writes the result to a file, to be used as a baseline.
+func (r *Result) Save(path string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(content, '\n'), 0644) // #nosec
+}
+
+// metric is a measurement compared with the baseline.
+type metric struct {
+	name string
+	// value returns the measurement as a number
+	value func(r *Result) float64
+	// higherIsBetter is set for the throughput, where a drop is a
+	// regression
+	higherIsBetter bool
+}
+
+func seconds(d time.Duration) float64 {
+	return d.Seconds()
+}
+
+var metrics = []metric{
+	{name: "provisioned hosts", value: func(r *Result) float64 { return float64(r.Provisioned) }, higherIsBetter: true},
+	{name: "reconciles per second", value: func(r *Result) float64 { return r.ReconcilesPerSecond }, higherIsBetter: true},
+	{name: "reconciles per host", value: func(r *Result) float64 { return r.ReconcilesPerHost }},
+	{name: "max reconciles per host", value: func(r *Result) float64 { return float64(r.MaxReconcilesPerHost) }},
+	{name: "steady reconciles per host per hour", value: func(r *Result) float64 { return r.SteadyReconcilesPerHostHour }},
+	{name: "errors", value: func(r *Result) float64 { return float64(r.Errors) }},
+	{name: "max queue depth", value: func(r *Result) float64 { return float64(r.MaxQueueDepth) }},
+	{name: "mean queue depth", value: func(r *Result) float64 { return r.MeanQueueDepth }},
+	{name: "writes per host", value: func(r *Result) float64 { return r.WritesPerHost }},
+	{name: "time to provisioned (p50)", value: func(r *Result) float64 { return seconds(r.TimeToProvisioned.P50) }},
+	{name: "time to provisioned (p90)", value: func(r *Result) float64 { return seconds(r.TimeToProvisioned.P90) }},
+	{name: "time to provisioned (max)", value: func(r *Result) float64 { return seconds(r.TimeToProvisioned.Max) }},
+}
+
+// Compare returns a description of each measurement of the result
+// that is worse than the baseline by more than the tolerance, given as
+// a fraction of the baseline value.
+func Compare(baseline, result *Result, tolerance float64) (regressions []string) {
+	if baseline.Config != result.Config {
+		regressions = append(regressions,
+			fmt.Sprintf("the baseline was measured with different settings: %+v", baseline.Config))
+	}
+	for _, m := range metrics {
+		before, after := m.value(baseline), m.value(result)
+		worse := after > before*(1+tolerance)
+		if m.higherIsBetter {
+			worse = after < before*(1-tolerance)
+		}
+		if worse {
+			regressions = append(regressions,
+				fmt.Sprintf("%s went from %.2f to %.2f", m.name, before, after))
+		}
+	}
+	return
+}
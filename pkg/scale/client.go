@@ -0,0 +1,132 @@
+package scale
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// countingClient counts the writes made by the reconciler and passes
+// the changes on to the harness as the watch events the controller
+// would receive. It also increases the generation of hosts when their
+// spec changes, as the API server does.
+type countingClient struct {
+	client.Client
+	harness *harness
+}
+
+func kindOf(obj runtime.Object) string {
+	switch obj.(type) {
+	case *metal3v1alpha1.BareMetalHost:
+		return "BareMetalHost"
+	case *corev1.Secret:
+		return "Secret"
+	case *corev1.Event:
+		return "Event"
+	}
+	return reflect.TypeOf(obj).String()
+}
+
+func (c *countingClient) current(ctx context.Context, obj runtime.Object) runtime.Object {
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return nil
+	}
+	old := obj.DeepCopyObject()
+	if err := c.Client.Get(ctx, key, old); err != nil {
+		return nil
+	}
+	return old
+}
+
+func (c *countingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	err := c.Client.Create(ctx, obj, opts...)
+	if err == nil {
+		c.harness.written(kindOf(obj), obj, nil, obj)
+	}
+	return err
+}
+
+func (c *countingClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	old := c.current(ctx, obj)
+	if oldHost, ok := old.(*metal3v1alpha1.BareMetalHost); ok {
+		host := obj.(*metal3v1alpha1.BareMetalHost)
+		host.Generation = oldHost.Generation
+		if !reflect.DeepEqual(oldHost.Spec, host.Spec) {
+			host.Generation++
+		}
+	}
+	err := c.Client.Update(ctx, obj, opts...)
+	if err == nil {
+		c.harness.written(kindOf(obj), obj, old, obj)
+	}
+	return err
+}
+
+func (c *countingClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	old := c.current(ctx, obj)
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	if err == nil {
+		c.harness.written(kindOf(obj), obj, old, obj)
+	}
+	return err
+}
+
+func (c *countingClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	err := c.Client.Delete(ctx, obj, opts...)
+	if err == nil {
+		c.harness.written(kindOf(obj), obj, nil, nil)
+	}
+	return err
+}
+
+func (c *countingClient) Status() client.StatusWriter {
+	return &countingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type countingStatusWriter struct {
+	client.StatusWriter
+	client *countingClient
+}
+
+func (w *countingStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	old := w.client.current(ctx, obj)
+	err := w.StatusWriter.Update(ctx, obj, opts...)
+	if err == nil {
+		w.client.harness.written(kindOf(obj)+"/status", obj, old, obj)
+	}
+	return err
+}
+
+func (w *countingStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	old := w.client.current(ctx, obj)
+	err := w.StatusWriter.Patch(ctx, obj, patch, opts...)
+	if err == nil {
+		w.client.harness.written(kindOf(obj)+"/status", obj, old, obj)
+	}
+	return err
+}
+
+// hostKey returns the host reconciled when obj changes: the host
+// itself, or the host owning it.
+func hostKey(obj runtime.Object) (string, bool) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", false
+	}
+	if _, ok := obj.(*metal3v1alpha1.BareMetalHost); ok {
+		return accessor.GetNamespace() + "/" + accessor.GetName(), true
+	}
+	owner := metav1.GetControllerOf(accessor)
+	if owner == nil || owner.Kind != "BareMetalHost" {
+		return "", false
+	}
+	return accessor.GetNamespace() + "/" + owner.Name, true
+}
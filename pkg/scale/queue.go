@@ -0,0 +1,142 @@
+package scale
+
+import (
+	"container/heap"
+	"time"
+)
+
+const (
+	// The per-item backoff of the default controller rate limiter.
+	baseRetryDelay = time.Millisecond * 5
+	maxRetryDelay  = time.Second * 1000
+)
+
+// queue simulates the work queue of a controller in virtual time. Like
+// the real one, a key is only queued once, and a key added while it is
+// being reconciled is queued again when the reconcile is done.
+type queue struct {
+	now time.Duration
+
+	ready      []string
+	queued     map[string]bool
+	processing map[string]bool
+	dirty      map[string]bool
+
+	delayed  delayHeap
+	waiting  map[string]time.Duration
+	failures map[string]int
+}
+
+func newQueue() *queue {
+	return &queue{
+		queued:     make(map[string]bool),
+		processing: make(map[string]bool),
+		dirty:      make(map[string]bool),
+		waiting:    make(map[string]time.Duration),
+		failures:   make(map[string]int),
+	}
+}
+
+// len returns the number of keys waiting for a worker.
+func (q *queue) len() int {
+	return len(q.ready)
+}
+
+func (q *queue) add(key string) {
+	if q.processing[key] {
+		q.dirty[key] = true
+		return
+	}
+	if q.queued[key] {
+		return
+	}
+	q.queued[key] = true
+	q.ready = append(q.ready, key)
+}
+
+func (q *queue) addAfter(key string, delay time.Duration) {
+	if delay <= 0 {
+		q.add(key)
+		return
+	}
+	due := q.now + delay
+	if current, ok := q.waiting[key]; ok && current <= due {
+		return
+	}
+	q.waiting[key] = due
+	heap.Push(&q.delayed, delayed{key: key, due: due})
+}
+
+func (q *queue) addRateLimited(key string) {
+	delay := baseRetryDelay << uint(q.failures[key])
+	if delay > maxRetryDelay || delay <= 0 {
+		delay = maxRetryDelay
+	}
+	q.failures[key]++
+	q.addAfter(key, delay)
+}
+
+func (q *queue) forget(key string) {
+	delete(q.failures, key)
+}
+
+// get hands the next key to a worker.
+func (q *queue) get() string {
+	key := q.ready[0]
+	q.ready = q.ready[1:]
+	delete(q.queued, key)
+	q.processing[key] = true
+	return key
+}
+
+// done marks the reconcile of the key as finished.
+func (q *queue) done(key string) {
+	delete(q.processing, key)
+	if q.dirty[key] {
+		delete(q.dirty, key)
+		q.add(key)
+	}
+}
+
+// promote queues the delayed keys that are due.
+func (q *queue) promote() {
+	for q.delayed.Len() > 0 && q.delayed[0].due <= q.now {
+		item := heap.Pop(&q.delayed).(delayed)
+		if q.waiting[item.key] != item.due {
+			// superseded by an earlier delay
+			continue
+		}
+		delete(q.waiting, item.key)
+		q.add(item.key)
+	}
+}
+
+// nextDue returns when the next delayed key is due.
+func (q *queue) nextDue() (time.Duration, bool) {
+	for q.delayed.Len() > 0 {
+		item := q.delayed[0]
+		if q.waiting[item.key] == item.due {
+			return item.due, true
+		}
+		heap.Pop(&q.delayed)
+	}
+	return 0, false
+}
+
+type delayed struct {
+	key string
+	due time.Duration
+}
+
+type delayHeap []delayed
+
+func (h delayHeap) Len() int            { return len(h) }
+func (h delayHeap) Less(i, j int) bool  { return h[i].due < h[j].due }
+func (h delayHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *delayHeap) Push(x interface{}) { *h = append(*h, x.(delayed)) }
+func (h *delayHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,470 @@
+// Package scale runs the BareMetalHost controller against a large
+// number of simulated hosts, to measure how it behaves at scale.
+//
+// The reconciler is the real one, working on a fake API client and
+// backed by the fixture provisioner or by the ironic provisioner
+// talking to a simulated Ironic. The work queue of the controller is
+// simulated in virtual time: each reconcile takes a fixed amount of
+// virtual time on one of a limited number of workers, and requeue
+// delays are waited for in virtual time, so hours of the life of a
+// cluster take seconds to run.
+package scale
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	controllers "github.com/metal3-io/baremetal-operator/controllers/metal3.io"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/fakeironic"
+)
+
+// The provisioners the hosts can be backed by.
+const (
+	BackendFixture    = "fixture"
+	BackendFakeIronic = "fake-ironic"
+)
+
+const namespace = "scale"
+
+// Config describes a scale test run.
+type Config struct {
+	// Hosts is the number of hosts created.
+	Hosts int
+	// Concurrency is the number of hosts reconciled at the same time.
+	Concurrency int
+	// Backend is the provisioner backing the hosts.
+	Backend string
+	// ReconcileTime is the virtual time taken by each reconcile.
+	ReconcileTime time.Duration
+	// Soak is how long the run goes on once all the hosts are
+	// provisioned, to measure the steady state.
+	Soak time.Duration
+	// Timeout is the virtual time after which the run stops, even if
+	// some hosts are not provisioned.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the settings of a run that is quick enough to
+// be part of a development cycle.
+func DefaultConfig() Config {
+	return Config{
+		Hosts:         1000,
+		Concurrency:   3,
+		Backend:       BackendFixture,
+		ReconcileTime: time.Millisecond * 100,
+		Soak:          time.Minute * 30,
+		Timeout:       time.Hour * 24,
+	}
+}
+
+// Percentiles summarizes a distribution of durations.
+type Percentiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+func percentiles(values []time.Duration) (p Percentiles) {
+	if len(values) == 0 {
+		return
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	at := func(q float64) time.Duration {
+		return values[int(q*float64(len(values)-1))]
+	}
+	return Percentiles{P50: at(0.5), P90: at(0.9), P99: at(0.99), Max: values[len(values)-1]}
+}
+
+// Result holds the measurements of a run. It can be saved to be used
+// as the baseline of later runs.
+type Result struct {
+	Config Config `json:"config"`
+
+	// Provisioned is the number of hosts that reached the provisioned
+	// state.
+	Provisioned int `json:"provisioned"`
+	// TimeToProvisioned is the virtual time it took hosts to be
+	// provisioned, from the start of the run.
+	TimeToProvisioned Percentiles `json:"timeToProvisioned"`
+	// VirtualTime is the length of the run in virtual time.
+	VirtualTime time.Duration `json:"virtualTime"`
+	// WallTime is the real time spent in the reconciler.
+	WallTime time.Duration `json:"wallTime"`
+
+	// Reconciles is the number of reconciles run.
+	Reconciles int `json:"reconciles"`
+	// ReconcilesPerSecond is the throughput of the reconciler in real
+	// time.
+	ReconcilesPerSecond float64 `json:"reconcilesPerSecond"`
+	// ReconcilesPerHost is the mean number of reconciles of a host.
+	ReconcilesPerHost float64 `json:"reconcilesPerHost"`
+	// MaxReconcilesPerHost is the number of reconciles of the host
+	// reconciled the most.
+	MaxReconcilesPerHost int `json:"maxReconcilesPerHost"`
+	// SteadyReconcilesPerHostHour is the rate of reconciles of a host
+	// once all the hosts are provisioned.
+	SteadyReconcilesPerHostHour float64 `json:"steadyReconcilesPerHostHour"`
+	// Errors is the number of reconciles that returned an error.
+	Errors int `json:"errors"`
+
+	// MaxQueueDepth is the highest number of hosts waiting for a
+	// worker.
+	MaxQueueDepth int `json:"maxQueueDepth"`
+	// MeanQueueDepth is the number of hosts waiting for a worker,
+	// averaged over virtual time.
+	MeanQueueDepth float64 `json:"meanQueueDepth"`
+
+	// Writes counts the writes to the API server by kind of object.
+	// Status updates are counted separately from other updates.
+	Writes map[string]int `json:"writes"`
+	// WritesPerHost is the mean number of writes made for a host.
+	WritesPerHost float64 `json:"writesPerHost"`
+}
+
+// completion is a reconcile that ends at a point in virtual time.
+type completion struct {
+	at     time.Duration
+	key    string
+	result reconcile.Result
+	err    error
+}
+
+type completionHeap []completion
+
+func (h completionHeap) Len() int            { return len(h) }
+func (h completionHeap) Less(i, j int) bool  { return h[i].at < h[j].at }
+func (h completionHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *completionHeap) Push(x interface{}) { *h = append(*h, x.(completion)) }
+func (h *completionHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+type harness struct {
+	config     Config
+	client     client.Client
+	reconciler *controllers.BareMetalHostReconciler
+	filter     predicate.Predicate
+	queue      *queue
+
+	running     completionHeap
+	reconciles  map[string]int
+	writes      map[string]int
+	hostWrites  map[string]int
+	provisioned map[string]time.Duration
+	errors      int
+	wallTime    time.Duration
+
+	steadySince      time.Duration
+	steadyReconciles int
+}
+
+// written records a write and queues the host it concerns, if the
+// controller would be told about it.
+func (h *harness) written(kind string, obj, old, new runtime.Object) {
+	h.writes[kind]++
+
+	if ev, ok := obj.(*corev1.Event); ok {
+		h.hostWrites[ev.InvolvedObject.Namespace+"/"+ev.InvolvedObject.Name]++
+		return
+	}
+	key, ok := hostKey(obj)
+	if !ok {
+		return
+	}
+	h.hostWrites[key]++
+
+	if old == nil || new == nil {
+		// creations and deletions are not made by the reconciler
+		h.queue.add(key)
+		return
+	}
+	oldMeta, err := meta.Accessor(old)
+	if err != nil {
+		return
+	}
+	newMeta, err := meta.Accessor(new)
+	if err != nil {
+		return
+	}
+	if h.filter.Update(event.UpdateEvent{
+		MetaOld:   oldMeta,
+		ObjectOld: old,
+		MetaNew:   newMeta,
+		ObjectNew: new,
+	}) {
+		h.queue.add(key)
+	}
+}
+
+func newHost(i int) (*metal3v1alpha1.BareMetalHost, *corev1.Secret) {
+	name := fmt.Sprintf("host-%04d", i)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-bmc-secret",
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"username": []byte("admin"),
+			"password": []byte("password"),
+		},
+	}
+	host := &metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  namespace,
+			Generation: 1,
+		},
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			Online: true,
+			BMC: metal3v1alpha1.BMCDetails{
+				Address:         fmt.Sprintf("ipmi://10.%d.%d.%d", (i>>16)&0xff, (i>>8)&0xff, i&0xff),
+				CredentialsName: secret.Name,
+			},
+			BootMACAddress: fmt.Sprintf("52:54:00:%02x:%02x:%02x", (i>>16)&0xff, (i>>8)&0xff, i&0xff),
+			Image: &metal3v1alpha1.Image{
+				URL:      "http://images.scale.test/image.qcow2",
+				Checksum: "http://images.scale.test/image.qcow2.md5sum",
+			},
+		},
+	}
+	return host, secret
+}
+
+// provisionerFactory returns the factory of the provisioner backing
+// the hosts, and a function to stop it.
+func provisionerFactory(backend string) (provisioner.Factory, func(), error) {
+	switch backend {
+	case BackendFixture:
+		fixtures := &fixture.Fixtures{}
+		return fixtures.New, func() {}, nil
+	case BackendFakeIronic:
+		sim := fakeironic.New().Start()
+		config := ironic.DefaultConfig()
+		config.IronicEndpoint = sim.IronicEndpoint()
+		config.InspectorEndpoint = sim.InspectorEndpoint()
+		config.DeployKernelURL = "http://images.scale.test/ironic-python-agent.kernel"
+		config.DeployRamdiskURL = "http://images.scale.test/ironic-python-agent.initramfs"
+		if err := ironic.Configure(config); err != nil {
+			sim.Stop()
+			return nil, nil, err
+		}
+		return ironic.New, sim.Stop, nil
+	}
+	return nil, nil, fmt.Errorf("unknown backend %q, use %s or %s", backend, BackendFixture, BackendFakeIronic)
+}
+
+// Run creates the hosts and reconciles them until they are all
+// provisioned and the soak time has passed, or the run times out.
+func Run(config Config) (*Result, error) {
+	if config.Hosts <= 0 || config.Concurrency <= 0 {
+		return nil, errors.New("the number of hosts and the concurrency must be positive")
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := metal3v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	factory, stop, err := provisionerFactory(config.Backend)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	h := &harness{
+		config:      config,
+		queue:       newQueue(),
+		reconciles:  make(map[string]int),
+		writes:      make(map[string]int),
+		hostWrites:  make(map[string]int),
+		provisioned: make(map[string]time.Duration),
+	}
+	h.client = &countingClient{
+		Client:  fakeclient.NewFakeClientWithScheme(scheme),
+		harness: h,
+	}
+	h.reconciler = &controllers.BareMetalHostReconciler{
+		Client:             h.client,
+		Scheme:             scheme,
+		ProvisionerFactory: factory,
+		Log:                ctrl.Log.WithName("controllers").WithName("BareMetalHost"),
+	}
+	h.filter = h.reconciler.EventFilter()
+
+	for i := 0; i < config.Hosts; i++ {
+		host, secret := newHost(i)
+		if err := h.client.Create(context.TODO(), secret); err != nil {
+			return nil, errors.Wrap(err, "could not create the BMC secret")
+		}
+		if err := h.client.Create(context.TODO(), host); err != nil {
+			return nil, errors.Wrap(err, "could not create the host")
+		}
+	}
+	// The creations are not made by the controller.
+	h.writes = make(map[string]int)
+	h.hostWrites = make(map[string]int)
+
+	return h.run(), nil
+}
+
+func (h *harness) run() *Result {
+	q := h.queue
+	maxDepth := 0
+	var depthTime float64
+	h.steadySince = -1
+
+	for {
+		for h.running.Len() > 0 && h.running[0].at <= q.now {
+			h.finish(heap.Pop(&h.running).(completion))
+		}
+		q.promote()
+		for h.running.Len() < h.config.Concurrency && q.len() > 0 {
+			h.start(q.get())
+		}
+
+		if h.steadySince < 0 && len(h.provisioned) == h.config.Hosts {
+			h.steadySince = q.now
+		}
+		if h.steadySince >= 0 && q.now >= h.steadySince+h.config.Soak {
+			break
+		}
+		if q.now >= h.config.Timeout {
+			break
+		}
+
+		next, ok := q.nextDue()
+		if h.running.Len() > 0 && (!ok || h.running[0].at < next) {
+			next, ok = h.running[0].at, true
+		}
+		if !ok {
+			break
+		}
+		if q.len() > maxDepth {
+			maxDepth = q.len()
+		}
+		depthTime += float64(q.len()) * float64(next-q.now)
+		q.now = next
+	}
+
+	return h.result(maxDepth, depthTime)
+}
+
+// start runs the reconcile of the host, which ends after the
+// reconcile time.
+func (h *harness) start(key string) {
+	parts := strings.SplitN(key, "/", 2)
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: parts[0], Name: parts[1]}}
+
+	begin := time.Now()
+	result, err := h.reconciler.Reconcile(request)
+	h.wallTime += time.Since(begin)
+
+	h.reconciles[key]++
+	if h.steadySince >= 0 {
+		h.steadyReconciles++
+	}
+	if err != nil {
+		h.errors++
+	}
+
+	end := h.queue.now + h.config.ReconcileTime
+	if _, done := h.provisioned[key]; !done {
+		host := &metal3v1alpha1.BareMetalHost{}
+		if h.client.Get(context.TODO(), request.NamespacedName, host) == nil &&
+			host.Status.Provisioning.State == metal3v1alpha1.StateProvisioned {
+			h.provisioned[key] = end
+		}
+	}
+	heap.Push(&h.running, completion{at: end, key: key, result: result, err: err})
+}
+
+// finish requeues the host the way the controller does with the
+// result of its reconcile.
+func (h *harness) finish(c completion) {
+	q := h.queue
+	switch {
+	case c.err != nil:
+		q.addRateLimited(c.key)
+	case c.result.RequeueAfter > 0:
+		q.forget(c.key)
+		q.addAfter(c.key, c.result.RequeueAfter)
+	case c.result.Requeue:
+		q.addRateLimited(c.key)
+	default:
+		q.forget(c.key)
+	}
+	q.done(c.key)
+}
+
+func (h *harness) result(maxDepth int, depthTime float64) *Result {
+	hosts := float64(h.config.Hosts)
+	result := &Result{
+		Config:        h.config,
+		Provisioned:   len(h.provisioned),
+		VirtualTime:   h.queue.now,
+		WallTime:      h.wallTime,
+		Errors:        h.errors,
+		MaxQueueDepth: maxDepth,
+		Writes:        h.writes,
+	}
+
+	var times []time.Duration
+	for _, at := range h.provisioned {
+		times = append(times, at)
+	}
+	result.TimeToProvisioned = percentiles(times)
+
+	for _, count := range h.reconciles {
+		result.Reconciles += count
+		if count > result.MaxReconcilesPerHost {
+			result.MaxReconcilesPerHost = count
+		}
+	}
+	result.ReconcilesPerHost = float64(result.Reconciles) / hosts
+	if h.wallTime > 0 {
+		result.ReconcilesPerSecond = float64(result.Reconciles) / h.wallTime.Seconds()
+	}
+	if h.steadySince >= 0 && h.queue.now > h.steadySince {
+		hours := (h.queue.now - h.steadySince).Hours()
+		result.SteadyReconcilesPerHostHour = float64(h.steadyReconciles) / hosts / hours
+	}
+	if h.queue.now > 0 {
+		result.MeanQueueDepth = depthTime / float64(h.queue.now)
+	}
+
+	writes := 0
+	for _, count := range h.hostWrites {
+		writes += count
+	}
+	result.WritesPerHost = float64(writes) / hosts
+	return result
+}
@@ -0,0 +1,129 @@
+package scale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+	q := newQueue()
+
+	q.add("a")
+	q.add("b")
+	q.add("a")
+	assert.Equal(t, 2, q.len())
+
+	key := q.get()
+	assert.Equal(t, "a", key)
+	// Adding a key being reconciled queues it again once it is done.
+	q.add("a")
+	assert.Equal(t, 1, q.len())
+	q.done("a")
+	assert.Equal(t, 2, q.len())
+
+	q.get()
+	q.get()
+	q.done("a")
+	q.done("b")
+	q.addAfter("a", time.Minute)
+	q.addAfter("a", time.Second)
+	due, ok := q.nextDue()
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, due)
+
+	q.now = time.Second
+	q.promote()
+	assert.Equal(t, 1, q.len())
+	q.now = time.Minute
+	q.promote()
+	assert.Equal(t, 1, q.len())
+	_, ok = q.nextDue()
+	assert.False(t, ok)
+}
+
+func TestQueueRateLimited(t *testing.T) {
+	q := newQueue()
+	q.addRateLimited("a")
+	q.addRateLimited("a")
+	q.addRateLimited("a")
+	due, _ := q.nextDue()
+	assert.Equal(t, baseRetryDelay, due)
+
+	q.forget("a")
+	q.now = due
+	q.promote()
+	q.get()
+	q.done("a")
+	q.addRateLimited("a")
+	due, _ = q.nextDue()
+	assert.Equal(t, q.now+baseRetryDelay, due)
+}
+
+func testConfig(backend string, hosts int) Config {
+	config := DefaultConfig()
+	config.Backend = backend
+	config.Hosts = hosts
+	config.Soak = time.Minute * 10
+	return config
+}
+
+func TestRunFixture(t *testing.T) {
+	result, err := Run(testConfig(BackendFixture, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 20, result.Provisioned)
+	assert.Equal(t, 0, result.Errors)
+	assert.True(t, result.TimeToProvisioned.P50 > 0)
+	assert.True(t, result.TimeToProvisioned.P50 <= result.TimeToProvisioned.Max)
+	assert.True(t, result.VirtualTime >= result.TimeToProvisioned.Max+time.Minute*10)
+	assert.True(t, result.MaxQueueDepth > 0)
+	assert.True(t, result.WritesPerHost > 0)
+	assert.Equal(t, 20, result.Writes["Secret"], "each BMC secret is adopted once")
+	// Provisioned hosts only have their power checked every minute.
+	assert.InDelta(t, 60, result.SteadyReconcilesPerHostHour, 10)
+}
+
+func TestRunFakeIronic(t *testing.T) {
+	result, err := Run(testConfig(BackendFakeIronic, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, result.Provisioned)
+	assert.Equal(t, 0, result.Errors)
+}
+
+func TestRunUnknownBackend(t *testing.T) {
+	_, err := Run(testConfig("magic", 1))
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &Result{
+		Config:              DefaultConfig(),
+		Provisioned:         1000,
+		ReconcilesPerSecond: 1000,
+		ReconcilesPerHost:   40,
+		WritesPerHost:       16,
+		TimeToProvisioned:   Percentiles{P50: time.Minute, P90: time.Minute * 2, Max: time.Minute * 3},
+	}
+
+	same := *baseline
+	same.ReconcilesPerHost = 42
+	assert.Empty(t, Compare(baseline, &same, 0.1))
+
+	worse := *baseline
+	worse.ReconcilesPerHost = 80
+	worse.ReconcilesPerSecond = 500
+	assert.Equal(t, []string{
+		"reconciles per second went from 1000.00 to 500.00",
+		"reconciles per host went from 40.00 to 80.00",
+	}, Compare(baseline, &worse, 0.1))
+
+	other := *baseline
+	other.Config.Hosts = 10
+	assert.Len(t, Compare(baseline, &other, 0.1), 1)
+}